@@ -0,0 +1,434 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+type arcItem struct {
+	// The cached data.
+	value interface{}
+
+	// A reference to the corresponding element in either t1 or t2.
+	node *list.Element
+
+	// Indication if the item was accessed more than once, meaning it is in t2.
+	frequent bool
+}
+
+type arcCache struct {
+	// The maximal amount of cached items.
+	capacity int
+
+	// The target size of t1, adapted according to hits in the ghost lists.
+	p int
+
+	// A cache that holds the data.
+	storage Cache
+
+	// Keys that were accessed once, from the most recently used to the least
+	// recently used.
+	t1 *list.List
+
+	// Keys that were accessed more than once, from the most recently used to
+	// the least recently used.
+	t2 *list.List
+
+	// Ghost lists holding keys that were recently evicted from t1 and t2.
+	b1 *list.List
+	b2 *list.List
+
+	// Allow finding the elements of the ghost lists by key.
+	b1Nodes map[interface{}]*list.Element
+	b2Nodes map[interface{}]*list.Element
+
+	mutex sync.Mutex
+}
+
+var _ Cache = (*arcCache)(nil)
+
+// NewArcCache creates a new Adaptive Replacement Cache instance using mapCache,
+// capacity must be greater than zero.
+func NewArcCache(capacity int) (Cache, error) {
+	if capacity < 1 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
+	return &arcCache{
+		capacity: capacity,
+		storage:  NewMapCache(),
+		t1:       list.New(),
+		t2:       list.New(),
+		b1:       list.New(),
+		b2:       list.New(),
+		b1Nodes:  map[interface{}]*list.Element{},
+		b2Nodes:  map[interface{}]*list.Element{},
+	}, nil
+}
+
+// Store caches a new value.
+func (arc *arcCache) Store(key, val interface{}) error {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.store(key, val)
+}
+
+func (arc *arcCache) store(key, val interface{}) error {
+	exists, err := arc.storage.Contains(key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	if node, inB1 := arc.b1Nodes[key]; inB1 {
+		// The key was evicted from t1 too early, favour recency.
+		arc.p = minInt(arc.capacity, arc.p+maxInt(arc.b2.Len()/arc.b1.Len(), 1))
+		arc.b1.Remove(node)
+		delete(arc.b1Nodes, key)
+
+		err := arc.replaceIfFull(false)
+		if err != nil {
+			return err
+		}
+
+		return arc.insert(key, val, true)
+	}
+
+	if node, inB2 := arc.b2Nodes[key]; inB2 {
+		// The key was evicted from t2 too early, favour frequency.
+		arc.p = maxInt(0, arc.p-maxInt(arc.b1.Len()/arc.b2.Len(), 1))
+		arc.b2.Remove(node)
+		delete(arc.b2Nodes, key)
+
+		err := arc.replaceIfFull(true)
+		if err != nil {
+			return err
+		}
+
+		return arc.insert(key, val, true)
+	}
+
+	l1 := arc.t1.Len() + arc.b1.Len()
+	total := l1 + arc.t2.Len() + arc.b2.Len()
+
+	if l1 >= arc.capacity {
+		if arc.t1.Len() < arc.capacity {
+			arc.removeGhost(arc.b1, arc.b1Nodes)
+
+			err := arc.replaceIfFull(false)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := arc.removeLive(arc.t1.Back().Value)
+			if err != nil {
+				return err
+			}
+		}
+	} else if total >= arc.capacity {
+		if total >= 2*arc.capacity {
+			arc.removeGhost(arc.b2, arc.b2Nodes)
+		}
+
+		err := arc.replaceIfFull(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return arc.insert(key, val, false)
+}
+
+func (arc *arcCache) insert(key, val interface{}, frequent bool) error {
+	item := &arcItem{value: val, frequent: frequent}
+	if frequent {
+		item.node = arc.t2.PushFront(key)
+	} else {
+		item.node = arc.t1.PushFront(key)
+	}
+
+	err := arc.storage.Store(key, item)
+	if err != nil {
+		arc.listOf(item).Remove(item.node)
+		return err
+	}
+
+	return nil
+}
+
+// Moves the least recently used key of either t1 or t2 to its ghost list,
+// if the cache is full.
+func (arc *arcCache) replaceIfFull(inB2 bool) error {
+	if arc.t1.Len()+arc.t2.Len() < arc.capacity {
+		return nil
+	}
+
+	if arc.t1.Len() > 0 &&
+		(arc.t1.Len() > arc.p || (inB2 && arc.t1.Len() == arc.p)) {
+		key := arc.t1.Back().Value
+		err := arc.removeLive(key)
+		if err != nil {
+			return err
+		}
+
+		arc.b1Nodes[key] = arc.b1.PushFront(key)
+	} else {
+		key := arc.t2.Back().Value
+		err := arc.removeLive(key)
+		if err != nil {
+			return err
+		}
+
+		arc.b2Nodes[key] = arc.b2.PushFront(key)
+	}
+
+	return nil
+}
+
+func (arc *arcCache) removeGhost(ghost *list.List, nodes map[interface{}]*list.Element) {
+	node := ghost.Back()
+	if node == nil {
+		return
+	}
+
+	ghost.Remove(node)
+	delete(nodes, node.Value)
+}
+
+func (arc *arcCache) removeLive(key interface{}) error {
+	item, err := arc.storage.Get(key)
+	if err != nil {
+		return err
+	}
+
+	err = arc.storage.Remove(key)
+	if err != nil {
+		return err
+	}
+
+	arcItem := item.(*arcItem)
+	arc.listOf(arcItem).Remove(arcItem.node)
+
+	return nil
+}
+
+func (arc *arcCache) listOf(item *arcItem) *list.List {
+	if item.frequent {
+		return arc.t2
+	}
+
+	return arc.t1
+}
+
+// Get a cached value, a value that was accessed more than once moves to t2.
+func (arc *arcCache) Get(key interface{}) (interface{}, error) {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.get(key)
+}
+
+func (arc *arcCache) get(key interface{}) (interface{}, error) {
+	item, err := arc.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	arcItem := item.(*arcItem)
+	if arcItem.frequent {
+		arc.t2.MoveToFront(arcItem.node)
+	} else {
+		arc.t1.Remove(arcItem.node)
+		arcItem.node = arc.t2.PushFront(key)
+		arcItem.frequent = true
+	}
+
+	return arcItem.value, nil
+}
+
+// Check whether a key is cached.
+func (arc *arcCache) Contains(key interface{}) (bool, error) {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.storage.Contains(key)
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (arc *arcCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.getOrStore(key, val)
+}
+
+func (arc *arcCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	actual, err := arc.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = arc.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a cached value.
+func (arc *arcCache) Remove(key interface{}) error {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.removeLive(key)
+}
+
+// Get a cached value and remove it.
+func (arc *arcCache) GetAndRemove(key interface{}) (interface{}, error) {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.getAndRemove(key)
+}
+
+func (arc *arcCache) getAndRemove(key interface{}) (interface{}, error) {
+	item, err := arc.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = arc.removeLive(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*arcItem).value, nil
+}
+
+// Replace a cached value, the value keeps its position.
+func (arc *arcCache) Replace(key, val interface{}) error {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.replace(key, val)
+}
+
+func (arc *arcCache) replace(key, val interface{}) error {
+	item, err := arc.storage.Get(key)
+	if err != nil {
+		return err
+	}
+
+	item.(*arcItem).value = val
+
+	return nil
+}
+
+// Cache a value, replacing the current value of the key if it exists, in
+// which case it keeps its place in the lists.
+func (arc *arcCache) StoreOrReplace(key, val interface{}) error {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	err := arc.replace(key, val)
+	if IsDoesNotExist(err) {
+		return arc.store(key, val)
+	}
+
+	return err
+}
+
+// Store several values.
+func (arc *arcCache) StoreMany(items map[interface{}]interface{}) error {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return storeMany(items, arc.store)
+}
+
+// Get several cached values.
+func (arc *arcCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return getMany(keys, arc.get)
+}
+
+// Clear all values and ghost entries.
+func (arc *arcCache) Clear() error {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.clear()
+}
+
+func (arc *arcCache) clear() error {
+	err := arc.storage.Clear()
+	if err != nil {
+		return err
+	}
+
+	arc.t1.Init()
+	arc.t2.Init()
+	arc.b1.Init()
+	arc.b2.Init()
+	arc.b1Nodes = map[interface{}]*list.Element{}
+	arc.b2Nodes = map[interface{}]*list.Element{}
+	arc.p = 0
+
+	return nil
+}
+
+// Get all keys.
+func (arc *arcCache) Keys() ([]interface{}, error) {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.storage.Keys()
+}
+
+// Calls fn for each cached key and value until fn returns false, ghost
+// entries are skipped.
+func (arc *arcCache) ForEach(fn func(key, val interface{}) bool) error {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.storage.ForEach(func(key, item interface{}) bool {
+		return fn(key, item.(*arcItem).value)
+	})
+}
+
+// Get the keys for which predicate returns true.
+func (arc *arcCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	arc.mutex.Lock()
+	defer arc.mutex.Unlock()
+
+	return arc.storage.FilterKeys(predicate)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
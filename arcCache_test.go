@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const ARCCacheSize = 3
+
+var _ = Describe("ARC Cache", func() {
+	var (
+		c            Cache
+		keys, values []string
+	)
+
+	for i := 0; i < ARCCacheSize+1; i++ {
+		keys = append(keys, fmt.Sprintf("test-key-%v", i))
+		values = append(values, fmt.Sprintf("test-value-%v", i))
+	}
+
+	BeforeEach(func() {
+		var err error
+		c, err = NewArcCache(ARCCacheSize)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("Store", func() {
+		It("should store a value", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value")
+			Expect(c.Get(keys[0])).To(Equal(values[0]))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(keys[0], values[0]))).To(BeTrue())
+		})
+
+		It("should not exceed its capacity", func() {
+			for i := 0; i < 10*ARCCacheSize; i++ {
+				Expect(c.Store(fmt.Sprintf("key-%d", i), i)).ToNot(HaveOccurred())
+			}
+
+			Expect(c.Keys()).To(HaveLen(ARCCacheSize))
+		})
+
+		It("should return an error for a non-positive capacity", func() {
+			_, err := NewArcCache(0)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+			_, err = NewArcCache(-1)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+
+		It("should evict a value that was accessed once before a value that was accessed twice", func() {
+			for i := 0; i < ARCCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			// Move keys[0] to the frequent list.
+			Expect(c.Get(keys[0])).To(Equal(values[0]))
+
+			Expect(c.Store(keys[ARCCacheSize], values[ARCCacheSize])).ToNot(HaveOccurred())
+			Expect(c.Contains(keys[0])).To(BeTrue(), "frequent value was evicted")
+			Expect(c.Contains(keys[1])).To(BeFalse(), "least recent value was not evicted")
+		})
+
+		It("should store a value that was recently evicted in the frequent list", func() {
+			for i := 0; i < ARCCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			// Move keys[0] to the frequent list, so storing a new value moves
+			// keys[1] to the ghost list.
+			Expect(c.Get(keys[0])).To(Equal(values[0]))
+			Expect(c.Store(keys[ARCCacheSize], values[ARCCacheSize])).ToNot(HaveOccurred())
+			Expect(c.Contains(keys[1])).To(BeFalse())
+
+			// Storing a ghost key again should put it in the frequent list and
+			// evict from the recent list instead.
+			Expect(c.Store(keys[1], values[1])).ToNot(HaveOccurred())
+			Expect(c.Contains(keys[0])).To(BeTrue())
+			Expect(c.Contains(keys[1])).To(BeTrue())
+			Expect(c.Contains(keys[2])).To(BeFalse())
+		})
+	})
+
+	Context("Get", func() {
+		It("should return an error when accessing a key that does not exist", func() {
+			_, err := c.Get("non-existent-key")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(c.Remove(keys[0])).ToNot(HaveOccurred())
+			Expect(c.Contains(keys[0])).To(BeFalse())
+		})
+
+		It("should return an error when attempting to remove a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Remove(keys[0]))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a value", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(c.Replace(keys[0], values[1])).ToNot(HaveOccurred())
+			Expect(c.Get(keys[0])).To(Equal(values[1]))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove all values", func() {
+			for i := 0; i < ARCCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(BeEmpty())
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+		})
+	})
+})
+
+func benchmarkZipfHitRate(b *testing.B, c Cache) {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, 10000)
+
+	hits := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := zipf.Uint64()
+		if _, err := c.Get(key); err == nil {
+			hits++
+		} else if err := c.Store(key, key); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+}
+
+func BenchmarkArcCacheZipf(b *testing.B) {
+	c, err := NewArcCache(100)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkZipfHitRate(b, c)
+}
+
+func BenchmarkLruCacheZipf(b *testing.B) {
+	c, err := NewLru(100)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkZipfHitRate(b, c)
+}
+
+func BenchmarkLfuCacheZipf(b *testing.B) {
+	c, err := NewLfu(100)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkZipfHitRate(b, c)
+}
@@ -0,0 +1,1054 @@
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+type boltCache struct {
+	// The database that holds the values and their deadlines.
+	db *bbolt.DB
+
+	// The bucket that holds the encoded values.
+	bucket []byte
+
+	// The bucket that holds the deadlines of temporary values.
+	ttlBucket []byte
+
+	// Holds the channels that stop the auto removal routines.
+	removeChannels map[string]*cacheChannel
+
+	// Holds the channels that stop the auto update routines.
+	updateChannels map[string]*cacheChannel
+
+	// Holds the update functions of the auto update routines.
+	updateFuncs map[string]func(currValue interface{}) (interface{}, error)
+
+	// Holds the ttls of values with a sliding expiration.
+	slidingTTLs map[string]time.Duration
+
+	// Holds the types of stored values to allow decoding them, values that
+	// were stored by a previous process are decoded into an interface{}.
+	valueTypes map[string]reflect.Type
+
+	// Encodes values to and from the database.
+	encoding Encoding
+
+	// Called with the errors of the auto update routines.
+	onUpdateError func(key string, err error)
+
+	mutex sync.Mutex
+}
+
+var _ UpdatingExpiringCache = (*boltCache)(nil)
+
+// Create a new Cache object that is backed up by a bbolt database, all values
+// are stored in a single bucket and their deadlines in a separate one.
+//
+// If the database already holds values, expired values are removed and the
+// others expire at their original deadlines.
+func NewBoltCache(dbPath, bucket string) (*boltCache, error) {
+	db, err := bbolt.Open(dbPath, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	bc := &boltCache{
+		db:             db,
+		bucket:         []byte(bucket),
+		ttlBucket:      []byte(bucket + "-ttl"),
+		removeChannels: map[string]*cacheChannel{},
+		updateChannels: map[string]*cacheChannel{},
+		updateFuncs:    map[string]func(currValue interface{}) (interface{}, error){},
+		slidingTTLs:    map[string]time.Duration{},
+		valueTypes:     map[string]reflect.Type{},
+		encoding:       JSONEncoding{},
+	}
+
+	err = bc.createBuckets()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	err = bc.restoreExpirations()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return bc, nil
+}
+
+func (bc *boltCache) createBuckets() error {
+	return bc.db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bc.bucket)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(bc.ttlBucket)
+		return err
+	})
+}
+
+// Remove expired values and recreate the expiration routines of the others.
+func (bc *boltCache) restoreExpirations() error {
+	deadlines := map[string]time.Time{}
+
+	err := bc.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bc.ttlBucket).ForEach(func(k, v []byte) error {
+			deadlines[string(k)] = decodeDeadline(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, deadline := range deadlines {
+		ttl := time.Until(deadline)
+		if ttl <= 0 {
+			err = bc.deleteEntry(key)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		bc.startExpirationRoutine(key, ttl)
+	}
+
+	return nil
+}
+
+// Close the underlying database, the cache should not be used afterwards.
+func (bc *boltCache) Close() error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	bc.stopRoutines()
+
+	return bc.db.Close()
+}
+
+// Store a permanent value in the cache.
+func (bc *boltCache) Store(key, val interface{}) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return bc.store(key, val)
+}
+
+func (bc *boltCache) store(key, val interface{}) error {
+	if err := bc.verifyInputs(key, val); err != nil {
+		return err
+	}
+
+	strKey := key.(string)
+
+	data, err := bc.encoding.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	err = bc.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bc.bucket)
+		if b.Get([]byte(strKey)) != nil {
+			return newError(errorTypeAlreadyExists,
+				fmt.Sprintf("key [%s] already exists", strKey))
+		}
+
+		return b.Put([]byte(strKey), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	bc.valueTypes[strKey] = reflect.TypeOf(val)
+
+	return nil
+}
+
+// Get a value from the cache, resets the expiration of sliding values.
+func (bc *boltCache) Get(key interface{}) (interface{}, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	val, err := bc.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	bc.resetSlidingExpiration(key.(string))
+
+	return val, nil
+}
+
+func (bc *boltCache) get(key interface{}) (interface{}, error) {
+	err := bc.verifyKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	strKey := key.(string)
+
+	var data []byte
+	err = bc.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bc.bucket).Get([]byte(strKey))
+		if v == nil {
+			return newError(errorTypeDoesNotExist,
+				fmt.Sprintf("key [%s] does not exist", strKey))
+		}
+
+		// Values returned by bbolt are only valid during the transaction.
+		data = append([]byte{}, v...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bc.decodeValue(strKey, data)
+}
+
+// Check whether a key exists in the cache without decoding its value.
+func (bc *boltCache) Contains(key interface{}) (bool, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return bc.contains(key)
+}
+
+func (bc *boltCache) contains(key interface{}) (bool, error) {
+	err := bc.verifyKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	exists := false
+	err = bc.db.View(func(tx *bbolt.Tx) error {
+		exists = tx.Bucket(bc.bucket).Get([]byte(key.(string))) != nil
+		return nil
+	})
+
+	return exists, err
+}
+
+// Get a value from the cache, or store val if the key does not exist.
+func (bc *boltCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	actual, err := bc.get(key)
+	if err == nil {
+		bc.resetSlidingExpiration(key.(string))
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = bc.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a value from the cache.
+func (bc *boltCache) Remove(key interface{}) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return bc.remove(key)
+}
+
+func (bc *boltCache) remove(key interface{}) error {
+	exists, err := bc.contains(key)
+	if err != nil {
+		return err
+	}
+
+	strKey := key.(string)
+
+	if !exists {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] does not exist", strKey))
+	}
+
+	err = bc.deleteEntry(strKey)
+	if err != nil {
+		return err
+	}
+
+	c, exists := bc.removeChannels[strKey]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(bc.removeChannels, strKey)
+	}
+
+	c, exists = bc.updateChannels[strKey]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(bc.updateChannels, strKey)
+		delete(bc.updateFuncs, strKey)
+	}
+
+	delete(bc.slidingTTLs, strKey)
+	delete(bc.valueTypes, strKey)
+
+	return nil
+}
+
+// Get a value from the cache and remove it.
+func (bc *boltCache) GetAndRemove(key interface{}) (interface{}, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	val, err := bc.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bc.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Replace a value in the cache with a permanent value.
+func (bc *boltCache) Replace(key, val interface{}) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	err := bc.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return bc.store(key, val)
+}
+
+// Store a permanent value in the cache, replacing the current value of the
+// key if it exists.
+func (bc *boltCache) StoreOrReplace(key, val interface{}) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	err := bc.remove(key)
+	if err != nil && !IsDoesNotExist(err) {
+		return err
+	}
+
+	return bc.store(key, val)
+}
+
+// Store several permanent values in the cache.
+func (bc *boltCache) StoreMany(items map[interface{}]interface{}) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return storeMany(items, bc.store)
+}
+
+// Get several values from the cache.
+func (bc *boltCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return getMany(keys, func(key interface{}) (interface{}, error) {
+		val, err := bc.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		bc.resetSlidingExpiration(key.(string))
+
+		return val, nil
+	})
+}
+
+// Remove all values from the cache, unlike directoryCache the cache remains
+// usable afterwards.
+func (bc *boltCache) Clear() error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	bc.stopRoutines()
+
+	err := bc.db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{bc.bucket, bc.ttlBucket} {
+			err := tx.DeleteBucket(name)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.CreateBucket(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bc.valueTypes = map[string]reflect.Type{}
+
+	return nil
+}
+
+// Get all keys in the cache.
+func (bc *boltCache) Keys() ([]interface{}, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return bc.keys()
+}
+
+func (bc *boltCache) keys() ([]interface{}, error) {
+	keys := []interface{}{}
+
+	err := bc.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bc.bucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Calls fn for each key and value in the cache until fn returns false.
+func (bc *boltCache) ForEach(fn func(key, val interface{}) bool) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	keys, err := bc.keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		val, err := bc.get(key)
+		if err != nil {
+			return err
+		}
+
+		if !fn(key, val) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Get the keys for which predicate returns true.
+func (bc *boltCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	keys, err := bc.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterKeys(keys, predicate), nil
+}
+
+// Stores a temporary value in the cache, ttl must be greater than zero.
+func (bc *boltCache) StoreWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return bc.storeWithExpiration(key, val, ttl)
+}
+
+// Stores a temporary value in the cache that is removed at deadline, deadline
+// must be in the future.
+func (bc *boltCache) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	ttl, err := ttlUntil(deadline)
+	if err != nil {
+		return err
+	}
+
+	return bc.storeWithExpiration(key, val, ttl)
+}
+
+func (bc *boltCache) storeWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	err := bc.store(key, val)
+	if err != nil {
+		return err
+	}
+
+	return bc.createExpirationRoutine(key.(string), ttl)
+}
+
+// Persist the deadline of a key and start its expiration routine.
+func (bc *boltCache) createExpirationRoutine(key string, ttl time.Duration) error {
+	deadline := time.Now().Add(ttl)
+
+	err := bc.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bc.ttlBucket).Put([]byte(key), encodeDeadline(deadline))
+	})
+	if err != nil {
+		return err
+	}
+
+	bc.startExpirationRoutine(key, ttl)
+
+	return nil
+}
+
+func (bc *boltCache) startExpirationRoutine(key string, ttl time.Duration) {
+	c := newCacheChannel()
+	bc.removeChannels[key] = c
+
+	expireSignalerRoutine := func(c *cacheChannel) {
+		<-time.After(ttl)
+		c.signal(proceed)
+	}
+
+	expireRoutine := func(key string, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		}
+
+		bc.mutex.Lock()
+		defer bc.mutex.Unlock()
+
+		// The expiration was reset while waiting for the mutex.
+		if bc.removeChannels[key] != c {
+			return
+		}
+
+		err := bc.remove(key)
+		if err != nil {
+			panic(newWrapperError(errorTypeUnexpectedError,
+				"an unexpected error occurred a background routine", err))
+		}
+	}
+
+	go expireSignalerRoutine(c)
+	go expireRoutine(key, c)
+}
+
+// Get the remaining ttl of a value in the cache.
+func (bc *boltCache) TTL(key interface{}) (time.Duration, bool, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	exists, err := bc.contains(key)
+	if err != nil {
+		return -1, false, err
+	}
+
+	if !exists {
+		return -1, false, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] does not exist", key.(string)))
+	}
+
+	deadline, hasTTL, err := bc.deadline(key.(string))
+	if err != nil || !hasTTL {
+		return -1, false, err
+	}
+
+	return time.Until(deadline), true, nil
+}
+
+// Stores a temporary value in the cache, every Get resets its ttl, ttl must be
+// greater than zero.
+func (bc *boltCache) StoreWithSlidingExpiration(key, val interface{},
+	ttl time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	err := bc.storeWithExpiration(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	bc.slidingTTLs[key.(string)] = ttl
+
+	return nil
+}
+
+func (bc *boltCache) resetSlidingExpiration(key string) {
+	ttl, isSliding := bc.slidingTTLs[key]
+	if !isSliding {
+		return
+	}
+
+	c, exists := bc.removeChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+	}
+
+	// Failing to persist the new deadline only shortens the ttl after a
+	// restart, the value still expires at the new deadline in this process.
+	err := bc.createExpirationRoutine(key, ttl)
+	if err != nil {
+		bc.startExpirationRoutine(key, ttl)
+	}
+}
+
+// Replaces a value in the cache with a temporary one, ttl must be greater than
+// zero.
+func (bc *boltCache) ReplaceWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	err := bc.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return bc.storeWithExpiration(key, val, ttl)
+}
+
+// Expire a value in the cache, ttl must be greater than zero.
+func (bc *boltCache) Expire(key interface{}, ttl time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	val, err := bc.get(key)
+	if err != nil {
+		return err
+	}
+
+	err = bc.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return bc.storeWithExpiration(key, val, ttl)
+}
+
+// Stores an updating value in the cache, period must be greater than zero.
+func (bc *boltCache) StoreWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return bc.storeWithUpdate(key, initialValue, updateFunc, period)
+}
+
+func (bc *boltCache) storeWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	err = bc.store(key, initialValue)
+	if err != nil {
+		return err
+	}
+
+	keyStr := key.(string)
+	c := newCacheChannel()
+	bc.updateChannels[keyStr] = c
+	bc.updateFuncs[keyStr] = updateFunc
+
+	updateSignalerRoutine := func(c *cacheChannel) {
+		<-time.After(period)
+		c.signal(proceed)
+	}
+
+	updateRoutine := func(key string, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		}
+
+		bc.mutex.Lock()
+		defer bc.mutex.Unlock()
+
+		// The update was canceled while waiting for the mutex.
+		if bc.updateChannels[key] != c {
+			return
+		}
+
+		currVal, err := bc.get(key)
+		if err != nil {
+			bc.unexpectedUpdateError(key, err)
+			return
+		}
+
+		newVal, err := updateFunc(currVal)
+		if err != nil {
+			if bc.onUpdateError != nil {
+				bc.onUpdateError(key, err)
+			}
+
+			// Keep the current value until the next update.
+			newVal = currVal
+		}
+
+		// The value should still be removed at its original deadline.
+		deadline, hasDeadline, err := bc.deadline(key)
+		if err != nil {
+			bc.unexpectedUpdateError(key, err)
+			return
+		}
+		slidingTTL, isSliding := bc.slidingTTLs[key]
+
+		err = bc.remove(key)
+		if err != nil {
+			bc.unexpectedUpdateError(key, err)
+			return
+		}
+
+		err = bc.storeWithUpdate(key, newVal, updateFunc, period)
+		if err == nil && hasDeadline {
+			err = bc.createExpirationRoutine(key, time.Until(deadline))
+		}
+
+		if err == nil && isSliding {
+			bc.slidingTTLs[key] = slidingTTL
+		}
+
+		if err != nil {
+			bc.unexpectedUpdateError(key, err)
+		}
+	}
+
+	go updateSignalerRoutine(c)
+	go updateRoutine(keyStr, c)
+
+	return nil
+}
+
+// Stores an updating value in the cache that is removed after totalTTL, even
+// if it is still being updated.
+func (bc *boltCache) StoreWithExpirationAndUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if totalTTL <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	err := bc.storeWithUpdate(key, initialValue, updateFunc, updatePeriod)
+	if err != nil {
+		return err
+	}
+
+	return bc.createExpirationRoutine(key.(string), totalTTL)
+}
+
+// Stores an updating value in the cache that is removed once it was not read
+// for totalTTL, every Get resets its deadline.
+func (bc *boltCache) StoreWithUpdateAndExpiration(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if totalTTL <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	err := bc.storeWithUpdate(key, initialValue, updateFunc, updatePeriod)
+	if err != nil {
+		return err
+	}
+
+	err = bc.createExpirationRoutine(key.(string), totalTTL)
+	if err != nil {
+		return err
+	}
+
+	bc.slidingTTLs[key.(string)] = totalTTL
+
+	return nil
+}
+
+// Replace a value with a continously updating value.
+func (bc *boltCache) ReplaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	err = bc.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return bc.storeWithUpdate(key, initialValue, updateFunc, period)
+}
+
+// Stop updating a value in the cache, the value keeps its current value. A
+// value stored with StoreWithUpdateAndExpiration also stops expiring.
+func (bc *boltCache) CancelUpdate(key interface{}) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	exists, err := bc.contains(key)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] does not exist", key.(string)))
+	}
+
+	strKey := key.(string)
+
+	c, exists := bc.updateChannels[strKey]
+	if !exists || c == nil {
+		return nil
+	}
+
+	c.signal(abort)
+	delete(bc.updateChannels, strKey)
+	delete(bc.updateFuncs, strKey)
+
+	// Its idle deadline only lasts as long as it is updated.
+	if _, isSliding := bc.slidingTTLs[strKey]; !isSliding {
+		return nil
+	}
+
+	err = bc.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bc.ttlBucket).Delete([]byte(strKey))
+	})
+	if err != nil {
+		return err
+	}
+
+	if rc, exists := bc.removeChannels[strKey]; exists && rc != nil {
+		rc.signal(abort)
+		delete(bc.removeChannels, strKey)
+	}
+
+	delete(bc.slidingTTLs, strKey)
+
+	return nil
+}
+
+// Change the period in which a value is updated, the next update happens
+// newPeriod after the call.
+func (bc *boltCache) SetUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if newPeriod <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	currVal, err := bc.get(key)
+	if err != nil {
+		return err
+	}
+
+	updateFunc, isUpdating := bc.updateFuncs[key.(string)]
+	if !isUpdating {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] is not being updated", key.(string)))
+	}
+
+	// The value should still be removed at its original deadline.
+	deadline, hasDeadline, err := bc.deadline(key.(string))
+	if err != nil {
+		return err
+	}
+	slidingTTL, isSliding := bc.slidingTTLs[key.(string)]
+
+	err = bc.remove(key)
+	if err != nil {
+		return err
+	}
+
+	err = bc.storeWithUpdate(key, currVal, updateFunc, newPeriod)
+	if err == nil && hasDeadline {
+		err = bc.createExpirationRoutine(key.(string), time.Until(deadline))
+	}
+
+	if err == nil && isSliding {
+		bc.slidingTTLs[key.(string)] = slidingTTL
+	}
+
+	return err
+}
+
+// Report an unexpected error of an auto update routine, panics if there is no
+// update error handler.
+func (bc *boltCache) unexpectedUpdateError(key string, err error) {
+	wrappedErr := newWrapperError(errorTypeUnexpectedError,
+		"an unexpected error occurred a background routine", err)
+	if bc.onUpdateError == nil {
+		panic(wrappedErr)
+	}
+
+	bc.onUpdateError(key, wrappedErr)
+}
+
+// Stop all the auto removal and auto update routines.
+func (bc *boltCache) stopRoutines() {
+	for key, c := range bc.removeChannels {
+		c.signal(abort)
+		delete(bc.removeChannels, key)
+	}
+
+	for key, c := range bc.updateChannels {
+		c.signal(abort)
+		delete(bc.updateChannels, key)
+		delete(bc.updateFuncs, key)
+	}
+
+	bc.slidingTTLs = map[string]time.Duration{}
+}
+
+// Delete a value and its deadline from the database.
+func (bc *boltCache) deleteEntry(key string) error {
+	return bc.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(bc.bucket).Delete([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(bc.ttlBucket).Delete([]byte(key))
+	})
+}
+
+func (bc *boltCache) deadline(key string) (time.Time, bool, error) {
+	var deadline time.Time
+	hasDeadline := false
+
+	err := bc.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bc.ttlBucket).Get([]byte(key))
+		if v != nil {
+			deadline = decodeDeadline(v)
+			hasDeadline = true
+		}
+
+		return nil
+	})
+
+	return deadline, hasDeadline, err
+}
+
+func (bc *boltCache) decodeValue(key string, data []byte) (interface{}, error) {
+	valType, known := bc.valueTypes[key]
+	if !known {
+		var val interface{}
+		err := bc.encoding.Unmarshal(data, &val)
+		if err != nil {
+			return nil, err
+		}
+
+		return val, nil
+	}
+
+	val := reflect.New(valType)
+	err := bc.encoding.Unmarshal(data, val.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return val.Elem().Interface(), nil
+}
+
+func (bc *boltCache) verifyKey(key interface{}) error {
+	_, isStr := key.(string)
+	if !isStr {
+		return newError(errorTypeInvalidKeyType,
+			fmt.Sprintf("invalid key type, expected: [string] found: [%T]", key))
+	}
+
+	return nil
+}
+
+func (bc *boltCache) verifyInputs(key, val interface{}) error {
+	err := bc.verifyKey(key)
+	if err != nil {
+		return err
+	}
+
+	if val == nil {
+		return newError(errorTypeNilValue, "value cannot be nil")
+	}
+
+	data, err := bc.encoding.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	tmpVal := reflect.New(reflect.TypeOf(val))
+	err = bc.encoding.Unmarshal(data, tmpVal.Interface())
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(val, tmpVal.Elem().Interface()) {
+		return newError(errorTypeUrecoverableValue,
+			"value cannot be fully recovered after being encoded,"+
+				" make sure val's type has json tags")
+	}
+
+	return nil
+}
+
+func encodeDeadline(deadline time.Time) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(deadline.UnixNano()))
+
+	return data
+}
+
+func decodeDeadline(data []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bolt Cache", func() {
+	var (
+		c      *boltCache
+		dbPath string     = path.Join(os.TempDir(), "bolt-cache.db")
+		key    string     = "key"
+		val    testStruct = testStruct{"Test", 0}
+	)
+
+	BeforeEach(func() {
+		Expect(os.RemoveAll(dbPath)).ToNot(HaveOccurred())
+
+		bc, err := NewBoltCache(dbPath, "test")
+		Expect(err).ToNot(HaveOccurred())
+
+		c = bc
+	})
+
+	AfterEach(func() {
+		Expect(c.Close()).ToNot(HaveOccurred())
+	})
+
+	Context("Store", func() {
+		It("should store a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(key, val))).To(BeTrue())
+		})
+
+		It("should return an error when the key is not a string", func() {
+			Expect(IsInvalidKeyType(c.Store(1, val))).To(BeTrue())
+		})
+	})
+
+	Context("Get", func() {
+		It("should return an error when attmpting to get a non-existent value", func() {
+			_, err := c.Get("IDoNotExist")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value from the cache", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+
+		It("should return an error when attempting to remove a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Remove(key))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a temporary value with a permanent value", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, testStruct{"New", 1})).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(testStruct{"New", 1}))
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove all values and remain usable", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("temp", val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(BeEmpty())
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return a slice of cache keys", func() {
+			Expect(c.Store("a", val)).ToNot(HaveOccurred())
+			Expect(c.Store("b", val)).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(ConsistOf("a", "b"))
+		})
+	})
+
+	Context("StoreWithExpiration", func() {
+		It("should store a temporary value", func() {
+			Expect(c.StoreWithExpiration(key, val, 500*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+
+			Eventually(func() bool {
+				_, err := c.Get(key)
+				return IsDoesNotExist(err)
+			}, testTimeout).Should(BeTrue(), "value should have been removed")
+		})
+	})
+
+	Context("ReplaceWithExpiration", func() {
+		It("should replace a permanent value with a temporary one", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.ReplaceWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+		})
+	})
+
+	Context("Expire", func() {
+		It("should put an expiration duration on a permanent value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Expire(key, 500*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+	})
+
+	Context("StoreWithUpdate", func() {
+		It("should store a value and continously update it", func() {
+			Expect(c.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return testStruct{"Test", currValue.(testStruct).Int + 1}, nil
+			}, 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() int {
+				v, err := c.Get(key)
+				Expect(err).ToNot(HaveOccurred())
+				return v.(testStruct).Int
+			}, testTimeout).Should(BeNumerically(">", 1))
+		})
+	})
+
+	Context("ReplaceWithUpdate", func() {
+		It("should replace a value with an updating one", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.ReplaceWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return testStruct{"Test", currValue.(testStruct).Int + 1}, nil
+			}, 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() int {
+				v, err := c.Get(key)
+				Expect(err).ToNot(HaveOccurred())
+				return v.(testStruct).Int
+			}, testTimeout).Should(BeNumerically(">", 0))
+		})
+	})
+
+	Context("Persistence", func() {
+		It("should recover values and deadlines after reopening the database", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("temp", val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("short", val, 100*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.Close()).ToNot(HaveOccurred())
+
+			time.Sleep(200 * time.Millisecond)
+
+			bc, err := NewBoltCache(dbPath, "test")
+			Expect(err).ToNot(HaveOccurred())
+			c = bc
+
+			Expect(c.Get(key)).To(Equal(map[string]interface{}{"str": "Test", "int": 0.0}))
+			Expect(c.Contains("short")).To(BeFalse(), "expired value should have been removed")
+
+			ttl, hasTTL, err := c.TTL("temp")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(ttl).To(BeNumerically("<", time.Minute))
+		})
+	})
+})
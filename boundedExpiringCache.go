@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type boundedExpiringCache struct {
+	ExpiringCache
+
+	// The maximal ttl of a value in nanoseconds, zero means there is no cap.
+	maxAge atomic.Int64
+}
+
+var _ BoundedExpiringCache = (*boundedExpiringCache)(nil)
+
+// NewBoundedExpiringCache creates a new BoundedExpiringCache object in which
+// no value that is stored in underlying lives longer than maxAge, zero
+// disables the cap.
+//
+// Permanent values are stored with a ttl of maxAge, and every ttl or deadline
+// is capped at maxAge. The ttl of a sliding value is capped, but accessing it
+// still extends its life.
+func NewBoundedExpiringCache(underlying ExpiringCache, maxAge time.Duration) (BoundedExpiringCache, error) {
+	bec := &boundedExpiringCache{
+		ExpiringCache: underlying,
+	}
+
+	err := bec.SetMaxAge(maxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	return bec, nil
+}
+
+// SetMaxAge caps the ttl of the values that are stored from now on, the ttls
+// of values that are already stored do not change.
+func (bec *boundedExpiringCache) SetMaxAge(maxAge time.Duration) error {
+	if maxAge < 0 {
+		return newError(errorTypeNonPositivePeriod, "maxAge cannot be negative")
+	}
+
+	bec.maxAge.Store(int64(maxAge))
+
+	return nil
+}
+
+// Returns ttl capped at the max age.
+func (bec *boundedExpiringCache) cap(ttl time.Duration) time.Duration {
+	maxAge := time.Duration(bec.maxAge.Load())
+	if maxAge > 0 && ttl > maxAge {
+		return maxAge
+	}
+
+	return ttl
+}
+
+// Store a value that is removed after the max age, or a permanent value if
+// there is no cap.
+func (bec *boundedExpiringCache) Store(key, val interface{}) error {
+	maxAge := time.Duration(bec.maxAge.Load())
+	if maxAge <= 0 {
+		return bec.ExpiringCache.Store(key, val)
+	}
+
+	return bec.ExpiringCache.StoreWithExpiration(key, val, maxAge)
+}
+
+// Get a value, or store val like Store if the key does not exist.
+//
+// Unlike the GetOrStore of underlying, getting and storing are not atomic
+// when there is a cap.
+func (bec *boundedExpiringCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	if bec.maxAge.Load() <= 0 {
+		return bec.ExpiringCache.GetOrStore(key, val)
+	}
+
+	actual, err := bec.ExpiringCache.Get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = bec.Store(key, val)
+	if IsAlreadyExists(err) {
+		// The key was stored since it was looked up.
+		actual, err = bec.ExpiringCache.Get(key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return actual, true, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Replace a value, the new value is removed after the max age if there is a
+// cap.
+func (bec *boundedExpiringCache) Replace(key, val interface{}) error {
+	maxAge := time.Duration(bec.maxAge.Load())
+	if maxAge <= 0 {
+		return bec.ExpiringCache.Replace(key, val)
+	}
+
+	return bec.ExpiringCache.ReplaceWithExpiration(key, val, maxAge)
+}
+
+// Store a value like Store, replacing the current value if the key exists.
+func (bec *boundedExpiringCache) StoreOrReplace(key, val interface{}) error {
+	if bec.maxAge.Load() <= 0 {
+		return bec.ExpiringCache.StoreOrReplace(key, val)
+	}
+
+	err := bec.Replace(key, val)
+	if IsDoesNotExist(err) {
+		return bec.Store(key, val)
+	}
+
+	return err
+}
+
+// Store several values like Store.
+func (bec *boundedExpiringCache) StoreMany(items map[interface{}]interface{}) error {
+	if bec.maxAge.Load() <= 0 {
+		return bec.ExpiringCache.StoreMany(items)
+	}
+
+	return storeMany(items, bec.Store)
+}
+
+// Store a value that is removed after ttl, capped at the max age.
+func (bec *boundedExpiringCache) StoreWithExpiration(key, val interface{}, ttl time.Duration) error {
+	return bec.ExpiringCache.StoreWithExpiration(key, val, bec.cap(ttl))
+}
+
+// Store a value that is removed at deadline, or after the max age if it is
+// sooner.
+func (bec *boundedExpiringCache) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	maxAge := time.Duration(bec.maxAge.Load())
+	if maxAge > 0 && time.Until(deadline) > maxAge {
+		return bec.ExpiringCache.StoreWithExpiration(key, val, maxAge)
+	}
+
+	return bec.ExpiringCache.StoreWithDeadline(key, val, deadline)
+}
+
+// Replace a value that is removed after ttl, capped at the max age.
+func (bec *boundedExpiringCache) ReplaceWithExpiration(key, val interface{}, ttl time.Duration) error {
+	return bec.ExpiringCache.ReplaceWithExpiration(key, val, bec.cap(ttl))
+}
+
+// Expire resets the ttl of a value, capped at the max age.
+func (bec *boundedExpiringCache) Expire(key interface{}, ttl time.Duration) error {
+	return bec.ExpiringCache.Expire(key, bec.cap(ttl))
+}
+
+// Store a value that is removed after ttl, capped at the max age, has passed
+// since it was last accessed.
+func (bec *boundedExpiringCache) StoreWithSlidingExpiration(key, val interface{}, ttl time.Duration) error {
+	return bec.ExpiringCache.StoreWithSlidingExpiration(key, val, bec.cap(ttl))
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bounded Expiring Cache", func() {
+	var (
+		c        BoundedExpiringCache
+		key, val string = "key", "val"
+	)
+
+	BeforeEach(func() {
+		var err error
+		c, err = NewBoundedExpiringCache(NewMapCache(), 5*time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// Returns the remaining ttl of key.
+	ttlOf := func(key interface{}) time.Duration {
+		remaining, hasTTL, err := c.TTL(key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hasTTL).To(BeTrue())
+
+		return remaining
+	}
+
+	Context("StoreWithExpiration", func() {
+		It("should cap the ttl at the max age", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Hour)).ToNot(HaveOccurred())
+			Expect(ttlOf(key)).To(BeNumerically("~", 5*time.Minute, time.Second))
+		})
+
+		It("should keep a ttl that is shorter than the max age", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			Expect(ttlOf(key)).To(BeNumerically("~", time.Minute, time.Second))
+		})
+
+		It("should remove the value once the max age passes", func() {
+			Expect(c.SetMaxAge(50 * time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration(key, val, time.Hour)).ToNot(HaveOccurred())
+
+			Eventually(func() (bool, error) {
+				return c.Contains(key)
+			}, time.Second).Should(BeFalse())
+		})
+	})
+
+	Context("Store", func() {
+		It("should store a value that expires after the max age", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(ttlOf(key)).To(BeNumerically("~", 5*time.Minute, time.Second))
+
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+			Expect(ttlOf(key)).To(BeNumerically("~", 5*time.Minute, time.Second))
+		})
+
+		It("should store a permanent value when the cap is disabled", func() {
+			Expect(c.SetMaxAge(0)).ToNot(HaveOccurred())
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+	})
+
+	Context("Expire", func() {
+		It("should cap the new ttl at the max age", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.Expire(key, time.Hour)).ToNot(HaveOccurred())
+			Expect(ttlOf(key)).To(BeNumerically("~", 5*time.Minute, time.Second))
+		})
+	})
+
+	Context("StoreWithDeadline", func() {
+		It("should cap the deadline at the max age", func() {
+			Expect(c.StoreWithDeadline(key, val, time.Now().Add(time.Hour))).ToNot(HaveOccurred())
+			Expect(ttlOf(key)).To(BeNumerically("~", 5*time.Minute, time.Second))
+		})
+	})
+
+	Context("SetMaxAge", func() {
+		It("should return an error for a negative max age", func() {
+			Expect(IsNonPositivePeriod(c.SetMaxAge(-time.Second))).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// The number of writes that can wait for a secondary shard.
+const broadcastQueueSize = 1024
+
+type broadcastShard struct {
+	cache Cache
+
+	// The writes that were not applied to the shard yet, in order.
+	queue chan func(shard Cache)
+}
+
+type broadcastCache struct {
+	// The shard that is written synchronously, its errors are returned.
+	primary Cache
+
+	// The shards that are written in the background.
+	secondaries []*broadcastShard
+
+	// How long a write waits for room in the queue of a secondary shard
+	// before it is skipped, writes wait as long as needed if it is not
+	// positive.
+	storeTimeout time.Duration
+
+	// Indication if the cache was closed, closed caches only write to the
+	// primary shard.
+	closed bool
+
+	// Guards closed, writes are queued under a read lock.
+	mutex sync.RWMutex
+
+	// Waits for the routines of the secondary shards.
+	wg sync.WaitGroup
+}
+
+var _ Cache = (*broadcastCache)(nil)
+
+// BroadcastOption configures a broadcastCache.
+type BroadcastOption func(*broadcastCache)
+
+// WithStoreTimeout limits how long a write waits for a secondary shard that
+// is falling behind, the write is skipped for that shard once d passes.
+func WithStoreTimeout(d time.Duration) BroadcastOption {
+	return func(bc *broadcastCache) {
+		bc.storeTimeout = d
+	}
+}
+
+// NewBroadcastCache creates a new Cache object that writes to all shards and
+// reads from the first shard that has the key.
+//
+// The first shard is the primary, it is written synchronously and its errors
+// are returned. Writes are applied to the other shards in the background in
+// the same order, and their errors are ignored. If shards is empty, a single
+// map is used.
+func NewBroadcastCache(shards ...Cache) *broadcastCache {
+	return NewBroadcastCacheWithOptions(shards)
+}
+
+// NewBroadcastCacheWithOptions creates a new Cache object like
+// NewBroadcastCache, configured by opts.
+func NewBroadcastCacheWithOptions(shards []Cache, opts ...BroadcastOption) *broadcastCache {
+	if len(shards) == 0 {
+		shards = []Cache{NewMapCache()}
+	}
+
+	bc := &broadcastCache{
+		primary: shards[0],
+	}
+
+	for _, opt := range opts {
+		opt(bc)
+	}
+
+	for _, shard := range shards[1:] {
+		s := &broadcastShard{
+			cache: shard,
+			queue: make(chan func(shard Cache), broadcastQueueSize),
+		}
+
+		bc.secondaries = append(bc.secondaries, s)
+		bc.wg.Add(1)
+		go bc.apply(s)
+	}
+
+	return bc
+}
+
+// Applies the queued writes of a secondary shard until it is closed.
+func (bc *broadcastCache) apply(s *broadcastShard) {
+	defer bc.wg.Done()
+
+	for write := range s.queue {
+		write(s.cache)
+	}
+}
+
+// Queues a write to all secondary shards.
+func (bc *broadcastCache) broadcast(write func(shard Cache)) {
+	bc.mutex.RLock()
+	defer bc.mutex.RUnlock()
+
+	if bc.closed {
+		return
+	}
+
+	for _, s := range bc.secondaries {
+		if bc.storeTimeout <= 0 {
+			s.queue <- write
+			continue
+		}
+
+		timer := time.NewTimer(bc.storeTimeout)
+		select {
+		case s.queue <- write:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Close applies the queued writes and stops writing to the secondary shards,
+// the cache only writes to the primary shard once it is closed.
+func (bc *broadcastCache) Close() error {
+	bc.mutex.Lock()
+	if !bc.closed {
+		bc.closed = true
+		for _, s := range bc.secondaries {
+			close(s.queue)
+		}
+	}
+	bc.mutex.Unlock()
+
+	bc.wg.Wait()
+
+	return nil
+}
+
+// Store a permanent value in all shards.
+func (bc *broadcastCache) Store(key, val interface{}) error {
+	err := bc.primary.Store(key, val)
+	if err != nil {
+		return err
+	}
+
+	bc.broadcast(func(shard Cache) {
+		shard.Store(key, val)
+	})
+
+	return nil
+}
+
+// Get a value from the first shard that has it.
+func (bc *broadcastCache) Get(key interface{}) (interface{}, error) {
+	val, err := bc.primary.Get(key)
+	if err == nil {
+		return val, nil
+	}
+
+	for _, s := range bc.secondaries {
+		secondaryVal, secondaryErr := s.cache.Get(key)
+		if secondaryErr == nil {
+			return secondaryVal, nil
+		}
+	}
+
+	return nil, err
+}
+
+// Check whether any of the shards has a key.
+func (bc *broadcastCache) Contains(key interface{}) (bool, error) {
+	exists, err := bc.primary.Contains(key)
+	if err == nil && exists {
+		return true, nil
+	}
+
+	for _, s := range bc.secondaries {
+		if secondaryExists, _ := s.cache.Contains(key); secondaryExists {
+			return true, nil
+		}
+	}
+
+	return exists, err
+}
+
+// Get a value from the primary shard, or store val in all shards if the key
+// does not exist.
+func (bc *broadcastCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	actual, loaded, err := bc.primary.GetOrStore(key, val)
+	if err != nil || loaded {
+		return actual, loaded, err
+	}
+
+	bc.broadcast(func(shard Cache) {
+		shard.GetOrStore(key, val)
+	})
+
+	return actual, false, nil
+}
+
+// Remove a value from all shards.
+func (bc *broadcastCache) Remove(key interface{}) error {
+	err := bc.primary.Remove(key)
+	if err != nil {
+		return err
+	}
+
+	bc.broadcast(func(shard Cache) {
+		shard.Remove(key)
+	})
+
+	return nil
+}
+
+// Get a value from the primary shard and remove it from all shards.
+func (bc *broadcastCache) GetAndRemove(key interface{}) (interface{}, error) {
+	val, err := bc.primary.GetAndRemove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	bc.broadcast(func(shard Cache) {
+		shard.Remove(key)
+	})
+
+	return val, nil
+}
+
+// Replace a value in all shards.
+func (bc *broadcastCache) Replace(key, val interface{}) error {
+	err := bc.primary.Replace(key, val)
+	if err != nil {
+		return err
+	}
+
+	bc.broadcast(func(shard Cache) {
+		shard.StoreOrReplace(key, val)
+	})
+
+	return nil
+}
+
+// Store a permanent value in all shards, replacing the current value if the
+// key exists.
+func (bc *broadcastCache) StoreOrReplace(key, val interface{}) error {
+	err := bc.primary.StoreOrReplace(key, val)
+	if err != nil {
+		return err
+	}
+
+	bc.broadcast(func(shard Cache) {
+		shard.StoreOrReplace(key, val)
+	})
+
+	return nil
+}
+
+// Store several permanent values in all shards.
+func (bc *broadcastCache) StoreMany(items map[interface{}]interface{}) error {
+	return storeMany(items, bc.Store)
+}
+
+// Get several values, each from the first shard that has it.
+func (bc *broadcastCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	return getMany(keys, bc.Get)
+}
+
+// Clear all shards.
+func (bc *broadcastCache) Clear() error {
+	err := bc.primary.Clear()
+	if err != nil {
+		return err
+	}
+
+	bc.broadcast(func(shard Cache) {
+		shard.Clear()
+	})
+
+	return nil
+}
+
+// Get all keys of the primary shard.
+func (bc *broadcastCache) Keys() ([]interface{}, error) {
+	return bc.primary.Keys()
+}
+
+// Calls fn for each key and value of the primary shard until fn returns
+// false.
+func (bc *broadcastCache) ForEach(fn func(key, val interface{}) bool) error {
+	return bc.primary.ForEach(fn)
+}
+
+// Get the keys of the primary shard for which predicate returns true.
+func (bc *broadcastCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	return bc.primary.FilterKeys(predicate)
+}
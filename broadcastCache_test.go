@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Blocks every Store until unblock is closed.
+type blockingCache struct {
+	Cache
+	unblock chan struct{}
+}
+
+func (bc *blockingCache) Store(key, val interface{}) error {
+	<-bc.unblock
+	return bc.Cache.Store(key, val)
+}
+
+var _ = Describe("Broadcast Cache", func() {
+	var (
+		c                  Cache
+		primary, secondary Cache
+		key, val, otherVal string = "key", "val", "other-val"
+	)
+
+	BeforeEach(func() {
+		primary = NewMapCache()
+		secondary = NewMapCache()
+		c = NewBroadcastCache(primary, secondary)
+	})
+
+	AfterEach(func() {
+		Expect(c.(*broadcastCache).Close()).ToNot(HaveOccurred())
+	})
+
+	Context("Store", func() {
+		It("should store a value in all shards", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(primary.Get(key)).To(Equal(val))
+			Eventually(func() (interface{}, error) {
+				return secondary.Get(key)
+			}).Should(Equal(val))
+		})
+
+		It("should not write to the secondary shards when the primary fails", func() {
+			Expect(primary.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(key, otherVal))).To(BeTrue())
+
+			Expect(c.(*broadcastCache).Close()).ToNot(HaveOccurred())
+			Expect(secondary.Contains(key)).To(BeFalse())
+		})
+	})
+
+	Context("Get", func() {
+		It("should get a value from the first shard that has it", func() {
+			Expect(secondary.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Contains(key)).To(BeTrue())
+		})
+
+		It("should return the error of the primary shard when no shard has the key", func() {
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should apply the writes to the secondary shards in order", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, otherVal)).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+
+			Expect(c.(*broadcastCache).Close()).ToNot(HaveOccurred())
+			Expect(secondary.Contains(key)).To(BeFalse())
+		})
+	})
+
+	Context("Clear", func() {
+		It("should clear all shards", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": val})).
+				ToNot(HaveOccurred())
+			Expect(c.Clear()).ToNot(HaveOccurred())
+
+			Expect(c.(*broadcastCache).Close()).ToNot(HaveOccurred())
+			Expect(secondary.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("WithStoreTimeout", func() {
+		It("should skip a secondary shard that falls behind", func() {
+			blocked := &blockingCache{Cache: NewMapCache(), unblock: make(chan struct{})}
+			c = NewBroadcastCacheWithOptions([]Cache{primary, blocked},
+				WithStoreTimeout(10*time.Millisecond))
+
+			// The first write blocks the shard and the rest fill its queue.
+			for i := 0; i <= broadcastQueueSize; i++ {
+				Expect(c.Store(i, val)).ToNot(HaveOccurred())
+			}
+
+			start := time.Now()
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+
+			close(blocked.unblock)
+			Expect(c.(*broadcastCache).Close()).ToNot(HaveOccurred())
+			Expect(blocked.Contains(key)).To(BeFalse())
+			Expect(blocked.Keys()).To(HaveLen(broadcastQueueSize + 1))
+		})
+	})
+})
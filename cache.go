@@ -1,19 +1,38 @@
 package cache
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // -----------------------------------------
 
-type Cache interface {
-	// Store a value permanently.
-	Store(key, val interface{}) error
-
+// ReadCache is a read only view of a cache.
+type ReadCache interface {
 	// Get a value.
 	Get(key interface{}) (interface{}, error)
 
+	// Check whether a key exists without getting its value.
+	Contains(key interface{}) (bool, error)
+
+	// Get all keys from the cache.
+	Keys() ([]interface{}, error)
+}
+
+// WriteCache is a write only view of a cache.
+type WriteCache interface {
+	// Store a value permanently.
+	Store(key, val interface{}) error
+
 	// Remove a value.
 	Remove(key interface{}) error
 
@@ -22,9 +41,36 @@ type Cache interface {
 
 	// Clears the cache.
 	Clear() error
+}
 
-	// Get all keys from the cache.
-	Keys() ([]interface{}, error)
+type Cache interface {
+	ReadCache
+	WriteCache
+
+	// Get a value, or store the given value if the key does not exist.
+	GetOrStore(key, val interface{}) (actual interface{}, loaded bool, err error)
+
+	// Get a value and remove it.
+	GetAndRemove(key interface{}) (interface{}, error)
+
+	// Store a value permanently, replacing the current value if the key
+	// already exists.
+	StoreOrReplace(key, val interface{}) error
+
+	// Store several values permanently.
+	StoreMany(items map[interface{}]interface{}) error
+
+	// Get several values, the values that were found are returned even if
+	// getting some of the others failed.
+	GetMany(keys []interface{}) (map[interface{}]interface{}, error)
+
+	// Calls fn for each key and value in the cache until fn returns false,
+	// fn must not call the cache.
+	ForEach(fn func(key, val interface{}) bool) error
+
+	// Get the keys for which predicate returns true, predicate must not call
+	// the cache.
+	FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error)
 }
 
 type ExpiringCache interface {
@@ -33,11 +79,37 @@ type ExpiringCache interface {
 	// Store a value that will be removed after the specified ttl.
 	StoreWithExpiration(key, val interface{}, ttl time.Duration) error
 
+	// Store a value that will be removed at the specified deadline.
+	StoreWithDeadline(key, val interface{}, deadline time.Time) error
+
 	// Replaces the value of a key.
 	ReplaceWithExpiration(key, val interface{}, ttl time.Duration) error
 
-	// Expire resets and updates the ttl of a value.
+	// Expire resets and updates the ttl of a value, an updating value stops
+	// updating.
 	Expire(key interface{}, ttl time.Duration) error
+
+	// Get the remaining ttl of a value, hasTTL is false for permanent values.
+	TTL(key interface{}) (remaining time.Duration, hasTTL bool, err error)
+
+	// Store a value that will be removed after the specified ttl has
+	// passed since it was last accessed.
+	StoreWithSlidingExpiration(key, val interface{}, ttl time.Duration) error
+}
+
+type BoundedExpiringCache interface {
+	ExpiringCache
+
+	// Caps the ttl of every stored value at maxAge, zero disables the cap.
+	SetMaxAge(maxAge time.Duration) error
+}
+
+type CopyableCache interface {
+	Cache
+
+	// Copies all values to dst, the keys that are not mentioned in the
+	// returned error were copied successfully.
+	CopyTo(dst Cache) error
 }
 
 type UpdatingCache interface {
@@ -45,18 +117,157 @@ type UpdatingCache interface {
 
 	// Stores a value and repeatedly updates it.
 	StoreWithUpdate(key, initialValue interface{},
-		updateFunc func(currValue interface{}) interface{},
+		updateFunc func(currValue interface{}) (interface{}, error),
 		period time.Duration) error
 
 	// Replaces a value and repeatedly updates it.
 	ReplaceWithUpdate(key, initialValue interface{},
-		updateFunc func(currValue interface{}) interface{},
+		updateFunc func(currValue interface{}) (interface{}, error),
 		period time.Duration) error
+
+	// Stops updating a value, the value keeps its current value.
+	CancelUpdate(key interface{}) error
+
+	// Changes the period in which a value is updated, keeping its update
+	// function.
+	SetUpdatePeriod(key interface{}, newPeriod time.Duration) error
 }
 
 type UpdatingExpiringCache interface {
 	UpdatingCache
 	ExpiringCache
+
+	// Stores a value, repeatedly updates it and removes it after totalTTL.
+	StoreWithExpirationAndUpdate(key, initialValue interface{},
+		updateFunc func(currValue interface{}) (interface{}, error),
+		updatePeriod, totalTTL time.Duration) error
+
+	// Stores a value, repeatedly updates it and removes it once it was not
+	// read for totalTTL.
+	StoreWithUpdateAndExpiration(key, initialValue interface{},
+		updateFunc func(currValue interface{}) (interface{}, error),
+		updatePeriod, totalTTL time.Duration) error
+}
+
+type VersionedCache interface {
+	Cache
+
+	// Get a value along with its version, which changes on every write.
+	GetWithVersion(key interface{}) (val interface{}, version int64, err error)
+
+	// Replace a value only if its version is still the expected version.
+	CompareAndSwap(key interface{}, expected int64, newVal interface{}) error
+}
+
+type ContextCache interface {
+	Cache
+
+	// Store a value permanently using the given context.
+	StoreCtx(ctx context.Context, key, val interface{}) error
+
+	// Get a value using the given context.
+	GetCtx(ctx context.Context, key interface{}) (interface{}, error)
+
+	// Remove a value using the given context.
+	RemoveCtx(ctx context.Context, key interface{}) error
+
+	// Replace a value using the given context.
+	ReplaceCtx(ctx context.Context, key, val interface{}) error
+
+	// Clears the cache using the given context.
+	ClearCtx(ctx context.Context) error
+}
+
+type PeekableCache interface {
+	Cache
+
+	// Get a value without updating the eviction metadata of the cache.
+	Peek(key interface{}) (interface{}, error)
+}
+
+// TransactionalCache is the view of a cache inside a transaction, it must not
+// be used once the transaction ends.
+type TransactionalCache interface {
+	Cache
+}
+
+// -----------------------------------------
+
+// EvictionOption configures a cache that evicts items, such as lruCache
+// and lfuCache.
+type EvictionOption func(*evictionOptions)
+
+type evictionOptions struct {
+	// Called with the key and value of every evicted item.
+	onEvict func(key, val interface{})
+
+	// Counts the evicted items, it is called along with onEvict.
+	countEviction func()
+
+	// The source of randomness of caches that evict random items.
+	randSource rand.Source
+
+	// Called with the errors of the auto update routines.
+	onUpdateError func(key interface{}, err error)
+
+	// Logs unexpected errors of background routines.
+	logger *slog.Logger
+}
+
+// WithEvictionCallback sets a function that is called with the key and value
+// of an evicted item, before it is removed from the storage.
+func WithEvictionCallback(onEvict func(key, val interface{})) EvictionOption {
+	return func(o *evictionOptions) {
+		o.onEvict = onEvict
+	}
+}
+
+// WithRandSource sets the source of randomness of a cache that evicts random
+// items, such as randomCache.
+func WithRandSource(src rand.Source) EvictionOption {
+	return func(o *evictionOptions) {
+		o.randSource = src
+	}
+}
+
+// WithEvictionUpdateErrorHandler sets a function that is called whenever
+// updating a value in the background fails, the value keeps its current value
+// when updateFunc returns an error.
+//
+// Without a handler, errors of updateFunc are ignored and unexpected errors
+// are logged and remove the value.
+func WithEvictionUpdateErrorHandler(onUpdateError func(key interface{}, err error)) EvictionOption {
+	return func(o *evictionOptions) {
+		o.onUpdateError = onUpdateError
+	}
+}
+
+// WithEvictionLogger sets the logger of unexpected errors in background
+// routines, slog.Default() is used by default.
+func WithEvictionLogger(logger *slog.Logger) EvictionOption {
+	return func(o *evictionOptions) {
+		o.logger = logger
+	}
+}
+
+func newEvictionOptions(opts []EvictionOption) evictionOptions {
+	o := evictionOptions{logger: slog.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.countEviction != nil {
+		onEvict := o.onEvict
+		o.onEvict = func(key, val interface{}) {
+			o.countEviction()
+
+			if onEvict != nil {
+				onEvict(key, val)
+			}
+		}
+	}
+
+	return o
 }
 
 // -----------------------------------------
@@ -100,6 +311,69 @@ func (ce cacheError) Error() string {
 	return ce.msg
 }
 
+func (ce cacheError) Unwrap() error {
+	return ce.nestedError
+}
+
+// Is reports whether target is a cacheError of the same type, so that
+// errors.Is(err, newError(errorTypeDoesNotExist, "")) matches any missing key.
+func (ce cacheError) Is(target error) bool {
+	targetErr, isCacheErr := target.(cacheError)
+	return isCacheErr && targetErr.errType == ce.errType
+}
+
+type jsonCacheError struct {
+	Type    errorType `json:"type"`
+	Message string    `json:"message"`
+	Nested  string    `json:"nested,omitempty"`
+}
+
+func (ce cacheError) MarshalJSON() ([]byte, error) {
+	jsonErr := jsonCacheError{
+		Type:    ce.errType,
+		Message: ce.msg,
+	}
+
+	if ce.nestedError != nil {
+		jsonErr.Nested = ce.nestedError.Error()
+	}
+
+	return json.Marshal(jsonErr)
+}
+
+// UnmarshalJSON reconstructs an error that was encoded by MarshalJSON, the
+// nested error only keeps its message.
+func (ce *cacheError) UnmarshalJSON(data []byte) error {
+	var jsonErr jsonCacheError
+
+	err := json.Unmarshal(data, &jsonErr)
+	if err != nil {
+		return err
+	}
+
+	ce.errType = jsonErr.Type
+	ce.msg = jsonErr.Message
+	ce.nestedError = nil
+	if jsonErr.Nested != "" {
+		ce.nestedError = errors.New(jsonErr.Nested)
+	}
+
+	return nil
+}
+
+// ErrorFromJSON decodes an error that was marshalled to JSON, the second
+// return value is set if data is not a valid encoded error.
+func ErrorFromJSON(data []byte) (error, error) {
+	var ce cacheError
+
+	err := json.Unmarshal(data, &ce)
+	if err != nil {
+		return nil, err
+	}
+
+	return ce, nil
+}
+
 type errorType string
 
 const (
@@ -111,6 +385,10 @@ const (
 	errorTypeInvalidKeyType              = "InvalidKeyType"
 	errorTypeInvalidMessage              = "InvalidMessage"
 	errorTypeCacheNotEmpty               = "CacheNotEmpty"
+	errorTypePartialFailure              = "PartialFailure"
+	errorTypeCapacityExceeded            = "CapacityExceeded"
+	errorTypeInvalidPage                 = "InvalidPage"
+	errorTypeVersionMismatch             = "VersionMismatch"
 )
 
 func newError(errType errorType, msg string) cacheError {
@@ -163,4 +441,318 @@ func IsInvalidMessage(err error) bool {
 	return isCacheErr && cacheErr.errType == errorTypeInvalidMessage
 }
 
+func IsCacheNotEmpty(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeCacheNotEmpty
+}
+
+func IsPartialFailure(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypePartialFailure
+}
+
+func IsCapacityExceeded(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeCapacityExceeded
+}
+
+func IsInvalidPage(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeInvalidPage
+}
+
+func IsVersionMismatch(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeVersionMismatch
+}
+
+// -----------------------------------------
+
+// The predicates below are aliases that follow the Is<Name>Error naming of
+// IsUnexpectedError and IsRedisError.
+
+// IsDoesntExistError is an alias of IsDoesNotExist.
+//
+// Deprecated: Use IsDoesNotExist.
+func IsDoesntExistError(err error) bool {
+	return IsDoesNotExist(err)
+}
+
+// IsAlreadyExistsError is an alias of IsAlreadyExists.
+//
+// Deprecated: Use IsAlreadyExists.
+func IsAlreadyExistsError(err error) bool {
+	return IsAlreadyExists(err)
+}
+
+// IsNonPositivePeriodError is an alias of IsNonPositivePeriod.
+//
+// Deprecated: Use IsNonPositivePeriod.
+func IsNonPositivePeriodError(err error) bool {
+	return IsNonPositivePeriod(err)
+}
+
+// IsNilUpdateFuncError is an alias of IsNilUpdateFunc.
+//
+// Deprecated: Use IsNilUpdateFunc.
+func IsNilUpdateFuncError(err error) bool {
+	return IsNilUpdateFunc(err)
+}
+
+// IsInvalidKeyTypeError is an alias of IsInvalidKeyType.
+//
+// Deprecated: Use IsInvalidKeyType.
+func IsInvalidKeyTypeError(err error) bool {
+	return IsInvalidKeyType(err)
+}
+
+// IsInvalidMessageError is an alias of IsInvalidMessage.
+//
+// Deprecated: Use IsInvalidMessage.
+func IsInvalidMessageError(err error) bool {
+	return IsInvalidMessage(err)
+}
+
+// IsCacheNotEmptyError is an alias of IsCacheNotEmpty.
+//
+// Deprecated: Use IsCacheNotEmpty.
+func IsCacheNotEmptyError(err error) bool {
+	return IsCacheNotEmpty(err)
+}
+
+// IsPartialFailureError is an alias of IsPartialFailure.
+//
+// Deprecated: Use IsPartialFailure.
+func IsPartialFailureError(err error) bool {
+	return IsPartialFailure(err)
+}
+
+// IsCapacityExceededError is an alias of IsCapacityExceeded.
+//
+// Deprecated: Use IsCapacityExceeded.
+func IsCapacityExceededError(err error) bool {
+	return IsCapacityExceeded(err)
+}
+
+// IsInvalidPageError is an alias of IsInvalidPage.
+//
+// Deprecated: Use IsInvalidPage.
+func IsInvalidPageError(err error) bool {
+	return IsInvalidPage(err)
+}
+
+// IsVersionMismatchError is an alias of IsVersionMismatch.
+//
+// Deprecated: Use IsVersionMismatch.
+func IsVersionMismatchError(err error) bool {
+	return IsVersionMismatch(err)
+}
+
+// IsUnrecoverableValueError is an alias of IsUnrecoverableValue.
+//
+// Deprecated: Use IsUnrecoverableValue.
+func IsUnrecoverableValueError(err error) bool {
+	return IsUnrecoverableValue(err)
+}
+
+// IsInvalidValueTypeError is an alias of IsInvalidValueType.
+//
+// Deprecated: Use IsInvalidValueType.
+func IsInvalidValueTypeError(err error) bool {
+	return IsInvalidValueType(err)
+}
+
+// IsNilValueError is an alias of IsNilValue.
+//
+// Deprecated: Use IsNilValue.
+func IsNilValueError(err error) bool {
+	return IsNilValue(err)
+}
+
+// IsClearedCacheError is an alias of IsClearedCache.
+//
+// Deprecated: Use IsClearedCache.
+func IsClearedCacheError(err error) bool {
+	return IsClearedCache(err)
+}
+
+// IsInvalidDecayFactorError is an alias of IsInvalidDecayFactor.
+//
+// Deprecated: Use IsInvalidDecayFactor.
+func IsInvalidDecayFactorError(err error) bool {
+	return IsInvalidDecayFactor(err)
+}
+
+// IsRateLimitExceededError is an alias of IsRateLimitExceeded.
+//
+// Deprecated: Use IsRateLimitExceeded.
+func IsRateLimitExceededError(err error) bool {
+	return IsRateLimitExceeded(err)
+}
+
+// IsCircuitOpenError is an alias of IsCircuitOpen.
+//
+// Deprecated: Use IsCircuitOpen.
+func IsCircuitOpenError(err error) bool {
+	return IsCircuitOpen(err)
+}
+
+// Combines the errors of a bulk operation by key, returns nil if there are
+// no errors.
+func combineErrors(errs map[interface{}]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	msgs := []string{}
+	for key, err := range errs {
+		msgs = append(msgs, fmt.Sprintf("key %v: %v", key, err))
+	}
+	sort.Strings(msgs)
+
+	return newError(errorTypePartialFailure,
+		fmt.Sprintf("%d operations failed: %s", len(errs), strings.Join(msgs, "; ")))
+}
+
+// -----------------------------------------
+
+// Stores each of the items using store, errors are combined by key.
+func storeMany(items map[interface{}]interface{},
+	store func(key, val interface{}) error) error {
+	errs := map[interface{}]error{}
+
+	for key, val := range items {
+		err := store(key, val)
+		if err != nil {
+			errs[key] = err
+		}
+	}
+
+	return combineErrors(errs)
+}
+
+// Gets each of the keys using get, errors are combined by key.
+func getMany(keys []interface{},
+	get func(key interface{}) (interface{}, error)) (map[interface{}]interface{}, error) {
+	vals := map[interface{}]interface{}{}
+	errs := map[interface{}]error{}
+
+	for _, key := range keys {
+		val, err := get(key)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+
+		vals[key] = val
+	}
+
+	return vals, combineErrors(errs)
+}
+
+// Returns the keys for which predicate returns true.
+func filterKeys(keys []interface{}, predicate func(key interface{}) bool) []interface{} {
+	filtered := []interface{}{}
+	for _, key := range keys {
+		if predicate(key) {
+			filtered = append(filtered, key)
+		}
+	}
+
+	return filtered
+}
+
+// Removes each of the keys using remove, returns the removed keys and the
+// errors combined by key.
+func removeMany(keys []interface{},
+	remove func(key interface{}) error) ([]interface{}, error) {
+	removed := []interface{}{}
+	errs := map[interface{}]error{}
+
+	for _, key := range keys {
+		err := remove(key)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+
+		removed = append(removed, key)
+	}
+
+	return removed, combineErrors(errs)
+}
+
+// Validates the parameters of the functions that store updating values.
+func validateUpdateParams(updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	if updateFunc == nil {
+		return newError(errorTypeNilUpdateFunc, "updateFunc cannot be nil")
+	}
+
+	if period <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	return nil
+}
+
+// Returns the ttl of a value that should be removed at deadline.
+func ttlUntil(deadline time.Time) (time.Duration, error) {
+	ttl := time.Until(deadline)
+	if ttl <= 0 {
+		return 0, newError(errorTypeNonPositivePeriod,
+			"deadline must be in the future")
+	}
+
+	return ttl, nil
+}
+
+// Returns limit keys starting at offset along with the total number of keys.
+func keysPage(keys []interface{}, offset, limit int) ([]interface{}, int, error) {
+	if offset < 0 || limit < 0 {
+		return nil, 0, newError(errorTypeInvalidPage,
+			"offset and limit cannot be negative")
+	}
+
+	total := len(keys)
+	if offset > total {
+		offset = total
+	}
+
+	end := total
+	if limit < total-offset {
+		end = offset + limit
+	}
+
+	return keys[offset:end], total, nil
+}
+
+// Counts the hits and misses of a cache's lookups, the counters are atomic so
+// they can be updated under a read lock.
+type hitStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func (hs *hitStats) recordLookup(found bool) {
+	if found {
+		hs.hits.Add(1)
+	} else {
+		hs.misses.Add(1)
+	}
+}
+
+// HitStats returns the number of lookups that found their key and the number
+// of lookups that did not.
+func (hs *hitStats) HitStats() (hits, misses int64) {
+	return hs.hits.Load(), hs.misses.Load()
+}
+
+// ResetStats sets the hit and miss counters to zero.
+func (hs *hitStats) ResetStats() {
+	hs.hits.Store(0)
+	hs.misses.Store(0)
+}
+
 // -----------------------------------------
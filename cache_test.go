@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache Error", func() {
+	Context("MarshalJSON", func() {
+		It("should encode the type, message and nested error", func() {
+			err := newWrapperError(errorTypeUnexpectedError, "failed", errors.New("nested"))
+
+			data, jsonErr := json.Marshal(err)
+			Expect(jsonErr).ToNot(HaveOccurred())
+			Expect(data).To(MatchJSON(`{"type":"UnexpectedError","message":"failed","nested":"nested"}`))
+		})
+
+		It("should omit a missing nested error", func() {
+			data, err := json.Marshal(newError(errorTypeDoesNotExist, "missing"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(MatchJSON(`{"type":"DoesNotExist","message":"missing"}`))
+		})
+	})
+
+	Context("ErrorFromJSON", func() {
+		It("should reconstruct an encoded error", func() {
+			data, err := json.Marshal(newWrapperError(errorTypeDoesNotExist, "missing", errors.New("nested")))
+			Expect(err).ToNot(HaveOccurred())
+
+			decoded, err := ErrorFromJSON(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(IsDoesNotExist(decoded)).To(BeTrue())
+			Expect(decoded.Error()).To(Equal("missing"))
+			Expect(errors.Unwrap(decoded)).To(MatchError("nested"))
+		})
+
+		It("should return an error for invalid json", func() {
+			_, err := ErrorFromJSON([]byte("{"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Is<Name>Error aliases", func() {
+		It("should match the same errors as the predicates they alias", func() {
+			aliases := map[errorType]func(error) bool{
+				errorTypeDoesNotExist:       IsDoesntExistError,
+				errorTypeAlreadyExists:      IsAlreadyExistsError,
+				errorTypeNonPositivePeriod:  IsNonPositivePeriodError,
+				errorTypeNilUpdateFunc:      IsNilUpdateFuncError,
+				errorTypeInvalidKeyType:     IsInvalidKeyTypeError,
+				errorTypeInvalidMessage:     IsInvalidMessageError,
+				errorTypeCacheNotEmpty:      IsCacheNotEmptyError,
+				errorTypePartialFailure:     IsPartialFailureError,
+				errorTypeCapacityExceeded:   IsCapacityExceededError,
+				errorTypeInvalidPage:        IsInvalidPageError,
+				errorTypeVersionMismatch:    IsVersionMismatchError,
+				errorTypeUrecoverableValue:  IsUnrecoverableValueError,
+				errorTypeInvalidValueType:   IsInvalidValueTypeError,
+				errorTypeNilValue:           IsNilValueError,
+				errorTypeClearedCache:       IsClearedCacheError,
+				errorTypeInvalidDecayFactor: IsInvalidDecayFactorError,
+				errorTypeRateLimitExceeded:  IsRateLimitExceededError,
+				errorTypeCircuitOpen:        IsCircuitOpenError,
+			}
+
+			for errType, alias := range aliases {
+				Expect(alias(newError(errType, "msg"))).To(BeTrue(), string(errType))
+				Expect(alias(newError(errorTypeUnexpectedError, "msg"))).To(BeFalse(), string(errType))
+			}
+		})
+	})
+
+	Context("errors.Is", func() {
+		It("should match errors of the same type through wrapping", func() {
+			err := fmt.Errorf("wrapped: %w", newError(errorTypeDoesNotExist, "missing"))
+
+			Expect(errors.Is(err, newError(errorTypeDoesNotExist, ""))).To(BeTrue())
+			Expect(errors.Is(err, newError(errorTypeAlreadyExists, ""))).To(BeFalse())
+		})
+
+		It("should match the nested error", func() {
+			nested := errors.New("nested")
+			err := newWrapperError(errorTypeUnexpectedError, "failed", nested)
+
+			Expect(errors.Is(err, nested)).To(BeTrue())
+
+			var target cacheError
+			Expect(errors.As(fmt.Errorf("wrapped: %w", err), &target)).To(BeTrue())
+			Expect(target.errType).To(Equal(errorType(errorTypeUnexpectedError)))
+		})
+	})
+})
+
+var _ = Describe("validateUpdateParams", func() {
+	updateFunc := func(currValue interface{}) (interface{}, error) {
+		return currValue, nil
+	}
+
+	It("should reject a nil updateFunc", func() {
+		Expect(IsNilUpdateFunc(validateUpdateParams(nil, time.Second))).To(BeTrue())
+	})
+
+	It("should reject a non-positive period", func() {
+		Expect(IsNonPositivePeriod(validateUpdateParams(updateFunc, 0))).To(BeTrue())
+	})
+
+	It("should accept valid parameters", func() {
+		Expect(validateUpdateParams(updateFunc, time.Second)).ToNot(HaveOccurred())
+	})
+})
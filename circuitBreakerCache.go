@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	errorTypeCircuitOpen errorType = "CircuitOpen"
+)
+
+func IsCircuitOpen(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeCircuitOpen
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuitBreakerCache struct {
+	underlying Cache
+
+	// The number of consecutive failures that open the circuit.
+	threshold int
+
+	// How long the circuit stays open before a probe is let through.
+	openDuration time.Duration
+
+	state circuitState
+
+	// The number of consecutive failures since the last success.
+	failures int
+
+	// When the circuit was last opened.
+	openedAt time.Time
+
+	// Indication if a probe is running while the circuit is half-open.
+	probing bool
+
+	// Gets the current time, replaced by tests.
+	now func() time.Time
+
+	mutex sync.Mutex
+}
+
+var _ Cache = (*circuitBreakerCache)(nil)
+
+// NewCacheWithCircuitBreaker creates a new Cache object that stops calling
+// underlying after threshold consecutive failures, all operations then fail
+// with CircuitOpen for openDuration.
+//
+// Once openDuration passes, a single operation is let through to probe
+// underlying, the circuit is closed if it succeeds and opened again if it
+// fails. Errors of the backend, such as Redis, etcd and Memcached errors,
+// and errors that do not come from this package count as failures, errors
+// that are caused by the caller such as DoesNotExist do not.
+func NewCacheWithCircuitBreaker(underlying Cache, threshold int, openDuration time.Duration) Cache {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &circuitBreakerCache{
+		underlying:   underlying,
+		threshold:    threshold,
+		openDuration: openDuration,
+		now:          time.Now,
+	}
+}
+
+// The errors of operations that reached a working backend. A partial failure
+// means that some of the operations succeeded.
+var callerErrorTypes = map[errorType]struct{}{
+	errorTypeAlreadyExists:      {},
+	errorTypeDoesNotExist:       {},
+	errorTypeNonPositivePeriod:  {},
+	errorTypeNilUpdateFunc:      {},
+	errorTypeInvalidKeyType:     {},
+	errorTypeInvalidMessage:     {},
+	errorTypeCacheNotEmpty:      {},
+	errorTypePartialFailure:     {},
+	errorTypeCapacityExceeded:   {},
+	errorTypeInvalidPage:        {},
+	errorTypeVersionMismatch:    {},
+	errorTypeUrecoverableValue:  {},
+	errorTypeInvalidValueType:   {},
+	errorTypeNilValue:           {},
+	errorTypeClearedCache:       {},
+	errorTypeInvalidDecayFactor: {},
+	errorTypeRateLimitExceeded:  {},
+	errorTypeCircuitOpen:        {},
+}
+
+// Returns whether an operation that returned err failed because of the
+// backend.
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	cacheErr, isCacheErr := err.(cacheError)
+	if !isCacheErr {
+		return true
+	}
+
+	_, isCallerError := callerErrorTypes[cacheErr.errType]
+
+	return !isCallerError
+}
+
+// Returns an error if the circuit does not let an operation through.
+func (cb *circuitBreakerCache) allow() error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.openDuration {
+			break
+		}
+
+		cb.state = circuitHalfOpen
+		cb.probing = true
+
+		return nil
+	case circuitHalfOpen:
+		if cb.probing {
+			break
+		}
+
+		cb.probing = true
+
+		return nil
+	default:
+		return nil
+	}
+
+	return newError(errorTypeCircuitOpen,
+		fmt.Sprintf("circuit is open after %d consecutive failures", cb.failures))
+}
+
+// Records the result of an operation that was let through.
+func (cb *circuitBreakerCache) record(err error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.probing = false
+
+	if !isBackendFailure(err) {
+		cb.state = circuitClosed
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.state == circuitHalfOpen || cb.failures >= cb.threshold {
+		cb.state = circuitOpen
+		cb.openedAt = cb.now()
+	}
+}
+
+// Calls op if the circuit lets it through.
+func (cb *circuitBreakerCache) do(op func() error) error {
+	err := cb.allow()
+	if err != nil {
+		return err
+	}
+
+	err = op()
+	cb.record(err)
+
+	return err
+}
+
+// Store a permanent value in the underlying cache.
+func (cb *circuitBreakerCache) Store(key, val interface{}) error {
+	return cb.do(func() error {
+		return cb.underlying.Store(key, val)
+	})
+}
+
+// Get a value from the underlying cache.
+func (cb *circuitBreakerCache) Get(key interface{}) (interface{}, error) {
+	var val interface{}
+	err := cb.do(func() error {
+		var err error
+		val, err = cb.underlying.Get(key)
+		return err
+	})
+
+	return val, err
+}
+
+// Check whether the underlying cache has a key.
+func (cb *circuitBreakerCache) Contains(key interface{}) (bool, error) {
+	var exists bool
+	err := cb.do(func() error {
+		var err error
+		exists, err = cb.underlying.Contains(key)
+		return err
+	})
+
+	return exists, err
+}
+
+// Get a value from the underlying cache, or store val if the key does not
+// exist.
+func (cb *circuitBreakerCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	var actual interface{}
+	var loaded bool
+	err := cb.do(func() error {
+		var err error
+		actual, loaded, err = cb.underlying.GetOrStore(key, val)
+		return err
+	})
+
+	return actual, loaded, err
+}
+
+// Remove a value from the underlying cache.
+func (cb *circuitBreakerCache) Remove(key interface{}) error {
+	return cb.do(func() error {
+		return cb.underlying.Remove(key)
+	})
+}
+
+// Get a value from the underlying cache and remove it.
+func (cb *circuitBreakerCache) GetAndRemove(key interface{}) (interface{}, error) {
+	var val interface{}
+	err := cb.do(func() error {
+		var err error
+		val, err = cb.underlying.GetAndRemove(key)
+		return err
+	})
+
+	return val, err
+}
+
+// Replace a value in the underlying cache.
+func (cb *circuitBreakerCache) Replace(key, val interface{}) error {
+	return cb.do(func() error {
+		return cb.underlying.Replace(key, val)
+	})
+}
+
+// Store a permanent value in the underlying cache, replacing the current
+// value if the key exists.
+func (cb *circuitBreakerCache) StoreOrReplace(key, val interface{}) error {
+	return cb.do(func() error {
+		return cb.underlying.StoreOrReplace(key, val)
+	})
+}
+
+// Store several permanent values in the underlying cache.
+func (cb *circuitBreakerCache) StoreMany(items map[interface{}]interface{}) error {
+	return cb.do(func() error {
+		return cb.underlying.StoreMany(items)
+	})
+}
+
+// Get several values from the underlying cache.
+func (cb *circuitBreakerCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	var vals map[interface{}]interface{}
+	err := cb.do(func() error {
+		var err error
+		vals, err = cb.underlying.GetMany(keys)
+		return err
+	})
+
+	return vals, err
+}
+
+// Clear the underlying cache.
+func (cb *circuitBreakerCache) Clear() error {
+	return cb.do(func() error {
+		return cb.underlying.Clear()
+	})
+}
+
+// Get all keys from the underlying cache.
+func (cb *circuitBreakerCache) Keys() ([]interface{}, error) {
+	var keys []interface{}
+	err := cb.do(func() error {
+		var err error
+		keys, err = cb.underlying.Keys()
+		return err
+	})
+
+	return keys, err
+}
+
+// Calls fn for each key and value in the underlying cache until fn returns
+// false.
+func (cb *circuitBreakerCache) ForEach(fn func(key, val interface{}) bool) error {
+	return cb.do(func() error {
+		return cb.underlying.ForEach(fn)
+	})
+}
+
+// Get the keys of the underlying cache for which predicate returns true.
+func (cb *circuitBreakerCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	var keys []interface{}
+	err := cb.do(func() error {
+		var err error
+		keys, err = cb.underlying.FilterKeys(predicate)
+		return err
+	})
+
+	return keys, err
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Fails Store and Get with err while it is set.
+type failingCache struct {
+	Cache
+	err   error
+	calls int
+}
+
+func (fc *failingCache) Store(key, val interface{}) error {
+	fc.calls++
+	if fc.err != nil {
+		return fc.err
+	}
+
+	return fc.Cache.Store(key, val)
+}
+
+func (fc *failingCache) Get(key interface{}) (interface{}, error) {
+	fc.calls++
+	if fc.err != nil {
+		return nil, fc.err
+	}
+
+	return fc.Cache.Get(key)
+}
+
+var _ = Describe("Circuit Breaker Cache", func() {
+	var (
+		c        Cache
+		failing  *failingCache
+		now      time.Time
+		key, val string = "key", "val"
+	)
+
+	BeforeEach(func() {
+		failing = &failingCache{
+			Cache: NewMapCache(),
+			err:   errors.New("connection refused"),
+		}
+		now = time.Now()
+
+		c = NewCacheWithCircuitBreaker(failing, 3, time.Minute)
+		c.(*circuitBreakerCache).now = func() time.Time {
+			return now
+		}
+	})
+
+	Context("Closed", func() {
+		It("should open after threshold consecutive failures", func() {
+			for i := 0; i < 3; i++ {
+				Expect(c.Store(key, val)).To(MatchError("connection refused"))
+			}
+
+			Expect(IsCircuitOpen(c.Store(key, val))).To(BeTrue())
+			_, err := c.Get(key)
+			Expect(IsCircuitOpen(err)).To(BeTrue())
+			Expect(failing.calls).To(Equal(3))
+		})
+
+		It("should reset the failures on a success", func() {
+			Expect(c.Store(key, val)).To(HaveOccurred())
+			Expect(c.Store(key, val)).To(HaveOccurred())
+
+			failing.err = nil
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			failing.err = errors.New("connection refused")
+			Expect(c.Store(key, val)).To(HaveOccurred())
+			Expect(c.Store(key, val)).To(HaveOccurred())
+			Expect(IsCircuitOpen(c.Store(key, val))).To(BeFalse())
+		})
+
+		It("should not count errors of missing or existing keys", func() {
+			failing.err = nil
+			for i := 0; i < 5; i++ {
+				_, err := c.Get(key)
+				Expect(IsDoesNotExist(err)).To(BeTrue())
+			}
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+		})
+
+		for _, errType := range []errorType{
+			errorTypeUnexpectedError,
+			errorTypeRedisError,
+			errorTypeEtcdError,
+			errorTypeMemcachedError,
+			errorTypeMultiLevelError,
+		} {
+			errType := errType
+			It(fmt.Sprintf("should count %s errors as failures", errType), func() {
+				failing.err = newError(errType, "backend failure")
+				for i := 0; i < 3; i++ {
+					Expect(c.Store(key, val)).To(HaveOccurred())
+				}
+
+				Expect(IsCircuitOpen(c.Store(key, val))).To(BeTrue())
+			})
+		}
+	})
+
+	Context("Half-open", func() {
+		BeforeEach(func() {
+			for i := 0; i < 3; i++ {
+				Expect(c.Store(key, val)).To(HaveOccurred())
+			}
+		})
+
+		It("should stay open until the open duration passes", func() {
+			now = now.Add(time.Minute - time.Second)
+			Expect(IsCircuitOpen(c.Store(key, val))).To(BeTrue())
+			Expect(failing.calls).To(Equal(3))
+		})
+
+		It("should close if the probe succeeds", func() {
+			failing.err = nil
+			now = now.Add(time.Minute)
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should open again if the probe fails", func() {
+			now = now.Add(time.Minute)
+
+			Expect(c.Store(key, val)).To(MatchError("connection refused"))
+			Expect(IsCircuitOpen(c.Store(key, val))).To(BeTrue())
+			Expect(failing.calls).To(Equal(4))
+		})
+	})
+
+	Context("NewCacheWithCircuitBreaker", func() {
+		It("should open after a single failure for a non-positive threshold", func() {
+			cb := NewCacheWithCircuitBreaker(failing, 0, time.Minute)
+			Expect(cb.Store(key, val)).To(HaveOccurred())
+			Expect(IsCircuitOpen(cb.Store(key, val))).To(BeTrue())
+		})
+	})
+})
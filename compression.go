@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor compresses encoded values before they are persisted, it is used
+// by caches that persist their values, such as directoryCache.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// ZstdCompressor compresses values using Zstandard, which is fast and has a
+// good compression ratio.
+type ZstdCompressor struct{}
+
+func (ZstdCompressor) Compress(data []byte) ([]byte, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (ZstdCompressor) Decompress(data []byte) ([]byte, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	return decoder.DecodeAll(data, nil)
+}
+
+// GzipCompressor compresses values using compress/gzip.
+type GzipCompressor struct{}
+
+func (GzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
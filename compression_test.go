@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+)
+
+func benchmarkCompression(b *testing.B, c Compressor) {
+	data := bytes.Repeat([]byte(`{"str":"Test","int":1}`), 256)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compressed, err := c.Compress(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		_, err = c.Decompress(compressed)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkZstdCompressor(b *testing.B) {
+	benchmarkCompression(b, ZstdCompressor{})
+}
+
+func BenchmarkGzipCompressor(b *testing.B) {
+	benchmarkCompression(b, GzipCompressor{})
+}
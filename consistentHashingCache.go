@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// The number of points each shard has on the ring, more points spread the
+// keys more evenly between the shards.
+const consistentHashingReplicas = 100
+
+type consistentHashingCache struct {
+	// The caches that hold the data, each key belongs to a single shard.
+	shards []Cache
+
+	// The sorted hashes of the points on the ring.
+	ring []uint32
+
+	// Holds the index of the shard that owns each point on the ring.
+	owners map[uint32]int
+}
+
+var _ Cache = (*consistentHashingCache)(nil)
+
+// NewConsistentHashingCache creates a new Cache object that routes each key
+// to one of shards by consistent hashing, shards may be of different types.
+//
+// A shard is placed on the ring by its index, so appending a shard or
+// removing the last one only moves the keys that the shard gains or loses.
+// If shards is empty, a single map is used.
+func NewConsistentHashingCache(shards []Cache) Cache {
+	if len(shards) == 0 {
+		shards = []Cache{NewMapCache()}
+	}
+
+	chc := &consistentHashingCache{
+		shards: shards,
+		owners: map[uint32]int{},
+	}
+
+	for i := range shards {
+		for replica := 0; replica < consistentHashingReplicas; replica++ {
+			point := ringHash(fmt.Sprintf("shard-%d-%d", i, replica))
+
+			// On a collision the point stays with the first shard.
+			if _, exists := chc.owners[point]; exists {
+				continue
+			}
+
+			chc.owners[point] = i
+			chc.ring = append(chc.ring, point)
+		}
+	}
+
+	sort.Slice(chc.ring, func(i, j int) bool {
+		return chc.ring[i] < chc.ring[j]
+	})
+
+	return chc
+}
+
+// Returns the position of s on the ring.
+func ringHash(s string) uint32 {
+	h := fnv.New32a()
+
+	// Writing to a hash never returns an error.
+	_, _ = h.Write([]byte(s))
+
+	// Similar strings have close FNV hashes, the finalizer of murmur3 spreads
+	// them over the ring.
+	sum := h.Sum32()
+	sum ^= sum >> 16
+	sum *= 0x85ebca6b
+	sum ^= sum >> 13
+	sum *= 0xc2b2ae35
+	sum ^= sum >> 16
+
+	return sum
+}
+
+// Returns the shard that owns the first point on the ring after the key.
+func (chc *consistentHashingCache) shard(key interface{}) Cache {
+	hash := ringHash(fmt.Sprintf("%v", key))
+
+	i := sort.Search(len(chc.ring), func(i int) bool {
+		return chc.ring[i] >= hash
+	})
+	if i == len(chc.ring) {
+		i = 0
+	}
+
+	return chc.shards[chc.owners[chc.ring[i]]]
+}
+
+// Store a permanent value in the key's shard.
+func (chc *consistentHashingCache) Store(key, val interface{}) error {
+	return chc.shard(key).Store(key, val)
+}
+
+// Get a value from the key's shard.
+func (chc *consistentHashingCache) Get(key interface{}) (interface{}, error) {
+	return chc.shard(key).Get(key)
+}
+
+// Check whether a key exists in the key's shard.
+func (chc *consistentHashingCache) Contains(key interface{}) (bool, error) {
+	return chc.shard(key).Contains(key)
+}
+
+// Get a value from the key's shard, or store val if the key does not exist.
+func (chc *consistentHashingCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	return chc.shard(key).GetOrStore(key, val)
+}
+
+// Remove a value from the key's shard.
+func (chc *consistentHashingCache) Remove(key interface{}) error {
+	return chc.shard(key).Remove(key)
+}
+
+// Get a value from the key's shard and remove it.
+func (chc *consistentHashingCache) GetAndRemove(key interface{}) (interface{}, error) {
+	return chc.shard(key).GetAndRemove(key)
+}
+
+// Replace a value in the key's shard.
+func (chc *consistentHashingCache) Replace(key, val interface{}) error {
+	return chc.shard(key).Replace(key, val)
+}
+
+// Store a permanent value in the key's shard, replacing the current value if
+// it exists.
+func (chc *consistentHashingCache) StoreOrReplace(key, val interface{}) error {
+	return chc.shard(key).StoreOrReplace(key, val)
+}
+
+// Store several permanent values, each in its key's shard.
+func (chc *consistentHashingCache) StoreMany(items map[interface{}]interface{}) error {
+	return storeMany(items, chc.Store)
+}
+
+// Get several values, each from its key's shard.
+func (chc *consistentHashingCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	return getMany(keys, chc.Get)
+}
+
+// Clear all shards.
+func (chc *consistentHashingCache) Clear() error {
+	for _, shard := range chc.shards {
+		err := shard.Clear()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the keys of all shards.
+func (chc *consistentHashingCache) Keys() ([]interface{}, error) {
+	keys := []interface{}{}
+
+	for _, shard := range chc.shards {
+		shardKeys, err := shard.Keys()
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, shardKeys...)
+	}
+
+	return keys, nil
+}
+
+// Calls fn for each key and value in all shards until fn returns false.
+func (chc *consistentHashingCache) ForEach(fn func(key, val interface{}) bool) error {
+	proceed := true
+	for _, shard := range chc.shards {
+		err := shard.ForEach(func(key, val interface{}) bool {
+			proceed = fn(key, val)
+			return proceed
+		})
+		if err != nil || !proceed {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the keys of all shards for which predicate returns true.
+func (chc *consistentHashingCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	keys := []interface{}{}
+
+	for _, shard := range chc.shards {
+		shardKeys, err := shard.FilterKeys(predicate)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, shardKeys...)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Consistent Hashing Cache", func() {
+	const keyCount = 1000
+
+	var (
+		c        Cache
+		shards   []Cache
+		key, val string = "test-key", "test-val"
+	)
+
+	BeforeEach(func() {
+		shards = []Cache{NewMapCache(), NewMapCache(), NewSyncMapCache()}
+		c = NewConsistentHashingCache(shards)
+	})
+
+	// Returns the index of the shard that holds each key.
+	ownerOf := func(shards []Cache) map[string]int {
+		owners := map[string]int{}
+		for i, shard := range shards {
+			keys, err := shard.Keys()
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, key := range keys {
+				owners[key.(string)] = i
+			}
+		}
+
+		return owners
+	}
+
+	storeKeys := func(c Cache) {
+		for i := 0; i < keyCount; i++ {
+			Expect(c.Store(fmt.Sprintf("key-%d", i), val)).ToNot(HaveOccurred())
+		}
+	}
+
+	Context("Store", func() {
+		It("should store a value in a single shard", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(ownerOf(shards)).To(HaveLen(1))
+		})
+
+		It("should spread the keys between all shards", func() {
+			storeKeys(c)
+
+			for _, shard := range shards {
+				keys, err := shard.Keys()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(keys)).To(BeNumerically(">", keyCount/10))
+			}
+		})
+	})
+
+	Context("Routing", func() {
+		It("should only move the keys of an appended shard", func() {
+			storeKeys(c)
+			before := ownerOf(shards)
+
+			grown := append(append([]Cache{}, NewMapCache(), NewMapCache(), NewSyncMapCache()), NewMapCache())
+			storeKeys(NewConsistentHashingCache(grown))
+			after := ownerOf(grown)
+
+			moved := 0
+			for key, owner := range after {
+				if owner != before[key] {
+					Expect(owner).To(Equal(len(grown) - 1))
+					moved++
+				}
+			}
+
+			Expect(moved).To(BeNumerically(">", 0))
+			Expect(moved).To(BeNumerically("<", keyCount/2))
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return the keys of all shards", func() {
+			storeKeys(c)
+			Expect(c.Keys()).To(HaveLen(keyCount))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should clear all shards", func() {
+			storeKeys(c)
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(ownerOf(shards)).To(BeEmpty())
+		})
+	})
+
+	Context("NewConsistentHashingCache", func() {
+		It("should use a single map when there are no shards", func() {
+			c = NewConsistentHashingCache(nil)
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+	})
+})
@@ -1,14 +1,22 @@
 package cache
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"log/slog"
 	"os"
 	"path"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"google.golang.org/protobuf/proto"
 )
 
 // -----------------------------------------
@@ -40,6 +48,37 @@ func IsClearedCache(err error) bool {
 	return isCacheErr && cacheErr.errType == errorTypeClearedCache
 }
 
+const (
+	// The suffix of the files that hold the type urls of protocol buffer
+	// values.
+	protoTypeFileSuffix = ".type"
+
+	// The suffix of the files that values are written to before they are
+	// renamed to their key files.
+	tempFileSuffix = ".tmp"
+
+	// The file that processes sharing the cache directory lock.
+	lockFileName = ".lock"
+)
+
+// Check whether a file in the cache directory is not a key file.
+func isInternalFile(name string) bool {
+	return name == lockFileName ||
+		strings.HasSuffix(name, protoTypeFileSuffix) ||
+		strings.HasSuffix(name, tempFileSuffix)
+}
+
+// EventType describes an external change of a watched key file.
+type EventType int
+
+const (
+	// The file was written by another process.
+	EventModified EventType = iota
+
+	// The file was deleted by another process.
+	EventDeleted
+)
+
 // -----------------------------------------
 
 type directoryCache struct {
@@ -52,29 +91,159 @@ type directoryCache struct {
 	// Holds the channels that stop the auto update routines.
 	updateChannels map[string]*cacheChannel
 
+	// Holds the update functions of the auto update routines.
+	updateFuncs map[string]func(currValue interface{}) (interface{}, error)
+
+	// Holds the ttls of values with a sliding expiration.
+	slidingTTLs map[string]time.Duration
+
+	// Holds the times in which temporary values will be removed.
+	deadlines map[string]time.Time
+
 	// Holds pointers to stored structs to allow recovery from a file.
 	valueTypes map[string]reflect.Type
 
+	// Holds the hashes of the files the cache wrote, to tell external
+	// changes apart from the cache's own.
+	fileHashes map[string][sha256.Size]byte
+
 	// Indication if the cache was cleared, if it was, it should not be usable.
 	cleared bool
 
+	// Encodes values to and from their files.
+	encoding Encoding
+
+	// Compresses the encoded values, values are not compressed if it is nil.
+	compressor Compressor
+
+	// Called with the errors of the auto update routines.
+	onUpdateError func(key string, err error)
+
+	// Logs the unexpected errors of the background routines.
+	logger *slog.Logger
+
+	// The permissions of the value files.
+	fileMode os.FileMode
+
+	// The permissions of the cache directory.
+	dirMode os.FileMode
+
+	// Whether value files are read and written under a lock on the lock
+	// file, which is shared with other processes.
+	processLock bool
+
+	// The open lock file while the lock is held, along with the number of
+	// operations that hold it, so nested operations reuse the lock.
+	lockFile      *os.File
+	lockDepth     int
+	lockExclusive bool
+
 	mutex sync.Mutex
 }
 
 var _ UpdatingExpiringCache = (*directoryCache)(nil)
+var _ ContextCache = (*directoryCache)(nil)
+var _ CopyableCache = (*directoryCache)(nil)
+
+// DirectoryCacheOption configures a directoryCache.
+type DirectoryCacheOption func(*directoryCache)
+
+// WithEncoding sets the encoding of the value files, JSONEncoding is used by
+// default.
+func WithEncoding(enc Encoding) DirectoryCacheOption {
+	return func(dc *directoryCache) {
+		dc.encoding = enc
+	}
+}
+
+// WithCompression compresses values after they are encoded, values are not
+// compressed by default.
+func WithCompression(c Compressor) DirectoryCacheOption {
+	return func(dc *directoryCache) {
+		dc.compressor = c
+	}
+}
+
+// WithDirectoryUpdateErrorHandler sets a function that is called whenever
+// updating a value in the background fails, the value keeps its current value
+// when updateFunc returns an error.
+//
+// Without a handler, errors of updateFunc are ignored and unexpected errors
+// are logged and remove the value.
+func WithDirectoryUpdateErrorHandler(onUpdateError func(key string, err error)) DirectoryCacheOption {
+	return func(dc *directoryCache) {
+		dc.onUpdateError = onUpdateError
+	}
+}
+
+// WithDirectoryLogger sets the logger of unexpected errors in background
+// routines, slog.Default() is used by default.
+func WithDirectoryLogger(logger *slog.Logger) DirectoryCacheOption {
+	return func(dc *directoryCache) {
+		dc.logger = logger
+	}
+}
+
+// WithFileMode sets the permissions of the value files, 0600 is used by
+// default.
+func WithFileMode(mode os.FileMode) DirectoryCacheOption {
+	return func(dc *directoryCache) {
+		dc.fileMode = mode
+	}
+}
+
+// WithDirMode sets the permissions of the cache directory when it is
+// created, 0700 is used by default.
+func WithDirMode(mode os.FileMode) DirectoryCacheOption {
+	return func(dc *directoryCache) {
+		dc.dirMode = mode
+	}
+}
+
+// WithExclusiveProcessLock sets whether value files are read and written
+// under an advisory lock on a .lock file in the cache directory, so that
+// several processes can share it. The lock is taken by default, callers that
+// control access to the directory can disable it to save the system calls.
+//
+// The lock is only supported on unix systems.
+func WithExclusiveProcessLock(enabled bool) DirectoryCacheOption {
+	return func(dc *directoryCache) {
+		dc.processLock = enabled
+	}
+}
 
 // Create a new Cache object that is backed up by a directory.
 //
-// If dir does not exist, it will be created.
-func NewDirectoryCache(dir string) (*directoryCache, error) {
+// If dir does not exist, it will be created along with its parents.
+func NewDirectoryCache(dir string, opts ...DirectoryCacheOption) (*directoryCache, error) {
+	dc := &directoryCache{
+		cacheDir:       dir,
+		removeChannels: map[string]*cacheChannel{},
+		updateChannels: map[string]*cacheChannel{},
+		updateFuncs:    map[string]func(currValue interface{}) (interface{}, error){},
+		slidingTTLs:    map[string]time.Duration{},
+		deadlines:      map[string]time.Time{},
+		valueTypes:     map[string]reflect.Type{},
+		fileHashes:     map[string][sha256.Size]byte{},
+		encoding:       JSONEncoding{},
+		logger:         slog.Default(),
+		fileMode:       0600,
+		dirMode:        0700,
+		processLock:    true,
+	}
+
+	for _, opt := range opts {
+		opt(dc)
+	}
+
 	_, err := os.Stat(dir)
 	if os.IsNotExist(err) {
-		err := os.Mkdir(dir, os.ModeDir)
+		err := os.MkdirAll(dir, dc.dirMode)
 		if err != nil {
 			return nil, err
 		}
 
-		err = os.Chmod(dir, 0700)
+		err = os.Chmod(dir, dc.dirMode)
 		if err != nil {
 			return nil, err
 		}
@@ -82,19 +251,100 @@ func NewDirectoryCache(dir string) (*directoryCache, error) {
 		return nil, err
 	}
 
-	return &directoryCache{
-		cacheDir:       dir,
-		removeChannels: map[string]*cacheChannel{},
-		updateChannels: map[string]*cacheChannel{},
-		valueTypes:     map[string]reflect.Type{},
-	}, nil
+	return dc, nil
+}
+
+// Create a new Cache object that is backed up by a directory, whose value
+// files and directory are created with the given permissions.
+func NewDirectoryCacheWithFilePermissions(dir string, fileMode, dirMode os.FileMode) (*directoryCache, error) {
+	return NewDirectoryCache(dir, WithFileMode(fileMode), WithDirMode(dirMode))
+}
+
+// Create a new Cache object that is backed up by a directory and recover the
+// values that are already stored in it.
+//
+// valueTypeRegistry maps each key to the type its file is decoded into, opts
+// should use the same encoding and compression that the files were written
+// with.
+func NewDirectoryCacheWithRecovery(dir string, valueTypeRegistry map[string]reflect.Type,
+	opts ...DirectoryCacheOption) (*directoryCache, error) {
+	dc, err := NewDirectoryCache(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		key := entry.Name()
+
+		// A temporary file is left by a write that did not complete.
+		if strings.HasSuffix(key, tempFileSuffix) {
+			err = os.Remove(path.Join(dir, key))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if isInternalFile(key) {
+			continue
+		}
+
+		// Protocol buffer values are decoded by GetProto.
+		if _, err := os.Stat(dc.protoTypeFileName(key)); err == nil {
+			data, err := os.ReadFile(path.Join(dir, key))
+			if err != nil {
+				return nil, err
+			}
+
+			dc.fileHashes[key] = sha256.Sum256(data)
+			continue
+		}
+
+		valType, exists := valueTypeRegistry[key]
+		if !exists {
+			return nil, newError(errorTypeInvalidValueType,
+				fmt.Sprintf("no value type is registered for key file [%s]", key))
+		}
+
+		dc.valueTypes[key] = valType
+
+		// Make sure the file can be decoded before it is served by Get.
+		data, err := os.ReadFile(path.Join(dir, key))
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = dc.decodeValue(key, data)
+		if err != nil {
+			return nil, err
+		}
+
+		dc.fileHashes[key] = sha256.Sum256(data)
+	}
+
+	return dc, nil
 }
 
 // Store a permanent value in the cache.
 func (dc *directoryCache) Store(key, val interface{}) error {
+	return dc.StoreCtx(context.Background(), key, val)
+}
+
+// Store a permanent value in the cache, unless ctx is done.
+func (dc *directoryCache) StoreCtx(ctx context.Context, key, val interface{}) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	return dc.store(key, val)
 }
 
@@ -109,12 +359,19 @@ func (dc *directoryCache) store(key, val interface{}) error {
 
 	strKey := key.(string)
 
+	// Another process must not store the key between the check and the write.
+	unlock, err := dc.lockDirectory(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if dc.fileExists(key) {
 		return newError(errorTypeAlreadyExists,
 			fmt.Sprintf("key file [%s] already exists", strKey))
 	}
 
-	err := dc.writeValueToFile(val, strKey)
+	err = dc.writeValueToFile(val, strKey)
 	if err != nil {
 		return err
 	}
@@ -124,12 +381,28 @@ func (dc *directoryCache) store(key, val interface{}) error {
 	return nil
 }
 
-// Get a value from the cache.
+// Get a value from the cache, resets the expiration of sliding values.
 func (dc *directoryCache) Get(key interface{}) (interface{}, error) {
+	return dc.GetCtx(context.Background(), key)
+}
+
+// Get a value from the cache, unless ctx is done.
+func (dc *directoryCache) GetCtx(ctx context.Context, key interface{}) (interface{}, error) {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.get(key)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	val, err := dc.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	dc.resetSlidingExpiration(key.(string))
+
+	return val, nil
 }
 
 func (dc *directoryCache) get(key interface{}) (interface{}, error) {
@@ -151,11 +424,86 @@ func (dc *directoryCache) get(key interface{}) (interface{}, error) {
 	return dc.readValueFromFile(key)
 }
 
+// Check whether a key exists in the cache without reading its file.
+func (dc *directoryCache) Contains(key interface{}) (bool, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.contains(key)
+}
+
+func (dc *directoryCache) contains(key interface{}) (bool, error) {
+	if dc.cleared {
+		return false, newError(errorTypeClearedCache,
+			"cannot reuse a cleared cache")
+	}
+
+	err := dc.verifyKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	return dc.fileExists(key), nil
+}
+
+// Get a value from the cache, or store val if the key does not exist.
+func (dc *directoryCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.getOrStore(key, val)
+}
+
+func (dc *directoryCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	if dc.cleared {
+		return nil, false, newError(errorTypeClearedCache,
+			"cannot reuse a cleared cache")
+	}
+
+	err := dc.verifyKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	unlock, err := dc.lockDirectory(true)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	if dc.fileExists(key) {
+		actual, err := dc.readValueFromFile(key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		dc.resetSlidingExpiration(key.(string))
+
+		return actual, true, nil
+	}
+
+	err = dc.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
 // Remove a value from the cache.
 func (dc *directoryCache) Remove(key interface{}) error {
+	return dc.RemoveCtx(context.Background(), key)
+}
+
+// Remove a value from the cache, unless ctx is done.
+func (dc *directoryCache) RemoveCtx(ctx context.Context, key interface{}) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	return dc.remove(key)
 }
 
@@ -170,6 +518,12 @@ func (dc *directoryCache) remove(key interface{}) error {
 		return err
 	}
 
+	unlock, err := dc.lockDirectory(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if !dc.fileExists(key) {
 		return newError(errorTypeDoesNotExist,
 			fmt.Sprintf("key [%s] does not exist",
@@ -183,297 +537,1280 @@ func (dc *directoryCache) remove(key interface{}) error {
 		return err
 	}
 
-	c, exists := dc.removeChannels[strKey]
-	if exists && c != nil {
-		c.signal(abort)
-		delete(dc.removeChannels, strKey)
+	err = os.Remove(dc.protoTypeFileName(strKey))
+	if err != nil && !os.IsNotExist(err) {
+		return err
 	}
 
-	c, exists = dc.updateChannels[strKey]
-	if exists && c != nil {
-		c.signal(abort)
-		delete(dc.updateChannels, strKey)
-	}
+	dc.forget(strKey)
 
 	return nil
 }
 
-// Replace a value in the cache with a permanent value.
-func (dc *directoryCache) Replace(key, val interface{}) error {
+// Remove several values from the cache at once, returns the removed keys
+// along with a partial failure for the keys that could not be removed.
+func (dc *directoryCache) BulkRemove(keys []interface{}) ([]interface{}, error) {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.replace(key, val)
+	return removeMany(keys, dc.remove)
 }
 
-func (dc *directoryCache) replace(key, val interface{}) error {
-	err := dc.remove(key)
+// Get a value from the cache and remove it.
+func (dc *directoryCache) GetAndRemove(key interface{}) (interface{}, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.getAndRemove(key)
+}
+
+func (dc *directoryCache) getAndRemove(key interface{}) (interface{}, error) {
+	val, err := dc.get(key)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = dc.store(key, val)
+	err = dc.remove(key)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return val, nil
 }
 
-// Clears the cache, cache should not be used again once it has been cleared.
-func (dc *directoryCache) Clear() error {
+// Replace a value in the cache with a permanent value.
+func (dc *directoryCache) Replace(key, val interface{}) error {
+	return dc.ReplaceCtx(context.Background(), key, val)
+}
+
+// Replace a value in the cache with a permanent value, unless ctx is done.
+func (dc *directoryCache) ReplaceCtx(ctx context.Context, key, val interface{}) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.clear()
-}
-
-func (dc *directoryCache) clear() error {
-	if dc.cleared {
-		return newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	files, err := ioutil.ReadDir(dc.cacheDir)
+	return dc.replace(key, val)
+}
+
+func (dc *directoryCache) replace(key, val interface{}) error {
+	// Another process must not store the key between the removal and the
+	// store.
+	unlock, err := dc.lockDirectory(true)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	for _, finf := range files {
-		err = dc.remove(finf.Name())
-		if err != nil && !IsDoesNotExist(err) {
-			return err
-		}
+	err = dc.remove(key)
+	if err != nil {
+		return err
 	}
 
-	err = os.RemoveAll(dc.cacheDir)
+	err = dc.store(key, val)
 	if err != nil {
 		return err
 	}
 
-	dc.cleared = true
-
 	return nil
 }
 
-// Get all keys in the cache.
-func (dc *directoryCache) Keys() ([]interface{}, error) {
+// Store a permanent value in the cache, replacing the current value of the
+// key if it exists.
+func (dc *directoryCache) StoreOrReplace(key, val interface{}) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.keys()
-}
-
-func (dc *directoryCache) keys() ([]interface{}, error) {
-	if dc.cleared {
-		return nil, newError(errorTypeClearedCache, "cannot reuse a cleared cache")
-	}
-
-	files, err := ioutil.ReadDir(dc.cacheDir)
+	unlock, err := dc.lockDirectory(true)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer unlock()
 
-	keys := []interface{}{}
-	for _, file := range files {
-		keys = append(keys, file.Name())
+	err = dc.replace(key, val)
+	if IsDoesNotExist(err) {
+		return dc.store(key, val)
 	}
 
-	return keys, nil
+	return err
 }
 
-// Stores a temporary value in the cache, ttl must be greater than zero.
-func (dc *directoryCache) StoreWithExpiration(key, val interface{},
-	ttl time.Duration) error {
+// Store several permanent values in the cache.
+func (dc *directoryCache) StoreMany(items map[interface{}]interface{}) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.storeWithExpiration(key, val, ttl)
+	return storeMany(items, dc.store)
 }
 
-func (dc *directoryCache) storeWithExpiration(key, val interface{},
-	ttl time.Duration) error {
-	if ttl <= 0 {
-		return newError(errorTypeNonPositivePeriod,
-			"period must be greater than zero")
-	}
+// Get several values from the cache.
+func (dc *directoryCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return getMany(keys, func(key interface{}) (interface{}, error) {
+		val, err := dc.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		dc.resetSlidingExpiration(key.(string))
+
+		return val, nil
+	})
+}
+
+// Store a protocol buffer message in the cache as a permanent value, the
+// message is written in its binary format and its type url is written to a
+// companion .type file. The encoding and compression of the cache are not
+// used.
+//
+// Messages can only be read with GetProto.
+func (dc *directoryCache) StoreProto(key string, msg proto.Message) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.cleared {
+		return newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	err := dc.verifyKey(key)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := dc.lockDirectory(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if dc.fileExists(key) {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key file [%s] already exists", key))
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return newWrapperError(errorTypeInvalidValueType,
+			fmt.Sprintf("value of key [%s] cannot be encoded as a protocol buffer", key), err)
+	}
+
+	// The type file is written last, so a failed write does not leave a type
+	// file without a key file behind.
+	err = dc.writeFile(path.Join(dc.cacheDir, key), data)
+	if err != nil {
+		return err
+	}
+
+	err = dc.writeFile(dc.protoTypeFileName(key), []byte(protoTypeURL(msg)))
+	if err != nil {
+		os.Remove(path.Join(dc.cacheDir, key))
+		return err
+	}
+
+	dc.fileHashes[key] = sha256.Sum256(data)
+
+	return nil
+}
+
+// Get a protocol buffer message that was stored with StoreProto into dst,
+// resets the expiration of sliding values. dst must be of the stored message
+// type.
+func (dc *directoryCache) GetProto(key string, dst proto.Message) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.cleared {
+		return newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	err := dc.verifyKey(key)
+	if err != nil {
+		return err
+	}
+
+	if !dc.fileExists(key) {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] does not exist", key))
+	}
+
+	typeURL, err := os.ReadFile(dc.protoTypeFileName(key))
+	if os.IsNotExist(err) {
+		return newError(errorTypeInvalidValueType,
+			fmt.Sprintf("key [%s] does not hold a protocol buffer message", key))
+	} else if err != nil {
+		return err
+	}
+
+	if string(typeURL) != protoTypeURL(dst) {
+		return newError(errorTypeInvalidValueType,
+			fmt.Sprintf("key [%s] holds a message of type [%s], found: [%s]",
+				key, typeURL, protoTypeURL(dst)))
+	}
+
+	data, err := os.ReadFile(path.Join(dc.cacheDir, key))
+	if err != nil {
+		return err
+	}
+
+	err = proto.Unmarshal(data, dst)
+	if err != nil {
+		return newWrapperError(errorTypeUrecoverableValue,
+			fmt.Sprintf("file of key [%s] cannot be decoded as a protocol buffer", key), err)
+	}
+
+	dc.resetSlidingExpiration(key)
+
+	return nil
+}
+
+// Returns the name of the file that holds the type url of a protocol buffer
+// value.
+func (dc *directoryCache) protoTypeFileName(key string) string {
+	return path.Join(dc.cacheDir, key+protoTypeFileSuffix)
+}
+
+// Returns the type url of msg, as used by google.protobuf.Any.
+func protoTypeURL(msg proto.Message) string {
+	return "type.googleapis.com/" + string(msg.ProtoReflect().Descriptor().FullName())
+}
+
+// CopyTo stores all values of the cache in dst as permanent values, the cache
+// is locked until all values are copied, so dst must not be the cache itself.
+func (dc *directoryCache) CopyTo(dst Cache) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	keys, err := dc.keys()
+	if err != nil {
+		return err
+	}
+
+	errs := map[interface{}]error{}
+	for _, key := range keys {
+		val, err := dc.get(key)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+
+		err = dst.Store(key, val)
+		if err != nil {
+			errs[key] = err
+		}
+	}
+
+	return combineErrors(errs)
+}
+
+// WriteTo streams all keys and encoded values of the cache to w, each entry is
+// written as the key length, the key, the value length and the value, lengths
+// are big endian uint32s. The type url of a protocol buffer message is written
+// as another entry, whose key is the key of the message with a .type suffix.
+//
+// The cache is locked until all entries are written, so the stream is a
+// consistent snapshot. Expiration and update metadata is not written.
+func (dc *directoryCache) WriteTo(w io.Writer) (int64, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	keys, err := dc.keys()
+	if err != nil {
+		return 0, err
+	}
+
+	cw := &countingWriter{w: w}
+	for _, key := range keys {
+		data, err := os.ReadFile(path.Join(dc.cacheDir, key.(string)))
+		if err != nil {
+			return cw.n, err
+		}
+
+		err = writeChunk(cw, []byte(key.(string)))
+		if err != nil {
+			return cw.n, err
+		}
+
+		err = writeChunk(cw, data)
+		if err != nil {
+			return cw.n, err
+		}
+
+		typeURL, err := os.ReadFile(dc.protoTypeFileName(key.(string)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return cw.n, err
+		}
+
+		err = writeChunk(cw, []byte(key.(string)+protoTypeFileSuffix))
+		if err != nil {
+			return cw.n, err
+		}
+
+		err = writeChunk(cw, typeURL)
+		if err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, nil
+}
+
+// ReadFrom populates an empty cache with the entries of a stream that was
+// written by WriteTo, using the same encoding. All restored values are
+// permanent and, like values stored by a previous process, are decoded into an
+// interface{}.
+//
+// Protocol buffer messages are restored along with their type urls, so they
+// can be read with GetProto. No value is stored if the stream is malformed or
+// holds entries with the names of the internal files of the cache.
+func (dc *directoryCache) ReadFrom(r io.Reader) (int64, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.cleared {
+		return 0, newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	// Another process must not store a key while the cache is restored.
+	unlock, err := dc.lockDirectory(true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	keys, err := dc.keys()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(keys) > 0 {
+		return 0, newError(errorTypeCacheNotEmpty, "cannot restore into a non empty cache")
+	}
+
+	cr := &countingReader{r: r}
+	entries := map[string][]byte{}
+	typeURLs := map[string][]byte{}
+	for {
+		key, err := readChunk(cr)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return cr.n, err
+		}
+
+		data, err := readChunk(cr)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return cr.n, err
+		}
+
+		// Keys are used as file names, so they must not leave the directory.
+		strKey := string(key)
+		if strKey == "" || strKey == "." || strKey == ".." ||
+			strings.ContainsAny(strKey, "/\\") {
+			return cr.n, newError(errorTypeInvalidKeyType,
+				fmt.Sprintf("invalid key [%s] in stream", strKey))
+		}
+
+		if strings.HasSuffix(strKey, protoTypeFileSuffix) {
+			typeURLs[strings.TrimSuffix(strKey, protoTypeFileSuffix)] = data
+			continue
+		}
+
+		if isInternalFile(strKey) {
+			return cr.n, newError(errorTypeInvalidKeyType,
+				fmt.Sprintf("invalid key [%s] in stream", strKey))
+		}
+
+		entries[strKey] = data
+	}
+
+	// A type url must belong to a message in the stream.
+	for key := range typeURLs {
+		if _, exists := entries[key]; !exists {
+			return cr.n, newError(errorTypeInvalidKeyType,
+				fmt.Sprintf("invalid key [%s] in stream", key+protoTypeFileSuffix))
+		}
+	}
+
+	written := []string{}
+	for key, data := range entries {
+		err := dc.restoreEntry(key, data, typeURLs[key])
+		if err != nil {
+			for _, writtenKey := range written {
+				os.Remove(path.Join(dc.cacheDir, writtenKey))
+				os.Remove(dc.protoTypeFileName(writtenKey))
+				delete(dc.valueTypes, writtenKey)
+				delete(dc.fileHashes, writtenKey)
+			}
+
+			return cr.n, err
+		}
+
+		written = append(written, key)
+	}
+
+	return cr.n, nil
+}
+
+// Writes the file of a restored entry, and the type file of a message if
+// typeURL is not nil.
+func (dc *directoryCache) restoreEntry(key string, data, typeURL []byte) error {
+	if typeURL != nil {
+		err := dc.writeFile(dc.protoTypeFileName(key), typeURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := dc.writeFile(path.Join(dc.cacheDir, key), data)
+	if err != nil {
+		os.Remove(dc.protoTypeFileName(key))
+		return err
+	}
+
+	// Messages are decoded by GetProto.
+	if typeURL == nil {
+		dc.valueTypes[key] = anyType
+	}
+
+	dc.fileHashes[key] = sha256.Sum256(data)
+
+	return nil
+}
+
+// WarmUp stores several permanent values in the cache at once, no value is
+// stored if any of the keys is already in use or if any of the values is
+// invalid.
+func (dc *directoryCache) WarmUp(entries map[interface{}]interface{}) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.cleared {
+		return newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	for key, val := range entries {
+		if err := dc.verifyInputs(key, val); err != nil {
+			return err
+		}
+
+		if dc.fileExists(key) {
+			return newError(errorTypeAlreadyExists,
+				fmt.Sprintf("key file [%s] already exists", key.(string)))
+		}
+	}
+
+	stored := []interface{}{}
+	for key, val := range entries {
+		err := dc.store(key, val)
+		if err != nil {
+			// Remove the values that were already stored, so that a failure
+			// to write a file does not leave the cache half populated.
+			for _, storedKey := range stored {
+				dc.remove(storedKey)
+			}
+
+			return err
+		}
+
+		stored = append(stored, key)
+	}
+
+	return nil
+}
+
+// Clears the cache, cache should not be used again once it has been cleared.
+func (dc *directoryCache) Clear() error {
+	return dc.ClearCtx(context.Background())
+}
+
+// Clears the cache, unless ctx is done.
+func (dc *directoryCache) ClearCtx(ctx context.Context) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return dc.clear()
+}
+
+func (dc *directoryCache) clear() error {
+	if dc.cleared {
+		return newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	files, err := os.ReadDir(dc.cacheDir)
+	if err != nil {
+		return err
+	}
+
+	for _, finf := range files {
+		if isInternalFile(finf.Name()) {
+			continue
+		}
+
+		err = dc.remove(finf.Name())
+		if err != nil && !IsDoesNotExist(err) {
+			return err
+		}
+	}
+
+	err = os.RemoveAll(dc.cacheDir)
+	if err != nil {
+		return err
+	}
+
+	dc.cleared = true
+
+	return nil
+}
+
+// Get all keys in the cache.
+func (dc *directoryCache) Keys() ([]interface{}, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.keys()
+}
+
+func (dc *directoryCache) keys() ([]interface{}, error) {
+	if dc.cleared {
+		return nil, newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	files, err := os.ReadDir(dc.cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []interface{}{}
+	for _, file := range files {
+		if !isInternalFile(file.Name()) {
+			keys = append(keys, file.Name())
+		}
+	}
+
+	return keys, nil
+}
+
+// Get limit keys starting at offset along with the total number of keys, keys
+// are ordered by their file names and files are not stated.
+func (dc *directoryCache) KeysPage(offset, limit int) ([]interface{}, int, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.cleared {
+		return nil, 0, newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	if offset < 0 || limit < 0 {
+		return nil, 0, newError(errorTypeInvalidPage,
+			"offset and limit cannot be negative")
+	}
+
+	entries, err := os.ReadDir(dc.cacheDir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !isInternalFile(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	keys := []interface{}{}
+	for i := offset; i < len(names) && len(keys) < limit; i++ {
+		keys = append(keys, names[i])
+	}
+
+	return keys, len(names), nil
+}
+
+// Calls fn for each key and value in the cache until fn returns false, each
+// file is decoded only when it is reached.
+func (dc *directoryCache) ForEach(fn func(key, val interface{}) bool) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	keys, err := dc.keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		val, err := dc.get(key)
+		if err != nil {
+			return err
+		}
+
+		if !fn(key, val) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Get the keys for which predicate returns true, predicate is called with the
+// file names and files are not read.
+func (dc *directoryCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	keys, err := dc.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterKeys(keys, predicate), nil
+}
+
+// Get a snapshot of all keys and values in the cache, all files are read
+// while the cache is locked.
+func (dc *directoryCache) GetAll() (map[interface{}]interface{}, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	keys, err := dc.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[interface{}]interface{}, len(keys))
+	for _, key := range keys {
+		val, err := dc.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		all[key] = val
+	}
+
+	return all, nil
+}
+
+// Watch calls fn whenever another process writes or deletes the file of key,
+// with the new value or with nil once the file is deleted. A deleted key is
+// no longer tracked by the cache.
+//
+// fn is called from a background routine until the returned function is
+// called, changes made through the cache itself are not reported.
+func (dc *directoryCache) Watch(key interface{},
+	fn func(event EventType, newVal interface{})) (context.CancelFunc, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.cleared {
+		return nil, newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	if err := dc.verifyKey(key); err != nil {
+		return nil, err
+	}
+
+	if fn == nil {
+		return nil, newError(errorTypeNilUpdateFunc, "fn cannot be nil")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	err = watcher.Add(dc.cacheDir)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go func() {
+		for ev := range watcher.Events {
+			if path.Base(ev.Name) != key.(string) {
+				continue
+			}
+
+			event, newVal, changed := dc.externalChange(key.(string))
+			if changed {
+				fn(event, newVal)
+			}
+		}
+	}()
+
+	// The watcher may also report errors, they are ignored since the next
+	// event re-reads the file anyway.
+	go func() {
+		for range watcher.Errors {
+		}
+	}()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			watcher.Close()
+		})
+	}
+
+	return cancel, nil
+}
+
+// Check whether the file of key was changed by another process since the
+// cache last wrote it, a file that cannot be decoded yet is not reported.
+func (dc *directoryCache) externalChange(key string) (EventType, interface{}, bool) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.cleared {
+		return 0, nil, false
+	}
+
+	// The cache always writes a value type before the file, so a file
+	// without one was not written by the cache.
+	if _, isTracked := dc.valueTypes[key]; !isTracked {
+		return 0, nil, false
+	}
+
+	data, err := os.ReadFile(path.Join(dc.cacheDir, key))
+	if os.IsNotExist(err) {
+		dc.forget(key)
+		return EventDeleted, nil, true
+	} else if err != nil {
+		return 0, nil, false
+	}
+
+	hash := sha256.Sum256(data)
+	if hash == dc.fileHashes[key] {
+		return 0, nil, false
+	}
+
+	newVal, err := dc.decodeValue(key, data)
+	if err != nil {
+		return 0, nil, false
+	}
+
+	dc.fileHashes[key] = hash
+
+	return EventModified, newVal, true
+}
+
+// Stop tracking a key whose file was deleted, along with its routines.
+func (dc *directoryCache) forget(key string) {
+	c, exists := dc.removeChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(dc.removeChannels, key)
+	}
+
+	c, exists = dc.updateChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(dc.updateChannels, key)
+		delete(dc.updateFuncs, key)
+	}
+
+	delete(dc.slidingTTLs, key)
+	delete(dc.deadlines, key)
+	delete(dc.valueTypes, key)
+	delete(dc.fileHashes, key)
+}
+
+// Stores a temporary value in the cache, ttl must be greater than zero.
+func (dc *directoryCache) StoreWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.storeWithExpiration(key, val, ttl)
+}
+
+// Stores a temporary value in the cache that is removed at deadline, deadline
+// must be in the future.
+func (dc *directoryCache) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	ttl, err := ttlUntil(deadline)
+	if err != nil {
+		return err
+	}
+
+	return dc.storeWithExpiration(key, val, ttl)
+}
+
+func (dc *directoryCache) storeWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
 
 	err := dc.store(key, val)
 	if err != nil {
 		return err
 	}
 
-	keyStr := key.(string)
+	dc.createExpirationRoutine(key.(string), ttl)
+
+	return nil
+}
+
+func (dc *directoryCache) createExpirationRoutine(key string, ttl time.Duration) {
 	c := newCacheChannel()
-	dc.removeChannels[keyStr] = c
+	dc.removeChannels[key] = c
+	dc.deadlines[key] = time.Now().Add(ttl)
 
 	expireSignalerRoutine := func(c *cacheChannel) {
 		<-time.After(ttl)
 		c.signal(proceed)
 	}
 
-	expireRoutine := func(key string, c *cacheChannel) {
-		msg, ok := <-c.c
-		if !ok || msg == abort {
-			return
-		} else {
-			dc.mutex.Lock()
-			defer dc.mutex.Unlock()
+	expireRoutine := func(key string, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		} else {
+			dc.mutex.Lock()
+			defer dc.mutex.Unlock()
+
+			// The expiration was reset while waiting for the mutex.
+			if dc.cleared || dc.removeChannels[key] != c {
+				return
+			}
+
+			// Delete the file from the directory
+			err := dc.remove(key)
+			if err != nil {
+				dc.logError(key, "expire", err)
+			}
+		}
+	}
+
+	go expireSignalerRoutine(c)
+	go expireRoutine(key, c)
+}
+
+// Get the remaining ttl of a value in the cache.
+func (dc *directoryCache) TTL(key interface{}) (time.Duration, bool, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.ttl(key)
+}
+
+func (dc *directoryCache) ttl(key interface{}) (time.Duration, bool, error) {
+	exists, err := dc.contains(key)
+	if err != nil {
+		return -1, false, err
+	}
+
+	if !exists {
+		return -1, false, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] does not exist", key.(string)))
+	}
+
+	deadline, hasTTL := dc.deadlines[key.(string)]
+	if !hasTTL {
+		return -1, false, nil
+	}
+
+	return time.Until(deadline), true, nil
+}
+
+// Stores a temporary value in the cache, every Get resets its ttl, ttl must be
+// greater than zero.
+func (dc *directoryCache) StoreWithSlidingExpiration(key, val interface{},
+	ttl time.Duration) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.storeWithSlidingExpiration(key, val, ttl)
+}
+
+func (dc *directoryCache) storeWithSlidingExpiration(key, val interface{},
+	ttl time.Duration) error {
+	err := dc.storeWithExpiration(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	dc.slidingTTLs[key.(string)] = ttl
+
+	return nil
+}
+
+func (dc *directoryCache) resetSlidingExpiration(key string) {
+	ttl, isSliding := dc.slidingTTLs[key]
+	if !isSliding {
+		return
+	}
+
+	c, exists := dc.removeChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+	}
+
+	dc.createExpirationRoutine(key, ttl)
+}
+
+// Replaces a value in the map with a temporary one, ttl must be greater than zero.
+func (dc *directoryCache) ReplaceWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.replaceWithExpiration(key, val, ttl)
+}
+
+func (dc *directoryCache) replaceWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	err := dc.remove(key)
+	if err != nil {
+		return err
+	}
+
+	err = dc.storeWithExpiration(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Expire a value in the cache, ttl must be greater than zero. An updating
+// value stops updating and is removed once ttl is over.
+func (dc *directoryCache) Expire(key interface{}, ttl time.Duration) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.expire(key, ttl)
+}
+
+func (dc *directoryCache) expire(key interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	if dc.cleared {
+		return newError(errorTypeClearedCache, "cannot reuse a cleared cache")
+	}
+
+	err := dc.verifyKey(key)
+	if err != nil {
+		return err
+	}
+
+	if !dc.fileExists(key) {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] does not exist",
+				key.(string)))
+	}
+
+	strKey := key.(string)
+
+	// The file is kept as is, the value only stops updating and sliding.
+	c, exists := dc.updateChannels[strKey]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(dc.updateChannels, strKey)
+		delete(dc.updateFuncs, strKey)
+	}
+
+	c, exists = dc.removeChannels[strKey]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(dc.removeChannels, strKey)
+	}
+
+	delete(dc.slidingTTLs, strKey)
+	dc.createExpirationRoutine(strKey, ttl)
+
+	return nil
+}
+
+// Stores an updating value in the map, period must be greater than zero.
+func (dc *directoryCache) StoreWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	return dc.storeWithUpdate(key, initialValue, updateFunc, period)
+}
+
+func (dc *directoryCache) storeWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	err = dc.store(key, initialValue)
+	if err != nil {
+		return err
+	}
+
+	keyStr := key.(string)
+	c := newCacheChannel()
+	dc.updateChannels[keyStr] = c
+	dc.updateFuncs[keyStr] = updateFunc
+
+	updateSignalerRoutine := func(c *cacheChannel) {
+		<-time.After(period)
+		c.signal(proceed)
+	}
+
+	updateRoutine := func(key string, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		} else {
+			dc.mutex.Lock()
+			defer dc.mutex.Unlock()
+
+			// The update was canceled while waiting for the mutex.
+			if dc.cleared || dc.updateChannels[key] != c {
+				return
+			}
+
+			// Another process must not find the key missing, or store it,
+			// between the removal and the store of the new value.
+			unlock, err := dc.lockDirectory(true)
+			if err != nil {
+				dc.unexpectedUpdateError(key, err)
+				return
+			}
+			defer unlock()
+
+			// Update the value using the update func
+			currVal, err := dc.get(key)
+			if err != nil {
+				dc.unexpectedUpdateError(key, err)
+				return
+			}
+
+			newVal, err := updateFunc(currVal)
+			if err != nil {
+				if dc.onUpdateError != nil {
+					dc.onUpdateError(key, err)
+				}
+
+				// Keep the current value until the next update.
+				newVal = currVal
+			}
+
+			// The value should still be removed at its original deadline.
+			deadline, hasDeadline := dc.deadlines[key]
+			slidingTTL, isSliding := dc.slidingTTLs[key]
 
-			if dc.cleared {
+			err = dc.remove(key)
+			if err != nil {
+				dc.unexpectedUpdateError(key, err)
 				return
 			}
 
-			// Delete the file from the directory
-			err := dc.remove(key)
+			err = dc.storeWithUpdate(key, newVal, updateFunc, period)
 			if err != nil {
-				panic(newWrapperError(errorTypeUnexpectedError,
-					"an unexpected error occurred a background routine", err))
+				dc.unexpectedUpdateError(key, err)
+			} else if hasDeadline {
+				dc.createExpirationRoutine(key, time.Until(deadline))
+				if isSliding {
+					dc.slidingTTLs[key] = slidingTTL
+				}
 			}
 		}
 	}
 
-	go expireSignalerRoutine(c)
-	go expireRoutine(keyStr, c)
+	go updateSignalerRoutine(c)
+	go updateRoutine(keyStr, c)
 
 	return nil
 }
 
-// Replaces a value in the map with a temporary one, ttl must be greater than zero.
-func (dc *directoryCache) ReplaceWithExpiration(key, val interface{},
-	ttl time.Duration) error {
+// Stores an updating value in the cache that is removed after totalTTL, even
+// if it is still being updated.
+func (dc *directoryCache) StoreWithExpirationAndUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.replaceWithExpiration(key, val, ttl)
-}
-
-func (dc *directoryCache) replaceWithExpiration(key, val interface{},
-	ttl time.Duration) error {
-	if ttl <= 0 {
+	if totalTTL <= 0 {
 		return newError(errorTypeNonPositivePeriod,
 			"period must be greater than zero")
 	}
 
-	err := dc.remove(key)
+	err := dc.storeWithUpdate(key, initialValue, updateFunc, updatePeriod)
 	if err != nil {
 		return err
 	}
 
-	err = dc.storeWithExpiration(key, val, ttl)
+	dc.createExpirationRoutine(key.(string), totalTTL)
+
+	return nil
+}
+
+// Stores an updating value in the cache that is removed once it was not read
+// for totalTTL, every Get resets its deadline.
+func (dc *directoryCache) StoreWithUpdateAndExpiration(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if totalTTL <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	err := dc.storeWithUpdate(key, initialValue, updateFunc, updatePeriod)
 	if err != nil {
 		return err
 	}
 
+	dc.createExpirationRoutine(key.(string), totalTTL)
+	dc.slidingTTLs[key.(string)] = totalTTL
+
 	return nil
 }
 
-// Expire a value in the cache, ttl must be greater than zero.
-func (dc *directoryCache) Expire(key interface{}, ttl time.Duration) error {
+// Stop updating a value in the cache, the value keeps its current value. A
+// value stored with StoreWithUpdateAndExpiration also stops expiring.
+func (dc *directoryCache) CancelUpdate(key interface{}) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.expire(key, ttl)
+	return dc.cancelUpdate(key)
 }
 
-func (dc *directoryCache) expire(key interface{}, ttl time.Duration) error {
-	if ttl <= 0 {
-		return newError(errorTypeNonPositivePeriod,
-			"period must be greater than zero")
+func (dc *directoryCache) cancelUpdate(key interface{}) error {
+	if dc.cleared {
+		return newError(errorTypeClearedCache, "cannot reuse a cleared cache")
 	}
 
-	val, err := dc.get(key)
-	if err != nil {
+	if err := dc.verifyKey(key); err != nil {
 		return err
 	}
 
-	err = dc.remove(key)
-	if err != nil {
-		return err
+	if !dc.fileExists(key) {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key file [%s] does not exist", key.(string)))
 	}
 
-	err = dc.storeWithExpiration(key, val, ttl)
-	if err != nil {
-		return err
+	strKey := key.(string)
+
+	c, exists := dc.updateChannels[strKey]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(dc.updateChannels, strKey)
+		delete(dc.updateFuncs, strKey)
+
+		// Its idle deadline only lasts as long as it is updated.
+		if _, isSliding := dc.slidingTTLs[strKey]; isSliding {
+			if rc, exists := dc.removeChannels[strKey]; exists && rc != nil {
+				rc.signal(abort)
+				delete(dc.removeChannels, strKey)
+			}
+			delete(dc.slidingTTLs, strKey)
+			delete(dc.deadlines, strKey)
+		}
 	}
 
 	return nil
 }
 
-// Stores an updating value in the map, period must be greater than zero.
-func (dc *directoryCache) StoreWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{},
-	period time.Duration) error {
+// Change the period in which a value is updated, the next update happens
+// newPeriod after the call.
+func (dc *directoryCache) SetUpdatePeriod(key interface{}, newPeriod time.Duration) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
 
-	return dc.storeWithUpdate(key, initialValue, updateFunc, period)
+	return dc.setUpdatePeriod(key, newPeriod)
 }
 
-func (dc *directoryCache) storeWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{},
-	period time.Duration) error {
-	if period <= 0 {
+func (dc *directoryCache) setUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	if newPeriod <= 0 {
 		return newError(errorTypeNonPositivePeriod,
-			"period must be greater than zero")
+			"period must be bigger than zero")
 	}
 
-	err := dc.store(key, initialValue)
+	currVal, err := dc.get(key)
 	if err != nil {
 		return err
 	}
 
-	keyStr := key.(string)
-	c := newCacheChannel()
-	dc.updateChannels[keyStr] = c
-
-	updateSignalerRoutine := func(c *cacheChannel) {
-		<-time.After(period)
-		c.signal(proceed)
+	updateFunc, isUpdating := dc.updateFuncs[key.(string)]
+	if !isUpdating {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key [%s] is not being updated", key.(string)))
 	}
 
-	updateRoutine := func(key string, c *cacheChannel) {
-		msg, ok := <-c.c
-		if !ok || msg == abort {
-			return
-		} else {
-			dc.mutex.Lock()
-			defer dc.mutex.Unlock()
-
-			if dc.cleared {
-				return
-			}
+	// The value should still be removed at its original deadline.
+	deadline, hasDeadline := dc.deadlines[key.(string)]
+	slidingTTL, isSliding := dc.slidingTTLs[key.(string)]
 
-			// Update the value using the update func
-			currVal, err := dc.get(key)
-			if err != nil {
-				panic(newWrapperError(errorTypeUnexpectedError,
-					"an unexpected error occurred a background routine", err))
-			}
+	err = dc.remove(key)
+	if err != nil {
+		return err
+	}
 
-			err = dc.remove(key)
-			if err != nil {
-				panic(newWrapperError(errorTypeUnexpectedError,
-					"an unexpected error occurred a background routine", err))
-			}
+	err = dc.storeWithUpdate(key, currVal, updateFunc, newPeriod)
+	if err != nil {
+		return err
+	}
 
-			err = dc.storeWithUpdate(key, updateFunc(currVal),
-				updateFunc, period)
-			if err != nil {
-				panic(newWrapperError(errorTypeUnexpectedError,
-					"an unexpected error occurred a background routine", err))
-			}
+	if hasDeadline {
+		dc.createExpirationRoutine(key.(string), time.Until(deadline))
+		if isSliding {
+			dc.slidingTTLs[key.(string)] = slidingTTL
 		}
 	}
 
-	go updateSignalerRoutine(c)
-	go updateRoutine(keyStr, c)
-
 	return nil
 }
 
+// Report an unexpected error of an auto update routine, if there is no update
+// error handler the error is logged and the value is removed.
+func (dc *directoryCache) unexpectedUpdateError(key string, err error) {
+	wrappedErr := newWrapperError(errorTypeUnexpectedError,
+		"an unexpected error occurred a background routine", err)
+	if dc.onUpdateError != nil {
+		dc.onUpdateError(key, wrappedErr)
+		return
+	}
+
+	dc.logError(key, "update", err)
+
+	// The value may have already been removed by the routine.
+	dc.remove(key)
+}
+
+// Log an unexpected error of a background routine.
+func (dc *directoryCache) logError(key string, operation string, err error) {
+	dc.logger.Error("an unexpected error occurred a background routine",
+		"key", key, "error", err, "operation", operation)
+}
+
 // Replace a value with a continously updating value.
 func (dc *directoryCache) ReplaceWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
 	period time.Duration) error {
 	dc.mutex.Lock()
 	defer dc.mutex.Unlock()
@@ -482,18 +1819,14 @@ func (dc *directoryCache) ReplaceWithUpdate(key, initialValue interface{},
 }
 
 func (dc *directoryCache) replaceWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
 	period time.Duration) error {
-	if updateFunc == nil {
-		return newError(errorTypeNilUpdateFunc, "updateFunc cannot be nil")
-	}
-
-	if period <= 0 {
-		return newError(errorTypeNonPositivePeriod,
-			"period must be greater than zero")
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
 	}
 
-	err := dc.remove(key)
+	err = dc.remove(key)
 	if err != nil {
 		return err
 	}
@@ -507,43 +1840,58 @@ func (dc *directoryCache) replaceWithUpdate(key, initialValue interface{},
 }
 
 func (dc *directoryCache) verifyKey(key interface{}) error {
-	_, isStr := key.(string)
+	strKey, isStr := key.(string)
 	if !isStr {
 		return newError(errorTypeInvalidKeyType,
 			fmt.Sprintf("invalid key type, expected: [string] found: [%s]",
 				reflect.TypeOf(key).Name()))
 	}
 
+	if isInternalFile(strKey) {
+		return newError(errorTypeInvalidKeyType,
+			fmt.Sprintf("key [%s] cannot be [%s] or end with [%s] or [%s]",
+				strKey, lockFileName, protoTypeFileSuffix, tempFileSuffix))
+	}
+
 	return nil
 }
 
 func (dc *directoryCache) verifyValue(val interface{}) error {
-	// TODO allow struct pointers as well
+	if val == nil || (reflect.TypeOf(val).Kind() == reflect.Ptr && reflect.ValueOf(val).IsNil()) {
+		return newError(errorTypeNilValue, "value cannot be nil")
+	}
+
+	isStructPtr := reflect.TypeOf(val).Kind() == reflect.Ptr &&
+		reflect.TypeOf(val).Elem().Kind() == reflect.Struct
+
 	if reflect.TypeOf(val).Kind() != reflect.Struct &&
 		reflect.TypeOf(val).Kind() != reflect.Map &&
 		reflect.TypeOf(val).Kind() != reflect.Slice &&
-		reflect.TypeOf(val).Kind() != reflect.Array {
+		reflect.TypeOf(val).Kind() != reflect.Array && !isStructPtr &&
+		!isScalarKind(reflect.TypeOf(val).Kind()) {
 		return newError(errorTypeInvalidValueType,
 			fmt.Sprintf("invalid value type, expected either of:"+
-				" [Struct, Map, Slice, Array] found: [%s]",
+				" [Struct, Map, Slice, Array, Struct Pointer, Scalar] found: [%s]",
 				reflect.TypeOf(val).String()))
 	}
 
-	jsonData, err := json.Marshal(val)
+	encoded := encodedValue(val)
+
+	data, err := dc.encoding.Marshal(encoded)
 	if err != nil {
 		return err
 	}
 
-	tmpVal := reflect.New(reflect.TypeOf(val)).Interface()
-	err = json.Unmarshal(jsonData, tmpVal)
+	tmpVal := reflect.New(reflect.TypeOf(encoded)).Interface()
+	err = dc.encoding.Unmarshal(data, tmpVal)
 	if err != nil {
 		return err
 	}
 
-	if !reflect.DeepEqual(val, reflect.ValueOf(tmpVal).Elem().Interface()) {
+	if !reflect.DeepEqual(encoded, reflect.ValueOf(tmpVal).Elem().Interface()) {
 		return newError(errorTypeUrecoverableValue,
-			"value cannot be fully recovered after marshalled to json,"+
-				" make sure val's type has json tags")
+			"value cannot be fully recovered after being encoded,"+
+				" make sure val's type has json tags or use another encoding")
 	}
 
 	return nil
@@ -568,41 +1916,130 @@ func (dc *directoryCache) fileExists(key interface{}) bool {
 	return err == nil
 }
 
+// Take the lock of the cache directory, exclusive for writes and shared for
+// reads, the returned function releases it. An operation that already holds
+// the lock takes it again without waiting, a shared lock is upgraded if an
+// exclusive lock is requested.
+func (dc *directoryCache) lockDirectory(exclusive bool) (func(), error) {
+	if !dc.processLock {
+		return func() {}, nil
+	}
+
+	if dc.lockDepth == 0 {
+		file, err := os.OpenFile(path.Join(dc.cacheDir, lockFileName), os.O_CREATE|os.O_RDONLY, dc.fileMode)
+		if err != nil {
+			return nil, err
+		}
+
+		err = lockFile(file, exclusive)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		dc.lockFile = file
+		dc.lockExclusive = exclusive
+	} else if exclusive && !dc.lockExclusive {
+		err := lockFile(dc.lockFile, true)
+		if err != nil {
+			return nil, err
+		}
+
+		dc.lockExclusive = true
+	}
+
+	dc.lockDepth++
+
+	return func() {
+		dc.lockDepth--
+		if dc.lockDepth == 0 {
+			// Closing the file releases the lock.
+			dc.lockFile.Close()
+			dc.lockFile = nil
+		}
+	}, nil
+}
+
 func (dc *directoryCache) writeValueToFile(val interface{}, strKey string) error {
 	fileName := path.Join(dc.cacheDir, strKey)
 
-	file, err := os.Create(fileName)
+	unlock, err := dc.lockDirectory(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := dc.encoding.Marshal(encodedValue(val))
+	if err != nil {
+		return err
+	}
+
+	if dc.compressor != nil {
+		data, err = dc.compressor.Compress(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = dc.writeFile(fileName, data)
+	if err != nil {
+		return err
+	}
+
+	dc.fileHashes[strKey] = sha256.Sum256(data)
+
+	return nil
+}
+
+// Write a file with the permissions of the value files. The data is written
+// to a temporary file that is renamed over fileName, so a crash never leaves
+// a partially written file.
+func (dc *directoryCache) writeFile(fileName string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(dc.cacheDir, path.Base(fileName)+".*"+tempFileSuffix)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	err = os.Chmod(fileName, 0600)
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(data)
 	if err != nil {
 		return err
 	}
 
-	jsonData, err := json.Marshal(val)
+	err = tmp.Sync()
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(jsonData)
+	err = tmp.Chmod(dc.fileMode)
 	if err != nil {
 		return err
 	}
 
-	err = file.Sync()
+	err = tmp.Close()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.Rename(tmp.Name(), fileName)
 }
 
 func (dc *directoryCache) readValueFromFile(key interface{}) (interface{}, error) {
 	fileName := path.Join(dc.cacheDir, key.(string))
-	_, err := os.Stat(fileName)
+
+	unlock, err := dc.lockDirectory(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, err = os.Stat(fileName)
 	if os.IsNotExist(err) {
 		return nil, newError(errorTypeDoesNotExist,
 			fmt.Sprintf("file for key [%s] does not exist", key.(string)))
@@ -611,23 +2048,188 @@ func (dc *directoryCache) readValueFromFile(key interface{}) (interface{}, error
 		if err != nil {
 			return nil, err
 		}
+		defer file.Close()
 
-		jsonData, err := ioutil.ReadAll(file)
+		data, err := io.ReadAll(file)
 		if err != nil {
 			return nil, err
 		}
 
-		valStruct := reflect.New(dc.valueTypes[key.(string)]).Interface()
+		return dc.decodeValue(key.(string), data)
+	} else {
+		return nil, err
+	}
+}
 
-		err = json.Unmarshal(jsonData, valStruct)
+// Decode the data of a key file into the key's value type.
+func (dc *directoryCache) decodeValue(key string, data []byte) (interface{}, error) {
+	var err error
+	if dc.compressor != nil {
+		data, err = dc.compressor.Decompress(data)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		return reflect.Indirect(reflect.ValueOf(valStruct)).Interface(), nil
-	} else {
+	// Keys that were not stored by this cache are decoded into an
+	// interface{}, unless they hold a protocol buffer message.
+	valType, exists := dc.valueTypes[key]
+	if !exists {
+		if _, err := os.Stat(dc.protoTypeFileName(key)); err == nil {
+			return nil, newError(errorTypeInvalidValueType,
+				fmt.Sprintf("key [%s] holds a protocol buffer message, use GetProto", key))
+		}
+
+		valType = anyType
+	}
+
+	// Scalars are written inside a wrapper with a single value field, which
+	// is decoded as a map.
+	if valType.Kind() == reflect.Interface {
+		var val interface{}
+		err = dc.encoding.Unmarshal(data, &val)
+		if err != nil {
+			return nil, err
+		}
+
+		if fields, isMap := val.(map[string]interface{}); isMap && len(fields) == 1 {
+			if scalar, isWrapped := fields["value"]; isWrapped {
+				return scalar, nil
+			}
+		}
+
+		return val, nil
+	}
+
+	if isScalarKind(valType.Kind()) {
+		wrapper := reflect.New(scalarWrapperType(valType))
+
+		err = dc.encoding.Unmarshal(data, wrapper.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		return wrapper.Elem().Field(0).Interface(), nil
+	}
+
+	// A struct pointer is decoded into a new struct and returned as is.
+	if valType.Kind() == reflect.Ptr {
+		valPtr := reflect.New(valType.Elem()).Interface()
+
+		err = dc.encoding.Unmarshal(data, valPtr)
+		if err != nil {
+			return nil, err
+		}
+
+		return valPtr, nil
+	}
+
+	valStruct := reflect.New(valType).Interface()
+
+	err = dc.encoding.Unmarshal(data, valStruct)
+	if err != nil {
 		return nil, err
 	}
+
+	return reflect.Indirect(reflect.ValueOf(valStruct)).Interface(), nil
+}
+
+// The type of values whose type is not known.
+var anyType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// Check whether values of a kind are encoded inside a scalar wrapper.
+func isScalarKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns a struct type with a single Value field of type t, scalars are
+// encoded inside it so that they are decoded back into their own type.
+func scalarWrapperType(t reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: t,
+		Tag:  `json:"value" msgpack:"value"`,
+	}})
+}
+
+// Returns the value that is encoded in the file of val.
+func encodedValue(val interface{}) interface{} {
+	valType := reflect.TypeOf(val)
+	if !isScalarKind(valType.Kind()) {
+		return val
+	}
+
+	wrapper := reflect.New(scalarWrapperType(valType)).Elem()
+	wrapper.Field(0).Set(reflect.ValueOf(val))
+
+	return wrapper.Interface()
 }
 
 // -----------------------------------------
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+
+	return n, err
+}
+
+// Write data prefixed by its length.
+func writeChunk(w io.Writer, data []byte) error {
+	err := binary.Write(w, binary.BigEndian, uint32(len(data)))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// Read data that was written by writeChunk, returns io.EOF only if the stream
+// ended before the chunk.
+func readChunk(r io.Reader) ([]byte, error) {
+	var length uint32
+	err := binary.Read(r, binary.BigEndian, &length)
+	if err != nil {
+		return nil, err
+	}
+
+	// The length is not trusted, so the buffer only grows with the data that
+	// is actually read.
+	var data bytes.Buffer
+	_, err = io.CopyN(&data, r, int64(length))
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Bytes(), nil
+}
@@ -1,12 +1,25 @@
 package cache
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
 	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type testStruct struct {
@@ -14,6 +27,14 @@ type testStruct struct {
 	Int int    `json:"int"`
 }
 
+// Sends a copy of every write to the channel.
+type chanWriter chan []byte
+
+func (cw chanWriter) Write(p []byte) (int, error) {
+	cw <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
 var _ = Describe("Directory Cache", func() {
 	var (
 		c   *directoryCache
@@ -47,10 +68,251 @@ var _ = Describe("Directory Cache", func() {
 		})
 
 		It("should return an error when attempting to store a value of an invalid type", func() {
-			Expect(IsInvalidValueType(c.Store(key, "val"))).To(BeTrue())
-			Expect(IsInvalidValueType(c.Store(key, 1))).To(BeTrue())
-			Expect(IsInvalidValueType(c.Store(key, 0.1))).To(BeTrue())
-			Expect(IsInvalidValueType(c.Store(key, true))).To(BeTrue())
+			Expect(IsInvalidValueType(c.Store(key, make(chan int)))).To(BeTrue())
+			Expect(IsInvalidValueType(c.Store(key, func() {}))).To(BeTrue())
+		})
+
+		It("should store scalar values and get them with their own type", func() {
+			scalars := map[interface{}]interface{}{
+				"string": "val",
+				"int":    42,
+				"uint8":  uint8(7),
+				"float":  0.1,
+				"bool":   true,
+			}
+
+			for k, v := range scalars {
+				Expect(c.Store(k, v)).ToNot(HaveOccurred())
+			}
+
+			for k, v := range scalars {
+				Expect(c.Get(k)).To(Equal(v))
+			}
+		})
+
+		It("should store a struct pointer and get it as a pointer", func() {
+			Expect(c.Store(key, &val)).ToNot(HaveOccurred())
+
+			v, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(v).To(BeAssignableToTypeOf(&testStruct{}))
+			Expect(v).To(Equal(&val))
+			Expect(v).ToNot(BeIdenticalTo(&val))
+		})
+
+		It("should return an error when attempting to store a pointer that is not a struct pointer", func() {
+			str := "val"
+			Expect(IsInvalidValueType(c.Store(key, &str))).To(BeTrue())
+		})
+
+		It("should return an error when attempting to store a nil value", func() {
+			Expect(IsNilValue(c.Store(key, nil))).To(BeTrue())
+			Expect(IsNilValue(c.Store(key, (*testStruct)(nil)))).To(BeTrue())
+		})
+	})
+
+	Context("Atomic writes", func() {
+		It("should not leave temporary files in the directory", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, testStruct{"Other", 1})).ToNot(HaveOccurred())
+
+			entries, err := os.ReadDir(c.cacheDir)
+			Expect(err).ToNot(HaveOccurred())
+
+			names := []string{}
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			Expect(names).To(ConsistOf(key, lockFileName))
+		})
+
+		It("should return an error for a key that ends with the temporary file suffix", func() {
+			Expect(IsInvalidKeyType(c.Store(key+".tmp", val))).To(BeTrue())
+		})
+	})
+
+	Context("NewDirectoryCache", func() {
+		It("should create a nested directory", func() {
+			rootDir := fmt.Sprintf("%s/%s", os.TempDir(), "nested-dir-cache")
+			Expect(os.RemoveAll(rootDir)).ToNot(HaveOccurred())
+			defer os.RemoveAll(rootDir)
+
+			cacheDir := path.Join(rootDir, "users", "session")
+			nc, err := NewDirectoryCache(cacheDir)
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := os.Stat(cacheDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+
+			Expect(nc.Store(key, val)).ToNot(HaveOccurred())
+			Expect(nc.Get(key)).To(Equal(val))
+		})
+	})
+
+	Context("NewDirectoryCacheWithFilePermissions", func() {
+		It("should create the directory and files with the given permissions", func() {
+			cacheDir := fmt.Sprintf("%s/%s", os.TempDir(), "shared-dir-cache")
+			Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+			defer os.RemoveAll(cacheDir)
+
+			sc, err := NewDirectoryCacheWithFilePermissions(cacheDir, 0660, 0770)
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := os.Stat(cacheDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0770)))
+
+			Expect(sc.Store(key, val)).ToNot(HaveOccurred())
+			info, err = os.Stat(path.Join(cacheDir, key))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0660)))
+		})
+
+		It("should create value files with the default permissions", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			info, err := os.Stat(path.Join(c.cacheDir, key))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+	})
+
+	Context("WithExclusiveProcessLock", func() {
+		// Takes the lock of the cache directory like another process would.
+		holdLock := func(dir string) *os.File {
+			file, err := os.OpenFile(path.Join(dir, lockFileName), os.O_CREATE|os.O_RDONLY, 0600)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lockFile(file, true)).ToNot(HaveOccurred())
+
+			return file
+		}
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("advisory locks are not supported")
+			}
+		})
+
+		It("should wait for the lock of another process before writing", func() {
+			file := holdLock(c.cacheDir)
+
+			stored := make(chan error, 1)
+			go func() {
+				stored <- c.Store(key, val)
+			}()
+
+			Consistently(stored, 100*time.Millisecond).ShouldNot(Receive())
+			Expect(file.Close()).ToNot(HaveOccurred())
+			Eventually(stored).Should(Receive(BeNil()))
+
+			Expect(c.Keys()).To(ConsistOf(key))
+		})
+
+		It("should check whether the key exists under the lock", func() {
+			file := holdLock(c.cacheDir)
+
+			stored := make(chan error, 1)
+			go func() {
+				stored <- c.Store(key, val)
+			}()
+
+			Consistently(stored, 100*time.Millisecond).ShouldNot(Receive())
+			Expect(os.WriteFile(path.Join(c.cacheDir, key), []byte(`"other"`), 0600)).ToNot(HaveOccurred())
+			Expect(file.Close()).ToNot(HaveOccurred())
+
+			var err error
+			Eventually(stored).Should(Receive(&err))
+			Expect(IsAlreadyExists(err)).To(BeTrue())
+		})
+
+		It("should update a value under the lock", func() {
+			locked := make(chan bool, 1)
+			Expect(c.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				select {
+				case locked <- c.lockDepth > 0:
+				default:
+				}
+
+				return currValue, nil
+			}, 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(locked, testTimeout).Should(Receive(BeTrue()))
+		})
+
+		It("should not take the lock when it is disabled", func() {
+			cacheDir := fmt.Sprintf("%s/%s", os.TempDir(), "unlocked-dir-cache")
+			Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+			defer os.RemoveAll(cacheDir)
+
+			uc, err := NewDirectoryCache(cacheDir, WithExclusiveProcessLock(false))
+			Expect(err).ToNot(HaveOccurred())
+
+			file := holdLock(cacheDir)
+			defer file.Close()
+
+			Expect(uc.Store(key, val)).ToNot(HaveOccurred())
+			Expect(uc.Get(key)).To(Equal(val))
+		})
+
+		It("should return an error for the name of the lock file", func() {
+			Expect(IsInvalidKeyType(c.Store(lockFileName, val))).To(BeTrue())
+		})
+	})
+
+	Context("NewDirectoryCacheWithRecovery", func() {
+		It("should recover the values of a previous cache", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			rc, err := NewDirectoryCacheWithRecovery(c.cacheDir, map[string]reflect.Type{
+				key: reflect.TypeOf(testStruct{}),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rc.Get(key)).To(Equal(val))
+		})
+
+		It("should recover a struct pointer", func() {
+			Expect(c.Store(key, &val)).ToNot(HaveOccurred())
+
+			rc, err := NewDirectoryCacheWithRecovery(c.cacheDir, map[string]reflect.Type{
+				key: reflect.TypeOf(&testStruct{}),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rc.Get(key)).To(Equal(&val))
+		})
+
+		It("should return an error for a file without a registered type", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			_, err := NewDirectoryCacheWithRecovery(c.cacheDir, map[string]reflect.Type{})
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+		})
+
+		It("should remove the temporary files of writes that did not complete", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			tmpFile := path.Join(c.cacheDir, key+".123.tmp")
+			Expect(os.WriteFile(tmpFile, []byte(`{"str":`), 0600)).ToNot(HaveOccurred())
+
+			rc, err := NewDirectoryCacheWithRecovery(c.cacheDir, map[string]reflect.Type{
+				key: reflect.TypeOf(testStruct{}),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rc.Keys()).To(ConsistOf(key))
+
+			_, err = os.Stat(tmpFile)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("should recover protocol buffer messages without a registered type", func() {
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).ToNot(HaveOccurred())
+
+			rc, err := NewDirectoryCacheWithRecovery(c.cacheDir, map[string]reflect.Type{})
+			Expect(err).ToNot(HaveOccurred())
+
+			msg := &wrapperspb.StringValue{}
+			Expect(rc.GetProto(key, msg)).ToNot(HaveOccurred())
+			Expect(msg.GetValue()).To(Equal("Test"))
 		})
 	})
 
@@ -65,12 +327,53 @@ var _ = Describe("Directory Cache", func() {
 			Expect(v).To(Equal(val))
 		})
 
+		It("should decode a key file that was not written by the cache", func() {
+			Expect(os.WriteFile(path.Join(c.cacheDir, "external"),
+				[]byte(`{"str":"External","int":1}`), 0600)).ToNot(HaveOccurred())
+
+			Expect(c.Get("external")).To(Equal(map[string]interface{}{"str": "External", "int": 1.0}))
+		})
+
 		It("should return an error when attmpting to get a non-existent value", func() {
 			_, err := c.Get("IDoNotExist")
 			Expect(IsDoesNotExist(err)).To(BeTrue())
 		})
 	})
 
+	Context("Contains", func() {
+		It("should return true for an existing key", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Contains(key)).To(BeTrue())
+		})
+
+		It("should return false for a non-existent key", func() {
+			Expect(c.Contains("IDoNotExist")).To(BeFalse())
+		})
+
+		It("should return an error when the key is not a string", func() {
+			_, err := c.Contains(1)
+			Expect(IsInvalidKeyType(err)).To(BeTrue())
+		})
+	})
+
+	Context("GetOrStore", func() {
+		It("should store a value when the key does not exist", func() {
+			actual, loaded, err := c.GetOrStore(key, val)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeFalse())
+			Expect(actual).To(Equal(val))
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return the existing value when the key exists", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			actual, loaded, err := c.GetOrStore(key, testStruct{"New", 0})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeTrue())
+			Expect(actual).To(Equal(val))
+		})
+	})
+
 	Context("Remove", func() {
 		BeforeEach(func() {
 			Expect(c.Store(key, val)).ToNot(HaveOccurred())
@@ -87,6 +390,32 @@ var _ = Describe("Directory Cache", func() {
 		})
 	})
 
+	Context("BulkRemove", func() {
+		It("should remove the existing values and report the others", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": val})).
+				ToNot(HaveOccurred())
+
+			removed, err := c.BulkRemove([]interface{}{key, "other-key", "non-existent", 1})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(removed).To(ConsistOf(key, "other-key"))
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("GetAndRemove", func() {
+		It("should return a value and remove it", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.GetAndRemove(key)).To(Equal(val))
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+
+		It("should return an error when attempting to get a non-existent value", func() {
+			_, err := c.GetAndRemove("IDoNotExist")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
 	Context("Replace", func() {
 		BeforeEach(func() {
 			Expect(c.Store(key, val)).ToNot(HaveOccurred())
@@ -112,6 +441,391 @@ var _ = Describe("Directory Cache", func() {
 		})
 	})
 
+	Context("StoreOrReplace", func() {
+		It("should store a value or replace the current one", func() {
+			Expect(c.StoreOrReplace(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreOrReplace(key, testStruct{"New", 1})).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(testStruct{"New", 1}))
+		})
+	})
+
+	Context("Context", func() {
+		It("should store and get a value using a context", func() {
+			ctx := context.Background()
+			Expect(c.StoreCtx(ctx, key, val)).ToNot(HaveOccurred())
+			Expect(c.GetCtx(ctx, key)).To(Equal(val))
+			Expect(c.ReplaceCtx(ctx, key, testStruct{"New", 0})).ToNot(HaveOccurred())
+			Expect(c.RemoveCtx(ctx, key)).ToNot(HaveOccurred())
+		})
+
+		It("should not access the directory when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			Expect(c.StoreCtx(ctx, key, val)).To(MatchError(context.Canceled))
+			Expect(c.Contains(key)).To(BeFalse())
+			_, err := c.GetCtx(ctx, key)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(c.ClearCtx(ctx)).To(MatchError(context.Canceled))
+		})
+	})
+
+	Context("StoreMany", func() {
+		It("should store the valid values and report the invalid ones", func() {
+			err := c.StoreMany(map[interface{}]interface{}{key: val, 1: val})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+	})
+
+	Context("GetMany", func() {
+		It("should return the found values along with a partial failure", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			vals, err := c.GetMany([]interface{}{key, "non-existent"})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(vals).To(Equal(map[interface{}]interface{}{key: val}))
+		})
+	})
+
+	Context("StoreProto", func() {
+		It("should store and get a protocol buffer message", func() {
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).ToNot(HaveOccurred())
+
+			msg := &wrapperspb.StringValue{}
+			Expect(c.GetProto(key, msg)).ToNot(HaveOccurred())
+			Expect(msg.GetValue()).To(Equal("Test"))
+			Expect(c.Keys()).To(ConsistOf(key))
+		})
+
+		It("should return an error when getting a message of another type", func() {
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).ToNot(HaveOccurred())
+			Expect(IsInvalidValueType(c.GetProto(key, &wrapperspb.Int64Value{}))).To(BeTrue())
+		})
+
+		It("should return an error when getting a value that is not a message", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsInvalidValueType(c.GetProto(key, &wrapperspb.StringValue{}))).To(BeTrue())
+
+			_, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return an error when getting a message with Get", func() {
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).ToNot(HaveOccurred())
+
+			_, err := c.Get(key)
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+		})
+
+		It("should remove the type file along with the message", func() {
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return an error for a key that ends with the type file suffix", func() {
+			Expect(IsInvalidKeyType(c.StoreProto(key+".type", wrapperspb.String("Test")))).To(BeTrue())
+		})
+
+		It("should not leave any file behind when a write fails", func() {
+			// The type file cannot be renamed over a directory.
+			typeDir := c.protoTypeFileName(key)
+			Expect(os.Mkdir(typeDir, 0700)).ToNot(HaveOccurred())
+
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).To(HaveOccurred())
+			Expect(os.RemoveAll(typeDir)).ToNot(HaveOccurred())
+
+			entries, err := os.ReadDir(c.cacheDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal(lockFileName))
+
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).ToNot(HaveOccurred())
+			msg := &wrapperspb.StringValue{}
+			Expect(c.GetProto(key, msg)).ToNot(HaveOccurred())
+			Expect(msg.GetValue()).To(Equal("Test"))
+		})
+	})
+
+	Context("WithEncoding", func() {
+		var bigVal big.Int
+
+		BeforeEach(func() {
+			bigVal.SetInt64(42)
+		})
+
+		It("should fail to store a value that cannot be recovered from json", func() {
+			Expect(c.Store(key, bigVal)).To(HaveOccurred())
+		})
+
+		It("should store and recover a value using gob", func() {
+			cacheDir := fmt.Sprintf("%s/%s", os.TempDir(), "gob-dir-cache")
+			Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+
+			gc, err := NewDirectoryCache(cacheDir, WithEncoding(GobEncoding{}))
+			Expect(err).ToNot(HaveOccurred())
+			defer gc.Clear()
+
+			Expect(gc.Store(key, bigVal)).ToNot(HaveOccurred())
+			v, err := gc.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(v).To(BeAssignableToTypeOf(bigVal))
+			recovered := v.(big.Int)
+			Expect(recovered.Cmp(&bigVal)).To(Equal(0))
+		})
+
+		It("should store and recover a scalar value using gob and msgpack", func() {
+			for _, enc := range []Encoding{GobEncoding{}, MsgpackEncoding{}} {
+				ec, err := NewDirectoryCache(c.cacheDir, WithEncoding(enc))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(ec.StoreOrReplace(key, int64(42))).ToNot(HaveOccurred())
+				Expect(ec.Get(key)).To(Equal(int64(42)))
+			}
+		})
+
+		It("should store and recover a value using msgpack", func() {
+			cacheDir := fmt.Sprintf("%s/%s", os.TempDir(), "msgpack-dir-cache")
+			Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+
+			mc, err := NewDirectoryCache(cacheDir, WithEncoding(MsgpackEncoding{}))
+			Expect(err).ToNot(HaveOccurred())
+			defer mc.Clear()
+
+			Expect(mc.Store(key, val)).ToNot(HaveOccurred())
+			Expect(mc.Get(key)).To(Equal(val))
+		})
+	})
+
+	Context("WithCompression", func() {
+		for name, compressor := range map[string]Compressor{
+			"zstd": ZstdCompressor{},
+			"gzip": GzipCompressor{},
+		} {
+			name, compressor := name, compressor
+
+			It("should store and recover a compressed value using "+name, func() {
+				cacheDir := fmt.Sprintf("%s/%s-%s", os.TempDir(), name, "dir-cache")
+				Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+
+				cc, err := NewDirectoryCache(cacheDir, WithCompression(compressor))
+				Expect(err).ToNot(HaveOccurred())
+				defer cc.Clear()
+
+				bigStruct := testStruct{strings.Repeat("Test", 1024), 1}
+				Expect(cc.Store(key, bigStruct)).ToNot(HaveOccurred())
+				Expect(cc.Get(key)).To(Equal(bigStruct))
+
+				info, err := os.Stat(path.Join(cacheDir, key))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Size()).To(BeNumerically("<", len(bigStruct.Str)))
+			})
+		}
+	})
+
+	Context("WarmUp", func() {
+		It("should store all values", func() {
+			Expect(c.WarmUp(map[interface{}]interface{}{key: val, "other-key": val})).
+				ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Get("other-key")).To(Equal(val))
+		})
+
+		It("should not store any value if one of them is invalid", func() {
+			err := c.WarmUp(map[interface{}]interface{}{key: val, "other-key": make(chan int)})
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+	})
+
+	Context("ForEach", func() {
+		BeforeEach(func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": val})).
+				ToNot(HaveOccurred())
+		})
+
+		It("should iterate over all values", func() {
+			vals := map[interface{}]interface{}{}
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				vals[key] = val
+				return true
+			})).ToNot(HaveOccurred())
+			Expect(vals).To(Equal(map[interface{}]interface{}{key: val, "other-key": val}))
+		})
+
+		It("should stop when fn returns false", func() {
+			calls := 0
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				calls++
+				return false
+			})).ToNot(HaveOccurred())
+			Expect(calls).To(Equal(1))
+		})
+	})
+
+	Context("GetAll", func() {
+		It("should return all values", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": val})).
+				ToNot(HaveOccurred())
+
+			Expect(c.GetAll()).To(Equal(map[interface{}]interface{}{key: val, "other-key": val}))
+		})
+
+		It("should return an error for a value that cannot be read", func() {
+			Expect(c.StoreProto(key, wrapperspb.String("Test"))).ToNot(HaveOccurred())
+
+			_, err := c.GetAll()
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+		})
+	})
+
+	Context("FilterKeys", func() {
+		It("should filter the file names", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"user-1": val, "user-2": val, "order-1": val})).
+				ToNot(HaveOccurred())
+			Expect(c.StoreProto("user-3", wrapperspb.String("Test"))).ToNot(HaveOccurred())
+
+			Expect(c.FilterKeys(func(k interface{}) bool {
+				return strings.HasPrefix(k.(string), "user-")
+			})).To(ConsistOf("user-1", "user-2", "user-3"))
+		})
+	})
+
+	Context("CopyTo", func() {
+		It("should copy all values to another cache", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			dst := NewMapCache()
+			Expect(c.CopyTo(dst)).ToNot(HaveOccurred())
+			Expect(dst.Get(key)).To(Equal(val))
+		})
+	})
+
+	Context("WriteTo", func() {
+		It("should write a stream that can be restored into another cache", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Store("other", testStruct{"Other", 1})).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			written, err := c.WriteTo(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(written).To(Equal(int64(buf.Len())))
+
+			restoreDir := fmt.Sprintf("%s/%s", os.TempDir(), "dir-cache-restore")
+			Expect(os.RemoveAll(restoreDir)).ToNot(HaveOccurred())
+			restored, err := NewDirectoryCache(restoreDir)
+			Expect(err).ToNot(HaveOccurred())
+			defer restored.Clear()
+
+			read, err := restored.ReadFrom(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(read).To(Equal(written))
+
+			Expect(restored.Keys()).To(Equal([]interface{}{key, "other"}))
+			Expect(restored.Get(key)).To(Equal(map[string]interface{}{"str": "Test", "int": 0.0}))
+			Expect(restored.Get("other")).To(Equal(map[string]interface{}{"str": "Other", "int": 1.0}))
+		})
+
+		It("should restore scalars and protocol buffer messages", func() {
+			Expect(c.Store("scalar", 42)).ToNot(HaveOccurred())
+			Expect(c.StoreProto("message", wrapperspb.String("Test"))).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			_, err := c.WriteTo(&buf)
+			Expect(err).ToNot(HaveOccurred())
+
+			restoreDir := fmt.Sprintf("%s/%s", os.TempDir(), "dir-cache-restore")
+			Expect(os.RemoveAll(restoreDir)).ToNot(HaveOccurred())
+			restored, err := NewDirectoryCache(restoreDir)
+			Expect(err).ToNot(HaveOccurred())
+			defer restored.Clear()
+
+			_, err = restored.ReadFrom(&buf)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(restored.Keys()).To(ConsistOf("scalar", "message"))
+			Expect(restored.Get("scalar")).To(Equal(42.0))
+
+			msg := &wrapperspb.StringValue{}
+			Expect(restored.GetProto("message", msg)).ToNot(HaveOccurred())
+			Expect(msg.GetValue()).To(Equal("Test"))
+		})
+	})
+
+	Context("ReadFrom", func() {
+		It("should return an error when attempting to restore into a non-empty cache", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			_, err := c.WriteTo(&buf)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = c.ReadFrom(&buf)
+			Expect(IsCacheNotEmpty(err)).To(BeTrue())
+		})
+
+		It("should not store any value from a truncated stream", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Store("other", testStruct{"Other", 1})).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			_, err := c.WriteTo(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Remove("other")).ToNot(HaveOccurred())
+
+			_, err = c.ReadFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+			Expect(err).To(Equal(io.ErrUnexpectedEOF))
+			Expect(c.Keys()).To(BeEmpty())
+		})
+
+		It("should not allocate the declared length of a truncated chunk", func() {
+			var buf bytes.Buffer
+			Expect(writeChunk(&buf, []byte(key))).ToNot(HaveOccurred())
+			Expect(binary.Write(&buf, binary.BigEndian, uint32(math.MaxUint32))).ToNot(HaveOccurred())
+			buf.WriteString(`"val"`)
+
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := c.ReadFrom(&buf)
+			runtime.ReadMemStats(&after)
+
+			Expect(err).To(Equal(io.ErrUnexpectedEOF))
+			Expect(after.TotalAlloc - before.TotalAlloc).To(BeNumerically("<", 1<<20))
+			Expect(c.Keys()).To(BeEmpty())
+		})
+
+		It("should return an error for keys that are not file names", func() {
+			var buf bytes.Buffer
+			Expect(writeChunk(&buf, []byte("../escape"))).ToNot(HaveOccurred())
+			Expect(writeChunk(&buf, []byte(`{"str":"Test","int":0}`))).ToNot(HaveOccurred())
+
+			_, err := c.ReadFrom(&buf)
+			Expect(IsInvalidKeyType(err)).To(BeTrue())
+			Expect(c.Keys()).To(BeEmpty())
+		})
+
+		It("should return an error for keys of internal files", func() {
+			for _, name := range []string{lockFileName, key + tempFileSuffix, key + protoTypeFileSuffix} {
+				var buf bytes.Buffer
+				Expect(writeChunk(&buf, []byte(name))).ToNot(HaveOccurred())
+				Expect(writeChunk(&buf, []byte(`{"str":"Test","int":0}`))).ToNot(HaveOccurred())
+
+				_, err := c.ReadFrom(&buf)
+				Expect(IsInvalidKeyType(err)).To(BeTrue())
+			}
+
+			entries, err := os.ReadDir(c.cacheDir)
+			Expect(err).ToNot(HaveOccurred())
+			for _, entry := range entries {
+				Expect(entry.Name()).To(Equal(lockFileName))
+			}
+		})
+	})
+
 	Context("Clear", func() {
 		It("should clear the cache", func() {
 			Expect(c.Clear()).ToNot(HaveOccurred(),
@@ -135,6 +849,84 @@ var _ = Describe("Directory Cache", func() {
 		})
 	})
 
+	Context("KeysPage", func() {
+		BeforeEach(func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": val, "b": val, "c": val})).ToNot(HaveOccurred())
+		})
+
+		It("should return a sorted page of keys and the total count", func() {
+			keys, total, err := c.KeysPage(1, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]interface{}{"b", "c"}))
+			Expect(total).To(Equal(3))
+		})
+
+		It("should return an error for a negative offset or limit", func() {
+			_, _, err := c.KeysPage(0, -1)
+			Expect(IsInvalidPage(err)).To(BeTrue())
+		})
+	})
+
+	Context("Watch", func() {
+		type watchEvent struct {
+			event  EventType
+			newVal interface{}
+		}
+
+		var events chan watchEvent
+
+		BeforeEach(func() {
+			events = make(chan watchEvent, 10)
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+		})
+
+		watch := func() func() {
+			cancel, err := c.Watch(key, func(event EventType, newVal interface{}) {
+				events <- watchEvent{event, newVal}
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			return cancel
+		}
+
+		It("should report a value that was written by another process", func() {
+			defer watch()()
+
+			newVal := testStruct{"External", 1}
+			data, err := JSONEncoding{}.Marshal(newVal)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.WriteFile(path.Join(c.cacheDir, key), data, 0600)).ToNot(HaveOccurred())
+
+			Eventually(events, testTimeout).Should(Receive(Equal(watchEvent{EventModified, newVal})))
+			Expect(c.Get(key)).To(Equal(newVal))
+		})
+
+		It("should report a file that was deleted by another process", func() {
+			defer watch()()
+
+			Expect(os.Remove(path.Join(c.cacheDir, key))).ToNot(HaveOccurred())
+
+			Eventually(events, testTimeout).Should(Receive(Equal(watchEvent{EventDeleted, nil})))
+			Expect(c.valueTypes).ToNot(HaveKey(key))
+		})
+
+		It("should not report changes made through the cache", func() {
+			defer watch()()
+
+			Expect(c.Replace(key, testStruct{"Replaced", 1})).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+
+			Consistently(events, 500*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("should stop reporting once canceled", func() {
+			watch()()
+
+			Expect(os.Remove(path.Join(c.cacheDir, key))).ToNot(HaveOccurred())
+			Consistently(events, 500*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
 	Context("StoreWithExpiration", func() {
 		It("should store a temporary value", func() {
 			Expect(c.StoreWithExpiration(key, val, 3*time.Second))
@@ -151,6 +943,61 @@ var _ = Describe("Directory Cache", func() {
 		})
 	})
 
+	Context("StoreWithDeadline", func() {
+		It("should store a value that is removed at the deadline", func() {
+			Expect(c.StoreWithDeadline(key, val, time.Now().Add(time.Minute))).ToNot(HaveOccurred())
+
+			remaining, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(remaining).To(BeNumerically("~", time.Minute, time.Second))
+		})
+
+		It("should return an error if the deadline has passed", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithDeadline(key, val, time.Now()))).To(BeTrue())
+		})
+	})
+
+	Context("TTL", func() {
+		It("should return the remaining ttl of a temporary value", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			remaining, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(remaining).To(BeNumerically("~", time.Minute, time.Second))
+		})
+
+		It("should return no ttl for a permanent value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+
+		It("should return an error for a non-existent key", func() {
+			_, _, err := c.TTL("IDoNotExist")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("StoreWithSlidingExpiration", func() {
+		It("should not remove a value as long as it is accessed", func() {
+			Expect(c.StoreWithSlidingExpiration(key, val, 2*time.Second)).ToNot(HaveOccurred())
+
+			Consistently(func() bool {
+				v, err := c.Get(key)
+				return v == val && err == nil
+			}, 4*time.Second, 500*time.Millisecond).Should(BeTrue(),
+				"value should not have been removed while being accessed")
+
+			Eventually(func() bool {
+				_, err := c.Get(key)
+				return IsDoesNotExist(err)
+			}, testTimeout, 3*time.Second).Should(BeTrue(),
+				"value was not removed after it was no longer accessed")
+		})
+	})
+
 	Context("ReplaceWithExpiration", func() {
 		It("should replace a permanent value with a temporary one", func() {
 			Expect(c.Store(key, val)).ToNot(HaveOccurred())
@@ -212,13 +1059,39 @@ var _ = Describe("Directory Cache", func() {
 				return IsDoesNotExist(err)
 			}, testTimeout).Should(BeTrue(), "value should have been removed")
 		})
+
+		It("should stop the updates of an updating value", func() {
+			Expect(c.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				curr := currValue.(testStruct)
+				return testStruct{curr.Str, curr.Int + 1}, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.Expire(key, 2*time.Second)).ToNot(HaveOccurred())
+
+			expiredVal, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			Consistently(func() interface{} {
+				v, _ := c.Get(key)
+				return v
+			}, time.Second).Should(Equal(expiredVal), "value should not have been updated")
+
+			Eventually(func() bool {
+				_, err := c.Get(key)
+				return IsDoesNotExist(err)
+			}, testTimeout).Should(BeTrue(), "value should have been removed")
+		})
 	})
 
 	Context("StoreWithUpdate", func() {
+		It("should return an error when updateFunc is nil", func() {
+			Expect(IsNilUpdateFunc(c.StoreWithUpdate(key, val, nil, time.Second))).To(BeTrue())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+
 		It("should store a value and continously update it", func() {
-			updateFunc := func(currValue interface{}) interface{} {
+			updateFunc := func(currValue interface{}) (interface{}, error) {
 				intVal := currValue.(testStruct).Int
-				return testStruct{"Test", intVal + 1}
+				return testStruct{"Test", intVal + 1}, nil
 			}
 
 			Expect(c.StoreWithUpdate(key,
@@ -240,12 +1113,165 @@ var _ = Describe("Directory Cache", func() {
 		})
 	})
 
+	Context("StoreWithExpirationAndUpdate", func() {
+		It("should update a value and remove it after the total ttl", func() {
+			Expect(c.StoreWithExpirationAndUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return testStruct{"Test", currValue.(testStruct).Int + 1}, nil
+			}, 50*time.Millisecond, 500*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() int {
+				v, err := c.Get(key)
+				if err != nil {
+					return 0
+				}
+				return v.(testStruct).Int
+			}, testTimeout).Should(BeNumerically(">", 1))
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+	})
+
+	Context("StoreWithUpdateAndExpiration", func() {
+		It("should keep a value that is being read past the total ttl", func() {
+			Expect(c.StoreWithUpdateAndExpiration(key, val, func(currValue interface{}) (interface{}, error) {
+				return testStruct{"Test", currValue.(testStruct).Int + 1}, nil
+			}, 50*time.Millisecond, 300*time.Millisecond)).ToNot(HaveOccurred())
+
+			Consistently(func() error {
+				_, err := c.Get(key)
+				return err
+			}, time.Second, 100*time.Millisecond).ShouldNot(HaveOccurred())
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+
+		It("should stop expiring a value once its update is canceled", func() {
+			Expect(c.StoreWithUpdateAndExpiration(key, val, func(currValue interface{}) (interface{}, error) {
+				return testStruct{"Test", currValue.(testStruct).Int + 1}, nil
+			}, 50*time.Millisecond, 300*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.CancelUpdate(key)).ToNot(HaveOccurred())
+
+			Consistently(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, 600*time.Millisecond).Should(BeTrue())
+		})
+	})
+
+	Context("CancelUpdate", func() {
+		It("should stop updating a value without removing it", func() {
+			Expect(c.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return testStruct{"Test", currValue.(testStruct).Int + 1}, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() int {
+				v, _ := c.Get(key)
+				return v.(testStruct).Int
+			}, testTimeout).Should(BeNumerically(">", 0))
+			Expect(c.CancelUpdate(key)).ToNot(HaveOccurred())
+
+			frozen, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(func() interface{} {
+				v, _ := c.Get(key)
+				return v
+			}, 500*time.Millisecond).Should(Equal(frozen))
+		})
+
+		It("should return an error for a non-existent key", func() {
+			Expect(IsDoesNotExist(c.CancelUpdate("non-existent"))).To(BeTrue())
+		})
+	})
+
+	Context("SetUpdatePeriod", func() {
+		It("should keep updating a value with the new period", func() {
+			Expect(c.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return testStruct{"Test", currValue.(testStruct).Int + 1}, nil
+			}, time.Hour)).ToNot(HaveOccurred())
+			Expect(c.SetUpdatePeriod(key, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() int {
+				v, _ := c.Get(key)
+				return v.(testStruct).Int
+			}, testTimeout).Should(BeNumerically(">", val.Int+1))
+		})
+
+		It("should return an error for a value that is not being updated", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsDoesNotExist(c.SetUpdatePeriod(key, time.Second))).To(BeTrue())
+		})
+	})
+
+	Context("WithDirectoryUpdateErrorHandler", func() {
+		It("should keep the current value and report the error when updateFunc fails", func() {
+			cacheDir := fmt.Sprintf("%s/%s", os.TempDir(), "update-error-dir-cache")
+			Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+
+			errs := make(chan error, 10)
+			ec, err := NewDirectoryCache(cacheDir,
+				WithDirectoryUpdateErrorHandler(func(key string, err error) {
+					errs <- err
+				}))
+			Expect(err).ToNot(HaveOccurred())
+			defer ec.Clear()
+
+			updateErr := fmt.Errorf("update failed")
+			Expect(ec.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return nil, updateErr
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(errs, testTimeout).Should(Receive(Equal(updateErr)))
+			Expect(ec.Get(key)).To(Equal(val))
+		})
+	})
+
+	Context("WithDirectoryLogger", func() {
+		It("should log an unexpected error of a background routine", func() {
+			cacheDir := fmt.Sprintf("%s/%s", os.TempDir(), "logger-dir-cache")
+			Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+
+			logs := make(chanWriter, 10)
+			lc, err := NewDirectoryCache(cacheDir,
+				WithDirectoryLogger(slog.New(slog.NewJSONHandler(logs, nil))))
+			Expect(err).ToNot(HaveOccurred())
+			defer lc.Clear()
+
+			Expect(lc.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return currValue, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			// Reading the value fails once its file is gone.
+			Expect(os.Remove(path.Join(cacheDir, key))).ToNot(HaveOccurred())
+
+			var entry map[string]interface{}
+			Eventually(logs, testTimeout).Should(Receive(WithTransform(func(data []byte) error {
+				return json.Unmarshal(data, &entry)
+			}, Succeed())))
+			Expect(entry).To(HaveKeyWithValue("level", "ERROR"))
+			Expect(entry).To(HaveKeyWithValue("key", key))
+			Expect(entry).To(HaveKeyWithValue("operation", "update"))
+			Expect(entry).To(HaveKey("error"))
+		})
+	})
+
 	Context("ReplaceWithUpdate", func() {
+		It("should not remove the current value if updateFunc is nil", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsNilUpdateFunc(c.ReplaceWithUpdate(key, val, nil, time.Second))).To(BeTrue())
+			Expect(c.Contains(key)).To(BeTrue())
+		})
+
 		It("should replace and continously update a permanent value", func() {
 			Expect(c.Store(key, val)).ToNot(HaveOccurred())
-			updateFunc := func(currValue interface{}) interface{} {
+			updateFunc := func(currValue interface{}) (interface{}, error) {
 				intVal := currValue.(testStruct).Int
-				return testStruct{"Test", intVal + 1}
+				return testStruct{"Test", intVal + 1}, nil
 			}
 
 			Expect(c.ReplaceWithUpdate(key,
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoding converts values to bytes and back, it is used by caches that
+// persist their values, such as directoryCache.
+type Encoding interface {
+	Marshal(val interface{}) ([]byte, error)
+	Unmarshal(data []byte, val interface{}) error
+}
+
+// JSONEncoding encodes values using encoding/json, values must have exported
+// fields with json tags in order to be fully recovered.
+type JSONEncoding struct{}
+
+func (JSONEncoding) Marshal(val interface{}) ([]byte, error) {
+	return json.Marshal(val)
+}
+
+func (JSONEncoding) Unmarshal(data []byte, val interface{}) error {
+	return json.Unmarshal(data, val)
+}
+
+// GobEncoding encodes values using encoding/gob, which supports types that
+// implement gob.GobEncoder such as big.Int.
+type GobEncoding struct{}
+
+func (GobEncoding) Marshal(val interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// Gob encoders with pointer receivers (such as big.Int's) can only be used
+	// on addressable values, so the value is encoded through a pointer.
+	ptr := reflect.New(reflect.TypeOf(val))
+	ptr.Elem().Set(reflect.ValueOf(val))
+
+	err := gob.NewEncoder(&buf).Encode(ptr.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobEncoding) Unmarshal(data []byte, val interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(val)
+}
+
+// MsgpackEncoding encodes values using MessagePack, which is faster than JSON
+// and produces smaller files.
+type MsgpackEncoding struct{}
+
+func (MsgpackEncoding) Marshal(val interface{}) ([]byte, error) {
+	return msgpack.Marshal(val)
+}
+
+func (MsgpackEncoding) Unmarshal(data []byte, val interface{}) error {
+	return msgpack.Unmarshal(data, val)
+}
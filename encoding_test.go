@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"testing"
+)
+
+func benchmarkEncoding(b *testing.B, enc Encoding) {
+	val := testStruct{"Test", 1}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := enc.Marshal(val)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		var decoded testStruct
+		err = enc.Unmarshal(data, &decoded)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONEncoding(b *testing.B) {
+	benchmarkEncoding(b, JSONEncoding{})
+}
+
+func BenchmarkGobEncoding(b *testing.B) {
+	benchmarkEncoding(b, GobEncoding{})
+}
+
+func BenchmarkMsgpackEncoding(b *testing.B) {
+	benchmarkEncoding(b, MsgpackEncoding{})
+}
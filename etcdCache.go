@@ -0,0 +1,830 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	errorTypeEtcdError errorType = "EtcdError"
+)
+
+func IsEtcdError(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeEtcdError
+}
+
+// The operations of etcd that EtcdCache uses, each one is a single request.
+type etcdClient interface {
+	// Put val when key does not exist, reports whether it was put.
+	putIfAbsent(ctx context.Context, key, val string, lease clientv3.LeaseID) (bool, error)
+
+	// Put val when key exists, reports whether it was put.
+	putIfPresent(ctx context.Context, key, val string, lease clientv3.LeaseID) (bool, error)
+
+	put(ctx context.Context, key, val string) error
+
+	// Attach a lease to an existing key without changing its value, reports
+	// whether the key exists.
+	attachLease(ctx context.Context, key string, lease clientv3.LeaseID) (bool, error)
+
+	// Returns nil if key does not exist.
+	get(ctx context.Context, key string) (*mvccpb.KeyValue, error)
+
+	// Returns the keys under prefix sorted by key.
+	getPrefix(ctx context.Context, prefix string, keysOnly bool) ([]*mvccpb.KeyValue, error)
+
+	// Returns the deleted key, or nil if key does not exist.
+	delete(ctx context.Context, key string) (*mvccpb.KeyValue, error)
+
+	deletePrefix(ctx context.Context, prefix string) error
+
+	grant(ctx context.Context, seconds int64) (clientv3.LeaseID, error)
+	revoke(ctx context.Context, lease clientv3.LeaseID) error
+
+	// Returns the remaining seconds of a lease.
+	timeToLive(ctx context.Context, lease clientv3.LeaseID) (int64, error)
+
+	keepAliveOnce(ctx context.Context, lease clientv3.LeaseID) error
+	watch(ctx context.Context, key string) clientv3.WatchChan
+	close() error
+}
+
+// EtcdCache is a client that implements ExpiringCache interface, all
+// instances that use the same prefix share their keys and values.
+type EtcdCache struct {
+	// Prepended to every key, Keys and Clear only cover keys under prefix.
+	prefix string
+
+	// The maximal duration of every request.
+	timeout time.Duration
+
+	// Holds the keys that this instance stored with a sliding expiration,
+	// their leases are renewed whenever this instance gets them.
+	slidingKeys map[string]struct{}
+
+	client etcdClient
+
+	mutex sync.Mutex
+}
+
+var _ ExpiringCache = (*EtcdCache)(nil)
+
+// --------------------------------------------------------------------------
+
+// NewEtcdCache creates and returns a reference to an EtcdCache instance that
+// stores its keys under prefix, dialTimeout also limits the duration of every
+// request. prefix must not be empty, a "/" is appended to it if it does not
+// end with one, so the keys of "app" do not cover the keys of "apple".
+//
+// Values are stored as bytes, []byte and string values are stored as is and
+// any other value is formatted with fmt. Cached values are returned as strings.
+func NewEtcdCache(endpoints []string, prefix string,
+	dialTimeout time.Duration) (*EtcdCache, error) {
+	// Keys and Clear would cover the whole keyspace of the cluster.
+	if prefix == "" {
+		return nil, newError(errorTypeInvalidKeyType, "prefix must not be empty")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+	})
+	if err != nil {
+		return nil, newWrapperError(errorTypeEtcdError, "could not connect to etcd", err)
+	}
+
+	return newEtcdCache(&etcdV3Client{client}, prefix, dialTimeout), nil
+}
+
+func newEtcdCache(client etcdClient, prefix string, timeout time.Duration) *EtcdCache {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &EtcdCache{
+		prefix:      prefix,
+		timeout:     timeout,
+		slidingKeys: map[string]struct{}{},
+		client:      client,
+	}
+}
+
+func (e *EtcdCache) etcdKey(key interface{}) string {
+	return e.prefix + fmt.Sprintf("%v", key)
+}
+
+func (e *EtcdCache) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), e.timeout)
+}
+
+func (e *EtcdCache) store(key, val interface{}, lease clientv3.LeaseID) error {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	etcdKey := e.etcdKey(key)
+
+	stored, err := e.client.putIfAbsent(ctx, etcdKey, etcdValue(val), lease)
+	if err != nil {
+		return newError(errorTypeEtcdError,
+			fmt.Sprintf("could not store key %v: %v", key, err))
+	}
+
+	if !stored {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	return nil
+}
+
+func (e *EtcdCache) get(key interface{}) (*mvccpb.KeyValue, error) {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	kv, err := e.client.get(ctx, e.etcdKey(key))
+	if err != nil {
+		return nil, newError(errorTypeEtcdError,
+			fmt.Sprintf("failed to get %v from etcd: %v", key, err))
+	}
+
+	if kv == nil {
+		return nil, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", key))
+	}
+
+	return kv, nil
+}
+
+// Get a value and renew its lease if this instance stored it with a sliding
+// expiration.
+func (e *EtcdCache) getAndTouch(key interface{}) (interface{}, error) {
+	kv, err := e.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	etcdKey := e.etcdKey(key)
+	if _, isSliding := e.slidingKeys[etcdKey]; isSliding {
+		if kv.Lease == int64(clientv3.NoLease) {
+			// Another client replaced the value with a permanent one.
+			delete(e.slidingKeys, etcdKey)
+		} else {
+			ctx, cancel := e.context()
+			defer cancel()
+
+			err = e.client.keepAliveOnce(ctx, clientv3.LeaseID(kv.Lease))
+			if err != nil {
+				return nil, newError(errorTypeEtcdError,
+					fmt.Sprintf("could not renew the lease of key %v: %v", key, err))
+			}
+		}
+	}
+
+	return string(kv.Value), nil
+}
+
+func (e *EtcdCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	for {
+		actual, err := e.getAndTouch(key)
+		if err == nil {
+			return actual, true, nil
+		}
+
+		if !IsDoesNotExist(err) {
+			return nil, false, err
+		}
+
+		err = e.store(key, val, clientv3.NoLease)
+		if err == nil {
+			return val, false, nil
+		}
+
+		// Another client stored the key in the meantime.
+		if !IsAlreadyExists(err) {
+			return nil, false, err
+		}
+	}
+}
+
+func (e *EtcdCache) getAndRemove(key interface{}) (interface{}, error) {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	etcdKey := e.etcdKey(key)
+
+	kv, err := e.client.delete(ctx, etcdKey)
+	if err != nil {
+		return nil, newError(errorTypeEtcdError,
+			fmt.Sprintf("could not delete key %v: %v", key, err))
+	}
+
+	if kv == nil {
+		return nil, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("could not delete key %v", key))
+	}
+
+	delete(e.slidingKeys, etcdKey)
+
+	return string(kv.Value), nil
+}
+
+func (e *EtcdCache) replace(key, val interface{}, lease clientv3.LeaseID) error {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	etcdKey := e.etcdKey(key)
+
+	replaced, err := e.client.putIfPresent(ctx, etcdKey, etcdValue(val), lease)
+	if err != nil {
+		return newError(errorTypeEtcdError,
+			fmt.Sprintf("could not replace key %v: %v", key, err))
+	}
+
+	if !replaced {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", key))
+	}
+
+	delete(e.slidingKeys, etcdKey)
+
+	return nil
+}
+
+func (e *EtcdCache) list(keysOnly bool) ([]*mvccpb.KeyValue, error) {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	kvs, err := e.client.getPrefix(ctx, e.prefix, keysOnly)
+	if err != nil {
+		return nil, newError(errorTypeEtcdError,
+			fmt.Sprintf("could not list keys with prefix %v: %v", e.prefix, err))
+	}
+
+	return kvs, nil
+}
+
+func (e *EtcdCache) clear() error {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	err := e.client.deletePrefix(ctx, e.prefix)
+	if err != nil {
+		return newError(errorTypeEtcdError,
+			fmt.Sprintf("could not delete keys with prefix %v: %v", e.prefix, err))
+	}
+
+	e.slidingKeys = map[string]struct{}{}
+
+	return nil
+}
+
+func (e *EtcdCache) keys() ([]interface{}, error) {
+	kvs, err := e.list(true)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []interface{}{}
+	for _, kv := range kvs {
+		keys = append(keys, strings.TrimPrefix(string(kv.Key), e.prefix))
+	}
+
+	return keys, nil
+}
+
+// Grant a lease of ttl, etcd leases have a resolution of a second so shorter
+// ttls are rounded up.
+func (e *EtcdCache) grant(ttl time.Duration) (clientv3.LeaseID, error) {
+	if ttl <= 0 {
+		return clientv3.NoLease, newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+
+	lease, err := e.client.grant(ctx, int64((ttl+time.Second-1)/time.Second))
+	if err != nil {
+		return clientv3.NoLease, newError(errorTypeEtcdError,
+			fmt.Sprintf("could not grant a lease: %v", err))
+	}
+
+	return lease, nil
+}
+
+// Revoke a lease that was not attached to any key, etcd revokes it by itself
+// once its ttl is over so errors are ignored.
+func (e *EtcdCache) revoke(lease clientv3.LeaseID) {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	_ = e.client.revoke(ctx, lease)
+}
+
+func (e *EtcdCache) storeWithExpiration(key, val interface{}, ttl time.Duration) error {
+	lease, err := e.grant(ttl)
+	if err != nil {
+		return err
+	}
+
+	err = e.store(key, val, lease)
+	if err != nil {
+		e.revoke(lease)
+		return err
+	}
+
+	return nil
+}
+
+func (e *EtcdCache) replaceWithExpiration(key, val interface{}, ttl time.Duration) error {
+	lease, err := e.grant(ttl)
+	if err != nil {
+		return err
+	}
+
+	err = e.replace(key, val, lease)
+	if err != nil {
+		e.revoke(lease)
+		return err
+	}
+
+	return nil
+}
+
+func (e *EtcdCache) expire(key interface{}, ttl time.Duration) error {
+	lease, err := e.grant(ttl)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+
+	etcdKey := e.etcdKey(key)
+
+	exists, err := e.client.attachLease(ctx, etcdKey, lease)
+	if err != nil {
+		e.revoke(lease)
+		return newError(errorTypeEtcdError,
+			fmt.Sprintf("could not expire key %v: %v", key, err))
+	}
+
+	if !exists {
+		e.revoke(lease)
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", key))
+	}
+
+	delete(e.slidingKeys, etcdKey)
+
+	return nil
+}
+
+func (e *EtcdCache) ttl(key interface{}) (time.Duration, bool, error) {
+	kv, err := e.get(key)
+	if err != nil {
+		return -1, false, err
+	}
+
+	if kv.Lease == int64(clientv3.NoLease) {
+		return -1, false, nil
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+
+	seconds, err := e.client.timeToLive(ctx, clientv3.LeaseID(kv.Lease))
+	if err != nil {
+		return -1, false, newError(errorTypeEtcdError,
+			fmt.Sprintf("could not get the ttl of key %v: %v", key, err))
+	}
+
+	// The lease expired after the key was read.
+	if seconds < 0 {
+		return -1, false, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", key))
+	}
+
+	return time.Duration(seconds) * time.Second, true, nil
+}
+
+func etcdValue(val interface{}) string {
+	switch v := val.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// --------------------------------------------------------------------------
+
+// Store permanent value in etcd.
+func (e *EtcdCache) Store(key, val interface{}) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.store(key, val, clientv3.NoLease)
+}
+
+// Get returns the value of a key from etcd.
+func (e *EtcdCache) Get(key interface{}) (interface{}, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.getAndTouch(key)
+}
+
+// Contains checks whether a key exists in etcd.
+func (e *EtcdCache) Contains(key interface{}) (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	_, err := e.get(key)
+	if IsDoesNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// GetOrStore returns the value of a key, or stores val if the key does not
+// exist.
+func (e *EtcdCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.getOrStore(key, val)
+}
+
+// Remove a key from etcd.
+func (e *EtcdCache) Remove(key interface{}) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	_, err := e.getAndRemove(key)
+
+	return err
+}
+
+// GetAndRemove returns the value of a key and removes it from etcd in a single
+// request.
+func (e *EtcdCache) GetAndRemove(key interface{}) (interface{}, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.getAndRemove(key)
+}
+
+// Replace the value of an existing key with a permanent value.
+func (e *EtcdCache) Replace(key, val interface{}) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.replace(key, val, clientv3.NoLease)
+}
+
+// StoreOrReplace stores a permanent value in etcd whether or not the key
+// already exists.
+func (e *EtcdCache) StoreOrReplace(key, val interface{}) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ctx, cancel := e.context()
+	defer cancel()
+
+	etcdKey := e.etcdKey(key)
+
+	err := e.client.put(ctx, etcdKey, etcdValue(val))
+	if err != nil {
+		return newError(errorTypeEtcdError,
+			fmt.Sprintf("could not store key %v: %v", key, err))
+	}
+
+	delete(e.slidingKeys, etcdKey)
+
+	return nil
+}
+
+// StoreMany stores several permanent values in etcd.
+func (e *EtcdCache) StoreMany(items map[interface{}]interface{}) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return storeMany(items, func(key, val interface{}) error {
+		return e.store(key, val, clientv3.NoLease)
+	})
+}
+
+// GetMany returns the values of several keys.
+func (e *EtcdCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return getMany(keys, e.getAndTouch)
+}
+
+// Clear removes all keys under the prefix of the cache, of all instances.
+func (e *EtcdCache) Clear() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.clear()
+}
+
+// Keys returns all keys under the prefix of the cache, without the prefix.
+func (e *EtcdCache) Keys() ([]interface{}, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.keys()
+}
+
+// ForEach calls fn for each key under the prefix of the cache and its value
+// until fn returns false, all values are read in a single request.
+func (e *EtcdCache) ForEach(fn func(key, val interface{}) bool) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	kvs, err := e.list(false)
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range kvs {
+		if !fn(strings.TrimPrefix(string(kv.Key), e.prefix), string(kv.Value)) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Get the keys for which predicate returns true.
+func (e *EtcdCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	keys, err := e.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterKeys(keys, predicate), nil
+}
+
+// StoreWithExpiration stores a value that etcd removes once its lease
+// expires, ttl must be greater than zero.
+func (e *EtcdCache) StoreWithExpiration(key, val interface{}, ttl time.Duration) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.storeWithExpiration(key, val, ttl)
+}
+
+// StoreWithDeadline stores a value that etcd removes at deadline, deadline
+// must be in the future.
+func (e *EtcdCache) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ttl, err := ttlUntil(deadline)
+	if err != nil {
+		return err
+	}
+
+	return e.storeWithExpiration(key, val, ttl)
+}
+
+// ReplaceWithExpiration replaces the value of an existing key with a
+// temporary value, ttl must be greater than zero.
+func (e *EtcdCache) ReplaceWithExpiration(key, val interface{}, ttl time.Duration) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.replaceWithExpiration(key, val, ttl)
+}
+
+// Expire attaches a new lease to an existing key, ttl must be greater than
+// zero.
+func (e *EtcdCache) Expire(key interface{}, ttl time.Duration) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.expire(key, ttl)
+}
+
+// TTL returns the remaining ttl of a key, the ttl of etcd leases has a
+// resolution of a second.
+func (e *EtcdCache) TTL(key interface{}) (time.Duration, bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.ttl(key)
+}
+
+// StoreWithSlidingExpiration stores a temporary value whose lease is renewed
+// whenever this instance gets it, ttl must be greater than zero.
+func (e *EtcdCache) StoreWithSlidingExpiration(key, val interface{}, ttl time.Duration) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	err := e.storeWithExpiration(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	e.slidingKeys[e.etcdKey(key)] = struct{}{}
+
+	return nil
+}
+
+// Watch calls onChange whenever the value of key changes in etcd, by any
+// instance, with the new value or with nil once the key is removed.
+//
+// onChange is called from a background routine until the returned function
+// is called.
+func (e *EtcdCache) Watch(key interface{},
+	onChange func(newVal interface{})) (context.CancelFunc, error) {
+	if onChange == nil {
+		return nil, newError(errorTypeNilUpdateFunc, "onChange cannot be nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := e.client.watch(ctx, e.etcdKey(key))
+
+	go func() {
+		for resp := range events {
+			for _, ev := range resp.Events {
+				if ev.Type == clientv3.EventTypeDelete {
+					onChange(nil)
+				} else {
+					onChange(string(ev.Kv.Value))
+				}
+			}
+		}
+	}()
+
+	return cancel, nil
+}
+
+// Close the connection to etcd, the keys are kept.
+func (e *EtcdCache) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.client.close()
+}
+
+// --------------------------------------------------------------------------
+
+// Implements etcdClient using the official etcd client.
+type etcdV3Client struct {
+	client *clientv3.Client
+}
+
+func leaseOptions(lease clientv3.LeaseID) []clientv3.OpOption {
+	if lease == clientv3.NoLease {
+		return nil
+	}
+
+	return []clientv3.OpOption{clientv3.WithLease(lease)}
+}
+
+func (c *etcdV3Client) putIfAbsent(ctx context.Context, key, val string,
+	lease clientv3.LeaseID) (bool, error) {
+	resp, err := c.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, val, leaseOptions(lease)...)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Succeeded, nil
+}
+
+func (c *etcdV3Client) putIfPresent(ctx context.Context, key, val string,
+	lease clientv3.LeaseID) (bool, error) {
+	resp, err := c.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), ">", 0)).
+		Then(clientv3.OpPut(key, val, leaseOptions(lease)...)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Succeeded, nil
+}
+
+func (c *etcdV3Client) put(ctx context.Context, key, val string) error {
+	_, err := c.client.Put(ctx, key, val)
+	return err
+}
+
+func (c *etcdV3Client) attachLease(ctx context.Context, key string,
+	lease clientv3.LeaseID) (bool, error) {
+	resp, err := c.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), ">", 0)).
+		Then(clientv3.OpPut(key, "", clientv3.WithIgnoreValue(), clientv3.WithLease(lease))).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Succeeded, nil
+}
+
+func (c *etcdV3Client) get(ctx context.Context, key string) (*mvccpb.KeyValue, error) {
+	resp, err := c.client.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	return resp.Kvs[0], nil
+}
+
+func (c *etcdV3Client) getPrefix(ctx context.Context, prefix string,
+	keysOnly bool) ([]*mvccpb.KeyValue, error) {
+	opts := []clientv3.OpOption{
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+	}
+	if keysOnly {
+		opts = append(opts, clientv3.WithKeysOnly())
+	}
+
+	resp, err := c.client.Get(ctx, prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Kvs, nil
+}
+
+func (c *etcdV3Client) delete(ctx context.Context, key string) (*mvccpb.KeyValue, error) {
+	resp, err := c.client.Delete(ctx, key, clientv3.WithPrevKV())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.PrevKvs) == 0 {
+		return nil, nil
+	}
+
+	return resp.PrevKvs[0], nil
+}
+
+func (c *etcdV3Client) deletePrefix(ctx context.Context, prefix string) error {
+	_, err := c.client.Delete(ctx, prefix, clientv3.WithPrefix())
+	return err
+}
+
+func (c *etcdV3Client) grant(ctx context.Context, seconds int64) (clientv3.LeaseID, error) {
+	resp, err := c.client.Grant(ctx, seconds)
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+
+	return resp.ID, nil
+}
+
+func (c *etcdV3Client) revoke(ctx context.Context, lease clientv3.LeaseID) error {
+	_, err := c.client.Revoke(ctx, lease)
+	return err
+}
+
+func (c *etcdV3Client) timeToLive(ctx context.Context, lease clientv3.LeaseID) (int64, error) {
+	resp, err := c.client.TimeToLive(ctx, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.TTL, nil
+}
+
+func (c *etcdV3Client) keepAliveOnce(ctx context.Context, lease clientv3.LeaseID) error {
+	_, err := c.client.KeepAliveOnce(ctx, lease)
+	return err
+}
+
+func (c *etcdV3Client) watch(ctx context.Context, key string) clientv3.WatchChan {
+	return c.client.Watch(ctx, key)
+}
+
+func (c *etcdV3Client) close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,479 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// An in-memory stand-in for an etcd cluster, leases never expire by
+// themselves.
+type etcdClientMock struct {
+	kvs map[string]*mvccpb.KeyValue
+
+	// Holds the granted ttl of each lease in seconds.
+	leases map[clientv3.LeaseID]int64
+
+	// Holds the number of renewals of each lease.
+	renewals map[clientv3.LeaseID]int
+
+	watchers map[string]chan clientv3.WatchResponse
+
+	// Returned by every call when set.
+	err error
+
+	mutex sync.Mutex
+}
+
+func newEtcdClientMock() *etcdClientMock {
+	return &etcdClientMock{
+		kvs:      map[string]*mvccpb.KeyValue{},
+		leases:   map[clientv3.LeaseID]int64{},
+		renewals: map[clientv3.LeaseID]int{},
+		watchers: map[string]chan clientv3.WatchResponse{},
+	}
+}
+
+func (mock *etcdClientMock) set(key, val string, lease clientv3.LeaseID) {
+	mock.kvs[key] = &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), Lease: int64(lease)}
+
+	if w, exists := mock.watchers[key]; exists {
+		w <- clientv3.WatchResponse{Events: []*clientv3.Event{
+			{Type: clientv3.EventTypePut, Kv: mock.kvs[key]},
+		}}
+	}
+}
+
+func (mock *etcdClientMock) remove(key string) *mvccpb.KeyValue {
+	kv := mock.kvs[key]
+	delete(mock.kvs, key)
+
+	if w, exists := mock.watchers[key]; exists && kv != nil {
+		w <- clientv3.WatchResponse{Events: []*clientv3.Event{
+			{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(key)}},
+		}}
+	}
+
+	return kv
+}
+
+func (mock *etcdClientMock) putIfAbsent(ctx context.Context, key, val string,
+	lease clientv3.LeaseID) (bool, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return false, mock.err
+	}
+
+	if _, exists := mock.kvs[key]; exists {
+		return false, nil
+	}
+
+	mock.set(key, val, lease)
+
+	return true, nil
+}
+
+func (mock *etcdClientMock) putIfPresent(ctx context.Context, key, val string,
+	lease clientv3.LeaseID) (bool, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return false, mock.err
+	}
+
+	if _, exists := mock.kvs[key]; !exists {
+		return false, nil
+	}
+
+	mock.set(key, val, lease)
+
+	return true, nil
+}
+
+func (mock *etcdClientMock) put(ctx context.Context, key, val string) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return mock.err
+	}
+
+	mock.set(key, val, clientv3.NoLease)
+
+	return nil
+}
+
+func (mock *etcdClientMock) attachLease(ctx context.Context, key string,
+	lease clientv3.LeaseID) (bool, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return false, mock.err
+	}
+
+	kv, exists := mock.kvs[key]
+	if !exists {
+		return false, nil
+	}
+
+	mock.set(key, string(kv.Value), lease)
+
+	return true, nil
+}
+
+func (mock *etcdClientMock) get(ctx context.Context, key string) (*mvccpb.KeyValue, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return nil, mock.err
+	}
+
+	return mock.kvs[key], nil
+}
+
+func (mock *etcdClientMock) getPrefix(ctx context.Context, prefix string,
+	keysOnly bool) ([]*mvccpb.KeyValue, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return nil, mock.err
+	}
+
+	keys := []string{}
+	for key := range mock.kvs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	kvs := []*mvccpb.KeyValue{}
+	for _, key := range keys {
+		kv := *mock.kvs[key]
+		if keysOnly {
+			kv.Value = nil
+		}
+
+		kvs = append(kvs, &kv)
+	}
+
+	return kvs, nil
+}
+
+func (mock *etcdClientMock) delete(ctx context.Context, key string) (*mvccpb.KeyValue, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return nil, mock.err
+	}
+
+	return mock.remove(key), nil
+}
+
+func (mock *etcdClientMock) deletePrefix(ctx context.Context, prefix string) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return mock.err
+	}
+
+	for key := range mock.kvs {
+		if strings.HasPrefix(key, prefix) {
+			mock.remove(key)
+		}
+	}
+
+	return nil
+}
+
+func (mock *etcdClientMock) grant(ctx context.Context, seconds int64) (clientv3.LeaseID, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return clientv3.NoLease, mock.err
+	}
+
+	lease := clientv3.LeaseID(len(mock.leases) + 1)
+	mock.leases[lease] = seconds
+
+	return lease, nil
+}
+
+func (mock *etcdClientMock) revoke(ctx context.Context, lease clientv3.LeaseID) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	for key, kv := range mock.kvs {
+		if kv.Lease == int64(lease) {
+			mock.remove(key)
+		}
+	}
+
+	return mock.err
+}
+
+func (mock *etcdClientMock) timeToLive(ctx context.Context, lease clientv3.LeaseID) (int64, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return 0, mock.err
+	}
+
+	return mock.leases[lease], nil
+}
+
+func (mock *etcdClientMock) keepAliveOnce(ctx context.Context, lease clientv3.LeaseID) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if mock.err != nil {
+		return mock.err
+	}
+
+	mock.renewals[lease]++
+
+	return nil
+}
+
+func (mock *etcdClientMock) watch(ctx context.Context, key string) clientv3.WatchChan {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	w := make(chan clientv3.WatchResponse, 10)
+	mock.watchers[key] = w
+
+	go func() {
+		<-ctx.Done()
+
+		mock.mutex.Lock()
+		defer mock.mutex.Unlock()
+
+		delete(mock.watchers, key)
+		close(w)
+	}()
+
+	return w
+}
+
+func (mock *etcdClientMock) close() error {
+	return mock.err
+}
+
+var _ = Describe("Etcd Cache", func() {
+	var (
+		c                        *EtcdCache
+		mock                     *etcdClientMock
+		key, val, nonExistentKey string = "test-key", "test-val", "non-existent"
+	)
+
+	BeforeEach(func() {
+		mock = newEtcdClientMock()
+		c = newEtcdCache(mock, "cache/", time.Second)
+	})
+
+	Context("Store", func() {
+		It("should store a value under the prefix", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(mock.kvs).To(HaveKey("cache/" + key))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(key, val))).To(BeTrue())
+		})
+
+		It("should wrap errors of the etcd client", func() {
+			mock.err = errors.New("connection refused")
+			Expect(IsEtcdError(c.Store(key, val))).To(BeTrue())
+		})
+	})
+
+	Context("Get", func() {
+		It("should return an error when attempting to get a non-existent value", func() {
+			_, err := c.Get(nonExistentKey)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("GetOrStore", func() {
+		It("should only store the first value", func() {
+			actual, loaded, err := c.GetOrStore(key, val)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeFalse())
+			Expect(actual).To(Equal(val))
+
+			actual, loaded, err = c.GetOrStore(key, "new-val")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeTrue())
+			Expect(actual).To(Equal(val))
+		})
+	})
+
+	Context("GetAndRemove", func() {
+		It("should return the removed value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.GetAndRemove(key)).To(Equal(val))
+			Expect(c.Contains(key)).To(BeFalse())
+			Expect(IsDoesNotExist(c.Remove(key))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a temporary value with a permanent one", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new-val"))
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+
+		It("should return an error when attempting to replace a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Replace(nonExistentKey, val))).To(BeTrue())
+		})
+	})
+
+	Context("NewEtcdCache", func() {
+		It("should return an error for an empty prefix", func() {
+			_, err := NewEtcdCache([]string{"127.0.0.1:2379"}, "", time.Second)
+			Expect(IsInvalidKeyType(err)).To(BeTrue())
+		})
+
+		It("should separate the prefix from the keys", func() {
+			c = newEtcdCache(mock, "app", time.Second)
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			mock.kvs["apple"] = &mvccpb.KeyValue{Key: []byte("apple")}
+
+			Expect(mock.kvs).To(HaveKey("app/" + key))
+			Expect(c.Keys()).To(ConsistOf(key))
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(mock.kvs).To(HaveKey("apple"))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should only remove the keys under the prefix", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2})).ToNot(HaveOccurred())
+			mock.kvs["other/a"] = &mvccpb.KeyValue{Key: []byte("other/a")}
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(BeEmpty())
+			Expect(mock.kvs).To(HaveLen(1))
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return the keys without the prefix", func() {
+			Expect(c.Store("b", val)).ToNot(HaveOccurred())
+			Expect(c.Store("a", val)).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(Equal([]interface{}{"a", "b"}))
+		})
+	})
+
+	Context("ForEach", func() {
+		It("should visit the keys and values under the prefix", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2})).ToNot(HaveOccurred())
+
+			visited := map[interface{}]interface{}{}
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				visited[key] = val
+				return true
+			})).ToNot(HaveOccurred())
+			Expect(visited).To(Equal(map[interface{}]interface{}{"a": "1", "b": "2"}))
+		})
+	})
+
+	Context("StoreWithExpiration", func() {
+		It("should attach a lease rounded up to seconds", func() {
+			Expect(c.StoreWithExpiration(key, val, 1500*time.Millisecond)).ToNot(HaveOccurred())
+
+			ttl, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(ttl).To(Equal(2 * time.Second))
+		})
+
+		It("should revoke the lease when the key is already in use", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.StoreWithExpiration(key, val, time.Minute))).To(BeTrue())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return an error for a non-positive ttl", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithExpiration(key, val, 0))).To(BeTrue())
+		})
+	})
+
+	Context("Expire", func() {
+		It("should attach a lease without changing the value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Expire(key, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+
+			ttl, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(ttl).To(Equal(time.Minute))
+		})
+
+		It("should return an error when attempting to expire a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Expire(nonExistentKey, time.Minute))).To(BeTrue())
+		})
+	})
+
+	Context("StoreWithSlidingExpiration", func() {
+		It("should renew the lease whenever the value is accessed", func() {
+			Expect(c.StoreWithSlidingExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("fixed", val, time.Minute)).ToNot(HaveOccurred())
+
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Get("fixed")).To(Equal(val))
+
+			lease := clientv3.LeaseID(mock.kvs["cache/"+key].Lease)
+			Expect(mock.renewals).To(Equal(map[clientv3.LeaseID]int{lease: 1}))
+		})
+	})
+
+	Context("Watch", func() {
+		It("should report changes until it is canceled", func() {
+			changes := make(chan interface{}, 10)
+			cancel, err := c.Watch(key, func(newVal interface{}) {
+				changes <- newVal
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Eventually(changes, testTimeout).Should(Receive(Equal(val)))
+
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Eventually(changes, testTimeout).Should(Receive(BeNil()))
+
+			cancel()
+			Eventually(func() int {
+				mock.mutex.Lock()
+				defer mock.mutex.Unlock()
+				return len(mock.watchers)
+			}, testTimeout).Should(BeZero())
+		})
+	})
+})
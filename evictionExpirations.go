@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// The eviction cache whose values are made temporary by evictionExpirations.
+type expirableCache interface {
+	store(key, val interface{}) error
+	peek(key interface{}) (interface{}, error)
+
+	// Removes a value and stops its expiration.
+	remove(key interface{}) error
+}
+
+// Keeps track of the temporary values of an eviction cache, it is embedded by
+// lruExpiringCache and lfuExpiringCache.
+type evictionExpirations struct {
+	cache expirableCache
+
+	// The mutex of the cache, the auto removal routines hold it.
+	cacheMutex *sync.Mutex
+
+	// Holds the channels that stop the auto removal routines.
+	removeChannels map[interface{}]*cacheChannel
+
+	// Holds the ttls of values with a sliding expiration.
+	slidingTTLs map[interface{}]time.Duration
+
+	// Holds the times in which temporary values will be removed.
+	deadlines map[interface{}]time.Time
+}
+
+func newEvictionExpirations(cache expirableCache, mutex *sync.Mutex) evictionExpirations {
+	return evictionExpirations{
+		cache:          cache,
+		cacheMutex:     mutex,
+		removeChannels: map[interface{}]*cacheChannel{},
+		slidingTTLs:    map[interface{}]time.Duration{},
+		deadlines:      map[interface{}]time.Time{},
+	}
+}
+
+// Store a temporary value, ttl must be greater than zero.
+func (ee *evictionExpirations) StoreWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	ee.cacheMutex.Lock()
+	defer ee.cacheMutex.Unlock()
+
+	return ee.storeWithExpiration(key, val, ttl)
+}
+
+// Store a temporary value that is removed at deadline, deadline must be in the
+// future.
+func (ee *evictionExpirations) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	ee.cacheMutex.Lock()
+	defer ee.cacheMutex.Unlock()
+
+	ttl, err := ttlUntil(deadline)
+	if err != nil {
+		return err
+	}
+
+	return ee.storeWithExpiration(key, val, ttl)
+}
+
+func (ee *evictionExpirations) storeWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	err := ee.cache.store(key, val)
+	if err != nil {
+		return err
+	}
+
+	ee.createExpirationRoutine(key, ttl)
+
+	return nil
+}
+
+// Store a temporary value, every Get resets its ttl, ttl must be greater
+// than zero.
+func (ee *evictionExpirations) StoreWithSlidingExpiration(key, val interface{},
+	ttl time.Duration) error {
+	ee.cacheMutex.Lock()
+	defer ee.cacheMutex.Unlock()
+
+	err := ee.storeWithExpiration(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	ee.slidingTTLs[key] = ttl
+
+	return nil
+}
+
+// Replace a cached value with a temporary value, ttl must be greater than
+// zero.
+func (ee *evictionExpirations) ReplaceWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	ee.cacheMutex.Lock()
+	defer ee.cacheMutex.Unlock()
+
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	err := ee.cache.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return ee.storeWithExpiration(key, val, ttl)
+}
+
+// Update the expiration of a cached value, ttl must be greater than zero.
+func (ee *evictionExpirations) Expire(key interface{}, ttl time.Duration) error {
+	ee.cacheMutex.Lock()
+	defer ee.cacheMutex.Unlock()
+
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	_, err := ee.cache.peek(key)
+	if err != nil {
+		return err
+	}
+
+	ee.stopExpiration(key)
+	ee.createExpirationRoutine(key, ttl)
+
+	return nil
+}
+
+// Get the remaining ttl of a cached value.
+func (ee *evictionExpirations) TTL(key interface{}) (time.Duration, bool, error) {
+	ee.cacheMutex.Lock()
+	defer ee.cacheMutex.Unlock()
+
+	_, err := ee.cache.peek(key)
+	if err != nil {
+		return -1, false, err
+	}
+
+	deadline, hasTTL := ee.deadlines[key]
+	if !hasTTL {
+		return -1, false, nil
+	}
+
+	return time.Until(deadline), true, nil
+}
+
+func (ee *evictionExpirations) createExpirationRoutine(key interface{}, ttl time.Duration) {
+	c := newCacheChannel()
+	ee.removeChannels[key] = c
+	ee.deadlines[key] = time.Now().Add(ttl)
+
+	expireSignalerRoutine := func(c *cacheChannel) {
+		<-time.After(ttl)
+		c.signal(proceed)
+	}
+
+	expireRoutine := func(key interface{}, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		}
+
+		ee.cacheMutex.Lock()
+		defer ee.cacheMutex.Unlock()
+
+		// The expiration was reset while waiting for the mutex.
+		if ee.removeChannels[key] != c {
+			return
+		}
+
+		// Removing the value from the eviction cache removes it from its
+		// eviction order as well.
+		ee.cache.remove(key)
+	}
+
+	go expireSignalerRoutine(c)
+	go expireRoutine(key, c)
+}
+
+func (ee *evictionExpirations) resetSlidingExpiration(key interface{}) {
+	ttl, isSliding := ee.slidingTTLs[key]
+	if !isSliding {
+		return
+	}
+
+	ee.stopExpiration(key)
+	ee.slidingTTLs[key] = ttl
+	ee.createExpirationRoutine(key, ttl)
+}
+
+// Stop the auto removal routine of a key, if it has one.
+func (ee *evictionExpirations) stopExpiration(key interface{}) {
+	c, exists := ee.removeChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+	}
+
+	delete(ee.removeChannels, key)
+	delete(ee.slidingTTLs, key)
+	delete(ee.deadlines, key)
+}
+
+// Stop the auto removal routines of all the keys.
+func (ee *evictionExpirations) stopExpirations() {
+	for key := range ee.removeChannels {
+		ee.stopExpiration(key)
+	}
+}
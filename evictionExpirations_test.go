@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The eviction caches whose temporary values are kept by evictionExpirations.
+type evictionExpiringCache interface {
+	ExpiringCache
+
+	Peek(key interface{}) (interface{}, error)
+	Count() int
+}
+
+var _ = Describe("Eviction Expirations", func() {
+	const (
+		ttl      = 100 * time.Millisecond
+		capacity = 3
+	)
+
+	var keys, values []string
+
+	for i := 0; i < capacity; i++ {
+		keys = append(keys, fmt.Sprintf("test-key-%v", i))
+		values = append(values, fmt.Sprintf("test-value-%v", i))
+	}
+
+	constructors := map[string]func() (evictionExpiringCache, error){
+		"LRU": func() (evictionExpiringCache, error) {
+			return NewLruWithExpiration(capacity)
+		},
+		"LFU": func() (evictionExpiringCache, error) {
+			return NewLfuWithExpiration(capacity)
+		},
+	}
+
+	for name, newCache := range constructors {
+		newCache := newCache
+
+		Describe(name, func() {
+			var c evictionExpiringCache
+
+			BeforeEach(func() {
+				var err error
+				c, err = newCache()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			Context("StoreWithExpiration", func() {
+				It("should return an error if ttl is non-positive", func() {
+					Expect(IsNonPositivePeriod(c.StoreWithExpiration(keys[0], values[0], 0))).To(BeTrue())
+				})
+
+				It("should not remove a value that was evicted and stored again", func() {
+					Expect(c.StoreWithExpiration(keys[0], values[0], ttl)).ToNot(HaveOccurred())
+					for i := 1; i <= capacity; i++ {
+						Expect(c.Store(fmt.Sprintf("extra-key-%d", i), i)).ToNot(HaveOccurred())
+					}
+					Expect(c.Contains(keys[0])).To(BeFalse(), "value was not evicted")
+
+					Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+					Consistently(func() bool {
+						exists, _ := c.Contains(keys[0])
+						return exists
+					}, 3*ttl).Should(BeTrue())
+				})
+			})
+
+			Context("ReplaceWithExpiration", func() {
+				It("should replace a permanent value with a temporary one", func() {
+					Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+					Expect(c.ReplaceWithExpiration(keys[0], values[1], ttl)).ToNot(HaveOccurred())
+					Expect(c.Get(keys[0])).To(Equal(values[1]))
+
+					Eventually(func() int {
+						return c.Count()
+					}, testTimeout).Should(Equal(0))
+				})
+
+				It("should fail to replace a non-existent value", func() {
+					Expect(IsDoesNotExist(c.ReplaceWithExpiration(keys[0], values[0], ttl))).To(BeTrue())
+				})
+			})
+
+			Context("Expire", func() {
+				It("should make a permanent value temporary", func() {
+					Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+					Expect(c.Expire(keys[0], ttl)).ToNot(HaveOccurred())
+
+					Eventually(func() int {
+						return c.Count()
+					}, testTimeout).Should(Equal(0))
+				})
+
+				It("should return an error when attempting to expire a non-existent key", func() {
+					Expect(IsDoesNotExist(c.Expire(keys[0], ttl))).To(BeTrue())
+				})
+			})
+
+			Context("Replace", func() {
+				It("should replace a temporary value with a permanent one", func() {
+					Expect(c.StoreWithExpiration(keys[0], values[0], ttl)).ToNot(HaveOccurred())
+					Expect(c.Replace(keys[0], values[1])).ToNot(HaveOccurred())
+
+					_, hasTTL, err := c.TTL(keys[0])
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hasTTL).To(BeFalse())
+					Consistently(func() bool {
+						exists, _ := c.Contains(keys[0])
+						return exists
+					}, 3*ttl).Should(BeTrue())
+				})
+			})
+
+			Context("StoreOrReplace", func() {
+				It("should replace a temporary value with a permanent one", func() {
+					Expect(c.StoreWithExpiration(keys[0], values[0], ttl)).ToNot(HaveOccurred())
+					Expect(c.StoreOrReplace(keys[0], values[1])).ToNot(HaveOccurred())
+
+					_, hasTTL, err := c.TTL(keys[0])
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hasTTL).To(BeFalse())
+					Expect(c.Peek(keys[0])).To(Equal(values[1]))
+				})
+			})
+
+			Context("StoreWithSlidingExpiration", func() {
+				It("should reset the ttl of a value when it is accessed", func() {
+					Expect(c.StoreWithSlidingExpiration(keys[0], values[0], time.Minute)).ToNot(HaveOccurred())
+					time.Sleep(ttl)
+
+					Expect(c.Get(keys[0])).To(Equal(values[0]))
+					remaining, hasTTL, err := c.TTL(keys[0])
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hasTTL).To(BeTrue())
+					Expect(remaining).To(BeNumerically(">", time.Minute-ttl))
+				})
+			})
+		})
+	}
+})
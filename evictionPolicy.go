@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// EvictionPolicy decides which key is evicted from a cache that has to free
+// space, such as the cache returned by NewSizeBoundedMapCache.
+type EvictionPolicy interface {
+	// Add tracks a key that was stored.
+	Add(key interface{})
+
+	// Touch records an access to a tracked key.
+	Touch(key interface{})
+
+	// Remove stops tracking a key.
+	Remove(key interface{})
+
+	// Victim returns the key that should be evicted next, ok is false if no
+	// key is tracked.
+	Victim() (key interface{}, ok bool)
+
+	// Clear stops tracking all keys.
+	Clear()
+}
+
+type lruPolicy struct {
+	// A doubly linked list that represents the order of the keys,
+	// from the most recently used to the least recently used.
+	list *list.List
+
+	// Holds the node of each key in the linked list.
+	nodes map[interface{}]*list.Element
+}
+
+var _ EvictionPolicy = (*lruPolicy)(nil)
+
+// NewLruPolicy creates an EvictionPolicy that evicts the least recently used
+// key, using the same ordering as lruCache.
+func NewLruPolicy() *lruPolicy {
+	return &lruPolicy{
+		list:  list.New(),
+		nodes: map[interface{}]*list.Element{},
+	}
+}
+
+func (p *lruPolicy) Add(key interface{}) {
+	if node, exists := p.nodes[key]; exists {
+		p.list.MoveToFront(node)
+		return
+	}
+
+	p.nodes[key] = p.list.PushFront(key)
+}
+
+func (p *lruPolicy) Touch(key interface{}) {
+	if node, exists := p.nodes[key]; exists {
+		p.list.MoveToFront(node)
+	}
+}
+
+func (p *lruPolicy) Remove(key interface{}) {
+	if node, exists := p.nodes[key]; exists {
+		p.list.Remove(node)
+		delete(p.nodes, key)
+	}
+}
+
+func (p *lruPolicy) Victim() (interface{}, bool) {
+	node := p.list.Back()
+	if node == nil {
+		return nil, false
+	}
+
+	return node.Value, true
+}
+
+func (p *lruPolicy) Clear() {
+	p.list.Init()
+	p.nodes = map[interface{}]*list.Element{}
+}
+
+type lfuPolicy struct {
+	// A min heap of the keys, where the least frequently used key is on top.
+	heap lfuHeap
+
+	// Holds the heap item of each key.
+	items map[interface{}]*lfuHeapItem
+
+	// The insertion sequence of the next added key.
+	nextInsertionSeq int64
+}
+
+var _ EvictionPolicy = (*lfuPolicy)(nil)
+
+// NewLfuPolicy creates an EvictionPolicy that evicts the least frequently
+// used key, using the same ordering as lfuCache.
+func NewLfuPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		heap:  lfuHeap{},
+		items: map[interface{}]*lfuHeapItem{},
+	}
+}
+
+func (p *lfuPolicy) Add(key interface{}) {
+	if _, exists := p.items[key]; exists {
+		p.Touch(key)
+		return
+	}
+
+	item := &lfuHeapItem{value: key, insertionSeq: p.nextInsertionSeq}
+	p.nextInsertionSeq++
+	heap.Push(&p.heap, item)
+	p.items[key] = item
+}
+
+func (p *lfuPolicy) Touch(key interface{}) {
+	if item, exists := p.items[key]; exists {
+		item.frequency++
+		heap.Fix(&p.heap, item.index)
+	}
+}
+
+func (p *lfuPolicy) Remove(key interface{}) {
+	if item, exists := p.items[key]; exists {
+		heap.Remove(&p.heap, item.index)
+		delete(p.items, key)
+	}
+}
+
+func (p *lfuPolicy) Victim() (interface{}, bool) {
+	if p.heap.Len() == 0 {
+		return nil, false
+	}
+
+	return p.heap[0].value, true
+}
+
+func (p *lfuPolicy) Clear() {
+	p.heap = lfuHeap{}
+	p.items = map[interface{}]*lfuHeapItem{}
+}
@@ -0,0 +1,297 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+type fifoItem struct {
+	// The cached data.
+	value interface{}
+
+	// A reference to the corresponding element in the linked list.
+	node *list.Element
+}
+
+type fifoCache struct {
+	// The maximal amount of cached items.
+	capacity int
+
+	// A cache that holds tha data.
+	storage *mapCache
+
+	// A doubly linked list that represents the order of the items,
+	// from the newest to the oldest.
+	list *list.List
+
+	// Called with the key and value of an evicted item.
+	onEvict func(key, val interface{})
+
+	mutex sync.Mutex
+}
+
+var _ PeekableCache = (*fifoCache)(nil)
+
+// NewFifoCache creates a new fifoCache instance using mapCache, when the cache
+// is full the oldest stored value is evicted. capacity must be greater than
+// zero.
+func NewFifoCache(capacity int, opts ...EvictionOption) (*fifoCache, error) {
+	if capacity < 1 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
+	o := newEvictionOptions(opts)
+
+	return &fifoCache{
+		capacity: capacity,
+		storage:  NewMapCache(),
+		list:     list.New(),
+		onEvict:  o.onEvict,
+	}, nil
+}
+
+// Store caches a new value.
+func (fifo *fifoCache) Store(key, val interface{}) error {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return fifo.store(key, val)
+}
+
+func (fifo *fifoCache) store(key, val interface{}) error {
+	exists, err := fifo.storage.Contains(key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	if fifo.list.Len() >= fifo.capacity {
+		err := fifo.evict()
+		if err != nil {
+			return err
+		}
+	}
+
+	node := fifo.list.PushFront(key)
+
+	err = fifo.storage.Store(key, &fifoItem{val, node})
+	if err != nil {
+		fifo.list.Remove(node)
+		return err
+	}
+
+	return nil
+}
+
+func (fifo *fifoCache) evict() error {
+	node := fifo.list.Back()
+	if node == nil {
+		return nil
+	}
+
+	item, err := fifo.storage.GetAndRemove(node.Value)
+	if err != nil {
+		return err
+	}
+
+	fifo.list.Remove(node)
+
+	if fifo.onEvict != nil {
+		fifo.onEvict(node.Value, item.(*fifoItem).value)
+	}
+
+	return nil
+}
+
+// Get a cached value, getting a value does not affect the eviction order.
+func (fifo *fifoCache) Get(key interface{}) (interface{}, error) {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return fifo.get(key)
+}
+
+func (fifo *fifoCache) get(key interface{}) (interface{}, error) {
+	item, err := fifo.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*fifoItem).value, nil
+}
+
+// Peek gets a cached value, it is the same as Get since accessing a value
+// does not affect the eviction order.
+func (fifo *fifoCache) Peek(key interface{}) (interface{}, error) {
+	return fifo.Get(key)
+}
+
+// Check whether a key is cached.
+func (fifo *fifoCache) Contains(key interface{}) (bool, error) {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return fifo.storage.Contains(key)
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (fifo *fifoCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	actual, err := fifo.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = fifo.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// GetOldestKey returns the key that will be evicted next, or nil if the cache
+// is empty.
+func (fifo *fifoCache) GetOldestKey() interface{} {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	node := fifo.list.Back()
+	if node == nil {
+		return nil
+	}
+
+	return node.Value
+}
+
+// Remove a cached value.
+func (fifo *fifoCache) Remove(key interface{}) error {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	_, err := fifo.getAndRemove(key)
+
+	return err
+}
+
+// Get a cached value and remove it.
+func (fifo *fifoCache) GetAndRemove(key interface{}) (interface{}, error) {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return fifo.getAndRemove(key)
+}
+
+func (fifo *fifoCache) getAndRemove(key interface{}) (interface{}, error) {
+	item, err := fifo.storage.GetAndRemove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fifo.list.Remove(item.(*fifoItem).node)
+
+	return item.(*fifoItem).value, nil
+}
+
+// Replace a cached value, the value keeps its place in the eviction order.
+func (fifo *fifoCache) Replace(key, val interface{}) error {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return fifo.replace(key, val)
+}
+
+func (fifo *fifoCache) replace(key, val interface{}) error {
+	item, err := fifo.storage.Get(key)
+	if err != nil {
+		return err
+	}
+
+	item.(*fifoItem).value = val
+
+	return nil
+}
+
+// Cache a value, replacing the current value of the key if it exists, in
+// which case it keeps its place in the eviction order.
+func (fifo *fifoCache) StoreOrReplace(key, val interface{}) error {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	err := fifo.replace(key, val)
+	if IsDoesNotExist(err) {
+		return fifo.store(key, val)
+	}
+
+	return err
+}
+
+// Store several values.
+func (fifo *fifoCache) StoreMany(items map[interface{}]interface{}) error {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return storeMany(items, fifo.store)
+}
+
+// Get several cached values.
+func (fifo *fifoCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return getMany(keys, fifo.get)
+}
+
+// Clear all values.
+func (fifo *fifoCache) Clear() error {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	err := fifo.storage.Clear()
+	if err != nil {
+		return err
+	}
+
+	fifo.list.Init()
+
+	return nil
+}
+
+// Get all keys.
+func (fifo *fifoCache) Keys() ([]interface{}, error) {
+	return fifo.storage.Keys()
+}
+
+// Calls fn for each cached key and value until fn returns false.
+func (fifo *fifoCache) ForEach(fn func(key, val interface{}) bool) error {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return fifo.storage.ForEach(func(key, item interface{}) bool {
+		return fn(key, item.(*fifoItem).value)
+	})
+}
+
+// Get the keys for which predicate returns true.
+func (fifo *fifoCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	return fifo.storage.FilterKeys(predicate)
+}
+
+// Count returns the number of cached items.
+func (fifo *fifoCache) Count() int {
+	fifo.mutex.Lock()
+	defer fifo.mutex.Unlock()
+
+	return fifo.list.Len()
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const FIFOCacheSize = 3
+
+var _ = Describe("FIFO Cache", func() {
+	var (
+		c            *fifoCache
+		keys, values []string
+	)
+
+	for i := 0; i < FIFOCacheSize; i++ {
+		keys = append(keys, fmt.Sprintf("test-key-%v", i))
+		values = append(values, fmt.Sprintf("test-value-%v", i))
+	}
+
+	BeforeEach(func() {
+		var err error
+		c, err = NewFifoCache(FIFOCacheSize)
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < FIFOCacheSize; i++ {
+			Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+		}
+	})
+
+	Context("Store", func() {
+		It("should evict the oldest value regardless of access", func() {
+			_, err := c.Get(keys[0])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.Store("extra-key", "extra-value")).ToNot(HaveOccurred())
+			Expect(c.Contains(keys[0])).To(BeFalse(), "oldest value was not evicted")
+			Expect(c.Contains(keys[1])).To(BeTrue())
+			Expect(c.Count()).To(Equal(FIFOCacheSize))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(IsAlreadyExists(c.Store(keys[0], values[0]))).To(BeTrue())
+			Expect(c.Contains(keys[0])).To(BeTrue(), "a value was evicted by a failed store")
+		})
+
+		It("should call the eviction callback with the oldest value", func() {
+			var evictedKey interface{}
+			var err error
+			c, err = NewFifoCache(1, WithEvictionCallback(func(key, val interface{}) {
+				evictedKey = key
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(c.Store(keys[1], values[1])).ToNot(HaveOccurred())
+			Expect(evictedKey).To(Equal(keys[0]))
+		})
+	})
+
+	Context("NewFifoCache", func() {
+		It("should return an error for a non-positive capacity", func() {
+			_, err := NewFifoCache(0)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+			_, err = NewFifoCache(-1)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+	})
+
+	Context("GetOldestKey", func() {
+		It("should return the first stored key", func() {
+			Expect(c.GetOldestKey()).To(Equal(keys[0]))
+			Expect(c.Remove(keys[0])).ToNot(HaveOccurred())
+			Expect(c.GetOldestKey()).To(Equal(keys[1]))
+		})
+
+		It("should return nil when the cache is empty", func() {
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.GetOldestKey()).To(BeNil())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a value without changing the eviction order", func() {
+			Expect(c.Replace(keys[0], "new-value")).ToNot(HaveOccurred())
+			Expect(c.Get(keys[0])).To(Equal("new-value"))
+			Expect(c.GetOldestKey()).To(Equal(keys[0]))
+		})
+
+		It("should fail to replace a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Replace("non-existent-key", values[0]))).To(BeTrue())
+		})
+	})
+
+	Context("GetAndRemove", func() {
+		It("should return a value and remove it", func() {
+			Expect(c.GetAndRemove(keys[1])).To(Equal(values[1]))
+			Expect(c.Contains(keys[1])).To(BeFalse())
+			Expect(c.Count()).To(Equal(FIFOCacheSize - 1))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove all values", func() {
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Count()).To(Equal(0))
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cache
+
+import "os"
+
+// Advisory locks are not supported, so the lock is never taken.
+func lockFile(file *os.File, exclusive bool) error {
+	return nil
+}
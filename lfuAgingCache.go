@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/heap"
+	"time"
+)
+
+const (
+	errorTypeInvalidDecayFactor errorType = "InvalidDecayFactor"
+)
+
+func IsInvalidDecayFactor(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeInvalidDecayFactor
+}
+
+type lfuAgingCache struct {
+	*lfuCache
+
+	// The frequencies are multiplied by it on every decay.
+	decayFactor float64
+
+	// Stops the decay routine.
+	stop chan struct{}
+}
+
+var _ PeekableCache = (*lfuAgingCache)(nil)
+var _ UpdatingCache = (*lfuAgingCache)(nil)
+
+// NewLfuWithAging creates a new lfuCache instance using mapCache, whose
+// frequencies are multiplied by decayFactor every decayInterval, so keys that
+// were hot in the past do not keep recently hot keys from being cached.
+//
+// capacity must be greater than zero, decayFactor must be in [0, 1) and
+// decayInterval must be positive. Stop the decay with Stop.
+func NewLfuWithAging(capacity uint, decayFactor float64, decayInterval time.Duration,
+	opts ...EvictionOption) (*lfuAgingCache, error) {
+	if decayFactor < 0 || decayFactor >= 1 {
+		return nil, newError(errorTypeInvalidDecayFactor, "decayFactor must be in [0, 1)")
+	}
+
+	if decayInterval <= 0 {
+		return nil, newError(errorTypeNonPositivePeriod, "decayInterval must be greater than zero")
+	}
+
+	lfu, err := NewLfu(capacity, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	lfac := &lfuAgingCache{
+		lfuCache:    lfu,
+		decayFactor: decayFactor,
+		stop:        make(chan struct{}),
+	}
+
+	go lfac.decayRoutine(decayInterval)
+
+	return lfac, nil
+}
+
+// Decays the frequencies every interval until the cache is stopped.
+func (lfac *lfuAgingCache) decayRoutine(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			lfac.decay()
+		case <-lfac.stop:
+			return
+		}
+	}
+}
+
+// Multiplies all frequencies by the decay factor.
+func (lfac *lfuAgingCache) decay() {
+	lfac.mutex.Lock()
+	defer lfac.mutex.Unlock()
+
+	for _, item := range lfac.heap {
+		item.frequency = int(float64(item.frequency) * lfac.decayFactor)
+	}
+
+	heap.Init(&lfac.heap)
+}
+
+// Stop the decay of the frequencies, the cache keeps working without it.
+func (lfac *lfuAgingCache) Stop() error {
+	lfac.mutex.Lock()
+	defer lfac.mutex.Unlock()
+
+	select {
+	case <-lfac.stop:
+	default:
+		close(lfac.stop)
+	}
+
+	return nil
+}
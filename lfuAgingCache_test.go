@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LFU Aging Cache", func() {
+	var c *lfuAgingCache
+
+	BeforeEach(func() {
+		var err error
+		c, err = NewLfuWithAging(3, 0.5, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(c.Stop()).ToNot(HaveOccurred())
+	})
+
+	// Gets key n times.
+	access := func(key interface{}, n int) {
+		for i := 0; i < n; i++ {
+			_, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	Context("Decay", func() {
+		It("should let a recently hot key win over a historically hot key", func() {
+			Expect(c.Store("historic", 1)).ToNot(HaveOccurred())
+			Expect(c.Store("recent", 2)).ToNot(HaveOccurred())
+			access("historic", 8)
+
+			c.decay()
+			c.decay()
+			access("recent", 3)
+
+			// Without the decay, historic would have 8 accesses against 3.
+			Expect(c.GetLeastFrequentlyUsedKey()).To(Equal("historic"))
+		})
+
+		It("should decay the frequencies every interval", func() {
+			Expect(c.Stop()).ToNot(HaveOccurred())
+
+			var err error
+			c, err = NewLfuWithAging(3, 0, 10*time.Millisecond)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.Store("historic", 1)).ToNot(HaveOccurred())
+			access("historic", 5)
+
+			Eventually(func() int {
+				c.mutex.Lock()
+				defer c.mutex.Unlock()
+
+				return c.heap[0].frequency
+			}).Should(BeZero())
+		})
+	})
+
+	Context("NewLfuWithAging", func() {
+		It("should return an error for an invalid decay factor", func() {
+			_, err := NewLfuWithAging(3, 1.5, time.Hour)
+			Expect(IsInvalidDecayFactor(err)).To(BeTrue())
+		})
+
+		It("should return an error for a non positive decay interval", func() {
+			_, err := NewLfuWithAging(3, 0.5, 0)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+	})
+})
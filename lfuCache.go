@@ -2,7 +2,10 @@ package cache
 
 import (
 	"container/heap"
+	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 )
 
 type lfuHeapItem struct {
@@ -13,6 +16,10 @@ type lfuHeapItem struct {
 	// The amount of time that a certain key has been accessed.
 	frequency int
 
+	// The order in which the key was stored, it breaks ties between keys
+	// with the same frequency.
+	insertionSeq int64
+
 	// The index of the item in the heap.
 	// It is needed by update and is maintained by the
 	// heap.Interface methods.
@@ -28,14 +35,19 @@ func (h lfuHeap) Len() int {
 	return len(h)
 }
 
+// Among keys with the same frequency, the oldest key is removed first.
 func (h lfuHeap) Less(i, j int) bool {
-	return h[i].frequency < h[j].frequency
+	if h[i].frequency != h[j].frequency {
+		return h[i].frequency < h[j].frequency
+	}
+
+	return h[i].insertionSeq < h[j].insertionSeq
 }
 
 func (h lfuHeap) Swap(i, j int) {
 	h[i], h[j] = h[j], h[i]
-	h[i].index = j
-	h[j].index = i
+	h[i].index = i
+	h[j].index = j
 }
 
 func (h *lfuHeap) Push(x interface{}) {
@@ -71,22 +83,48 @@ type lfuCache struct {
 	// frequency is the higher priority to remove from the heap.
 	heap lfuHeap
 
+	// The insertion sequence of the next stored key.
+	nextInsertionSeq int64
+
+	// Called with the key and value of an evicted item.
+	onEvict func(key, val interface{})
+
+	// Holds the channels that stop the auto update routines.
+	updateChannels map[interface{}]*cacheChannel
+
+	// Holds the update functions of the auto update routines.
+	updateFuncs map[interface{}]func(currValue interface{}) (interface{}, error)
+
+	// Called with the errors of the auto update routines.
+	onUpdateError func(key interface{}, err error)
+
+	// Logs unexpected errors of background routines.
+	logger *slog.Logger
+
 	mutex sync.Mutex
 }
 
-var _ Cache = (*lfuCache)(nil)
+var _ PeekableCache = (*lfuCache)(nil)
+var _ UpdatingCache = (*lfuCache)(nil)
 
-// NewLfu creates a new lfuCache instance using mapCache.
-func NewLfu(capacity int) *lfuCache {
-	return &lfuCache{
-		capacity: capacity,
-		storage:  NewMapCache(),
-		heap:     lfuHeap{},
+// NewLfu creates a new lfuCache instance using mapCache, capacity must be
+// greater than zero.
+func NewLfu(capacity uint, opts ...EvictionOption) (*lfuCache, error) {
+	if capacity == 0 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
 	}
+
+	return newLfu(int(capacity), NewMapCache(), opts), nil
 }
 
-// NewLfuWithCustomCache creates a new lfuCache with custom cache.
-func NewLfuWithCustomCache(capacity int, cache Cache) (*lfuCache, error) {
+// NewLfuWithCustomCache creates a new lfuCache with custom cache, capacity
+// must be greater than zero.
+func NewLfuWithCustomCache(capacity uint, cache Cache,
+	opts ...EvictionOption) (*lfuCache, error) {
+	if capacity == 0 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
 	keys, err := cache.Keys()
 	if err != nil {
 		return nil, err
@@ -96,11 +134,22 @@ func NewLfuWithCustomCache(capacity int, cache Cache) (*lfuCache, error) {
 		return nil, newError(errorTypeCacheNotEmpty, "supplied cache must be empty")
 	}
 
+	return newLfu(int(capacity), cache, opts), nil
+}
+
+func newLfu(capacity int, storage Cache, opts []EvictionOption) *lfuCache {
+	o := newEvictionOptions(opts)
+
 	return &lfuCache{
-		capacity: capacity,
-		storage:  cache,
-		heap:     lfuHeap{},
-	}, nil
+		capacity:       capacity,
+		storage:        storage,
+		heap:           lfuHeap{},
+		onEvict:        o.onEvict,
+		updateChannels: map[interface{}]*cacheChannel{},
+		updateFuncs:    map[interface{}]func(currValue interface{}) (interface{}, error){},
+		onUpdateError:  o.onUpdateError,
+		logger:         o.logger,
+	}
 }
 
 // Store caches a new value.
@@ -115,8 +164,9 @@ func (lfu *lfuCache) Store(key, val interface{}) error {
 func (lfu *lfuCache) store(key, val interface{}) error {
 	// Create a new lfu heap item.
 	heapItem := &lfuHeapItem{
-		value:     key,
-		frequency: 0,
+		value:        key,
+		frequency:    0,
+		insertionSeq: lfu.nextInsertionSeq,
 	}
 
 	// Create a new lfu item.
@@ -130,11 +180,11 @@ func (lfu *lfuCache) store(key, val interface{}) error {
 
 	// Add the new key to the heap.
 	heap.Push(&lfu.heap, heapItem)
+	lfu.nextInsertionSeq++
 
 	// If the inner cache is full, remove the least frequently used.
 	if lfu.heap.Len() > lfu.capacity {
-		heapItem := heap.Pop(&lfu.heap).(*lfuHeapItem)
-		err := lfu.storage.Remove(heapItem.value)
+		err := lfu.evict()
 		if err != nil {
 			return err
 		}
@@ -143,6 +193,23 @@ func (lfu *lfuCache) store(key, val interface{}) error {
 	return nil
 }
 
+func (lfu *lfuCache) evict() error {
+	heapItem := heap.Pop(&lfu.heap).(*lfuHeapItem)
+
+	item, err := lfu.storage.Get(heapItem.value)
+	if err != nil {
+		return err
+	}
+
+	if lfu.onEvict != nil {
+		lfu.onEvict(heapItem.value, item.(lfuItem).value)
+	}
+
+	lfu.stopUpdate(heapItem.value)
+
+	return lfu.storage.Remove(heapItem.value)
+}
+
 // Get a cached value.
 func (lfu *lfuCache) Get(key interface{}) (interface{}, error) {
 	lfu.mutex.Lock()
@@ -167,6 +234,57 @@ func (lfu *lfuCache) get(key interface{}) (interface{}, error) {
 	return lfuItem.value, nil
 }
 
+// Check whether a key is cached without increasing its frequency.
+func (lfu *lfuCache) Contains(key interface{}) (bool, error) {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.storage.Contains(key)
+}
+
+// Peek gets a cached value without increasing its frequency.
+func (lfu *lfuCache) Peek(key interface{}) (interface{}, error) {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.peek(key)
+}
+
+func (lfu *lfuCache) peek(key interface{}) (interface{}, error) {
+	item, err := lfu.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(lfuItem).value, nil
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (lfu *lfuCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.getOrStore(key, val)
+}
+
+func (lfu *lfuCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	actual, err := lfu.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = lfu.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
 // GetLeastFrequentlyUsedKey returns the next key that will popped from the heap
 // on the next store.
 func (lfu *lfuCache) GetLeastFrequentlyUsedKey() interface{} {
@@ -204,9 +322,33 @@ func (lfu *lfuCache) remove(key interface{}) error {
 		}
 	}
 
+	lfu.stopUpdate(key)
+
 	return nil
 }
 
+// Get a cached value and remove it.
+func (lfu *lfuCache) GetAndRemove(key interface{}) (interface{}, error) {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.getAndRemove(key)
+}
+
+func (lfu *lfuCache) getAndRemove(key interface{}) (interface{}, error) {
+	val, err := lfu.peek(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lfu.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
 func (lfu *lfuCache) Replace(key, value interface{}) error {
 	lfu.mutex.Lock()
 	defer lfu.mutex.Unlock()
@@ -228,6 +370,219 @@ func (lfu *lfuCache) replace(key, value interface{}) error {
 	return nil
 }
 
+// StoreOrReplace caches a value, replacing the current value of the key if it
+// exists.
+func (lfu *lfuCache) StoreOrReplace(key, value interface{}) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	err := lfu.replace(key, value)
+	if IsDoesNotExist(err) {
+		return lfu.store(key, value)
+	}
+
+	return err
+}
+
+// Store a continuously updating value, updates do not change the frequency of
+// the value, period must be greater than zero.
+func (lfu *lfuCache) StoreWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.storeWithUpdate(key, initialValue, updateFunc, period)
+}
+
+func (lfu *lfuCache) storeWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	err = lfu.store(key, initialValue)
+	if err != nil {
+		return err
+	}
+
+	lfu.createUpdateRoutine(key, updateFunc, period)
+
+	return nil
+}
+
+// Replace a cached value with a continuously updating value.
+func (lfu *lfuCache) ReplaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.replaceWithUpdate(key, initialValue, updateFunc, period)
+}
+
+func (lfu *lfuCache) replaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	err = lfu.replace(key, initialValue)
+	if err != nil {
+		return err
+	}
+
+	lfu.createUpdateRoutine(key, updateFunc, period)
+
+	return nil
+}
+
+func (lfu *lfuCache) createUpdateRoutine(key interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) {
+	c := newCacheChannel()
+	lfu.updateChannels[key] = c
+	lfu.updateFuncs[key] = updateFunc
+
+	updateSignalerRoutine := func(c *cacheChannel) {
+		<-time.After(period)
+		c.signal(proceed)
+	}
+
+	updateRoutine := func(key interface{}, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		} else {
+			lfu.mutex.Lock()
+			defer lfu.mutex.Unlock()
+
+			// The update was canceled while waiting for the mutex.
+			if lfu.updateChannels[key] != c {
+				return
+			}
+
+			item, err := lfu.storage.Get(key)
+			if err != nil {
+				lfu.unexpectedUpdateError(key, err)
+				return
+			}
+
+			// Keep the current value until the next update if updateFunc
+			// fails.
+			currItem := item.(lfuItem)
+			newVal, err := updateFunc(currItem.value)
+			if err != nil {
+				if lfu.onUpdateError != nil {
+					lfu.onUpdateError(key, err)
+				}
+			} else {
+				currItem.value = newVal
+
+				err = lfu.storage.Replace(key, currItem)
+				if err != nil {
+					lfu.unexpectedUpdateError(key, err)
+					return
+				}
+			}
+
+			lfu.createUpdateRoutine(key, updateFunc, period)
+		}
+	}
+
+	go updateSignalerRoutine(c)
+	go updateRoutine(key, c)
+}
+
+// Report an unexpected error of an auto update routine, if there is no update
+// error handler the error is logged and the value is removed.
+func (lfu *lfuCache) unexpectedUpdateError(key interface{}, err error) {
+	wrappedErr := newWrapperError(errorTypeUnexpectedError,
+		"an unexpected error occurred a background routine", err)
+	if lfu.onUpdateError != nil {
+		lfu.onUpdateError(key, wrappedErr)
+		return
+	}
+
+	lfu.logger.Error("an unexpected error occurred a background routine",
+		"key", key, "error", err, "operation", "update")
+
+	// The value may have already been removed from the storage.
+	lfu.remove(key)
+}
+
+// Stop updating a cached value, the value keeps its current value.
+func (lfu *lfuCache) CancelUpdate(key interface{}) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	_, err := lfu.peek(key)
+	if err != nil {
+		return err
+	}
+
+	lfu.stopUpdate(key)
+
+	return nil
+}
+
+// Change the period in which a value is updated, the next update happens
+// newPeriod after the call.
+func (lfu *lfuCache) SetUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	if newPeriod <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	_, err := lfu.peek(key)
+	if err != nil {
+		return err
+	}
+
+	updateFunc, isUpdating := lfu.updateFuncs[key]
+	if !isUpdating {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v is not being updated", key))
+	}
+
+	lfu.stopUpdate(key)
+	lfu.createUpdateRoutine(key, updateFunc, newPeriod)
+
+	return nil
+}
+
+func (lfu *lfuCache) stopUpdate(key interface{}) {
+	c, exists := lfu.updateChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(lfu.updateChannels, key)
+		delete(lfu.updateFuncs, key)
+	}
+}
+
+// Store several values.
+func (lfu *lfuCache) StoreMany(items map[interface{}]interface{}) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return storeMany(items, lfu.store)
+}
+
+// Get several cached values.
+func (lfu *lfuCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return getMany(keys, lfu.get)
+}
+
 func (lfu *lfuCache) Clear() error {
 	lfu.mutex.Lock()
 	defer lfu.mutex.Unlock()
@@ -244,6 +599,10 @@ func (lfu *lfuCache) clear() error {
 	// Clear the heap.
 	lfu.heap = nil
 
+	for key := range lfu.updateChannels {
+		lfu.stopUpdate(key)
+	}
+
 	return nil
 }
 
@@ -251,6 +610,40 @@ func (lfu *lfuCache) Keys() ([]interface{}, error) {
 	return lfu.storage.Keys()
 }
 
+// Calls fn for each cached key and value until fn returns false, without
+// changing their frequencies.
+func (lfu *lfuCache) ForEach(fn func(key, val interface{}) bool) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.storage.ForEach(func(key, item interface{}) bool {
+		return fn(key, item.(lfuItem).value)
+	})
+}
+
+// Get the keys for which predicate returns true.
+func (lfu *lfuCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	return lfu.storage.FilterKeys(predicate)
+}
+
+// Get a snapshot of all keys and values in the cache, the frequencies of the
+// keys are not changed.
+func (lfu *lfuCache) GetAll() (map[interface{}]interface{}, error) {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	all := map[interface{}]interface{}{}
+	err := lfu.storage.ForEach(func(key, item interface{}) bool {
+		all[key] = item.(lfuItem).value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
 func (lfu *lfuCache) Count() int {
 	lfu.mutex.Lock()
 	defer lfu.mutex.Unlock()
@@ -283,3 +676,33 @@ func (lfu *lfuCache) IsEmpty() bool {
 func (lfu *lfuCache) isEmpty() bool {
 	return lfu.heap.Len() < 1
 }
+
+// Capacity returns the maximal amount of cached items.
+func (lfu *lfuCache) Capacity() int {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	return lfu.capacity
+}
+
+// SetCapacity changes the maximal amount of cached items, the least frequently
+// used items are evicted until the cache fits the new capacity.
+func (lfu *lfuCache) SetCapacity(n int) error {
+	lfu.mutex.Lock()
+	defer lfu.mutex.Unlock()
+
+	if n <= 0 {
+		return newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
+	for lfu.heap.Len() > n {
+		err := lfu.evict()
+		if err != nil {
+			return err
+		}
+	}
+
+	lfu.capacity = n
+
+	return nil
+}
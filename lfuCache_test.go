@@ -2,6 +2,8 @@ package cache
 
 import (
 	"fmt"
+	"log/slog"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -21,7 +23,9 @@ var _ = Describe("LFU Cache", func() {
 	}
 
 	BeforeEach(func() {
-		c = NewLfu(LFUCacheSize)
+		var err error
+		c, err = NewLfu(LFUCacheSize)
+		Expect(err).ToNot(HaveOccurred())
 	})
 
 	Context("Store", func() {
@@ -53,6 +57,16 @@ var _ = Describe("LFU Cache", func() {
 			_, err = c.Get(keys[2])
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("should remove the oldest value among values with the same frequency", func() {
+			// None of the stored values was accessed.
+			Expect(c.Store("extra-key", "extra-value")).ToNot(HaveOccurred(), "failed storing extra value")
+
+			Expect(c.Contains(keys[0])).To(BeFalse(), "the oldest value was not evicted")
+			for i := 1; i < LFUCacheSize; i++ {
+				Expect(c.Contains(keys[i])).To(BeTrue())
+			}
+		})
 	})
 
 	Context("Get", func() {
@@ -76,6 +90,38 @@ var _ = Describe("LFU Cache", func() {
 		})
 	})
 
+	Context("Contains", func() {
+		It("should return true for a cached key", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value")
+			Expect(c.Contains(keys[0])).To(BeTrue())
+		})
+
+		It("should return false for a key that does not exist", func() {
+			Expect(c.Contains("non-existent-key")).To(BeFalse())
+		})
+	})
+
+	Context("Peek", func() {
+		BeforeEach(func() {
+			for i := 0; i < LFUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+		})
+
+		It("should return a value without updating the heap", func() {
+			lfuKey := c.GetLeastFrequentlyUsedKey()
+			val, err := c.Peek(lfuKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(values[0]))
+			Expect(c.GetLeastFrequentlyUsedKey()).To(Equal(lfuKey))
+		})
+
+		It("should return an error when peeking a key that does not exist", func() {
+			_, err := c.Peek("non-existent-key")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
 	Context("Remove", func() {
 		BeforeEach(func() {
 			for i := 0; i < LFUCacheSize; i++ {
@@ -90,6 +136,165 @@ var _ = Describe("LFU Cache", func() {
 		})
 	})
 
+	Context("GetAndRemove", func() {
+		It("should return a value and remove it", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value")
+			Expect(c.GetAndRemove(keys[0])).To(Equal(values[0]))
+			Expect(c.Contains(keys[0])).To(BeFalse())
+			Expect(c.Count()).To(Equal(0))
+		})
+	})
+
+	Context("StoreWithUpdate", func() {
+		counter := func(currValue interface{}) (interface{}, error) {
+			return currValue.(int) + 1, nil
+		}
+
+		It("should continuously update a value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, counter, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, testTimeout).Should(BeNumerically(">", 1))
+		})
+
+		It("should stop updating an evicted value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.SetCapacity(1)).ToNot(HaveOccurred())
+			Expect(c.Store(keys[1], 0)).ToNot(HaveOccurred())
+
+			_, err := c.Peek(keys[0])
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+			Expect(c.updateChannels).To(BeEmpty())
+		})
+
+		It("should stop updating a removed value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.Remove(keys[0])).ToNot(HaveOccurred())
+			Expect(c.Store(keys[0], 0)).ToNot(HaveOccurred())
+
+			Consistently(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, 300*time.Millisecond).Should(Equal(0))
+		})
+
+		It("should return an error for invalid inputs", func() {
+			Expect(IsNilUpdateFunc(c.StoreWithUpdate(keys[0], 0, nil, time.Second))).To(BeTrue())
+			Expect(IsNonPositivePeriod(c.StoreWithUpdate(keys[0], 0, counter, 0))).To(BeTrue())
+			Expect(c.Contains(keys[0])).To(BeFalse())
+		})
+
+		It("should pass an unexpected error of the storage to the update error handler", func() {
+			errs := make(chan error, 1)
+			uc, err := NewLfuWithCustomCache(LFUCacheSize, &replaceFailingCache{NewMapCache()},
+				WithEvictionUpdateErrorHandler(func(key interface{}, err error) {
+					select {
+					case errs <- err:
+					default:
+					}
+				}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uc.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			var updateErr error
+			Eventually(errs, testTimeout).Should(Receive(&updateErr))
+			Expect(IsUnexpectedError(updateErr)).To(BeTrue())
+			Expect(uc.Peek(keys[0])).To(Equal(0))
+		})
+
+		It("should log an unexpected error of the storage and remove the value", func() {
+			logs := make(chanWriter, 10)
+			uc, err := NewLfuWithCustomCache(LFUCacheSize, &replaceFailingCache{NewMapCache()},
+				WithEvictionLogger(slog.New(slog.NewJSONHandler(logs, nil))))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uc.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(logs, testTimeout).Should(Receive())
+			Expect(uc.Contains(keys[0])).To(BeFalse())
+		})
+	})
+
+	Context("ReplaceWithUpdate", func() {
+		It("should replace a value with an updating value", func() {
+			Expect(c.Store(keys[0], 0)).ToNot(HaveOccurred())
+			Expect(c.ReplaceWithUpdate(keys[0], 10, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, testTimeout).Should(BeNumerically(">", 10))
+		})
+
+		It("should return an error when attempting to replace a non-existent value", func() {
+			Expect(IsDoesNotExist(c.ReplaceWithUpdate(keys[0], 0, func(currValue interface{}) (interface{}, error) {
+				return currValue, nil
+			}, time.Second))).To(BeTrue())
+		})
+	})
+
+	Context("CancelUpdate", func() {
+		It("should keep the current value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 50*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.CancelUpdate(keys[0])).ToNot(HaveOccurred())
+
+			Consistently(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, 300*time.Millisecond).Should(Equal(0))
+		})
+	})
+
+	Context("GetMany", func() {
+		It("should update the frequency of each fetched value", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{keys[0]: values[0], keys[1]: values[1]})).
+				ToNot(HaveOccurred())
+
+			vals, err := c.GetMany([]interface{}{keys[0]})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vals).To(HaveKeyWithValue(keys[0], values[0]))
+			Expect(c.GetLeastFrequentlyUsedKey()).To(Equal(keys[1]))
+		})
+	})
+
+	Context("ForEach", func() {
+		It("should iterate over all values without changing their frequencies", func() {
+			for i := 0; i < LFUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+			lfuKey := c.GetLeastFrequentlyUsedKey()
+
+			vals := map[interface{}]interface{}{}
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				vals[key] = val
+				return true
+			})).ToNot(HaveOccurred())
+			Expect(vals).To(HaveLen(LFUCacheSize))
+			Expect(vals).To(HaveKeyWithValue(keys[1], values[1]))
+			Expect(c.GetLeastFrequentlyUsedKey()).To(Equal(lfuKey))
+		})
+	})
+
+	Context("GetAll", func() {
+		It("should return all values without changing their frequencies", func() {
+			for i := 0; i < LFUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+			lfuKey := c.GetLeastFrequentlyUsedKey()
+
+			all, err := c.GetAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(all).To(HaveLen(LFUCacheSize))
+			Expect(all).To(HaveKeyWithValue(keys[1], values[1]))
+			Expect(c.GetLeastFrequentlyUsedKey()).To(Equal(lfuKey))
+		})
+	})
+
 	Context("Clear", func() {
 		BeforeEach(func() {
 			for i := 0; i < LFUCacheSize; i++ {
@@ -146,12 +351,78 @@ var _ = Describe("LFU Cache", func() {
 		})
 	})
 
+	Context("WithEvictionCallback", func() {
+		It("should call the callback with the evicted key and value", func() {
+			var evictedKey, evictedVal interface{}
+			var err error
+			c, err = NewLfu(LFUCacheSize, WithEvictionCallback(func(key, val interface{}) {
+				evictedKey, evictedVal = key, val
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < LFUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+			Expect(evictedKey).To(BeNil(), "callback was called before the cache was full")
+
+			// Enforce keys[2] to be the lfu item.
+			_, err = c.Get(keys[0])
+			Expect(err).ToNot(HaveOccurred())
+			_, err = c.Get(keys[1])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.Store("extra-key", "extra-value")).ToNot(HaveOccurred())
+			Expect(evictedKey).To(Equal(keys[2]))
+			Expect(evictedVal).To(Equal(values[2]))
+		})
+	})
+
+	Context("SetCapacity", func() {
+		BeforeEach(func() {
+			for i := 0; i < LFUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+		})
+
+		It("should evict the least frequently used values when shrinking", func() {
+			_, err := c.Get(keys[1])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.SetCapacity(1)).ToNot(HaveOccurred())
+			Expect(c.Capacity()).To(Equal(1))
+			Expect(c.Count()).To(Equal(1))
+			Expect(c.Contains(keys[1])).To(BeTrue(), "most frequently used value was evicted")
+		})
+
+		It("should return an error for a non-positive capacity", func() {
+			Expect(IsNonPositivePeriod(c.SetCapacity(-1))).To(BeTrue())
+		})
+	})
+
+	Context("NewLfu", func() {
+		It("should return an error when the capacity is zero", func() {
+			_, err := NewLfu(0)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+
+			_, err = NewLfuWithCustomCache(0, NewMapCache())
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+	})
+
 	Context("NewLfuWithCustomCache", func() {
 		It("should return an error when being supplied with a non empty cache", func() {
 			mapCache := NewMapCache()
 			Expect(mapCache.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value in map cache")
-			_, err := NewLruWithCustomCache(LFUCacheSize, mapCache)
-			Expect(err).To(HaveOccurred())
+			_, err := NewLfuWithCustomCache(LFUCacheSize, mapCache)
+			Expect(IsCacheNotEmpty(err)).To(BeTrue())
+		})
+
+		It("should use an empty cache as the storage", func() {
+			mapCache := NewMapCache()
+			lfu, err := NewLfuWithCustomCache(LFUCacheSize, mapCache)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lfu.Store(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(mapCache.Contains(keys[0])).To(BeTrue(), "value was not stored in the supplied cache")
 		})
 	})
 })
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"time"
+)
+
+type lfuExpiringCache struct {
+	*lfuCache
+	evictionExpirations
+}
+
+var _ ExpiringCache = (*lfuExpiringCache)(nil)
+
+// NewLfuWithExpiration creates a new lfuCache instance using mapCache, that
+// also supports temporary values, capacity must be greater than zero.
+//
+// Expired values are removed from the heap as well, just like evicted
+// or removed values.
+func NewLfuWithExpiration(capacity uint, opts ...EvictionOption) (*lfuExpiringCache, error) {
+	lfu, err := NewLfu(capacity, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	lfec := &lfuExpiringCache{lfuCache: lfu}
+	lfec.evictionExpirations = newEvictionExpirations(lfec, &lfu.mutex)
+
+	// An evicted value should not be removed again once its ttl is over.
+	onEvict := lfec.onEvict
+	lfec.onEvict = func(key, val interface{}) {
+		lfec.stopExpiration(key)
+
+		if onEvict != nil {
+			onEvict(key, val)
+		}
+	}
+
+	return lfec, nil
+}
+
+// Get a cached value, the ttl of a value with a sliding expiration is reset.
+func (lfec *lfuExpiringCache) Get(key interface{}) (interface{}, error) {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	val, err := lfec.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	lfec.resetSlidingExpiration(key)
+
+	return val, nil
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (lfec *lfuExpiringCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	actual, loaded, err := lfec.getOrStore(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if loaded {
+		lfec.resetSlidingExpiration(key)
+	}
+
+	return actual, loaded, nil
+}
+
+// Get several cached values.
+func (lfec *lfuExpiringCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	return getMany(keys, func(key interface{}) (interface{}, error) {
+		val, err := lfec.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		lfec.resetSlidingExpiration(key)
+
+		return val, nil
+	})
+}
+
+// Remove a cached value.
+func (lfec *lfuExpiringCache) Remove(key interface{}) error {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	return lfec.remove(key)
+}
+
+func (lfec *lfuExpiringCache) remove(key interface{}) error {
+	err := lfec.lfuCache.remove(key)
+	if err != nil {
+		return err
+	}
+
+	lfec.stopExpiration(key)
+
+	return nil
+}
+
+// Get a cached value and remove it.
+func (lfec *lfuExpiringCache) GetAndRemove(key interface{}) (interface{}, error) {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	val, err := lfec.peek(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lfec.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Replace a cached value with a permanent value.
+func (lfec *lfuExpiringCache) Replace(key, val interface{}) error {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	err := lfec.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return lfec.store(key, val)
+}
+
+// Cache a permanent value, replacing the current value of the key if it
+// exists.
+func (lfec *lfuExpiringCache) StoreOrReplace(key, val interface{}) error {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	err := lfec.remove(key)
+	if err != nil && !IsDoesNotExist(err) {
+		return err
+	}
+
+	return lfec.store(key, val)
+}
+
+// Replace a cached value with a continuously updating permanent value.
+func (lfec *lfuExpiringCache) ReplaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	err := lfec.replaceWithUpdate(key, initialValue, updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	lfec.stopExpiration(key)
+
+	return nil
+}
+
+// Clear all values.
+func (lfec *lfuExpiringCache) Clear() error {
+	lfec.mutex.Lock()
+	defer lfec.mutex.Unlock()
+
+	lfec.stopExpirations()
+
+	return lfec.clear()
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LFU Expiring Cache", func() {
+	const ttl = 100 * time.Millisecond
+
+	var (
+		c            *lfuExpiringCache
+		keys, values []string
+	)
+
+	for i := 0; i < LFUCacheSize; i++ {
+		keys = append(keys, fmt.Sprintf("test-key-%v", i))
+		values = append(values, fmt.Sprintf("test-value-%v", i))
+	}
+
+	BeforeEach(func() {
+		var err error
+		c, err = NewLfuWithExpiration(LFUCacheSize)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("StoreWithExpiration", func() {
+		It("should remove a value from the cache and the heap once expired", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration(keys[1], values[1], ttl)).ToNot(HaveOccurred())
+			Expect(c.Count()).To(Equal(2))
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(keys[1])
+				return exists
+			}, testTimeout).Should(BeFalse())
+			Expect(c.Count()).To(Equal(1))
+			Expect(c.GetLeastFrequentlyUsedKey()).To(Equal(keys[0]))
+		})
+	})
+})
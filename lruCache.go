@@ -2,7 +2,11 @@ package cache
 
 import (
 	"container/list"
+	"fmt"
+	"log/slog"
 	"sync"
+	"time"
+	"unsafe"
 )
 
 type lruItem struct {
@@ -27,22 +31,48 @@ type lruCache struct {
 	// from the most recently used to the least recently used.
 	list *list.List
 
+	// Called with the key and value of an evicted item.
+	onEvict func(key, val interface{})
+
+	// Holds the channels that stop the auto update routines.
+	updateChannels map[interface{}]*cacheChannel
+
+	// Holds the update functions of the auto update routines.
+	updateFuncs map[interface{}]func(currValue interface{}) (interface{}, error)
+
+	// Called with the errors of the auto update routines.
+	onUpdateError func(key interface{}, err error)
+
+	// Logs unexpected errors of background routines.
+	logger *slog.Logger
+
+	// Counts the lookups of Get, GetMany and GetOrStore.
+	hitStats
+
 	mutex sync.Mutex
 }
 
-var _ Cache = (*lruCache)(nil)
+var _ PeekableCache = (*lruCache)(nil)
+var _ UpdatingCache = (*lruCache)(nil)
 
-// NewLru creates a new lruCache instance using mapCache.
-func NewLru(capacity int) *lruCache {
-	return &lruCache{
-		capacity: capacity,
-		storage:  NewMapCache(),
-		list:     list.New(),
+// NewLru creates a new lruCache instance using mapCache, capacity must be
+// greater than zero.
+func NewLru(capacity uint, opts ...EvictionOption) (*lruCache, error) {
+	if capacity == 0 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
 	}
+
+	return newLru(int(capacity), NewMapCache(), opts), nil
 }
 
-// NewLruWithCustomCache creates a new lruCache with custom cache.
-func NewLruWithCustomCache(capacity int, cache Cache) (*lruCache, error) {
+// NewLruWithCustomCache creates a new lruCache with custom cache, capacity
+// must be greater than zero.
+func NewLruWithCustomCache(capacity uint, cache Cache,
+	opts ...EvictionOption) (*lruCache, error) {
+	if capacity == 0 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
 	keys, err := cache.Keys()
 	if err != nil {
 		return nil, err
@@ -52,11 +82,22 @@ func NewLruWithCustomCache(capacity int, cache Cache) (*lruCache, error) {
 		return nil, newError(errorTypeCacheNotEmpty, "supplied cache must be empty")
 	}
 
+	return newLru(int(capacity), cache, opts), nil
+}
+
+func newLru(capacity int, storage Cache, opts []EvictionOption) *lruCache {
+	o := newEvictionOptions(opts)
+
 	return &lruCache{
-		capacity: capacity,
-		storage:  cache,
-		list:     list.New(),
-	}, nil
+		capacity:       capacity,
+		storage:        storage,
+		list:           list.New(),
+		onEvict:        o.onEvict,
+		updateChannels: map[interface{}]*cacheChannel{},
+		updateFuncs:    map[interface{}]func(currValue interface{}) (interface{}, error){},
+		onUpdateError:  o.onUpdateError,
+		logger:         o.logger,
+	}
 }
 
 // Store caches a new value.
@@ -79,13 +120,13 @@ func (lru *lruCache) store(key, val interface{}) error {
 
 	// If storing the value failed, remove the linked list node.
 	if err != nil {
-		lru.list.Remove(lru.list.Back())
+		lru.list.Remove(node)
 		return err
 	}
 
 	// If the cache is full, remove the least recently used item.
 	if lru.isFull() {
-		err := lru.storage.Remove(lru.list.Back().Value)
+		err := lru.evict()
 		if err != nil {
 			return err
 		}
@@ -97,6 +138,29 @@ func (lru *lruCache) store(key, val interface{}) error {
 	return nil
 }
 
+func (lru *lruCache) evict() error {
+	node := lru.list.Back()
+
+	item, err := lru.storage.Get(node.Value)
+	if err != nil {
+		return err
+	}
+
+	if lru.onEvict != nil {
+		lru.onEvict(node.Value, item.(lruItem).value)
+	}
+
+	err = lru.storage.Remove(node.Value)
+	if err != nil {
+		return err
+	}
+
+	lru.stopUpdate(node.Value)
+	lru.list.Remove(node)
+
+	return nil
+}
+
 // Get a cached value.
 func (lru *lruCache) Get(key interface{}) (interface{}, error) {
 	lru.mutex.Lock()
@@ -107,6 +171,7 @@ func (lru *lruCache) Get(key interface{}) (interface{}, error) {
 
 func (lru *lruCache) get(key interface{}) (interface{}, error) {
 	item, err := lru.storage.Get(key)
+	lru.recordLookup(err == nil)
 	if err != nil {
 		return nil, err
 	}
@@ -119,14 +184,104 @@ func (lru *lruCache) get(key interface{}) (interface{}, error) {
 	return lruItem.value, nil
 }
 
-// GetMostRecentlyUsedKey returns the key from the front of the linked list.
+// Check whether a key is cached without moving it to the head of the
+// linked list.
+func (lru *lruCache) Contains(key interface{}) (bool, error) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.storage.Contains(key)
+}
+
+// Peek gets a cached value without moving it to the head of the linked list.
+func (lru *lruCache) Peek(key interface{}) (interface{}, error) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.peek(key)
+}
+
+func (lru *lruCache) peek(key interface{}) (interface{}, error) {
+	item, err := lru.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(lruItem).value, nil
+}
+
+// Promote moves a key to the front of the linked list without getting its
+// value, so it is the last to be evicted.
+func (lru *lruCache) Promote(key interface{}) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.promote(key)
+}
+
+func (lru *lruCache) promote(key interface{}) error {
+	item, err := lru.storage.Get(key)
+	if err != nil {
+		return err
+	}
+
+	lru.list.MoveToFront(item.(lruItem).node)
+
+	return nil
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (lru *lruCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.getOrStore(key, val)
+}
+
+func (lru *lruCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	actual, err := lru.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = lru.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// GetMostRecentlyUsedKey returns the key from the front of the linked list,
+// or nil if the cache is empty.
 func (lru *lruCache) GetMostRecentlyUsedKey() interface{} {
-	return lru.list.Front().Value
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	node := lru.list.Front()
+	if node == nil {
+		return nil
+	}
+
+	return node.Value
 }
 
-// GetLeastRecentlyUsedKey returns the key from the back of the linked list.
+// GetLeastRecentlyUsedKey returns the key from the back of the linked list,
+// or nil if the cache is empty.
 func (lru *lruCache) GetLeastRecentlyUsedKey() interface{} {
-	return lru.list.Back().Value
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	node := lru.list.Back()
+	if node == nil {
+		return nil
+	}
+
+	return node.Value
 }
 
 // Remove a cahced value.
@@ -151,10 +306,33 @@ func (lru *lruCache) remove(key interface{}) error {
 	lruItem, _ := item.(lruItem)
 	lru.list.Remove(lruItem.node)
 	lru.numberOfItems--
+	lru.stopUpdate(key)
 
 	return nil
 }
 
+// Get a cached value and remove it.
+func (lru *lruCache) GetAndRemove(key interface{}) (interface{}, error) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.getAndRemove(key)
+}
+
+func (lru *lruCache) getAndRemove(key interface{}) (interface{}, error) {
+	val, err := lru.peek(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lru.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
 // Replace a cached value.
 func (lru *lruCache) Replace(key, val interface{}) error {
 	lru.mutex.Lock()
@@ -177,6 +355,219 @@ func (lru *lruCache) replace(key, val interface{}) error {
 	return nil
 }
 
+// StoreOrReplace caches a value, replacing the current value of the key if it
+// exists.
+func (lru *lruCache) StoreOrReplace(key, val interface{}) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	err := lru.replace(key, val)
+	if IsDoesNotExist(err) {
+		return lru.store(key, val)
+	}
+
+	return err
+}
+
+// Store a continuously updating value, updates do not change the position of
+// the value, period must be greater than zero.
+func (lru *lruCache) StoreWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.storeWithUpdate(key, initialValue, updateFunc, period)
+}
+
+func (lru *lruCache) storeWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	err = lru.store(key, initialValue)
+	if err != nil {
+		return err
+	}
+
+	lru.createUpdateRoutine(key, updateFunc, period)
+
+	return nil
+}
+
+// Replace a cached value with a continuously updating value.
+func (lru *lruCache) ReplaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.replaceWithUpdate(key, initialValue, updateFunc, period)
+}
+
+func (lru *lruCache) replaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	err = lru.replace(key, initialValue)
+	if err != nil {
+		return err
+	}
+
+	lru.createUpdateRoutine(key, updateFunc, period)
+
+	return nil
+}
+
+func (lru *lruCache) createUpdateRoutine(key interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) {
+	c := newCacheChannel()
+	lru.updateChannels[key] = c
+	lru.updateFuncs[key] = updateFunc
+
+	updateSignalerRoutine := func(c *cacheChannel) {
+		<-time.After(period)
+		c.signal(proceed)
+	}
+
+	updateRoutine := func(key interface{}, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		} else {
+			lru.mutex.Lock()
+			defer lru.mutex.Unlock()
+
+			// The update was canceled while waiting for the mutex.
+			if lru.updateChannels[key] != c {
+				return
+			}
+
+			item, err := lru.storage.Get(key)
+			if err != nil {
+				lru.unexpectedUpdateError(key, err)
+				return
+			}
+
+			// Keep the current value until the next update if updateFunc
+			// fails.
+			currItem := item.(lruItem)
+			newVal, err := updateFunc(currItem.value)
+			if err != nil {
+				if lru.onUpdateError != nil {
+					lru.onUpdateError(key, err)
+				}
+			} else {
+				currItem.value = newVal
+
+				err = lru.storage.Replace(key, currItem)
+				if err != nil {
+					lru.unexpectedUpdateError(key, err)
+					return
+				}
+			}
+
+			lru.createUpdateRoutine(key, updateFunc, period)
+		}
+	}
+
+	go updateSignalerRoutine(c)
+	go updateRoutine(key, c)
+}
+
+// Report an unexpected error of an auto update routine, if there is no update
+// error handler the error is logged and the value is removed.
+func (lru *lruCache) unexpectedUpdateError(key interface{}, err error) {
+	wrappedErr := newWrapperError(errorTypeUnexpectedError,
+		"an unexpected error occurred a background routine", err)
+	if lru.onUpdateError != nil {
+		lru.onUpdateError(key, wrappedErr)
+		return
+	}
+
+	lru.logger.Error("an unexpected error occurred a background routine",
+		"key", key, "error", err, "operation", "update")
+
+	// The value may have already been removed from the storage.
+	lru.remove(key)
+}
+
+// Stop updating a cached value, the value keeps its current value.
+func (lru *lruCache) CancelUpdate(key interface{}) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	_, err := lru.peek(key)
+	if err != nil {
+		return err
+	}
+
+	lru.stopUpdate(key)
+
+	return nil
+}
+
+// Change the period in which a value is updated, the next update happens
+// newPeriod after the call.
+func (lru *lruCache) SetUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	if newPeriod <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be greater than zero")
+	}
+
+	_, err := lru.peek(key)
+	if err != nil {
+		return err
+	}
+
+	updateFunc, isUpdating := lru.updateFuncs[key]
+	if !isUpdating {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v is not being updated", key))
+	}
+
+	lru.stopUpdate(key)
+	lru.createUpdateRoutine(key, updateFunc, newPeriod)
+
+	return nil
+}
+
+func (lru *lruCache) stopUpdate(key interface{}) {
+	c, exists := lru.updateChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(lru.updateChannels, key)
+		delete(lru.updateFuncs, key)
+	}
+}
+
+// Store several values.
+func (lru *lruCache) StoreMany(items map[interface{}]interface{}) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return storeMany(items, lru.store)
+}
+
+// Get several cached values.
+func (lru *lruCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return getMany(keys, lru.get)
+}
+
 // Clear all values from lru cache.
 func (lru *lruCache) Clear() error {
 	lru.mutex.Lock()
@@ -192,8 +583,10 @@ func (lru *lruCache) clear() error {
 	}
 
 	// Remove all nodes from linked list.
-	for node := lru.list.Front(); node != nil; node = node.Next() {
-		lru.list.Remove(node)
+	lru.list.Init()
+
+	for key := range lru.updateChannels {
+		lru.stopUpdate(key)
 	}
 
 	lru.numberOfItems = 0
@@ -206,6 +599,40 @@ func (lru *lruCache) Keys() ([]interface{}, error) {
 	return lru.storage.Keys()
 }
 
+// Calls fn for each cached key and value until fn returns false, without
+// changing the order of the linked list.
+func (lru *lruCache) ForEach(fn func(key, val interface{}) bool) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.storage.ForEach(func(key, item interface{}) bool {
+		return fn(key, item.(lruItem).value)
+	})
+}
+
+// Get the keys for which predicate returns true.
+func (lru *lruCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	return lru.storage.FilterKeys(predicate)
+}
+
+// Get a snapshot of all keys and values in the cache, the order of eviction
+// is not changed.
+func (lru *lruCache) GetAll() (map[interface{}]interface{}, error) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	all := map[interface{}]interface{}{}
+	err := lru.storage.ForEach(func(key, item interface{}) bool {
+		all[key] = item.(lruItem).value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
 // Count return the number of cached items,
 func (lru *lruCache) Count() int {
 	lru.mutex.Lock()
@@ -218,6 +645,26 @@ func (lru *lruCache) count() int {
 	return lru.numberOfItems
 }
 
+// Size returns the estimated size of the cached keys and values in bytes,
+// including the linked list that tracks their order.
+func (lru *lruCache) Size() int64 {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	size := int64(0)
+	for node := lru.list.Front(); node != nil; node = node.Next() {
+		val, err := lru.peek(node.Value)
+		if err != nil {
+			continue
+		}
+
+		size += int64(unsafe.Sizeof(*node)) + int64(unsafe.Sizeof(lruItem{}))
+		size += sizeOf(node.Value) + sizeOf(val)
+	}
+
+	return size
+}
+
 // IsFull returns true if cache is full.
 func (lru *lruCache) IsFull() bool {
 	lru.mutex.Lock()
@@ -241,3 +688,35 @@ func (lru *lruCache) IsEmpty() bool {
 func (lru *lruCache) isEmpty() bool {
 	return lru.numberOfItems < 1
 }
+
+// Capacity returns the maximal amount of cached items.
+func (lru *lruCache) Capacity() int {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	return lru.capacity
+}
+
+// SetCapacity changes the maximal amount of cached items, the least recently
+// used items are evicted until the cache fits the new capacity.
+func (lru *lruCache) SetCapacity(n int) error {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	if n <= 0 {
+		return newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
+	for lru.numberOfItems > n {
+		err := lru.evict()
+		if err != nil {
+			return err
+		}
+
+		lru.numberOfItems--
+	}
+
+	lru.capacity = n
+
+	return nil
+}
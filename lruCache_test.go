@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -9,6 +12,15 @@ import (
 
 const LRUCacheSize = 3
 
+// Fails Replace, like a storage whose backend is down.
+type replaceFailingCache struct {
+	Cache
+}
+
+func (rc *replaceFailingCache) Replace(key, val interface{}) error {
+	return errors.New("connection refused")
+}
+
 var _ = Describe("LRU Cache", func() {
 	var (
 		c            *lruCache
@@ -21,7 +33,9 @@ var _ = Describe("LRU Cache", func() {
 	}
 
 	BeforeEach(func() {
-		c = NewLru(LRUCacheSize)
+		var err error
+		c, err = NewLru(LRUCacheSize)
+		Expect(err).ToNot(HaveOccurred())
 	})
 
 	Context("Store", func() {
@@ -77,6 +91,81 @@ var _ = Describe("LRU Cache", func() {
 		})
 	})
 
+	Context("Contains", func() {
+		It("should return true for a cached key without moving it to the front of the linked list", func() {
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			Expect(c.Contains(keys[0])).To(BeTrue())
+			Expect(c.GetMostRecentlyUsedKey()).To(Equal(keys[LRUCacheSize-1]))
+		})
+
+		It("should return false for a key that does not exist", func() {
+			Expect(c.Contains("non-existent-key")).To(BeFalse())
+		})
+	})
+
+	Context("Peek", func() {
+		It("should return a value without moving it to the front of the linked list", func() {
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			val, err := c.Peek(keys[0])
+			Expect(err).ToNot(HaveOccurred(), "failed to peek a key")
+			Expect(val).To(Equal(values[0]), "got the wrong value from cache")
+			Expect(c.GetMostRecentlyUsedKey()).To(Equal(keys[LRUCacheSize-1]),
+				"most recent value should not have been updated")
+		})
+
+		It("should return an error when peeking a key that does not exist", func() {
+			_, err := c.Peek("non-existent-key")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Promote", func() {
+		It("should protect a key from being the next one to be evicted", func() {
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			Expect(c.Promote(keys[0])).ToNot(HaveOccurred())
+			Expect(c.GetMostRecentlyUsedKey()).To(Equal(keys[0]))
+
+			Expect(c.Store("new-key", "new-value")).ToNot(HaveOccurred())
+			Expect(c.Contains(keys[0])).To(BeTrue())
+			Expect(c.Contains(keys[1])).To(BeFalse())
+		})
+
+		It("should return an error when promoting a key that does not exist", func() {
+			Expect(IsDoesNotExist(c.Promote("non-existent-key"))).To(BeTrue())
+		})
+	})
+
+	Context("GetLeastRecentlyUsedKey", func() {
+		It("should return the key that was accessed least recently", func() {
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+			Expect(c.GetLeastRecentlyUsedKey()).To(Equal(keys[0]))
+
+			_, err := c.Get(keys[0])
+			Expect(err).ToNot(HaveOccurred())
+			_, err = c.Get(keys[1])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.GetLeastRecentlyUsedKey()).To(Equal(keys[2]))
+			Expect(c.GetMostRecentlyUsedKey()).To(Equal(keys[1]))
+		})
+
+		It("should return nil when the cache is empty", func() {
+			Expect(c.GetLeastRecentlyUsedKey()).To(BeNil())
+			Expect(c.GetMostRecentlyUsedKey()).To(BeNil())
+		})
+	})
+
 	Context("Remove", func() {
 		BeforeEach(func() {
 			for i := 0; i < LRUCacheSize; i++ {
@@ -102,6 +191,15 @@ var _ = Describe("LRU Cache", func() {
 		})
 	})
 
+	Context("GetAndRemove", func() {
+		It("should return a value and remove it", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value")
+			Expect(c.GetAndRemove(keys[0])).To(Equal(values[0]))
+			Expect(c.Contains(keys[0])).To(BeFalse())
+			Expect(c.Count()).To(Equal(0))
+		})
+	})
+
 	Context("Replace", func() {
 		BeforeEach(func() {
 			c.Store(keys[0], values[0])
@@ -113,6 +211,222 @@ var _ = Describe("LRU Cache", func() {
 		})
 	})
 
+	Context("StoreOrReplace", func() {
+		It("should store a value or replace the current one", func() {
+			Expect(c.StoreOrReplace(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(c.StoreOrReplace(keys[0], values[1])).ToNot(HaveOccurred())
+			Expect(c.Get(keys[0])).To(Equal(values[1]))
+			Expect(c.Count()).To(Equal(1))
+		})
+	})
+
+	Context("StoreWithUpdate", func() {
+		counter := func(currValue interface{}) (interface{}, error) {
+			return currValue.(int) + 1, nil
+		}
+
+		It("should continuously update a value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, counter, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, testTimeout).Should(BeNumerically(">", 1))
+		})
+
+		It("should not move an updated value to the head of the linked list", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.Store(keys[1], 0)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, testTimeout).Should(BeNumerically(">", 1))
+			Expect(c.GetLeastRecentlyUsedKey()).To(Equal(keys[0]))
+		})
+
+		It("should stop updating an evicted value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+			for i := 1; i <= LRUCacheSize; i++ {
+				Expect(c.Store(fmt.Sprintf("other-%d", i), 0)).ToNot(HaveOccurred())
+			}
+
+			Expect(c.Contains(keys[0])).To(BeFalse())
+			Expect(c.updateChannels).To(BeEmpty())
+		})
+
+		It("should stop updating a removed value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.Remove(keys[0])).ToNot(HaveOccurred())
+			Expect(c.Store(keys[0], 0)).ToNot(HaveOccurred())
+
+			Consistently(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, 300*time.Millisecond).Should(Equal(0))
+		})
+
+		It("should return an error for invalid inputs", func() {
+			Expect(IsNilUpdateFunc(c.StoreWithUpdate(keys[0], 0, nil, time.Second))).To(BeTrue())
+			Expect(IsNonPositivePeriod(c.StoreWithUpdate(keys[0], 0, counter, 0))).To(BeTrue())
+			Expect(c.Contains(keys[0])).To(BeFalse())
+		})
+
+		It("should pass an unexpected error of the storage to the update error handler", func() {
+			errs := make(chan error, 1)
+			uc, err := NewLruWithCustomCache(LRUCacheSize, &replaceFailingCache{NewMapCache()},
+				WithEvictionUpdateErrorHandler(func(key interface{}, err error) {
+					select {
+					case errs <- err:
+					default:
+					}
+				}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uc.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			var updateErr error
+			Eventually(errs, testTimeout).Should(Receive(&updateErr))
+			Expect(IsUnexpectedError(updateErr)).To(BeTrue())
+			Expect(uc.Peek(keys[0])).To(Equal(0))
+		})
+
+		It("should log an unexpected error of the storage and remove the value", func() {
+			logs := make(chanWriter, 10)
+			uc, err := NewLruWithCustomCache(LRUCacheSize, &replaceFailingCache{NewMapCache()},
+				WithEvictionLogger(slog.New(slog.NewJSONHandler(logs, nil))))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uc.StoreWithUpdate(keys[0], 0, counter, 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(logs, testTimeout).Should(Receive())
+			Expect(uc.Contains(keys[0])).To(BeFalse())
+		})
+	})
+
+	Context("ReplaceWithUpdate", func() {
+		It("should replace a value with an updating value", func() {
+			Expect(c.Store(keys[0], 0)).ToNot(HaveOccurred())
+			Expect(c.ReplaceWithUpdate(keys[0], 10, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, testTimeout).Should(BeNumerically(">", 10))
+		})
+
+		It("should return an error when attempting to replace a non-existent value", func() {
+			Expect(IsDoesNotExist(c.ReplaceWithUpdate(keys[0], 0, func(currValue interface{}) (interface{}, error) {
+				return currValue, nil
+			}, time.Second))).To(BeTrue())
+		})
+	})
+
+	Context("CancelUpdate", func() {
+		It("should keep the current value", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 50*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.CancelUpdate(keys[0])).ToNot(HaveOccurred())
+
+			Consistently(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, 300*time.Millisecond).Should(Equal(0))
+		})
+	})
+
+	Context("SetUpdatePeriod", func() {
+		It("should keep updating a value with the new period", func() {
+			Expect(c.StoreWithUpdate(keys[0], 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, time.Hour)).ToNot(HaveOccurred())
+			Expect(c.SetUpdatePeriod(keys[0], 50*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Peek(keys[0])
+				return v
+			}, testTimeout).Should(BeNumerically(">", 1))
+		})
+	})
+
+	Context("StoreMany", func() {
+		It("should store all values", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{keys[0]: values[0], keys[1]: values[1]})).
+				ToNot(HaveOccurred())
+			Expect(c.Count()).To(Equal(2))
+		})
+	})
+
+	Context("GetMany", func() {
+		It("should return the found values along with a partial failure", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value")
+
+			vals, err := c.GetMany([]interface{}{keys[0], "non-existent-key"})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(vals).To(Equal(map[interface{}]interface{}{keys[0]: values[0]}))
+		})
+	})
+
+	Context("HitStats", func() {
+		It("should count the hits and misses of lookups", func() {
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value")
+
+			c.Get(keys[0])
+			c.Get("non-existent-key")
+			c.GetOrStore(keys[0], values[0])
+
+			hits, misses := c.HitStats()
+			Expect(hits).To(Equal(int64(2)))
+			Expect(misses).To(Equal(int64(1)))
+
+			c.ResetStats()
+			hits, misses = c.HitStats()
+			Expect(hits).To(BeZero())
+			Expect(misses).To(BeZero())
+		})
+	})
+
+	Context("Size", func() {
+		It("should grow with the cached values", func() {
+			Expect(c.Size()).To(BeZero())
+
+			Expect(c.Store(keys[0], make([]byte, 128))).ToNot(HaveOccurred(), "failed storing a value")
+			Expect(c.Size()).To(BeNumerically(">=", 128+len(keys[0])))
+		})
+	})
+
+	Context("ForEach", func() {
+		It("should iterate over all values without changing their order", func() {
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			vals := map[interface{}]interface{}{}
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				vals[key] = val
+				return true
+			})).ToNot(HaveOccurred())
+			Expect(vals).To(HaveLen(LRUCacheSize))
+			Expect(vals).To(HaveKeyWithValue(keys[0], values[0]))
+			Expect(c.GetLeastRecentlyUsedKey()).To(Equal(keys[0]))
+		})
+	})
+
+	Context("GetAll", func() {
+		It("should return all values without changing their order", func() {
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+
+			all, err := c.GetAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(all).To(HaveLen(LRUCacheSize))
+			Expect(all).To(HaveKeyWithValue(keys[0], values[0]))
+			Expect(c.GetLeastRecentlyUsedKey()).To(Equal(keys[0]))
+		})
+	})
+
 	Context("Clear", func() {
 		BeforeEach(func() {
 			for i := 0; i < LRUCacheSize; i++ {
@@ -163,12 +477,79 @@ var _ = Describe("LRU Cache", func() {
 		})
 	})
 
+	Context("WithEvictionCallback", func() {
+		It("should call the callback with the evicted key and value", func() {
+			var evictedKey, evictedVal interface{}
+			var err error
+			c, err = NewLru(LRUCacheSize, WithEvictionCallback(func(key, val interface{}) {
+				evictedKey, evictedVal = key, val
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+			Expect(evictedKey).To(BeNil(), "callback was called before the cache was full")
+
+			Expect(c.Store("extra-key", "extra-value")).ToNot(HaveOccurred())
+			Expect(evictedKey).To(Equal(keys[0]))
+			Expect(evictedVal).To(Equal(values[0]))
+		})
+	})
+
+	Context("SetCapacity", func() {
+		BeforeEach(func() {
+			for i := 0; i < LRUCacheSize; i++ {
+				Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+			}
+		})
+
+		It("should evict the least recently used values when shrinking", func() {
+			_, err := c.Get(keys[0])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.SetCapacity(1)).ToNot(HaveOccurred())
+			Expect(c.Capacity()).To(Equal(1))
+			Expect(c.Count()).To(Equal(1))
+			Expect(c.Contains(keys[0])).To(BeTrue(), "most recently used value was evicted")
+		})
+
+		It("should allow storing more values when growing", func() {
+			Expect(c.SetCapacity(LRUCacheSize + 1)).ToNot(HaveOccurred())
+			Expect(c.Store("extra-key", "extra-value")).ToNot(HaveOccurred())
+			Expect(c.Count()).To(Equal(LRUCacheSize + 1))
+		})
+
+		It("should return an error for a non-positive capacity", func() {
+			Expect(IsNonPositivePeriod(c.SetCapacity(0))).To(BeTrue())
+			Expect(c.Capacity()).To(Equal(LRUCacheSize))
+		})
+	})
+
+	Context("NewLru", func() {
+		It("should return an error when the capacity is zero", func() {
+			_, err := NewLru(0)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+
+			_, err = NewLruWithCustomCache(0, NewMapCache())
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+	})
+
 	Context("NewLruWithCustomCache", func() {
 		It("should return an error when being supplied with a non empty cache", func() {
 			mapCache := NewMapCache()
 			Expect(mapCache.Store(keys[0], values[0])).ToNot(HaveOccurred(), "failed storing a value in map cache")
 			_, err := NewLruWithCustomCache(LRUCacheSize, mapCache)
-			Expect(err).To(HaveOccurred())
+			Expect(IsCacheNotEmpty(err)).To(BeTrue())
+		})
+
+		It("should use an empty cache as the storage", func() {
+			mapCache := NewMapCache()
+			lru, err := NewLruWithCustomCache(LRUCacheSize, mapCache)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lru.Store(keys[0], values[0])).ToNot(HaveOccurred())
+			Expect(mapCache.Contains(keys[0])).To(BeTrue(), "value was not stored in the supplied cache")
 		})
 	})
 })
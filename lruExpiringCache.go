@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"time"
+)
+
+type lruExpiringCache struct {
+	*lruCache
+	evictionExpirations
+}
+
+var _ ExpiringCache = (*lruExpiringCache)(nil)
+
+// NewLruWithExpiration creates a new lruCache instance using mapCache, that
+// also supports temporary values, capacity must be greater than zero.
+//
+// Expired values are removed from the linked list as well, just like evicted
+// or removed values.
+func NewLruWithExpiration(capacity uint, opts ...EvictionOption) (*lruExpiringCache, error) {
+	lru, err := NewLru(capacity, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	lec := &lruExpiringCache{lruCache: lru}
+	lec.evictionExpirations = newEvictionExpirations(lec, &lru.mutex)
+
+	// An evicted value should not be removed again once its ttl is over.
+	onEvict := lec.onEvict
+	lec.onEvict = func(key, val interface{}) {
+		lec.stopExpiration(key)
+
+		if onEvict != nil {
+			onEvict(key, val)
+		}
+	}
+
+	return lec, nil
+}
+
+// Get a cached value, the ttl of a value with a sliding expiration is reset.
+func (lec *lruExpiringCache) Get(key interface{}) (interface{}, error) {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	val, err := lec.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	lec.resetSlidingExpiration(key)
+
+	return val, nil
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (lec *lruExpiringCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	actual, loaded, err := lec.getOrStore(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if loaded {
+		lec.resetSlidingExpiration(key)
+	}
+
+	return actual, loaded, nil
+}
+
+// Get several cached values.
+func (lec *lruExpiringCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	return getMany(keys, func(key interface{}) (interface{}, error) {
+		val, err := lec.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		lec.resetSlidingExpiration(key)
+
+		return val, nil
+	})
+}
+
+// Remove a cached value.
+func (lec *lruExpiringCache) Remove(key interface{}) error {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	return lec.remove(key)
+}
+
+func (lec *lruExpiringCache) remove(key interface{}) error {
+	err := lec.lruCache.remove(key)
+	if err != nil {
+		return err
+	}
+
+	lec.stopExpiration(key)
+
+	return nil
+}
+
+// Get a cached value and remove it.
+func (lec *lruExpiringCache) GetAndRemove(key interface{}) (interface{}, error) {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	val, err := lec.peek(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lec.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Replace a cached value with a permanent value.
+func (lec *lruExpiringCache) Replace(key, val interface{}) error {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	err := lec.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return lec.store(key, val)
+}
+
+// Cache a permanent value, replacing the current value of the key if it
+// exists.
+func (lec *lruExpiringCache) StoreOrReplace(key, val interface{}) error {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	err := lec.remove(key)
+	if err != nil && !IsDoesNotExist(err) {
+		return err
+	}
+
+	return lec.store(key, val)
+}
+
+// Replace a cached value with a continuously updating permanent value.
+func (lec *lruExpiringCache) ReplaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	err := lec.replaceWithUpdate(key, initialValue, updateFunc, period)
+	if err != nil {
+		return err
+	}
+
+	lec.stopExpiration(key)
+
+	return nil
+}
+
+// Clear all values.
+func (lec *lruExpiringCache) Clear() error {
+	lec.mutex.Lock()
+	defer lec.mutex.Unlock()
+
+	lec.stopExpirations()
+
+	return lec.clear()
+}
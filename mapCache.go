@@ -1,205 +1,1567 @@
 package cache
 
 import (
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 )
 
+// The default interval in which the janitor removes expired values.
+const defaultJanitorInterval = 100 * time.Millisecond
+
+type expirationItem struct {
+	// The key of a temporary value.
+	key interface{}
+
+	// The time in which the value is removed.
+	deadline time.Time
+
+	// The index of the item in the heap, maintained by the heap.Interface
+	// methods.
+	index int
+}
+
+// A min heap of the temporary values, where the value that expires first is
+// on top.
+type expirationHeap []*expirationItem
+
+var _ heap.Interface = (*expirationHeap)(nil)
+
+func (h expirationHeap) Len() int {
+	return len(h)
+}
+
+func (h expirationHeap) Less(i, j int) bool {
+	return h[i].deadline.Before(h[j].deadline)
+}
+
+func (h expirationHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expirationHeap) Push(x interface{}) {
+	item := x.(*expirationItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expirationHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[0 : n-1]
+	return item
+}
+
 type mapCache struct {
 	// Holds the key/values in the cache
 	cacheMap map[interface{}]interface{}
 
-	// Holds the channels that stop the auto removal routines.
-	removeChannels map[interface{}]*cacheChannel
+	// Holds the temporary values ordered by their deadlines.
+	expirations expirationHeap
+
+	// Holds the heap item of each temporary value.
+	expirationItems map[interface{}]*expirationItem
+
+	// The interval in which the janitor removes expired values.
+	janitorInterval time.Duration
+
+	// Stops the running janitor, nil if the janitor is not running.
+	stopJanitor chan struct{}
+
+	// Whether the janitor was stopped with Stop.
+	janitorStopped bool
+
+	// Holds the channels that stop the auto update routines.
+	updateChannels map[interface{}]*cacheChannel
+
+	// Holds the update functions of the auto update routines.
+	updateFuncs map[interface{}]func(currValue interface{}) (interface{}, error)
+
+	// Holds the ttls of values with a sliding expiration.
+	slidingTTLs map[interface{}]time.Duration
+
+	// Holds the times in which temporary values will be removed.
+	deadlines map[interface{}]time.Time
+
+	// The maximal estimated size of the stored keys and values in bytes, zero
+	// means there is no limit.
+	sizeLimit int64
+
+	// The estimated size of the stored keys and values, only tracked when
+	// there is a size limit.
+	size int64
+
+	// Holds the estimated size of each entry, only tracked when there is a
+	// size limit.
+	entrySizes map[interface{}]int64
+
+	// The maximal number of stored keys, zero means there is no limit.
+	maxItems int
+
+	// The maximal number of elements in a slice that is built by Append, zero
+	// means there is no limit.
+	maxSliceLength int
+
+	// The ttl of values that are stored with Store, zero means they are
+	// permanent.
+	defaultTTL time.Duration
+
+	// Indication if defaultTTL was set, even to a non-positive ttl.
+	hasDefaultTTL bool
+
+	// The number of keys the map is allocated for.
+	initialCapacity int
+
+	// Called with the errors of the auto update routines.
+	onUpdateError func(key interface{}, err error)
+
+	// Logs the unexpected errors of the background routines.
+	logger *slog.Logger
+
+	// Holds the channels that are notified of the new values of each key.
+	subscribers map[interface{}][]chan interface{}
+
+	// Holds the notifications of a running transaction, they are sent once
+	// it succeeds. Nil while there is no transaction.
+	pendingNotifications []pendingNotification
+
+	// Holds the version of each value.
+	versions map[interface{}]int64
+
+	// The version of the latest write, versions are shared by all keys so a
+	// key that is removed and stored again never reuses a version.
+	lastVersion int64
+
+	// Counts the lookups of Get, GetMany and GetOrStore.
+	hitStats
+
+	// Read only operations only take a read lock.
+	mutex sync.RWMutex
+}
+
+var _ UpdatingExpiringCache = (*mapCache)(nil)
+var _ CopyableCache = (*mapCache)(nil)
+var _ VersionedCache = (*mapCache)(nil)
+
+// MapCacheOption configures a mapCache.
+type MapCacheOption func(*mapCache)
+
+// WithSizeLimit limits the estimated size of the stored keys and values,
+// storing a value that exceeds the limit returns a CapacityExceeded error.
+//
+// An updating value that exceeds the limit after an update is removed.
+func WithSizeLimit(bytes int64) MapCacheOption {
+	return func(m *mapCache) {
+		m.sizeLimit = bytes
+	}
+}
+
+// WithUpdateErrorHandler sets a function that is called whenever updating a
+// value in the background fails, the value keeps its current value when
+// updateFunc returns an error.
+//
+// Without a handler, errors of updateFunc are ignored and unexpected errors
+// are logged and remove the value.
+func WithUpdateErrorHandler(onUpdateError func(key interface{}, err error)) MapCacheOption {
+	return func(m *mapCache) {
+		m.onUpdateError = onUpdateError
+	}
+}
+
+// WithLogger sets the logger of unexpected errors in background routines,
+// slog.Default() is used by default.
+func WithLogger(logger *slog.Logger) MapCacheOption {
+	return func(m *mapCache) {
+		m.logger = logger
+	}
+}
+
+// WithInitialCapacity allocates the map for n keys up front.
+func WithInitialCapacity(n int) MapCacheOption {
+	return func(m *mapCache) {
+		m.initialCapacity = n
+	}
+}
+
+// WithDefaultTTL makes every value that is stored or replaced without a ttl
+// removed after d, like StoreWithExpiration. d must be greater than zero,
+// otherwise storing a value without a ttl fails.
+func WithDefaultTTL(d time.Duration) MapCacheOption {
+	return func(m *mapCache) {
+		m.defaultTTL = d
+		m.hasDefaultTTL = true
+	}
+}
+
+// WithMaxItems limits the number of stored keys, storing a new key when the
+// cache is full returns an AlreadyExists error.
+func WithMaxItems(n int) MapCacheOption {
+	return func(m *mapCache) {
+		m.maxItems = n
+	}
+}
+
+// WithMaxSliceLength limits the slices that are built by Append to n
+// elements, the oldest element is dropped when an element is appended to a
+// full slice.
+func WithMaxSliceLength(n int) MapCacheOption {
+	return func(m *mapCache) {
+		m.maxSliceLength = n
+	}
+}
+
+// WithJanitorInterval sets the interval in which expired values are removed,
+// 100 milliseconds by default.
+func WithJanitorInterval(d time.Duration) MapCacheOption {
+	return func(m *mapCache) {
+		m.janitorInterval = d
+	}
+}
+
+// NewMapCacheWithOptions creates a new UpdatingExpiringCache object that is
+// backed by a map and configured by opts.
+func NewMapCacheWithOptions(opts ...MapCacheOption) UpdatingExpiringCache {
+	return NewMapCache(opts...)
+}
+
+// NewMapCache creates a new Cache object that is backed by a map.
+func NewMapCache(opts ...MapCacheOption) *mapCache {
+	m := &mapCache{
+		cacheMap:        map[interface{}]interface{}{},
+		expirationItems: map[interface{}]*expirationItem{},
+		janitorInterval: defaultJanitorInterval,
+		updateChannels:  map[interface{}]*cacheChannel{},
+		updateFuncs:     map[interface{}]func(currValue interface{}) (interface{}, error){},
+		slidingTTLs:     map[interface{}]time.Duration{},
+		deadlines:       map[interface{}]time.Time{},
+		entrySizes:      map[interface{}]int64{},
+		logger:          slog.Default(),
+		subscribers:     map[interface{}][]chan interface{}{},
+		versions:        map[interface{}]int64{},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.initialCapacity > 0 {
+		m.cacheMap = make(map[interface{}]interface{}, m.initialCapacity)
+	}
+
+	return m
+}
+
+// Store a value in the map, it is permanent unless the cache has a default
+// ttl.
+func (m *mapCache) Store(key, val interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.storeWithDefaultTTL(key, val)
+}
+
+func (m *mapCache) storeWithDefaultTTL(key, val interface{}) error {
+	if m.hasDefaultTTL {
+		return m.storeWithExpiration(key, val, m.defaultTTL)
+	}
+
+	return m.store(key, val)
+}
+
+func (m *mapCache) replaceWithDefaultTTL(key, val interface{}) error {
+	if m.hasDefaultTTL {
+		return m.replaceWithExpiration(key, val, m.defaultTTL)
+	}
+
+	return m.replace(key, val)
+}
+
+func (m *mapCache) store(key, val interface{}) error {
+	m.removeIfExpired(key)
+
+	if _, exists := m.cacheMap[key]; exists {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	return m.setValue(key, val)
+}
+
+// Set a value in the map, the size of the value is verified and tracked if
+// the map has a size limit.
+func (m *mapCache) setValue(key, val interface{}) error {
+	if _, exists := m.cacheMap[key]; !exists && m.maxItems > 0 &&
+		len(m.cacheMap) >= m.maxItems {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("storing key %v would exceed the limit of %d keys",
+				key, m.maxItems))
+	}
+
+	if m.sizeLimit > 0 {
+		entrySize := sizeOf(key) + sizeOf(val)
+		if m.size+entrySize > m.sizeLimit {
+			return newError(errorTypeCapacityExceeded,
+				fmt.Sprintf("storing key %v would exceed the size limit of %d bytes",
+					key, m.sizeLimit))
+		}
+
+		m.entrySizes[key] = entrySize
+		m.size += entrySize
+	}
+
+	m.cacheMap[key] = val
+	m.lastVersion++
+	m.versions[key] = m.lastVersion
+	m.notify(key, val)
+
+	return nil
+}
+
+// Overwrite the value of an existing key, its expiration is kept.
+func (m *mapCache) overwriteValue(key, val interface{}) error {
+	if m.sizeLimit > 0 {
+		entrySize := sizeOf(key) + sizeOf(val)
+		if m.size-m.entrySizes[key]+entrySize > m.sizeLimit {
+			return newError(errorTypeCapacityExceeded,
+				fmt.Sprintf("storing key %v would exceed the size limit of %d bytes",
+					key, m.sizeLimit))
+		}
+
+		m.size += entrySize - m.entrySizes[key]
+		m.entrySizes[key] = entrySize
+	}
+
+	m.cacheMap[key] = val
+	m.lastVersion++
+	m.versions[key] = m.lastVersion
+	m.notify(key, val)
+	m.resetSlidingExpiration(key)
+
+	return nil
+}
+
+// Delete a value from the map, along with its tracked size.
+func (m *mapCache) deleteValue(key interface{}) {
+	if entrySize, exists := m.entrySizes[key]; exists {
+		m.size -= entrySize
+		delete(m.entrySizes, key)
+	}
+
+	delete(m.cacheMap, key)
+	delete(m.versions, key)
+}
+
+// Subscribe to the values of a key, the returned channel receives the new
+// value whenever the key is stored, replaced or updated, until cancel is
+// called. The key does not have to exist.
+//
+// Only the latest value is kept for a subscriber that falls behind.
+func (m *mapCache) Subscribe(key interface{}) (<-chan interface{}, context.CancelFunc, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	c := make(chan interface{}, 1)
+	m.subscribers[key] = append(m.subscribers[key], c)
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+
+			m.unsubscribe(key, c)
+			close(c)
+		})
+	}
+
+	return c, cancel, nil
+}
+
+func (m *mapCache) unsubscribe(key interface{}, c chan interface{}) {
+	subscribers := m.subscribers[key]
+	for i, subscriber := range subscribers {
+		if subscriber == c {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+
+	if len(subscribers) == 0 {
+		delete(m.subscribers, key)
+	} else {
+		m.subscribers[key] = subscribers
+	}
+}
+
+// A notification that is sent once the running transaction succeeds.
+type pendingNotification struct {
+	key interface{}
+	val interface{}
+}
+
+// Notifies the subscribers of a key of its new value, or queues the
+// notification if a transaction is running.
+func (m *mapCache) notify(key, val interface{}) {
+	if m.pendingNotifications != nil {
+		m.pendingNotifications = append(m.pendingNotifications, pendingNotification{key, val})
+		return
+	}
+
+	m.send(key, val)
+}
+
+// Send a new value to the subscribers of a key without blocking, an unread
+// value is replaced.
+func (m *mapCache) send(key, val interface{}) {
+	for _, c := range m.subscribers[key] {
+		select {
+		case c <- val:
+		default:
+			select {
+			case <-c:
+			default:
+			}
+
+			c <- val
+		}
+	}
+}
+
+// Size returns the estimated size of the stored keys and values in bytes.
+func (m *mapCache) Size() int64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	size := int64(0)
+	for key, val := range m.cacheMap {
+		size += sizeOf(key) + sizeOf(val)
+	}
+
+	return size
+}
+
+// Get a value from the map, resets the expiration of sliding values.
+//
+// Only values with a sliding expiration take the write lock.
+func (m *mapCache) Get(key interface{}) (interface{}, error) {
+	m.mutex.RLock()
+	val, err := m.get(key)
+	_, isSliding := m.slidingTTLs[key]
+	isExpired := m.expired(key)
+	m.mutex.RUnlock()
+
+	m.recordLookup(err == nil)
+
+	if isExpired {
+		m.mutex.Lock()
+		m.removeIfExpired(key)
+		m.mutex.Unlock()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !isSliding {
+		return val, nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	val, err = m.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	m.resetSlidingExpiration(key)
+
+	return val, nil
+}
+
+func (m *mapCache) get(key interface{}) (interface{}, error) {
+	if _, exists := m.cacheMap[key]; !exists || m.expired(key) {
+		return nil, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", key))
+	}
+
+	return m.cacheMap[key], nil
+}
+
+// Check whether a key exists in the map.
+func (m *mapCache) Contains(key interface{}) (bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.contains(key)
+}
+
+func (m *mapCache) contains(key interface{}) (bool, error) {
+	_, exists := m.cacheMap[key]
+	return exists && !m.expired(key), nil
+}
+
+// Returns whether the deadline of a value has passed, such values are
+// treated as missing even if the janitor did not remove them yet.
+func (m *mapCache) expired(key interface{}) bool {
+	deadline, exists := m.deadlines[key]
+	return exists && !deadline.After(time.Now())
+}
+
+// Removes a value whose deadline has passed without waiting for the janitor.
+func (m *mapCache) removeIfExpired(key interface{}) {
+	if m.expired(key) {
+		m.stopExpiration(key)
+		m.remove(key)
+	}
+}
+
+// Get a value from the map along with its version, resets the expiration of
+// sliding values.
+func (m *mapCache) GetWithVersion(key interface{}) (interface{}, int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	val, err := m.get(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m.resetSlidingExpiration(key)
+
+	return val, m.versions[key], nil
+}
+
+// Replace a value in the map only if its version is expected, otherwise a
+// VersionMismatch error is returned.
+func (m *mapCache) CompareAndSwap(key interface{}, expected int64, newVal interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	version, exists := m.versions[key]
+	if !exists {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v does not exist", key))
+	}
+
+	if version != expected {
+		return newError(errorTypeVersionMismatch,
+			fmt.Sprintf("key %v is at version %d, expected version %d",
+				key, version, expected))
+	}
+
+	return m.replace(key, newVal)
+}
+
+// Add delta to the int64 value of a key and return the new value, the key is
+// stored with delta if it does not exist. The expiration of the key is kept.
+func (m *mapCache) Increment(key interface{}, delta int64) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.increment(key, delta)
+}
+
+func (m *mapCache) increment(key interface{}, delta int64) (int64, error) {
+	m.removeIfExpired(key)
+
+	current, exists := m.cacheMap[key]
+	if !exists {
+		err := m.storeWithDefaultTTL(key, delta)
+		if err != nil {
+			return 0, err
+		}
+
+		return delta, nil
+	}
+
+	n, isInt64 := current.(int64)
+	if !isInt64 {
+		return 0, newError(errorTypeInvalidValueType,
+			fmt.Sprintf("value of key %v is of type %T, not int64", key, current))
+	}
+
+	n += delta
+	err := m.overwriteValue(key, n)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Subtract delta from the int64 value of a key and return the new value, the
+// key is stored with -delta if it does not exist.
+func (m *mapCache) Decrement(key interface{}, delta int64) (int64, error) {
+	return m.Increment(key, -delta)
+}
+
+// Append an element to the []interface{} value of a key, the key is stored
+// with a slice of the element if it does not exist. The expiration of the key
+// is kept.
+//
+// The slice is copied, so slices that were returned by Get are not modified.
+func (m *mapCache) Append(key interface{}, element interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.append(key, element)
+}
+
+func (m *mapCache) append(key interface{}, element interface{}) error {
+	m.removeIfExpired(key)
+
+	current, exists := m.cacheMap[key]
+	if !exists {
+		return m.storeWithDefaultTTL(key, []interface{}{element})
+	}
+
+	elements, isSlice := current.([]interface{})
+	if !isSlice {
+		return newError(errorTypeInvalidValueType,
+			fmt.Sprintf("value of key %v is of type %T, not []interface{}", key, current))
+	}
+
+	// The oldest elements are dropped to make room for the new one.
+	if m.maxSliceLength > 0 && len(elements) >= m.maxSliceLength {
+		elements = elements[len(elements)-m.maxSliceLength+1:]
+	}
+
+	appended := make([]interface{}, len(elements), len(elements)+1)
+	copy(appended, elements)
+
+	return m.overwriteValue(key, append(appended, element))
+}
+
+// The state that a failed transaction restores.
+type mapCacheSnapshot struct {
+	values      map[interface{}]interface{}
+	entrySizes  map[interface{}]int64
+	size        int64
+	versions    map[interface{}]int64
+	deadlines   map[interface{}]time.Time
+	slidingTTLs map[interface{}]time.Duration
+}
+
+// Transaction calls fn with a view of the map while holding the lock, so the
+// operations of fn are not interleaved with other operations. If fn returns
+// an error, the values and expirations of the map are restored to their state
+// before the transaction and the error is returned. Subscribers are only
+// notified of the values that were written by a successful transaction.
+//
+// fn must only use tx, calling the map itself deadlocks. The map is copied
+// when the transaction starts, and updating values that were removed by a
+// failed transaction are restored without their updates.
+func (m *mapCache) Transaction(fn func(tx TransactionalCache) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	snapshot := m.snapshot()
+	m.pendingNotifications = []pendingNotification{}
+	defer func() {
+		m.pendingNotifications = nil
+	}()
+
+	err := fn(&mapCacheTransaction{m: m})
+	if err != nil {
+		m.rollback(snapshot)
+		return err
+	}
+
+	for _, n := range m.pendingNotifications {
+		m.send(n.key, n.val)
+	}
+
+	return nil
+}
+
+func (m *mapCache) snapshot() mapCacheSnapshot {
+	snapshot := mapCacheSnapshot{
+		values:      make(map[interface{}]interface{}, len(m.cacheMap)),
+		entrySizes:  make(map[interface{}]int64, len(m.entrySizes)),
+		size:        m.size,
+		versions:    make(map[interface{}]int64, len(m.versions)),
+		deadlines:   make(map[interface{}]time.Time, len(m.deadlines)),
+		slidingTTLs: make(map[interface{}]time.Duration, len(m.slidingTTLs)),
+	}
+
+	for key, val := range m.cacheMap {
+		snapshot.values[key] = val
+	}
+	for key, entrySize := range m.entrySizes {
+		snapshot.entrySizes[key] = entrySize
+	}
+	for key, version := range m.versions {
+		snapshot.versions[key] = version
+	}
+	for key, deadline := range m.deadlines {
+		snapshot.deadlines[key] = deadline
+	}
+	for key, ttl := range m.slidingTTLs {
+		snapshot.slidingTTLs[key] = ttl
+	}
+
+	return snapshot
+}
+
+// Restore the state of a snapshot, the versions of the latest writes are not
+// reused.
+func (m *mapCache) rollback(snapshot mapCacheSnapshot) {
+	for key := range m.deadlines {
+		if _, existed := snapshot.deadlines[key]; !existed {
+			m.stopExpiration(key)
+		}
+	}
+
+	for key, deadline := range snapshot.deadlines {
+		if current, exists := m.deadlines[key]; !exists || !current.Equal(deadline) {
+			m.setDeadline(key, deadline)
+		}
+	}
+
+	m.cacheMap = snapshot.values
+	m.entrySizes = snapshot.entrySizes
+	m.size = snapshot.size
+	m.versions = snapshot.versions
+	m.slidingTTLs = snapshot.slidingTTLs
+}
+
+// The view of a map inside a transaction, the lock of the map is already held.
+type mapCacheTransaction struct {
+	m *mapCache
+}
+
+var _ TransactionalCache = (*mapCacheTransaction)(nil)
+
+// Get a value from the map.
+func (tx *mapCacheTransaction) Get(key interface{}) (interface{}, error) {
+	return tx.m.lookup(key)
+}
+
+// Check whether a key exists in the map.
+func (tx *mapCacheTransaction) Contains(key interface{}) (bool, error) {
+	return tx.m.contains(key)
+}
+
+// Get the keys of the map.
+func (tx *mapCacheTransaction) Keys() ([]interface{}, error) {
+	return tx.m.keys()
+}
+
+// Store a value in the map, it is permanent unless the cache has a default
+// ttl.
+func (tx *mapCacheTransaction) Store(key, val interface{}) error {
+	return tx.m.storeWithDefaultTTL(key, val)
+}
+
+// Remove a value from the map.
+func (tx *mapCacheTransaction) Remove(key interface{}) error {
+	return tx.m.remove(key)
+}
+
+// Replace a value in the map, the new value is permanent unless the cache has
+// a default ttl.
+func (tx *mapCacheTransaction) Replace(key, val interface{}) error {
+	return tx.m.replaceWithDefaultTTL(key, val)
+}
+
+// Clear the map.
+func (tx *mapCacheTransaction) Clear() error {
+	return tx.m.clear()
+}
+
+// Get a value from the map, or store val if the key does not exist.
+func (tx *mapCacheTransaction) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	return tx.m.getOrStore(key, val)
+}
+
+// Get a value from the map and remove it.
+func (tx *mapCacheTransaction) GetAndRemove(key interface{}) (interface{}, error) {
+	return tx.m.getAndRemove(key)
+}
+
+// Store a value in the map, replacing the current value of the key if it
+// exists. The value is permanent unless the cache has a default ttl.
+func (tx *mapCacheTransaction) StoreOrReplace(key, val interface{}) error {
+	return tx.m.storeOrReplace(key, val)
+}
+
+// Store several values in the map, they are permanent unless the cache has a
+// default ttl.
+func (tx *mapCacheTransaction) StoreMany(items map[interface{}]interface{}) error {
+	return storeMany(items, tx.m.storeWithDefaultTTL)
+}
+
+// Get several values from the map.
+func (tx *mapCacheTransaction) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	return getMany(keys, tx.m.lookup)
+}
+
+// Calls fn for each key and value in the map until fn returns false.
+func (tx *mapCacheTransaction) ForEach(fn func(key, val interface{}) bool) error {
+	return tx.m.forEach(fn)
+}
+
+// Get the keys for which predicate returns true.
+func (tx *mapCacheTransaction) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	return tx.m.filterKeys(predicate)
+}
+
+// Get a value from the map, or store val if the key does not exist.
+func (m *mapCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.getOrStore(key, val)
+}
+
+func (m *mapCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	m.removeIfExpired(key)
+
+	actual, exists := m.cacheMap[key]
+	m.recordLookup(exists)
+
+	if exists {
+		m.resetSlidingExpiration(key)
+		return actual, true, nil
+	}
+
+	err := m.storeWithDefaultTTL(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a value from the map.
+func (m *mapCache) Remove(key interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.remove(key)
+}
+
+func (m *mapCache) remove(key interface{}) error {
+	_, err := m.get(key)
+	if err != nil {
+		m.removeIfExpired(key)
+		return err
+	}
+
+	m.stopExpiration(key)
+
+	c, exists := m.updateChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(m.updateChannels, key)
+		delete(m.updateFuncs, key)
+	}
+
+	delete(m.slidingTTLs, key)
+	m.deleteValue(key)
+
+	return nil
+}
+
+// Remove several values from the map at once, returns the removed keys along
+// with a partial failure for the keys that could not be removed.
+func (m *mapCache) BulkRemove(keys []interface{}) ([]interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return removeMany(keys, m.remove)
+}
+
+// Get a value from the map and remove it.
+func (m *mapCache) GetAndRemove(key interface{}) (interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.getAndRemove(key)
+}
+
+func (m *mapCache) getAndRemove(key interface{}) (interface{}, error) {
+	val, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Replace a value in the map, the new value is permanent unless the cache has
+// a default ttl.
+func (m *mapCache) Replace(key, val interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.replaceWithDefaultTTL(key, val)
+}
+
+func (m *mapCache) replace(key, val interface{}) error {
+	err := m.remove(key)
+	if err != nil {
+		return err
+	}
+
+	err = m.store(key, val)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Store a value in the map, replacing the current value of the key if it
+// exists. The value is permanent unless the cache has a default ttl.
+func (m *mapCache) StoreOrReplace(key, val interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.storeOrReplace(key, val)
+}
+
+func (m *mapCache) storeOrReplace(key, val interface{}) error {
+	err := m.replaceWithDefaultTTL(key, val)
+	if IsDoesNotExist(err) {
+		return m.storeWithDefaultTTL(key, val)
+	}
+
+	return err
+}
+
+// Store several values in the map, they are permanent unless the cache has a
+// default ttl.
+func (m *mapCache) StoreMany(items map[interface{}]interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return storeMany(items, m.storeWithDefaultTTL)
+}
+
+// Get several values from the map.
+func (m *mapCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return getMany(keys, m.lookup)
+}
+
+// Get a value, record the lookup and reset the expiration of sliding values,
+// the write lock must be held.
+func (m *mapCache) lookup(key interface{}) (interface{}, error) {
+	val, err := m.get(key)
+	m.recordLookup(err == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m.resetSlidingExpiration(key)
+
+	return val, nil
+}
+
+// WarmUp stores several permanent values in the map at once, no value is
+// stored if any of the keys is already in use or if the values exceed the size
+// limit.
+func (m *mapCache) WarmUp(entries map[interface{}]interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for key := range entries {
+		if _, exists := m.cacheMap[key]; exists && !m.expired(key) {
+			return newError(errorTypeAlreadyExists,
+				fmt.Sprintf("key %v is already in use", key))
+		}
+	}
+
+	for key := range entries {
+		m.removeIfExpired(key)
+	}
+
+	return m.setValues(entries)
+}
+
+// CopyTo stores all values of the map in dst as permanent values, the map is
+// locked until all values are copied, so dst must not be the map itself.
+func (m *mapCache) CopyTo(dst Cache) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	items := make(map[interface{}]interface{}, len(m.cacheMap))
+	m.forEach(func(key, val interface{}) bool {
+		items[key] = val
+		return true
+	})
+
+	return storeMany(items, dst.Store)
+}
+
+type snapshotEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Snapshot writes all values in the map to w using gob, custom key and value
+// types must be registered with gob.Register.
+//
+// Expiration and update metadata is not written, values are restored as
+// permanent values.
+func (m *mapCache) Snapshot(w io.Writer) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entries := []snapshotEntry{}
+	m.forEach(func(key, val interface{}) bool {
+		entries = append(entries, snapshotEntry{key, val})
+		return true
+	})
+
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// Restore populates an empty map with the values of a snapshot that was
+// written by Snapshot, all restored values are permanent.
+func (m *mapCache) Restore(r io.Reader) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.cacheMap) > 0 {
+		return newError(errorTypeCacheNotEmpty, "cannot restore into a non empty cache")
+	}
+
+	entries := []snapshotEntry{}
+	err := gob.NewDecoder(r).Decode(&entries)
+	if err != nil {
+		return err
+	}
+
+	values := map[interface{}]interface{}{}
+	for _, entry := range entries {
+		values[entry.Key] = entry.Value
+	}
+
+	return m.setValues(values)
+}
+
+type exportEntry struct {
+	Key       interface{} `json:"key"`
+	Value     interface{} `json:"value"`
+	ExpiresAt *time.Time  `json:"expiresAt,omitempty"`
+}
+
+// Export writes all values in the map to w as NDJSON, one line per entry in
+// the format {"key":...,"value":...,"expiresAt":"RFC3339"}. expiresAt is
+// omitted for permanent values.
+//
+// Keys and values must be encodable as JSON, update metadata is not written.
+func (m *mapCache) Export(w io.Writer) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	enc := json.NewEncoder(w)
+	for key, val := range m.cacheMap {
+		if m.expired(key) {
+			continue
+		}
+
+		entry := exportEntry{Key: key, Value: val}
+		if deadline, exists := m.deadlines[key]; exists {
+			entry.ExpiresAt = &deadline
+		}
+
+		err := enc.Encode(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Import populates an empty map with the entries that were written by
+// Export, entries whose expiresAt has passed are skipped. The other entries
+// keep their deadlines, sliding values are imported as temporary values.
+//
+// Keys and values are decoded as JSON into interface{}, so numbers are
+// decoded as float64.
+func (m *mapCache) Import(r io.Reader) (loaded, skipped int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.cacheMap) > 0 {
+		return 0, 0, newError(errorTypeCacheNotEmpty, "cannot import into a non empty cache")
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		entry := exportEntry{}
+		err = dec.Decode(&entry)
+		if err == io.EOF {
+			return loaded, skipped, nil
+		}
+		if err != nil {
+			return loaded, skipped, err
+		}
+
+		// Objects and arrays cannot be used as map keys.
+		switch entry.Key.(type) {
+		case map[string]interface{}, []interface{}:
+			return loaded, skipped, newError(errorTypeInvalidKeyType,
+				fmt.Sprintf("key %v cannot be used as a map key", entry.Key))
+		}
+
+		if entry.ExpiresAt == nil {
+			err = m.store(entry.Key, entry.Value)
+		} else if ttl := time.Until(*entry.ExpiresAt); ttl > 0 {
+			err = m.storeWithExpiration(entry.Key, entry.Value, ttl)
+		} else {
+			skipped++
+			continue
+		}
+
+		if err != nil {
+			return loaded, skipped, err
+		}
+
+		loaded++
+	}
+}
+
+type mapCacheJSONOptions struct {
+	// Maps each key to the type its value is decoded into.
+	registry map[string]reflect.Type
+}
+
+// JSONOption configures NewMapCacheFromJSON.
+type JSONOption func(*mapCacheJSONOptions)
+
+// WithStrictTypes decodes the value of each key into the type registry maps
+// it to, every key must be registered.
+func WithStrictTypes(registry map[string]reflect.Type) JSONOption {
+	return func(o *mapCacheJSONOptions) {
+		o.registry = registry
+	}
+}
+
+// NewMapCacheFromJSON creates a new UpdatingExpiringCache object that is
+// backed by a map and populated with the keys and values of the JSON object
+// that is read from r, all values are permanent.
+//
+// The values are kept as json.RawMessage unless WithStrictTypes is used.
+func NewMapCacheFromJSON(r io.Reader, opts ...JSONOption) (UpdatingExpiringCache, error) {
+	o := mapCacheJSONOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	raw := map[string]json.RawMessage{}
+	err := json.NewDecoder(r).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[interface{}]interface{}, len(raw))
+	for key, data := range raw {
+		if o.registry == nil {
+			entries[key] = data
+			continue
+		}
+
+		valType, exists := o.registry[key]
+		if !exists {
+			return nil, newError(errorTypeInvalidValueType,
+				fmt.Sprintf("no value type is registered for key %v", key))
+		}
+
+		val := reflect.New(valType)
+		err = json.Unmarshal(data, val.Interface())
+		if err != nil {
+			return nil, newWrapperError(errorTypeInvalidValueType,
+				fmt.Sprintf("value of key %v cannot be decoded into %v", key, valType), err)
+		}
+
+		entries[key] = val.Elem().Interface()
+	}
+
+	m := NewMapCache()
+	err = m.WarmUp(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Set several values in the map, no value is set if they exceed the size
+// limit together. Subscribers are notified once all values are set.
+func (m *mapCache) setValues(values map[interface{}]interface{}) error {
+	inTransaction := m.pendingNotifications != nil
+	if !inTransaction {
+		m.pendingNotifications = []pendingNotification{}
+		defer func() {
+			m.pendingNotifications = nil
+		}()
+	}
+	queued := len(m.pendingNotifications)
+
+	setKeys := []interface{}{}
+	for key, val := range values {
+		err := m.setValue(key, val)
+		if err != nil {
+			for _, setKey := range setKeys {
+				m.deleteValue(setKey)
+			}
+			m.pendingNotifications = m.pendingNotifications[:queued]
+
+			return err
+		}
+
+		setKeys = append(setKeys, key)
+	}
+
+	if !inTransaction {
+		for _, n := range m.pendingNotifications {
+			m.send(n.key, n.val)
+		}
+	}
+
+	return nil
+}
+
+// Clear the map.
+func (m *mapCache) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.clear()
+}
+
+func (m *mapCache) clear() error {
+	for key := range m.cacheMap {
+		// Expired values are removed as well, instead of being missing.
+		m.stopExpiration(key)
+
+		err := m.remove(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get cache keys.
+func (m *mapCache) Keys() ([]interface{}, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.keys()
+}
+
+// Calls fn for each key and value in the map until fn returns false.
+func (m *mapCache) ForEach(fn func(key, val interface{}) bool) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.forEach(fn)
+}
+
+func (m *mapCache) forEach(fn func(key, val interface{}) bool) error {
+	for key, val := range m.cacheMap {
+		if m.expired(key) {
+			continue
+		}
+
+		if !fn(key, val) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Get the keys for which predicate returns true.
+func (m *mapCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.filterKeys(predicate)
+}
+
+func (m *mapCache) filterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	keys := []interface{}{}
+	for key := range m.cacheMap {
+		if !m.expired(key) && predicate(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Get a snapshot of all keys and values in the map.
+func (m *mapCache) GetAll() (map[interface{}]interface{}, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	all := make(map[interface{}]interface{}, len(m.cacheMap))
+	for key, val := range m.cacheMap {
+		if !m.expired(key) {
+			all[key] = val
+		}
+	}
+
+	return all, nil
+}
+
+func (m *mapCache) keys() ([]interface{}, error) {
+	keys := []interface{}{}
+
+	for key := range m.cacheMap {
+		if !m.expired(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Get limit keys starting at offset along with the total number of keys, keys
+// are ordered by their string representation so that pages are stable.
+func (m *mapCache) KeysPage(offset, limit int) ([]interface{}, int, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	keys, err := m.keys()
+	if err != nil {
+		return nil, 0, err
+	}
 
-	// Holds the channels that stop the auto update routines.
-	updateChannels map[interface{}]*cacheChannel
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
 
-	mutex sync.Mutex
+	return keysPage(keys, offset, limit)
 }
 
-var _ UpdatingExpiringCache = (*mapCache)(nil)
+// Store a temporary value in the map, ttl must be greater than zero.
+func (m *mapCache) StoreWithExpiration(key, val interface{}, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-// NewMapCache creates a new Cache object that is backed by a map.
-func NewMapCache() *mapCache {
-	return &mapCache{
-		cacheMap:       map[interface{}]interface{}{},
-		removeChannels: map[interface{}]*cacheChannel{},
-		updateChannels: map[interface{}]*cacheChannel{},
-	}
+	return m.storeWithExpiration(key, val, ttl)
 }
 
-// Store permanent value in the map.
-func (m *mapCache) Store(key, val interface{}) error {
+// Store a temporary value in the map that is removed at deadline, deadline
+// must be in the future.
+func (m *mapCache) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	return m.store(key, val)
+	ttl, err := ttlUntil(deadline)
+	if err != nil {
+		return err
+	}
+
+	return m.storeWithExpiration(key, val, ttl)
 }
 
-func (m *mapCache) store(key, val interface{}) error {
-	if _, exists := m.cacheMap[key]; exists {
-		return newError(errorTypeAlreadyExists,
-			fmt.Sprintf("key %v is already in use", key))
+func (m *mapCache) storeWithExpiration(key, val interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
 	}
 
-	m.cacheMap[key] = val
+	err := m.store(key, val)
+	if err != nil {
+		return err
+	}
+
+	m.setDeadline(key, time.Now().Add(ttl))
 
 	return nil
 }
 
-// Get a value from the map.
-func (m *mapCache) Get(key interface{}) (interface{}, error) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+// Sets the time in which a value is removed by the janitor.
+func (m *mapCache) setDeadline(key interface{}, deadline time.Time) {
+	m.deadlines[key] = deadline
+
+	if item, exists := m.expirationItems[key]; exists {
+		item.deadline = deadline
+		heap.Fix(&m.expirations, item.index)
+	} else {
+		item := &expirationItem{key: key, deadline: deadline}
+		heap.Push(&m.expirations, item)
+		m.expirationItems[key] = item
+	}
 
-	return m.get(key)
+	m.startJanitor()
 }
 
-func (m *mapCache) get(key interface{}) (interface{}, error) {
-	if _, exists := m.cacheMap[key]; !exists {
-		return nil, newError(errorTypeDoesNotExist,
-			fmt.Sprintf("key %v doesn't exist", key))
+// Makes a value permanent.
+func (m *mapCache) stopExpiration(key interface{}) {
+	if item, exists := m.expirationItems[key]; exists {
+		heap.Remove(&m.expirations, item.index)
+		delete(m.expirationItems, key)
 	}
 
-	return m.cacheMap[key], nil
+	delete(m.deadlines, key)
 }
 
-// Remove a value from the map.
-func (m *mapCache) Remove(key interface{}) error {
+// Start the janitor that removes expired values, it is started on
+// construction and only runs while there are temporary values.
+func (m *mapCache) Start() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	return m.remove(key)
+	m.janitorStopped = false
+	m.startJanitor()
+
+	return nil
 }
 
-func (m *mapCache) remove(key interface{}) error {
-	_, err := m.get(key)
-	if err != nil {
-		return err
-	}
+// Stop the janitor, expired values are kept in memory until Start is called
+// or until they are accessed, but they are not returned.
+func (m *mapCache) Stop() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-	c, exists := m.removeChannels[key]
-	if exists && c != nil {
-		c.signal(abort)
-		delete(m.removeChannels, key)
-	}
+	m.janitorStopped = true
 
-	c, exists = m.updateChannels[key]
-	if exists && c != nil {
-		c.signal(abort)
-		delete(m.updateChannels, key)
+	if m.stopJanitor != nil {
+		close(m.stopJanitor)
+		m.stopJanitor = nil
 	}
 
-	delete(m.cacheMap, key)
-
 	return nil
 }
 
-// Replace a value in the map.
-func (m *mapCache) Replace(key, val interface{}) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+func (m *mapCache) startJanitor() {
+	if m.janitorStopped || m.stopJanitor != nil || m.expirations.Len() == 0 {
+		return
+	}
 
-	return m.replace(key, val)
+	stop := make(chan struct{})
+	m.stopJanitor = stop
+
+	go m.janitor(stop)
 }
 
-func (m *mapCache) replace(key, val interface{}) error {
-	err := m.remove(key)
-	if err != nil {
-		return err
-	}
+// Removes the expired values every janitorInterval, returns once there are no
+// temporary values left so that an idle cache has no running goroutines.
+func (m *mapCache) janitor(stop chan struct{}) {
+	ticker := time.NewTicker(m.janitorInterval)
+	defer ticker.Stop()
 
-	err = m.store(key, val)
-	if err != nil {
-		return err
-	}
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
 
-	return nil
-}
+		m.mutex.Lock()
 
-// Clear the map.
-func (m *mapCache) Clear() error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+		// The janitor was stopped while waiting for the mutex.
+		if m.stopJanitor != stop {
+			m.mutex.Unlock()
+			return
+		}
 
-	return m.clear()
-}
+		m.removeExpired(time.Now())
 
-func (m *mapCache) clear() error {
-	for key := range m.cacheMap {
-		err := m.remove(key)
-		if err != nil {
-			return err
+		if m.expirations.Len() == 0 {
+			m.stopJanitor = nil
+			m.mutex.Unlock()
+			return
 		}
+
+		m.mutex.Unlock()
 	}
+}
 
-	return nil
+func (m *mapCache) removeExpired(now time.Time) {
+	for m.expirations.Len() > 0 && !m.expirations[0].deadline.After(now) {
+		key := m.expirations[0].key
+		m.stopExpiration(key)
+
+		// Ignoring errors here because if the value was already removed
+		// manually we shouldn't care, removing the value stops its updates
+		// as well.
+		m.remove(key)
+	}
 }
 
-// Get cache keys.
-func (m *mapCache) Keys() ([]interface{}, error) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+// Get the remaining ttl of a value in the map.
+func (m *mapCache) TTL(key interface{}) (time.Duration, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
-	return m.keys()
+	return m.ttl(key)
 }
 
-func (m *mapCache) keys() ([]interface{}, error) {
-	keys := []interface{}{}
+func (m *mapCache) ttl(key interface{}) (time.Duration, bool, error) {
+	_, err := m.get(key)
+	if err != nil {
+		return -1, false, err
+	}
 
-	for key := range m.cacheMap {
-		keys = append(keys, key)
+	deadline, hasTTL := m.deadlines[key]
+	if !hasTTL {
+		return -1, false, nil
 	}
 
-	return keys, nil
+	return time.Until(deadline), true, nil
 }
 
-// Store a temporary value in the map, ttl must be greater than zero.
-func (m *mapCache) StoreWithExpiration(key, val interface{}, ttl time.Duration) error {
+// Store a temporary value in the map, every Get resets its ttl, ttl must be
+// greater than zero.
+func (m *mapCache) StoreWithSlidingExpiration(key, val interface{},
+	ttl time.Duration) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	return m.storeWithExpiration(key, val, ttl)
+	return m.storeWithSlidingExpiration(key, val, ttl)
 }
 
-func (m *mapCache) storeWithExpiration(key, val interface{}, ttl time.Duration) error {
-	if ttl <= 0 {
-		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
-	}
-
-	err := m.store(key, val)
+func (m *mapCache) storeWithSlidingExpiration(key, val interface{},
+	ttl time.Duration) error {
+	err := m.storeWithExpiration(key, val, ttl)
 	if err != nil {
 		return err
 	}
 
-	c := newCacheChannel()
-	m.removeChannels[key] = c
-
-	expireSignalerRoutine := func(c *cacheChannel) {
-		<-time.After(ttl)
-		c.signal(proceed)
-	}
+	m.slidingTTLs[key] = ttl
 
-	expireRoutine := func(key interface{}, c *cacheChannel) {
-		msg, ok := <-c.c
-		if !ok || msg == abort {
-			return
-		} else {
-			m.mutex.Lock()
-			defer m.mutex.Unlock()
+	return nil
+}
 
-			// Ignoring errors here because if the value was already
-			// removed manually we shouldn't care
-			delete(m.cacheMap, key)
-		}
+func (m *mapCache) resetSlidingExpiration(key interface{}) {
+	ttl, isSliding := m.slidingTTLs[key]
+	if !isSliding {
+		return
 	}
 
-	go expireSignalerRoutine(c)
-	go expireRoutine(key, c)
-
-	return nil
+	m.setDeadline(key, time.Now().Add(ttl))
 }
 
 // Replace a value in the map with a temporary value, ttl must be greater than zero.
@@ -232,6 +1594,7 @@ func (m *mapCache) replaceWithExpiration(key, val interface{},
 }
 
 // Update the expiration of a value in the map, ttl must be greater than zero.
+// An updating value stops updating and is removed once ttl is over.
 func (m *mapCache) Expire(key interface{}, ttl time.Duration) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -245,27 +1608,28 @@ func (m *mapCache) expire(key interface{}, ttl time.Duration) error {
 			"period must be greater than zero")
 	}
 
-	val, err := m.get(key)
+	_, err := m.get(key)
 	if err != nil {
 		return err
 	}
 
-	err = m.remove(key)
-	if err != nil {
-		return err
+	// The value is kept as is, it only stops updating and sliding.
+	c, exists := m.updateChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(m.updateChannels, key)
+		delete(m.updateFuncs, key)
 	}
 
-	err = m.storeWithExpiration(key, val, ttl)
-	if err != nil {
-		return err
-	}
+	delete(m.slidingTTLs, key)
+	m.setDeadline(key, time.Now().Add(ttl))
 
 	return nil
 }
 
 // Store an updating value in the map.
 func (m *mapCache) StoreWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{}, period time.Duration) error {
+	updateFunc func(currValue interface{}) (interface{}, error), period time.Duration) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -273,24 +1637,21 @@ func (m *mapCache) StoreWithUpdate(key, initialValue interface{},
 }
 
 func (m *mapCache) storeWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
 	period time.Duration) error {
-	if updateFunc == nil {
-		return newError(errorTypeNilUpdateFunc, "updateFunc cannot be nil")
-	}
-
-	if period <= 0 {
-		return newError(errorTypeNonPositivePeriod,
-			"period must be bigger than zero")
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
 	}
 
-	err := m.store(key, initialValue)
+	err = m.store(key, initialValue)
 	if err != nil {
 		return err
 	}
 
 	c := newCacheChannel()
 	m.updateChannels[key] = c
+	m.updateFuncs[key] = updateFunc
 
 	updateSignalerRoutine := func(c *cacheChannel) {
 		<-time.After(period)
@@ -305,22 +1666,49 @@ func (m *mapCache) storeWithUpdate(key, initialValue interface{},
 			m.mutex.Lock()
 			defer m.mutex.Unlock()
 
+			// The update was canceled while waiting for the mutex.
+			if m.updateChannels[key] != c {
+				return
+			}
+
 			currVal, err := m.get(key)
 			if err != nil {
-				panic(newWrapperError(errorTypeUnexpectedError,
-					"an unexpected error occurred a background routine", err))
+				m.unexpectedUpdateError(key, err)
+				return
 			}
 
-			err = m.remove(key)
+			newVal, err := updateFunc(currVal)
 			if err != nil {
-				panic(newWrapperError(errorTypeUnexpectedError,
-					"an unexpected error occurred a background routine", err))
+				if m.onUpdateError != nil {
+					m.onUpdateError(key, err)
+				}
+
+				// Keep the current value until the next update.
+				newVal = currVal
 			}
 
-			err = m.storeWithUpdate(key, updateFunc(currVal), updateFunc, period)
+			// The value should still be removed at its original deadline.
+			deadline, hasDeadline := m.deadlines[key]
+			slidingTTL, isSliding := m.slidingTTLs[key]
+
+			err = m.remove(key)
 			if err != nil {
-				panic(newWrapperError(errorTypeUnexpectedError,
-					"an unexpected error occurred a background routine", err))
+				m.unexpectedUpdateError(key, err)
+				return
+			}
+
+			err = m.storeWithUpdate(key, newVal, updateFunc, period)
+			if IsCapacityExceeded(err) {
+				if m.onUpdateError != nil {
+					m.onUpdateError(key, err)
+				}
+			} else if err != nil {
+				m.unexpectedUpdateError(key, err)
+			} else if hasDeadline {
+				m.setDeadline(key, deadline)
+				if isSliding {
+					m.slidingTTLs[key] = slidingTTL
+				}
 			}
 		}
 	}
@@ -331,9 +1719,157 @@ func (m *mapCache) storeWithUpdate(key, initialValue interface{},
 	return nil
 }
 
+// Store an updating value in the map that is removed after totalTTL, even if
+// it is still being updated.
+func (m *mapCache) StoreWithExpirationAndUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if totalTTL <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	err := m.storeWithUpdate(key, initialValue, updateFunc, updatePeriod)
+	if err != nil {
+		return err
+	}
+
+	m.setDeadline(key, time.Now().Add(totalTTL))
+
+	return nil
+}
+
+// Store an updating value in the map that is removed once it was not read for
+// totalTTL, every Get resets its deadline.
+func (m *mapCache) StoreWithUpdateAndExpiration(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if totalTTL <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	err := m.storeWithUpdate(key, initialValue, updateFunc, updatePeriod)
+	if err != nil {
+		return err
+	}
+
+	m.setDeadline(key, time.Now().Add(totalTTL))
+	m.slidingTTLs[key] = totalTTL
+
+	return nil
+}
+
+// Stop updating a value in the map, the value keeps its current value. A
+// value stored with StoreWithUpdateAndExpiration also stops expiring.
+func (m *mapCache) CancelUpdate(key interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.cancelUpdate(key)
+}
+
+func (m *mapCache) cancelUpdate(key interface{}) error {
+	_, err := m.get(key)
+	if err != nil {
+		return err
+	}
+
+	c, exists := m.updateChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(m.updateChannels, key)
+		delete(m.updateFuncs, key)
+
+		// Its idle deadline only lasts as long as it is updated.
+		if _, isSliding := m.slidingTTLs[key]; isSliding {
+			delete(m.slidingTTLs, key)
+			m.stopExpiration(key)
+		}
+	}
+
+	return nil
+}
+
+// Change the period in which a value is updated, the next update happens
+// newPeriod after the call.
+func (m *mapCache) SetUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.setUpdatePeriod(key, newPeriod)
+}
+
+func (m *mapCache) setUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	if newPeriod <= 0 {
+		return newError(errorTypeNonPositivePeriod,
+			"period must be bigger than zero")
+	}
+
+	currVal, err := m.get(key)
+	if err != nil {
+		return err
+	}
+
+	updateFunc, isUpdating := m.updateFuncs[key]
+	if !isUpdating {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v is not being updated", key))
+	}
+
+	// The value should still be removed at its original deadline.
+	deadline, hasDeadline := m.deadlines[key]
+	slidingTTL, isSliding := m.slidingTTLs[key]
+
+	err = m.remove(key)
+	if err != nil {
+		return err
+	}
+
+	err = m.storeWithUpdate(key, currVal, updateFunc, newPeriod)
+	if err != nil {
+		return err
+	}
+
+	if hasDeadline {
+		m.setDeadline(key, deadline)
+		if isSliding {
+			m.slidingTTLs[key] = slidingTTL
+		}
+	}
+
+	return nil
+}
+
+// Report an unexpected error of an auto update routine, if there is no update
+// error handler the error is logged and the value is removed.
+func (m *mapCache) unexpectedUpdateError(key interface{}, err error) {
+	wrappedErr := newWrapperError(errorTypeUnexpectedError,
+		"an unexpected error occurred a background routine", err)
+	if m.onUpdateError != nil {
+		m.onUpdateError(key, wrappedErr)
+		return
+	}
+
+	m.logError(key, "update", err)
+
+	// The value may have already been removed by the routine.
+	m.remove(key)
+}
+
+// Log an unexpected error of a background routine.
+func (m *mapCache) logError(key interface{}, operation string, err error) {
+	m.logger.Error("an unexpected error occurred a background routine",
+		"key", key, "error", err, "operation", operation)
+}
+
 // Replace a value with a continously updating value.
 func (m *mapCache) ReplaceWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
 	period time.Duration) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -342,18 +1878,14 @@ func (m *mapCache) ReplaceWithUpdate(key, initialValue interface{},
 }
 
 func (m *mapCache) replaceWithUpdate(key, initialValue interface{},
-	updateFunc func(currValue interface{}) interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
 	period time.Duration) error {
-	if updateFunc == nil {
-		return newError(errorTypeNilUpdateFunc, "updateFunc cannot be nil")
-	}
-
-	if period <= 0 {
-		return newError(errorTypeNonPositivePeriod,
-			"period must be greater than zero")
+	err := validateUpdateParams(updateFunc, period)
+	if err != nil {
+		return err
 	}
 
-	err := m.remove(key)
+	err = m.remove(key)
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,13 @@
 package cache
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -54,6 +60,272 @@ var _ = Describe("Map Cache", func() {
 		})
 	})
 
+	Context("Contains", func() {
+		It("should return true for an existing key", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Contains(key)).To(BeTrue())
+		})
+
+		It("should return false for a non-existent key", func() {
+			Expect(c.Contains(nonExistentKey)).To(BeFalse())
+		})
+	})
+
+	Context("CompareAndSwap", func() {
+		var m *mapCache
+
+		BeforeEach(func() {
+			m = c.(*mapCache)
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+		})
+
+		It("should replace a value whose version did not change", func() {
+			_, version, err := m.GetWithVersion(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(m.CompareAndSwap(key, version, "new-val")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new-val"))
+
+			_, newVersion, err := m.GetWithVersion(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(newVersion).To(BeNumerically(">", version))
+		})
+
+		It("should return an error if the value was written since it was read", func() {
+			_, version, err := m.GetWithVersion(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Replace(key, "other-val")).ToNot(HaveOccurred())
+
+			Expect(IsVersionMismatch(m.CompareAndSwap(key, version, "new-val"))).To(BeTrue())
+			Expect(c.Get(key)).To(Equal("other-val"))
+		})
+
+		It("should not reuse the version of a removed value", func() {
+			_, version, err := m.GetWithVersion(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			Expect(IsVersionMismatch(m.CompareAndSwap(key, version, "new-val"))).To(BeTrue())
+		})
+
+		It("should return an error for a non-existent key", func() {
+			Expect(IsDoesNotExist(m.CompareAndSwap(nonExistentKey, 1, val))).To(BeTrue())
+			_, _, err := m.GetWithVersion(nonExistentKey)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Increment", func() {
+		var m *mapCache
+
+		BeforeEach(func() {
+			m = c.(*mapCache)
+		})
+
+		It("should store delta for a non-existent key", func() {
+			Expect(m.Increment(key, 5)).To(Equal(int64(5)))
+			Expect(c.Get(key)).To(Equal(int64(5)))
+		})
+
+		It("should add delta to an existing value", func() {
+			Expect(c.Store(key, int64(5))).ToNot(HaveOccurred())
+			Expect(m.Increment(key, 2)).To(Equal(int64(7)))
+			Expect(m.Decrement(key, 10)).To(Equal(int64(-3)))
+			Expect(c.Get(key)).To(Equal(int64(-3)))
+		})
+
+		It("should return an error for a value that is not an int64", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			_, err := m.Increment(key, 1)
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should keep the expiration of the key", func() {
+			Expect(c.StoreWithExpiration(key, int64(1), time.Hour)).ToNot(HaveOccurred())
+			Expect(m.Increment(key, 1)).To(Equal(int64(2)))
+
+			_, hasTTL, err := m.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+		})
+
+		It("should not lose concurrent increments", func() {
+			done := make(chan struct{})
+			for i := 0; i < 100; i++ {
+				go func() {
+					defer GinkgoRecover()
+					_, err := m.Increment(key, 1)
+					Expect(err).ToNot(HaveOccurred())
+					done <- struct{}{}
+				}()
+			}
+
+			for i := 0; i < 100; i++ {
+				<-done
+			}
+
+			Expect(c.Get(key)).To(Equal(int64(100)))
+		})
+	})
+
+	Context("Append", func() {
+		var m *mapCache
+
+		BeforeEach(func() {
+			m = c.(*mapCache)
+		})
+
+		It("should start a slice for a non-existent key", func() {
+			Expect(m.Append(key, 1)).ToNot(HaveOccurred())
+			Expect(m.Append(key, 2)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal([]interface{}{1, 2}))
+		})
+
+		It("should not modify a slice that was returned by Get", func() {
+			Expect(c.Store(key, []interface{}{1})).ToNot(HaveOccurred())
+			before, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(m.Append(key, 2)).ToNot(HaveOccurred())
+			Expect(before).To(Equal([]interface{}{1}))
+		})
+
+		It("should return an error for a value that is not a slice", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsInvalidValueType(m.Append(key, 1))).To(BeTrue())
+		})
+
+		It("should drop the oldest element of a full slice", func() {
+			m = NewMapCache(WithMaxSliceLength(3))
+			for i := 1; i <= 5; i++ {
+				Expect(m.Append(key, i)).ToNot(HaveOccurred())
+			}
+
+			Expect(m.Get(key)).To(Equal([]interface{}{3, 4, 5}))
+		})
+
+		It("should track the size of the growing slice", func() {
+			m = NewMapCache(WithSizeLimit(200))
+			Expect(m.Append(key, val)).ToNot(HaveOccurred())
+
+			var err error
+			for i := 0; i < 100 && err == nil; i++ {
+				err = m.Append(key, val)
+			}
+
+			Expect(IsCapacityExceeded(err)).To(BeTrue())
+			Expect(m.Size()).To(BeNumerically("<=", 200))
+		})
+	})
+
+	Context("Transaction", func() {
+		var m *mapCache
+
+		BeforeEach(func() {
+			m = c.(*mapCache)
+		})
+
+		It("should apply the operations of a successful transaction", func() {
+			Expect(c.Store(key, 1)).ToNot(HaveOccurred())
+
+			Expect(m.Transaction(func(tx TransactionalCache) error {
+				v, err := tx.Get(key)
+				if err != nil {
+					return err
+				}
+
+				return tx.Replace(key, v.(int)+1)
+			})).ToNot(HaveOccurred())
+
+			Expect(c.Get(key)).To(Equal(2))
+		})
+
+		It("should roll back a failed transaction", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("temp", val, time.Hour)).ToNot(HaveOccurred())
+			failure := fmt.Errorf("failed")
+
+			Expect(m.Transaction(func(tx TransactionalCache) error {
+				Expect(tx.Replace(key, "new-val")).ToNot(HaveOccurred())
+				Expect(tx.Store("new-key", val)).ToNot(HaveOccurred())
+				Expect(tx.Remove("temp")).ToNot(HaveOccurred())
+				return failure
+			})).To(Equal(failure))
+
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Contains("new-key")).To(BeFalse())
+
+			_, hasTTL, err := m.TTL("temp")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+		})
+
+		It("should notify subscribers only after a successful transaction", func() {
+			Expect(c.Store(key, 1)).ToNot(HaveOccurred())
+			values, cancel, err := m.Subscribe(key)
+			Expect(err).ToNot(HaveOccurred())
+			defer cancel()
+
+			Expect(m.Transaction(func(tx TransactionalCache) error {
+				Expect(tx.Replace(key, 2)).ToNot(HaveOccurred())
+				Expect(values).ToNot(Receive())
+				return fmt.Errorf("failed")
+			})).To(HaveOccurred())
+			Expect(values).ToNot(Receive())
+
+			Expect(m.Transaction(func(tx TransactionalCache) error {
+				return tx.Replace(key, 3)
+			})).ToNot(HaveOccurred())
+			Expect(values).To(Receive(Equal(3)))
+		})
+
+		It("should not interleave with other operations", func() {
+			Expect(c.Store(key, 0)).ToNot(HaveOccurred())
+
+			done := make(chan struct{})
+			for i := 0; i < 50; i++ {
+				go func() {
+					defer GinkgoRecover()
+					Expect(m.Transaction(func(tx TransactionalCache) error {
+						v, err := tx.Get(key)
+						if err != nil {
+							return err
+						}
+
+						return tx.Replace(key, v.(int)+1)
+					})).ToNot(HaveOccurred())
+					done <- struct{}{}
+				}()
+			}
+
+			for i := 0; i < 50; i++ {
+				<-done
+			}
+
+			Expect(c.Get(key)).To(Equal(50))
+		})
+	})
+
+	Context("GetOrStore", func() {
+		It("should store a value when the key does not exist", func() {
+			actual, loaded, err := c.GetOrStore(key, val)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeFalse())
+			Expect(actual).To(Equal(val))
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return the existing value when the key exists", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			actual, loaded, err := c.GetOrStore(key, "new-val")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeTrue())
+			Expect(actual).To(Equal(val))
+		})
+	})
+
 	Context("Remove", func() {
 		BeforeEach(func() {
 			c.Store(key, val)
@@ -75,6 +347,32 @@ var _ = Describe("Map Cache", func() {
 		})
 	})
 
+	Context("BulkRemove", func() {
+		It("should remove the existing values and report the others", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": val})).
+				ToNot(HaveOccurred())
+
+			removed, err := c.(*mapCache).BulkRemove([]interface{}{key, "other-key", "non-existent"})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(removed).To(ConsistOf(key, "other-key"))
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("GetAndRemove", func() {
+		It("should return a value and remove it", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.GetAndRemove(key)).To(Equal(val))
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+
+		It("should return an error when attempting to get a non-existent value", func() {
+			_, err := c.GetAndRemove(nonExistentKey)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
 	Context("Replace", func() {
 		BeforeEach(func() {
 			c.Store(key, val)
@@ -105,6 +403,389 @@ var _ = Describe("Map Cache", func() {
 		})
 	})
 
+	Context("StoreOrReplace", func() {
+		It("should store a value when the key does not exist", func() {
+			Expect(c.StoreOrReplace(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should replace a temporary value with a permanent one", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.StoreOrReplace(key, "new")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new"))
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+	})
+
+	Context("StoreMany", func() {
+		It("should store all values", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": "other-val"})).
+				ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Get("other-key")).To(Equal("other-val"))
+		})
+	})
+
+	Context("GetMany", func() {
+		BeforeEach(func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+		})
+
+		It("should return the found values along with a partial failure", func() {
+			vals, err := c.GetMany([]interface{}{key, nonExistentKey})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(vals).To(Equal(map[interface{}]interface{}{key: val}))
+		})
+
+		It("should not return an error when all values are found", func() {
+			vals, err := c.GetMany([]interface{}{key})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vals).To(HaveKeyWithValue(key, val))
+		})
+	})
+
+	Context("HitStats", func() {
+		It("should count the hits and misses of lookups", func() {
+			m := c.(*mapCache)
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			c.Get(key)
+			c.Get(nonExistentKey)
+			c.GetMany([]interface{}{key, nonExistentKey})
+			c.GetOrStore(key, val)
+
+			hits, misses := m.HitStats()
+			Expect(hits).To(Equal(int64(3)))
+			Expect(misses).To(Equal(int64(2)))
+		})
+
+		It("should reset the counters", func() {
+			m := c.(*mapCache)
+			c.Get(nonExistentKey)
+			m.ResetStats()
+
+			hits, misses := m.HitStats()
+			Expect(hits).To(BeZero())
+			Expect(misses).To(BeZero())
+		})
+	})
+
+	Context("WarmUp", func() {
+		It("should store all values", func() {
+			Expect(c.(*mapCache).WarmUp(map[interface{}]interface{}{key: val, "other-key": "other-val"})).
+				ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Get("other-key")).To(Equal("other-val"))
+		})
+
+		It("should not store any value if a key is already in use", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			err := c.(*mapCache).WarmUp(map[interface{}]interface{}{key: val, "other-key": "other-val"})
+			Expect(IsAlreadyExists(err)).To(BeTrue())
+			Expect(c.Contains("other-key")).To(BeFalse())
+		})
+
+		It("should store a value over an expired value that was not removed yet", func() {
+			m := c.(*mapCache)
+			Expect(m.Stop()).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration(key, val, 10*time.Millisecond)).ToNot(HaveOccurred())
+			time.Sleep(30 * time.Millisecond)
+
+			Expect(m.WarmUp(map[interface{}]interface{}{key: "new-val"})).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new-val"))
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+	})
+
+	Context("Snapshot", func() {
+		It("should restore the values of a snapshot as permanent values", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("temp-key", 1, time.Minute)).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			Expect(c.(*mapCache).Snapshot(&buf)).ToNot(HaveOccurred())
+
+			restored := NewMapCache()
+			Expect(restored.Restore(&buf)).ToNot(HaveOccurred())
+			Expect(restored.Get(key)).To(Equal(val))
+			Expect(restored.Get("temp-key")).To(Equal(1))
+
+			_, hasTTL, err := restored.TTL("temp-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+
+		It("should return an error when restoring into a non empty cache", func() {
+			var buf bytes.Buffer
+			Expect(c.(*mapCache).Snapshot(&buf)).ToNot(HaveOccurred())
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.(*mapCache).Restore(&buf)).To(HaveOccurred())
+		})
+	})
+
+	Context("Export", func() {
+		It("should export one JSON line per entry", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("temp-key", 1, time.Minute)).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			Expect(c.(*mapCache).Export(&buf)).ToNot(HaveOccurred())
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			Expect(lines).To(ConsistOf(
+				`{"key":"test-key","value":"test-val"}`,
+				MatchRegexp(`^\{"key":"temp-key","value":1,"expiresAt":"[^"]+"\}$`),
+			))
+		})
+
+		It("should import the exported entries with their deadlines", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("temp-key", 1, time.Minute)).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			Expect(c.(*mapCache).Export(&buf)).ToNot(HaveOccurred())
+
+			imported := NewMapCache()
+			loaded, skipped, err := imported.Import(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(Equal(2))
+			Expect(skipped).To(BeZero())
+
+			Expect(imported.Get(key)).To(Equal(val))
+			Expect(imported.Get("temp-key")).To(Equal(float64(1)))
+
+			remaining, hasTTL, err := imported.TTL("temp-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(remaining).To(BeNumerically("~", time.Minute, time.Second))
+		})
+
+		It("should skip expired entries on import", func() {
+			data := `{"key":"a","value":1}
+{"key":"b","value":2,"expiresAt":"2000-01-01T00:00:00Z"}
+`
+			imported := NewMapCache()
+			loaded, skipped, err := imported.Import(strings.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(Equal(1))
+			Expect(skipped).To(Equal(1))
+			Expect(imported.Keys()).To(ConsistOf("a"))
+		})
+
+		It("should return an error when importing into a non empty cache", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			_, _, err := c.(*mapCache).Import(strings.NewReader(`{"key":"a","value":1}`))
+			Expect(IsCacheNotEmpty(err)).To(BeTrue())
+		})
+
+		It("should return an error for a key that cannot be used as a map key", func() {
+			_, _, err := NewMapCache().Import(strings.NewReader(`{"key":{"a":1},"value":1}`))
+			Expect(IsInvalidKeyType(err)).To(BeTrue())
+		})
+	})
+
+	Context("NewMapCacheFromJSON", func() {
+		It("should keep the values as raw JSON", func() {
+			jc, err := NewMapCacheFromJSON(strings.NewReader(`{"a": 1, "b": {"str": "b"}}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jc.Keys()).To(ConsistOf("a", "b"))
+			Expect(jc.Get("a")).To(Equal(json.RawMessage(`1`)))
+			Expect(jc.Get("b")).To(Equal(json.RawMessage(`{"str": "b"}`)))
+		})
+
+		It("should decode the values into the registered types", func() {
+			jc, err := NewMapCacheFromJSON(strings.NewReader(`{"a": 1, "b": {"str": "b", "int": 2}}`),
+				WithStrictTypes(map[string]reflect.Type{
+					"a": reflect.TypeOf(0),
+					"b": reflect.TypeOf(testStruct{}),
+				}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jc.Get("a")).To(Equal(1))
+			Expect(jc.Get("b")).To(Equal(testStruct{"b", 2}))
+		})
+
+		It("should return an error for an unregistered key", func() {
+			_, err := NewMapCacheFromJSON(strings.NewReader(`{"a": 1}`),
+				WithStrictTypes(map[string]reflect.Type{}))
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+		})
+
+		It("should return an error for a value of another type", func() {
+			_, err := NewMapCacheFromJSON(strings.NewReader(`{"a": "text"}`),
+				WithStrictTypes(map[string]reflect.Type{"a": reflect.TypeOf(0)}))
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+		})
+
+		It("should return an error if the input is not an object", func() {
+			_, err := NewMapCacheFromJSON(strings.NewReader(`[1, 2]`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Size", func() {
+		It("should grow with the stored values", func() {
+			Expect(c.(*mapCache).Size()).To(BeZero())
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			size := c.(*mapCache).Size()
+			Expect(size).To(BeNumerically(">=", len(key)+len(val)))
+
+			Expect(c.Store("slice-key", make([]int64, 100))).ToNot(HaveOccurred())
+			Expect(c.(*mapCache).Size()).To(BeNumerically(">=", size+800))
+		})
+	})
+
+	Context("WithSizeLimit", func() {
+		It("should return an error when a value would exceed the limit", func() {
+			c = NewMapCache(WithSizeLimit(1024))
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsCapacityExceeded(c.Store("big-key", make([]byte, 1024)))).To(BeTrue())
+			Expect(c.Contains("big-key")).To(BeFalse())
+		})
+
+		It("should free space when a value is removed", func() {
+			c = NewMapCache(WithSizeLimit(1024))
+
+			Expect(c.Store(key, make([]byte, 512))).ToNot(HaveOccurred())
+			Expect(IsCapacityExceeded(c.Store("other-key", make([]byte, 512)))).To(BeTrue())
+
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Store("other-key", make([]byte, 512))).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("NewMapCacheWithOptions", func() {
+		It("should pre-allocate the map", func() {
+			c = NewMapCacheWithOptions(WithInitialCapacity(128))
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should remove values that were stored without a ttl after the default ttl", func() {
+			c = NewMapCacheWithOptions(WithDefaultTTL(500 * time.Millisecond))
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+
+		It("should apply the default ttl to every value that is stored without a ttl", func() {
+			c = NewMapCacheWithOptions(WithDefaultTTL(time.Hour))
+			_, _, err := c.GetOrStore(key, val)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1})).ToNot(HaveOccurred())
+			Expect(c.StoreOrReplace("b", 2)).ToNot(HaveOccurred())
+			Expect(c.StoreWithUpdate("c", 3, func(currValue interface{}) (interface{}, error) {
+				return currValue, nil
+			}, time.Hour)).ToNot(HaveOccurred())
+			Expect(c.Replace("c", 4)).ToNot(HaveOccurred())
+
+			for _, k := range []interface{}{key, "a", "b", "c"} {
+				_, hasTTL, err := c.TTL(k)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hasTTL).To(BeTrue())
+			}
+		})
+
+		It("should return an error when storing a new key in a full cache", func() {
+			c = NewMapCacheWithOptions(WithMaxItems(1))
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			Expect(IsAlreadyExists(c.Store(nonExistentKey, val))).To(BeTrue())
+			Expect(c.Replace(key, "other-val")).ToNot(HaveOccurred())
+
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Store(nonExistentKey, val)).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("ForEach", func() {
+		BeforeEach(func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": "other-val"})).
+				ToNot(HaveOccurred())
+		})
+
+		It("should iterate over all values", func() {
+			vals := map[interface{}]interface{}{}
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				vals[key] = val
+				return true
+			})).ToNot(HaveOccurred())
+			Expect(vals).To(Equal(map[interface{}]interface{}{key: val, "other-key": "other-val"}))
+		})
+
+		It("should stop when fn returns false", func() {
+			calls := 0
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				calls++
+				return false
+			})).ToNot(HaveOccurred())
+			Expect(calls).To(Equal(1))
+		})
+	})
+
+	Context("GetAll", func() {
+		It("should return a copy of all values", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": "other-val"})).
+				ToNot(HaveOccurred())
+
+			all, err := c.(*mapCache).GetAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(all).To(Equal(map[interface{}]interface{}{key: val, "other-key": "other-val"}))
+
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(all).To(HaveKey(key))
+		})
+	})
+
+	Context("FilterKeys", func() {
+		It("should return only the keys that satisfy predicate", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{1: val, 2: val, 3: val, 4: val})).
+				ToNot(HaveOccurred())
+
+			Expect(c.FilterKeys(func(k interface{}) bool {
+				return k.(int)%2 == 0
+			})).To(ConsistOf(2, 4))
+		})
+	})
+
+	Context("CopyTo", func() {
+		It("should copy all values to another cache", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": "other-val"})).
+				ToNot(HaveOccurred())
+
+			dst := NewMapCache()
+			Expect(c.(*mapCache).CopyTo(dst)).ToNot(HaveOccurred())
+			Expect(dst.Get(key)).To(Equal(val))
+			Expect(dst.Get("other-key")).To(Equal("other-val"))
+		})
+
+		It("should return a partial failure when some values cannot be stored", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": "other-val"})).
+				ToNot(HaveOccurred())
+
+			dst := NewMapCache()
+			Expect(dst.Store(key, "existing")).ToNot(HaveOccurred())
+
+			Expect(IsPartialFailure(c.(*mapCache).CopyTo(dst))).To(BeTrue())
+			Expect(dst.Get("other-key")).To(Equal("other-val"))
+		})
+	})
+
 	Context("Clear", func() {
 		It("should remove all values", func() {
 			Expect(c.Clear()).ToNot(HaveOccurred())
@@ -126,6 +807,32 @@ var _ = Describe("Map Cache", func() {
 		})
 	})
 
+	Context("KeysPage", func() {
+		BeforeEach(func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2, "c": 3})).ToNot(HaveOccurred())
+		})
+
+		It("should return a sorted page of keys and the total count", func() {
+			keys, total, err := c.(*mapCache).KeysPage(1, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]interface{}{"b"}))
+			Expect(total).To(Equal(3))
+
+			keys, _, err = c.(*mapCache).KeysPage(2, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]interface{}{"c"}))
+
+			keys, _, err = c.(*mapCache).KeysPage(5, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(BeEmpty())
+		})
+
+		It("should return an error for a negative offset or limit", func() {
+			_, _, err := c.(*mapCache).KeysPage(-1, 1)
+			Expect(IsInvalidPage(err)).To(BeTrue())
+		})
+	})
+
 	Context("StoreWithExpiration", func() {
 		It("should add a value", func() {
 			c.StoreWithExpiration(key, val, time.Minute)
@@ -157,6 +864,68 @@ var _ = Describe("Map Cache", func() {
 		})
 	})
 
+	Context("StoreWithDeadline", func() {
+		It("should remove a value at the deadline", func() {
+			Expect(c.StoreWithDeadline(key, val, time.Now().Add(500*time.Millisecond))).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+
+		It("should return an error if the deadline has passed", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithDeadline(key, val, time.Now().Add(-time.Second)))).To(BeTrue())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+	})
+
+	Context("TTL", func() {
+		It("should return the remaining ttl of a temporary value", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			remaining, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(remaining).To(BeNumerically("~", time.Minute, time.Second))
+		})
+
+		It("should return no ttl for a permanent value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			remaining, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+			Expect(remaining).To(BeEquivalentTo(-1))
+		})
+
+		It("should return an error for a non-existent key", func() {
+			_, _, err := c.TTL(nonExistentKey)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("StoreWithSlidingExpiration", func() {
+		It("should not remove a value as long as it is accessed", func() {
+			Expect(c.StoreWithSlidingExpiration(key, val, 2*time.Second)).ToNot(HaveOccurred())
+
+			Consistently(func() bool {
+				v, err := c.Get(key)
+				return v == val && err == nil
+			}, 4*time.Second, 500*time.Millisecond).Should(BeTrue(),
+				"value should not have been removed while being accessed")
+
+			Eventually(func() bool {
+				_, err := c.Get(key)
+				return IsDoesNotExist(err)
+			}, testTimeout, 3*time.Second).Should(BeTrue(),
+				"value was not removed after it was no longer accessed")
+		})
+
+		It("should return an error if ttl is non-positive", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithSlidingExpiration(key, val, 0))).To(BeTrue())
+		})
+	})
+
 	Context("ReplaceWithExpiration", func() {
 		BeforeEach(func() {
 			c.Store(key, val)
@@ -214,12 +983,104 @@ var _ = Describe("Map Cache", func() {
 				return IsDoesNotExist(err)
 			}, testTimeout).Should(BeTrue())
 		})
+
+		It("should stop the updates of an updating value", func() {
+			updatingKey := "updatingKey"
+			Expect(c.StoreWithUpdate(updatingKey, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.Expire(updatingKey, 2*time.Second)).ToNot(HaveOccurred())
+
+			expiredVal, err := c.Get(updatingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			Consistently(func() interface{} {
+				v, _ := c.Get(updatingKey)
+				return v
+			}, time.Second).Should(Equal(expiredVal), "value should not have been updated")
+
+			Eventually(func() bool {
+				_, err := c.Get(updatingKey)
+				return IsDoesNotExist(err)
+			}, testTimeout).Should(BeTrue(), "value should have been removed")
+		})
+	})
+
+	Context("Janitor", func() {
+		It("should remove many temporary values without a goroutine for each", func() {
+			goroutines := runtime.NumGoroutine()
+			for i := 0; i < 1000; i++ {
+				Expect(c.StoreWithExpiration(i, val, 200*time.Millisecond)).ToNot(HaveOccurred())
+			}
+			Expect(runtime.NumGoroutine()).To(BeNumerically("<=", goroutines+1))
+
+			Eventually(func() int {
+				keys, _ := c.Keys()
+				return len(keys)
+			}, testTimeout).Should(BeZero())
+		})
+
+		It("should exit once there are no temporary values", func() {
+			Expect(c.StoreWithExpiration(key, val, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				m := c.(*mapCache)
+				m.mutex.RLock()
+				defer m.mutex.RUnlock()
+
+				return m.stopJanitor == nil
+			}, testTimeout).Should(BeTrue())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+
+		It("should hide expired values while it is stopped", func() {
+			m := c.(*mapCache)
+			Expect(m.Stop()).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration(key, val, 10*time.Millisecond)).ToNot(HaveOccurred())
+			time.Sleep(30 * time.Millisecond)
+
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+			Expect(c.Contains(key)).To(BeFalse())
+			_, _, err = c.TTL(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+			Expect(c.Keys()).To(BeEmpty())
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should clear expired values while it is stopped", func() {
+			Expect(c.(*mapCache).Stop()).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration(key, val, 10*time.Millisecond)).ToNot(HaveOccurred())
+			time.Sleep(30 * time.Millisecond)
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.(*mapCache).cacheMap).To(BeEmpty())
+		})
+
+		It("should remove values at the configured interval", func() {
+			c = NewMapCache(WithJanitorInterval(time.Second))
+			Expect(c.StoreWithExpiration(key, val, 10*time.Millisecond)).ToNot(HaveOccurred())
+
+			cached := func() int {
+				m := c.(*mapCache)
+				m.mutex.RLock()
+				defer m.mutex.RUnlock()
+
+				return len(m.cacheMap)
+			}
+
+			Consistently(cached, 500*time.Millisecond).Should(Equal(1))
+			Expect(c.Contains(key)).To(BeFalse())
+			Eventually(cached, testTimeout).Should(BeZero())
+		})
 	})
 
 	Context("StoreWithUpdate", func() {
 		It("should continuosly update the value after the specified duration", func() {
-			c.StoreWithUpdate(key, 0, func(currValue interface{}) interface{} {
-				return currValue.(int) + 1
+			c.StoreWithUpdate(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
 			}, 1*time.Second)
 
 			Consistently(func() bool {
@@ -240,14 +1101,220 @@ var _ = Describe("Map Cache", func() {
 		It("should return an error if nil updateFunc was provided", func() {
 			Expect(IsNilUpdateFunc(c.StoreWithUpdate(key, val, nil, 0))).To(Equal(true))
 		})
+
+		It("should keep the current value and report the error when updateFunc fails", func() {
+			errs := make(chan error, 10)
+			c = NewMapCache(WithUpdateErrorHandler(func(key interface{}, err error) {
+				errs <- err
+			}))
+
+			updateErr := fmt.Errorf("update failed")
+			Expect(c.StoreWithUpdate(key, val, func(currValue interface{}) (interface{}, error) {
+				return nil, updateErr
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(errs, testTimeout).Should(Receive(Equal(updateErr)))
+			Expect(c.Get(key)).To(Equal(val))
+		})
+	})
+
+	Context("StoreWithExpirationAndUpdate", func() {
+		It("should update a value and remove it after the total ttl", func() {
+			Expect(c.StoreWithExpirationAndUpdate(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 50*time.Millisecond, 500*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Get(key)
+				return v
+			}, testTimeout).Should(BeNumerically(">", 1))
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue(), "the deadline was lost after an update")
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+
+		It("should return an error if the total ttl is non-positive", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithExpirationAndUpdate(key, 0,
+				func(currValue interface{}) (interface{}, error) {
+					return currValue, nil
+				}, time.Second, 0))).To(BeTrue())
+		})
+	})
+
+	Context("StoreWithUpdateAndExpiration", func() {
+		It("should keep a value that is being read past the total ttl", func() {
+			Expect(c.StoreWithUpdateAndExpiration(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 50*time.Millisecond, 300*time.Millisecond)).ToNot(HaveOccurred())
+
+			Consistently(func() error {
+				_, err := c.Get(key)
+				return err
+			}, time.Second, 100*time.Millisecond).ShouldNot(HaveOccurred())
+			Expect(c.Get(key)).To(BeNumerically(">", 1))
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+
+		It("should stop expiring a value once its update is canceled", func() {
+			Expect(c.StoreWithUpdateAndExpiration(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 50*time.Millisecond, 300*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.CancelUpdate(key)).ToNot(HaveOccurred())
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+			Consistently(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, 600*time.Millisecond).Should(BeTrue())
+		})
+
+		It("should return an error if the total ttl is non-positive", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithUpdateAndExpiration(key, 0,
+				func(currValue interface{}) (interface{}, error) {
+					return currValue, nil
+				}, time.Second, 0))).To(BeTrue())
+		})
+	})
+
+	Context("CancelUpdate", func() {
+		It("should stop updating a value without removing it", func() {
+			Expect(c.StoreWithUpdate(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Get(key)
+				return v
+			}, testTimeout).ShouldNot(Equal(0))
+			Expect(c.CancelUpdate(key)).ToNot(HaveOccurred())
+
+			frozen, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(func() interface{} {
+				v, _ := c.Get(key)
+				return v
+			}, 500*time.Millisecond).Should(Equal(frozen))
+		})
+
+		It("should return an error for a non-existent key", func() {
+			Expect(IsDoesNotExist(c.CancelUpdate(nonExistentKey))).To(BeTrue())
+		})
+	})
+
+	Context("SetUpdatePeriod", func() {
+		It("should keep updating a value with the new period", func() {
+			Expect(c.StoreWithUpdate(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, time.Hour)).ToNot(HaveOccurred())
+			Expect(c.SetUpdatePeriod(key, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() interface{} {
+				v, _ := c.Get(key)
+				return v
+			}, testTimeout).Should(BeNumerically(">", 1))
+		})
+
+		It("should keep the original deadline", func() {
+			Expect(c.StoreWithExpirationAndUpdate(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, time.Hour, 500*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(c.SetUpdatePeriod(key, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+
+		It("should return an error for a value that is not being updated", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsDoesNotExist(c.SetUpdatePeriod(key, time.Second))).To(BeTrue())
+			Expect(IsDoesNotExist(c.SetUpdatePeriod(nonExistentKey, time.Second))).To(BeTrue())
+		})
+
+		It("should return an error for a non-positive period", func() {
+			Expect(c.StoreWithUpdate(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue, nil
+			}, time.Hour)).ToNot(HaveOccurred())
+			Expect(IsNonPositivePeriod(c.SetUpdatePeriod(key, 0))).To(BeTrue())
+		})
+	})
+
+	Context("Subscribe", func() {
+		It("should receive stored, replaced and updated values", func() {
+			values, cancel, err := c.(*mapCache).Subscribe(key)
+			Expect(err).ToNot(HaveOccurred())
+			defer cancel()
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Eventually(values).Should(Receive(Equal(val)))
+
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+			Eventually(values).Should(Receive(Equal("new-val")))
+
+			Expect(c.ReplaceWithUpdate(key, 0, func(currValue interface{}) (interface{}, error) {
+				return currValue.(int) + 1, nil
+			}, 100*time.Millisecond)).ToNot(HaveOccurred())
+			Eventually(values).Should(Receive(Equal(0)))
+			Eventually(values, testTimeout).Should(Receive(BeNumerically(">", 0)))
+		})
+
+		It("should keep only the latest value of a slow subscriber", func() {
+			values, cancel, err := c.(*mapCache).Subscribe(key)
+			Expect(err).ToNot(HaveOccurred())
+			defer cancel()
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+
+			Expect(values).To(Receive(Equal("new-val")))
+			Expect(values).ToNot(Receive())
+		})
+
+		It("should close the channel when canceled", func() {
+			values, cancel, err := c.(*mapCache).Subscribe(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			cancel()
+			cancel()
+			Expect(values).To(BeClosed())
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+		})
+
+		It("should not receive the values of a warm up that failed", func() {
+			m := NewMapCache(WithMaxItems(1))
+			values, cancel, err := m.Subscribe(key)
+			Expect(err).ToNot(HaveOccurred())
+			defer cancel()
+			otherValues, cancelOther, err := m.Subscribe("other-key")
+			Expect(err).ToNot(HaveOccurred())
+			defer cancelOther()
+
+			err = m.WarmUp(map[interface{}]interface{}{key: val, "other-key": "other-val"})
+			Expect(IsAlreadyExists(err)).To(BeTrue())
+			Expect(values).ToNot(Receive())
+			Expect(otherValues).ToNot(Receive())
+		})
 	})
 
 	Context("ReplaceWithUpdate", func() {
 		It("should replace and continously update a permanent value", func() {
 			Expect(c.Store(key, val)).ToNot(HaveOccurred())
-			updateFunc := func(currValue interface{}) interface{} {
+			updateFunc := func(currValue interface{}) (interface{}, error) {
 				intVal := currValue.(testStruct).Int
-				return testStruct{"Test", intVal + 1}
+				return testStruct{"Test", intVal + 1}, nil
 			}
 
 			Expect(c.ReplaceWithUpdate(key,
@@ -271,3 +1338,9 @@ var _ = Describe("Map Cache", func() {
 		})
 	})
 })
+
+// About eight reads for every write, reads of different goroutines do not
+// block each other.
+func BenchmarkMapCacheReadHeavy(b *testing.B) {
+	benchmarkCacheReadRatio(b, NewMapCache(), 89)
+}
@@ -0,0 +1,687 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+const (
+	errorTypeMemcachedError errorType = "MemcachedError"
+)
+
+func IsMemcachedError(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeMemcachedError
+}
+
+// Memcached treats expirations longer than 30 days as unix timestamps.
+const memcachedMaxRelativeExpiration = 30 * 24 * time.Hour
+
+// The subset of memcache.Client that MemcachedCache uses.
+type memcachedClient interface {
+	Add(item *memcache.Item) error
+	Get(key string) (*memcache.Item, error)
+	GetMulti(keys []string) (map[string]*memcache.Item, error)
+	Replace(item *memcache.Item) error
+	Set(item *memcache.Item) error
+	Touch(key string, seconds int32) error
+	Delete(key string) error
+}
+
+// MemcachedCache is a client that implements Cache interface.
+type MemcachedCache struct {
+	// This dictionary is maintained in order to keep track of this
+	// instance's keys, memcached has no way of listing its keys.
+	keysSet map[string]struct{}
+
+	// Holds the channels that stop the auto removal routines.
+	removeChannels map[string]*cacheChannel
+
+	// Holds the ttls of keys with a sliding expiration.
+	slidingTTLs map[string]time.Duration
+
+	// Holds the times in which temporary keys expire, memcached does not
+	// expose the remaining ttl of a key.
+	deadlines map[string]time.Time
+
+	client memcachedClient
+
+	mutex sync.Mutex
+}
+
+var _ (ExpiringCache) = (*MemcachedCache)(nil)
+
+// --------------------------------------------------------------------------
+
+// NewMemcachedCache creates and returns a reference to a MemcachedCache
+// instance.
+//
+// Values are stored as bytes, []byte and string values are stored as is and
+// any other value is formatted with fmt. Cached values are returned as strings.
+func NewMemcachedCache(servers []string) *MemcachedCache {
+	return &MemcachedCache{
+		keysSet:        map[string]struct{}{},
+		removeChannels: map[string]*cacheChannel{},
+		slidingTTLs:    map[string]time.Duration{},
+		deadlines:      map[string]time.Time{},
+		client:         memcache.New(servers...),
+	}
+}
+
+func (m *MemcachedCache) store(key, val interface{}, ttl time.Duration) error {
+	strKey := fmt.Sprintf("%v", key)
+
+	err := m.client.Add(newMemcachedItem(strKey, val, ttl))
+	if err == memcache.ErrNotStored {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", strKey))
+	}
+
+	if err != nil {
+		return newError(errorTypeMemcachedError,
+			fmt.Sprintf("could not store key %v: %v", strKey, err))
+	}
+
+	m.keysSet[strKey] = struct{}{}
+
+	return nil
+}
+
+func (m *MemcachedCache) get(key interface{}) (interface{}, error) {
+	strKey := fmt.Sprintf("%v", key)
+
+	if _, ok := m.keysSet[strKey]; !ok {
+		return nil, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("cannot get key %v", strKey))
+	}
+
+	item, err := m.client.Get(strKey)
+	if err == memcache.ErrCacheMiss {
+		// The key was evicted or removed by another client.
+		m.forget(strKey)
+
+		return nil, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", strKey))
+	}
+
+	if err != nil {
+		return nil, newError(errorTypeMemcachedError,
+			fmt.Sprintf("failed to get %v from memcached: %v", strKey, err))
+	}
+
+	return string(item.Value), nil
+}
+
+func (m *MemcachedCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	actual, err := m.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = m.store(key, val, 0)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+func (m *MemcachedCache) remove(key interface{}) error {
+	strKey := fmt.Sprintf("%v", key)
+
+	if _, ok := m.keysSet[strKey]; !ok {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("cannot remove key %v", strKey))
+	}
+
+	err := m.client.Delete(strKey)
+	if err == memcache.ErrCacheMiss {
+		m.forget(strKey)
+
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("could not delete key %v", strKey))
+	}
+
+	if err != nil {
+		return newError(errorTypeMemcachedError,
+			fmt.Sprintf("could not delete key %v: %v", strKey, err))
+	}
+
+	m.forget(strKey)
+
+	return nil
+}
+
+func (m *MemcachedCache) getAndRemove(key interface{}) (interface{}, error) {
+	val, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+func (m *MemcachedCache) replace(key, val interface{}, ttl time.Duration) error {
+	strKey := fmt.Sprintf("%v", key)
+
+	err := m.client.Replace(newMemcachedItem(strKey, val, ttl))
+	if err == memcache.ErrNotStored {
+		m.forget(strKey)
+
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", strKey))
+	}
+
+	if err != nil {
+		return newError(errorTypeMemcachedError,
+			fmt.Sprintf("could not replace key %v: %v", strKey, err))
+	}
+
+	m.stopExpiration(strKey)
+	m.keysSet[strKey] = struct{}{}
+
+	return nil
+}
+
+func (m *MemcachedCache) getMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	vals := map[interface{}]interface{}{}
+	errs := map[interface{}]error{}
+
+	existingKeys := []interface{}{}
+	strKeys := []string{}
+	for _, key := range keys {
+		strKey := fmt.Sprintf("%v", key)
+		if _, ok := m.keysSet[strKey]; !ok {
+			errs[key] = newError(errorTypeDoesNotExist,
+				fmt.Sprintf("cannot get key %v", strKey))
+			continue
+		}
+
+		existingKeys = append(existingKeys, key)
+		strKeys = append(strKeys, strKey)
+	}
+
+	if len(strKeys) == 0 {
+		return vals, combineErrors(errs)
+	}
+
+	items, err := m.client.GetMulti(strKeys)
+	if err != nil {
+		return nil, newError(errorTypeMemcachedError,
+			fmt.Sprintf("failed to get keys from memcached: %v", err))
+	}
+
+	for i, strKey := range strKeys {
+		item, found := items[strKey]
+		if !found {
+			m.forget(strKey)
+			errs[existingKeys[i]] = newError(errorTypeDoesNotExist,
+				fmt.Sprintf("key %v doesn't exist", strKey))
+			continue
+		}
+
+		vals[existingKeys[i]] = string(item.Value)
+		m.resetSlidingExpiration(strKey)
+	}
+
+	return vals, combineErrors(errs)
+}
+
+func (m *MemcachedCache) clear() error {
+	for strKey := range m.keysSet {
+		err := m.client.Delete(strKey)
+		if err != nil && err != memcache.ErrCacheMiss {
+			return newError(errorTypeMemcachedError,
+				fmt.Sprintf("could not delete key %v: %v", strKey, err))
+		}
+
+		m.forget(strKey)
+	}
+
+	return nil
+}
+
+func (m *MemcachedCache) keys() ([]interface{}, error) {
+	strKeys := []string{}
+	for key := range m.keysSet {
+		strKeys = append(strKeys, key)
+	}
+
+	sort.Strings(strKeys)
+
+	keys := []interface{}{}
+	for _, key := range strKeys {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (m *MemcachedCache) storeWithExpiration(key, val interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	err := m.store(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	m.createExpirationRoutine(fmt.Sprintf("%v", key), ttl)
+
+	return nil
+}
+
+func (m *MemcachedCache) replaceWithExpiration(key, val interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	err := m.replace(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	m.createExpirationRoutine(fmt.Sprintf("%v", key), ttl)
+
+	return nil
+}
+
+func (m *MemcachedCache) expire(key interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
+	}
+
+	strKey := fmt.Sprintf("%v", key)
+
+	if _, ok := m.keysSet[strKey]; !ok {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("cannot expire key %v", strKey))
+	}
+
+	err := m.client.Touch(strKey, memcachedExpiration(ttl))
+	if err == memcache.ErrCacheMiss {
+		m.forget(strKey)
+
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", strKey))
+	}
+
+	if err != nil {
+		return newError(errorTypeMemcachedError,
+			fmt.Sprintf("could not expire key %v: %v", strKey, err))
+	}
+
+	m.stopExpiration(strKey)
+	m.createExpirationRoutine(strKey, ttl)
+
+	return nil
+}
+
+func (m *MemcachedCache) ttl(key interface{}) (time.Duration, bool, error) {
+	strKey := fmt.Sprintf("%v", key)
+
+	if _, ok := m.keysSet[strKey]; !ok {
+		return -1, false, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("cannot get ttl of key %v", strKey))
+	}
+
+	deadline, hasTTL := m.deadlines[strKey]
+	if !hasTTL {
+		return -1, false, nil
+	}
+
+	return time.Until(deadline), true, nil
+}
+
+func (m *MemcachedCache) storeWithSlidingExpiration(key, val interface{}, ttl time.Duration) error {
+	err := m.storeWithExpiration(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	m.slidingTTLs[fmt.Sprintf("%v", key)] = ttl
+
+	return nil
+}
+
+func (m *MemcachedCache) resetSlidingExpiration(strKey string) error {
+	ttl, isSliding := m.slidingTTLs[strKey]
+	if !isSliding {
+		return nil
+	}
+
+	err := m.client.Touch(strKey, memcachedExpiration(ttl))
+	if err != nil {
+		return newError(errorTypeMemcachedError,
+			fmt.Sprintf("could not expire key %v: %v", strKey, err))
+	}
+
+	c, exists := m.removeChannels[strKey]
+	if exists && c != nil {
+		c.signal(abort)
+	}
+
+	m.createExpirationRoutine(strKey, ttl)
+
+	return nil
+}
+
+func (m *MemcachedCache) createExpirationRoutine(strKey string, ttl time.Duration) {
+	c := newCacheChannel()
+	m.removeChannels[strKey] = c
+	m.deadlines[strKey] = time.Now().Add(ttl)
+
+	expireSignalerRoutine := func(c *cacheChannel) {
+		<-time.After(ttl)
+		c.signal(proceed)
+	}
+
+	expireRoutine := func(strKey string, c *cacheChannel) {
+		msg, ok := <-c.c
+		if !ok || msg == abort {
+			return
+		}
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		// The expiration was reset while waiting for the mutex.
+		if m.removeChannels[strKey] != c {
+			return
+		}
+
+		// Memcached removes the value by itself.
+		m.forget(strKey)
+	}
+
+	go expireSignalerRoutine(c)
+	go expireRoutine(strKey, c)
+}
+
+// Stop the auto removal routine of a key, if it has one.
+func (m *MemcachedCache) stopExpiration(strKey string) {
+	c, exists := m.removeChannels[strKey]
+	if exists && c != nil {
+		c.signal(abort)
+	}
+
+	delete(m.removeChannels, strKey)
+	delete(m.slidingTTLs, strKey)
+	delete(m.deadlines, strKey)
+}
+
+// Stop tracking a key that is no longer in memcached.
+func (m *MemcachedCache) forget(strKey string) {
+	m.stopExpiration(strKey)
+	delete(m.keysSet, strKey)
+}
+
+func newMemcachedItem(strKey string, val interface{}, ttl time.Duration) *memcache.Item {
+	var data []byte
+	switch v := val.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		data = []byte(fmt.Sprintf("%v", v))
+	}
+
+	return &memcache.Item{
+		Key:        strKey,
+		Value:      data,
+		Expiration: memcachedExpiration(ttl),
+	}
+}
+
+// Convert a ttl to a memcached expiration, memcached expirations have a
+// resolution of a second so shorter ttls are rounded up.
+func memcachedExpiration(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		return 0
+	}
+
+	if ttl > memcachedMaxRelativeExpiration {
+		return int32(time.Now().Add(ttl).Unix())
+	}
+
+	return int32((ttl + time.Second - 1) / time.Second)
+}
+
+// --------------------------------------------------------------------------
+
+// Store permanent value in memcached.
+func (m *MemcachedCache) Store(key, val interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.store(key, val, 0)
+}
+
+// Get returns the value of a key from memcached.
+func (m *MemcachedCache) Get(key interface{}) (interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	val, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.resetSlidingExpiration(fmt.Sprintf("%v", key))
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Contains checks whether a key is maintained by this MemcachedCache instance
+// and is still in memcached.
+func (m *MemcachedCache) Contains(key interface{}) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, err := m.get(key)
+	if IsDoesNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// GetOrStore returns the value of a key, or stores val if the key does not
+// exist.
+func (m *MemcachedCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.getOrStore(key, val)
+}
+
+// Remove a key from memcached.
+func (m *MemcachedCache) Remove(key interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.remove(key)
+}
+
+// GetAndRemove returns the value of a key and removes it from memcached.
+func (m *MemcachedCache) GetAndRemove(key interface{}) (interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.getAndRemove(key)
+}
+
+// Replace the value of an existing key with a permanent value.
+func (m *MemcachedCache) Replace(key, val interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.replace(key, val, 0)
+}
+
+// StoreOrReplace stores a permanent value in memcached whether or not the key
+// already exists.
+func (m *MemcachedCache) StoreOrReplace(key, val interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	strKey := fmt.Sprintf("%v", key)
+
+	err := m.client.Set(newMemcachedItem(strKey, val, 0))
+	if err != nil {
+		return newError(errorTypeMemcachedError,
+			fmt.Sprintf("could not store key %v: %v", strKey, err))
+	}
+
+	m.stopExpiration(strKey)
+	m.keysSet[strKey] = struct{}{}
+
+	return nil
+}
+
+// StoreMany stores several permanent values in memcached.
+func (m *MemcachedCache) StoreMany(items map[interface{}]interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return storeMany(items, func(key, val interface{}) error {
+		return m.store(key, val, 0)
+	})
+}
+
+// GetMany returns the values of several keys using a single request.
+func (m *MemcachedCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.getMany(keys)
+}
+
+// Clear all values that maintained by this MemcachedCache instance.
+func (m *MemcachedCache) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.clear()
+}
+
+// Keys return all keys that maintained by this MemcachedCache instance.
+func (m *MemcachedCache) Keys() ([]interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.keys()
+}
+
+// ForEach calls fn for each key and value that are maintained by this
+// MemcachedCache instance until fn returns false.
+func (m *MemcachedCache) ForEach(fn func(key, val interface{}) bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys, err := m.keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		val, err := m.get(key)
+		if IsDoesNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if !fn(key, val) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Get the keys for which predicate returns true.
+func (m *MemcachedCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys, err := m.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterKeys(keys, predicate), nil
+}
+
+// StoreWithExpiration stores a key-value pair in memcached for limited time.
+func (m *MemcachedCache) StoreWithExpiration(key, val interface{}, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.storeWithExpiration(key, val, ttl)
+}
+
+// StoreWithDeadline stores a key-value pair in memcached until deadline.
+func (m *MemcachedCache) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ttl, err := ttlUntil(deadline)
+	if err != nil {
+		return err
+	}
+
+	return m.storeWithExpiration(key, val, ttl)
+}
+
+// ReplaceWithExpiration replaces a key-value pair in memcached for limited
+// time.
+func (m *MemcachedCache) ReplaceWithExpiration(key, val interface{}, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.replaceWithExpiration(key, val, ttl)
+}
+
+// TTL returns the remaining ttl of a key, as tracked by this instance.
+func (m *MemcachedCache) TTL(key interface{}) (time.Duration, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.ttl(key)
+}
+
+// StoreWithSlidingExpiration stores a key-value pair in memcached that
+// expires after it was not accessed for ttl.
+func (m *MemcachedCache) StoreWithSlidingExpiration(key, val interface{}, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.storeWithSlidingExpiration(key, val, ttl)
+}
+
+// Expire a key-value pair.
+func (m *MemcachedCache) Expire(key interface{}, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.expire(key, ttl)
+}
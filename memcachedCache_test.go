@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// An in-memory stand-in for a memcached server.
+type memcachedClientMock struct {
+	items map[string]*memcache.Item
+
+	// Returned by every call when set.
+	err error
+}
+
+func newMemcachedClientMock() *memcachedClientMock {
+	return &memcachedClientMock{items: map[string]*memcache.Item{}}
+}
+
+func (mock *memcachedClientMock) Add(item *memcache.Item) error {
+	if mock.err != nil {
+		return mock.err
+	}
+
+	if _, exists := mock.items[item.Key]; exists {
+		return memcache.ErrNotStored
+	}
+
+	mock.items[item.Key] = item
+
+	return nil
+}
+
+func (mock *memcachedClientMock) Get(key string) (*memcache.Item, error) {
+	if mock.err != nil {
+		return nil, mock.err
+	}
+
+	item, exists := mock.items[key]
+	if !exists {
+		return nil, memcache.ErrCacheMiss
+	}
+
+	return item, nil
+}
+
+func (mock *memcachedClientMock) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	if mock.err != nil {
+		return nil, mock.err
+	}
+
+	items := map[string]*memcache.Item{}
+	for _, key := range keys {
+		if item, exists := mock.items[key]; exists {
+			items[key] = item
+		}
+	}
+
+	return items, nil
+}
+
+func (mock *memcachedClientMock) Replace(item *memcache.Item) error {
+	if mock.err != nil {
+		return mock.err
+	}
+
+	if _, exists := mock.items[item.Key]; !exists {
+		return memcache.ErrNotStored
+	}
+
+	mock.items[item.Key] = item
+
+	return nil
+}
+
+func (mock *memcachedClientMock) Set(item *memcache.Item) error {
+	if mock.err != nil {
+		return mock.err
+	}
+
+	mock.items[item.Key] = item
+
+	return nil
+}
+
+func (mock *memcachedClientMock) Touch(key string, seconds int32) error {
+	if mock.err != nil {
+		return mock.err
+	}
+
+	item, exists := mock.items[key]
+	if !exists {
+		return memcache.ErrCacheMiss
+	}
+
+	item.Expiration = seconds
+
+	return nil
+}
+
+func (mock *memcachedClientMock) Delete(key string) error {
+	if mock.err != nil {
+		return mock.err
+	}
+
+	if _, exists := mock.items[key]; !exists {
+		return memcache.ErrCacheMiss
+	}
+
+	delete(mock.items, key)
+
+	return nil
+}
+
+var _ = Describe("Memcached Cache", func() {
+	var (
+		c                        *MemcachedCache
+		mock                     *memcachedClientMock
+		key, val, nonExistentKey string = "test-key", "test-val", "non-existent"
+	)
+
+	BeforeEach(func() {
+		c = NewMemcachedCache(nil)
+		mock = newMemcachedClientMock()
+		c.client = mock
+	})
+
+	Context("Store", func() {
+		It("should store a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(mock.items[key].Expiration).To(BeZero())
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(key, val))).To(BeTrue())
+		})
+
+		It("should wrap errors of the memcached client", func() {
+			mock.err = errors.New("connection refused")
+			err := c.Store(key, val)
+			Expect(err).To(HaveOccurred())
+			Expect(IsMemcachedError(err)).To(BeTrue())
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("Get", func() {
+		It("should return an error when attempting to get a non-existent value", func() {
+			_, err := c.Get(nonExistentKey)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+
+		It("should forget a key that was evicted by memcached", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			delete(mock.items, key)
+
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(mock.items).ToNot(HaveKey(key))
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+
+		It("should return an error when attempting to remove a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Remove(nonExistentKey))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a temporary value with a permanent one", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new-val"))
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+			Expect(mock.items[key].Expiration).To(BeZero())
+		})
+
+		It("should return an error when attempting to replace a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Replace(nonExistentKey, val))).To(BeTrue())
+		})
+	})
+
+	Context("StoreOrReplace", func() {
+		It("should store a value or replace the current one", func() {
+			Expect(c.StoreOrReplace(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreWithExpiration("temp", val, time.Minute)).ToNot(HaveOccurred())
+			Expect(c.StoreOrReplace("temp", "new-val")).ToNot(HaveOccurred())
+
+			Expect(c.Get("temp")).To(Equal("new-val"))
+			_, hasTTL, err := c.TTL("temp")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+	})
+
+	Context("GetMany", func() {
+		It("should return the found values along with a partial failure", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": "1", "b": "2"})).ToNot(HaveOccurred())
+
+			vals, err := c.GetMany([]interface{}{"a", "b", nonExistentKey})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(vals).To(Equal(map[interface{}]interface{}{"a": "1", "b": "2"}))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove all the keys of the instance", func() {
+			Expect(c.Store("a", val)).ToNot(HaveOccurred())
+			Expect(c.Store("b", val)).ToNot(HaveOccurred())
+			mock.items["other"] = &memcache.Item{Key: "other"}
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(BeEmpty())
+			Expect(mock.items).To(HaveLen(1), "keys of other clients should not be removed")
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return the keys of the instance", func() {
+			Expect(c.Store("b", val)).ToNot(HaveOccurred())
+			Expect(c.Store("a", val)).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(Equal([]interface{}{"a", "b"}))
+		})
+	})
+
+	Context("StoreWithExpiration", func() {
+		It("should store a value with a memcached expiration", func() {
+			Expect(c.StoreWithExpiration(key, val, 1500*time.Millisecond)).ToNot(HaveOccurred())
+			Expect(mock.items[key].Expiration).To(Equal(int32(2)), "ttl should be rounded up to seconds")
+
+			ttl, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(ttl).To(BeNumerically("<=", 1500*time.Millisecond))
+		})
+
+		It("should stop tracking a value once its ttl is over", func() {
+			Expect(c.StoreWithExpiration(key, val, 100*time.Millisecond)).ToNot(HaveOccurred())
+
+			Eventually(func() []interface{} {
+				keys, _ := c.Keys()
+				return keys
+			}, testTimeout).Should(BeEmpty())
+		})
+
+		It("should return an error for a non-positive ttl", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithExpiration(key, val, 0))).To(BeTrue())
+		})
+	})
+
+	Context("ReplaceWithExpiration", func() {
+		It("should replace a permanent value with a temporary one", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.ReplaceWithExpiration(key, "new-val", time.Minute)).ToNot(HaveOccurred())
+			Expect(mock.items[key].Expiration).To(Equal(int32(60)))
+			Expect(c.Get(key)).To(Equal("new-val"))
+		})
+	})
+
+	Context("Expire", func() {
+		It("should touch a value with the new ttl", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Expire(key, time.Minute)).ToNot(HaveOccurred())
+			Expect(mock.items[key].Expiration).To(Equal(int32(60)))
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+		})
+
+		It("should return an error when attempting to expire a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Expire(nonExistentKey, time.Minute))).To(BeTrue())
+		})
+	})
+
+	Context("StoreWithSlidingExpiration", func() {
+		It("should touch a value whenever it is accessed", func() {
+			Expect(c.StoreWithSlidingExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+			mock.items[key].Expiration = 1
+
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(mock.items[key].Expiration).To(Equal(int32(60)))
+		})
+	})
+})
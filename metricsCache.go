@@ -0,0 +1,451 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type cacheMetrics struct {
+	// The latency of the operations, by operation.
+	durations *prometheus.HistogramVec
+
+	// The number of failed operations, by operation, missing keys are not
+	// counted as failures.
+	errors *prometheus.CounterVec
+
+	// The number of lookups that found their key.
+	hits prometheus.Counter
+
+	// The number of lookups that did not find their key.
+	misses prometheus.Counter
+
+	// The number of cached items, collected from Keys on every scrape.
+	items prometheus.GaugeFunc
+}
+
+func newCacheMetrics(namespace string, underlying Cache) *cacheMetrics {
+	return &cacheMetrics{
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "operation_duration_seconds",
+			Help:      "The latency of cache operations.",
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "errors_total",
+			Help:      "The number of failed cache operations.",
+		}, []string{"operation"}),
+		hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "The number of cache lookups that found their key.",
+		}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "The number of cache lookups that did not find their key.",
+		}),
+		items: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "items",
+			Help:      "The number of cached items.",
+		}, func() float64 {
+			keys, err := underlying.Keys()
+			if err != nil {
+				return 0
+			}
+
+			return float64(len(keys))
+		}),
+	}
+}
+
+func (cm *cacheMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{cm.durations, cm.errors, cm.hits, cm.misses, cm.items}
+}
+
+// Record the latency of an operation and whether it failed.
+func (cm *cacheMetrics) observe(operation string, start time.Time, err error) {
+	cm.durations.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		cm.errors.WithLabelValues(operation).Inc()
+	}
+}
+
+// Record a lookup of a single key, a missing key is counted as a miss rather
+// than a failure.
+func (cm *cacheMetrics) observeLookup(operation string, start time.Time, err error) {
+	switch {
+	case err == nil:
+		cm.hits.Inc()
+	case IsDoesNotExist(err):
+		cm.misses.Inc()
+		err = nil
+	}
+
+	cm.observe(operation, start, err)
+}
+
+// WithEvictionMetrics counts the evicted items of a cache such as lruCache
+// and lfuCache, the counter is registered with reg under namespace and
+// registering it panics if it is already registered.
+func WithEvictionMetrics(namespace string, reg prometheus.Registerer) EvictionOption {
+	evictions := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "The number of evicted cache items.",
+	})
+	reg.MustRegister(evictions)
+
+	return func(o *evictionOptions) {
+		o.countEviction = evictions.Inc
+	}
+}
+
+type metricsCache struct {
+	// The cache that holds the data.
+	underlying Cache
+
+	metrics *cacheMetrics
+}
+
+var _ Cache = (*metricsCache)(nil)
+
+// NewMetricsCache creates a new Cache object that records Prometheus metrics
+// for every operation of underlying, the metrics are registered with reg
+// under namespace and registering them panics if they are already registered.
+//
+// The returned cache also implements ExpiringCache, UpdatingCache or
+// UpdatingExpiringCache when underlying does. Evictions happen inside
+// underlying, so they are counted by passing WithEvictionMetrics to the
+// constructor of underlying.
+func NewMetricsCache(underlying Cache, namespace string, reg prometheus.Registerer) Cache {
+	metrics := newCacheMetrics(namespace, underlying)
+	reg.MustRegister(metrics.collectors()...)
+
+	mc := &metricsCache{
+		underlying: underlying,
+		metrics:    metrics,
+	}
+
+	switch c := underlying.(type) {
+	case UpdatingExpiringCache:
+		return &metricsUpdatingExpiringCache{
+			metricsCache:    mc,
+			expiringMetrics: &expiringMetrics{c, metrics},
+			updatingMetrics: &updatingMetrics{c, metrics},
+			underlying:      c,
+		}
+	case ExpiringCache:
+		return &metricsExpiringCache{mc, &expiringMetrics{c, metrics}}
+	case UpdatingCache:
+		return &metricsUpdatingCache{mc, &updatingMetrics{c, metrics}}
+	}
+
+	return mc
+}
+
+// Store a permanent value.
+func (mc *metricsCache) Store(key, val interface{}) error {
+	start := time.Now()
+	err := mc.underlying.Store(key, val)
+	mc.metrics.observe("store", start, err)
+
+	return err
+}
+
+// Get a value, counted as a hit or a miss.
+func (mc *metricsCache) Get(key interface{}) (interface{}, error) {
+	start := time.Now()
+	val, err := mc.underlying.Get(key)
+	mc.metrics.observeLookup("get", start, err)
+
+	return val, err
+}
+
+// Check whether a key exists.
+func (mc *metricsCache) Contains(key interface{}) (bool, error) {
+	start := time.Now()
+	exists, err := mc.underlying.Contains(key)
+	mc.metrics.observe("contains", start, err)
+
+	return exists, err
+}
+
+// Get a value, or store val if the key does not exist, counted as a hit or a
+// miss.
+func (mc *metricsCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	start := time.Now()
+	actual, loaded, err := mc.underlying.GetOrStore(key, val)
+	if err == nil && !loaded {
+		mc.metrics.misses.Inc()
+		mc.metrics.observe("get_or_store", start, nil)
+	} else {
+		mc.metrics.observeLookup("get_or_store", start, err)
+	}
+
+	return actual, loaded, err
+}
+
+// Remove a value.
+func (mc *metricsCache) Remove(key interface{}) error {
+	start := time.Now()
+	err := mc.underlying.Remove(key)
+	mc.metrics.observe("remove", start, err)
+
+	return err
+}
+
+// Get a value and remove it, counted as a hit or a miss.
+func (mc *metricsCache) GetAndRemove(key interface{}) (interface{}, error) {
+	start := time.Now()
+	val, err := mc.underlying.GetAndRemove(key)
+	mc.metrics.observeLookup("get_and_remove", start, err)
+
+	return val, err
+}
+
+// Replace a value.
+func (mc *metricsCache) Replace(key, val interface{}) error {
+	start := time.Now()
+	err := mc.underlying.Replace(key, val)
+	mc.metrics.observe("replace", start, err)
+
+	return err
+}
+
+// Store a permanent value, replacing the current value if the key exists.
+func (mc *metricsCache) StoreOrReplace(key, val interface{}) error {
+	start := time.Now()
+	err := mc.underlying.StoreOrReplace(key, val)
+	mc.metrics.observe("store_or_replace", start, err)
+
+	return err
+}
+
+// Store several permanent values.
+func (mc *metricsCache) StoreMany(items map[interface{}]interface{}) error {
+	start := time.Now()
+	err := mc.underlying.StoreMany(items)
+	mc.metrics.observe("store_many", start, err)
+
+	return err
+}
+
+// Get several values, each key is counted as a hit or a miss.
+func (mc *metricsCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	start := time.Now()
+	vals, err := mc.underlying.GetMany(keys)
+
+	if err == nil || IsPartialFailure(err) {
+		mc.metrics.hits.Add(float64(len(vals)))
+		mc.metrics.misses.Add(float64(len(keys) - len(vals)))
+	}
+
+	mc.metrics.observe("get_many", start, err)
+
+	return vals, err
+}
+
+// Clear the cache.
+func (mc *metricsCache) Clear() error {
+	start := time.Now()
+	err := mc.underlying.Clear()
+	mc.metrics.observe("clear", start, err)
+
+	return err
+}
+
+// Get all keys.
+func (mc *metricsCache) Keys() ([]interface{}, error) {
+	start := time.Now()
+	keys, err := mc.underlying.Keys()
+	mc.metrics.observe("keys", start, err)
+
+	return keys, err
+}
+
+// Calls fn for each key and value until fn returns false, the latency
+// includes the time spent in fn.
+func (mc *metricsCache) ForEach(fn func(key, val interface{}) bool) error {
+	start := time.Now()
+	err := mc.underlying.ForEach(fn)
+	mc.metrics.observe("for_each", start, err)
+
+	return err
+}
+
+// Get the keys for which predicate returns true, the latency includes the
+// time spent in predicate.
+func (mc *metricsCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	start := time.Now()
+	keys, err := mc.underlying.FilterKeys(predicate)
+	mc.metrics.observe("filter_keys", start, err)
+
+	return keys, err
+}
+
+// Records the metrics of the ExpiringCache methods.
+type expiringMetrics struct {
+	underlying ExpiringCache
+
+	metrics *cacheMetrics
+}
+
+// Store a temporary value.
+func (em *expiringMetrics) StoreWithExpiration(key, val interface{}, ttl time.Duration) error {
+	start := time.Now()
+	err := em.underlying.StoreWithExpiration(key, val, ttl)
+	em.metrics.observe("store_with_expiration", start, err)
+
+	return err
+}
+
+// Store a value that is removed at deadline.
+func (em *expiringMetrics) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	start := time.Now()
+	err := em.underlying.StoreWithDeadline(key, val, deadline)
+	em.metrics.observe("store_with_deadline", start, err)
+
+	return err
+}
+
+// Replace a value with a temporary value.
+func (em *expiringMetrics) ReplaceWithExpiration(key, val interface{}, ttl time.Duration) error {
+	start := time.Now()
+	err := em.underlying.ReplaceWithExpiration(key, val, ttl)
+	em.metrics.observe("replace_with_expiration", start, err)
+
+	return err
+}
+
+// Update the expiration of a value.
+func (em *expiringMetrics) Expire(key interface{}, ttl time.Duration) error {
+	start := time.Now()
+	err := em.underlying.Expire(key, ttl)
+	em.metrics.observe("expire", start, err)
+
+	return err
+}
+
+// Get the remaining ttl of a value.
+func (em *expiringMetrics) TTL(key interface{}) (time.Duration, bool, error) {
+	start := time.Now()
+	ttl, hasTTL, err := em.underlying.TTL(key)
+	em.metrics.observe("ttl", start, err)
+
+	return ttl, hasTTL, err
+}
+
+// Store a temporary value with a sliding expiration.
+func (em *expiringMetrics) StoreWithSlidingExpiration(key, val interface{}, ttl time.Duration) error {
+	start := time.Now()
+	err := em.underlying.StoreWithSlidingExpiration(key, val, ttl)
+	em.metrics.observe("store_with_sliding_expiration", start, err)
+
+	return err
+}
+
+// Records the metrics of the UpdatingCache methods.
+type updatingMetrics struct {
+	underlying UpdatingCache
+
+	metrics *cacheMetrics
+}
+
+// Store a continuously updating value.
+func (um *updatingMetrics) StoreWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	start := time.Now()
+	err := um.underlying.StoreWithUpdate(key, initialValue, updateFunc, period)
+	um.metrics.observe("store_with_update", start, err)
+
+	return err
+}
+
+// Replace a value with a continuously updating value.
+func (um *updatingMetrics) ReplaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	start := time.Now()
+	err := um.underlying.ReplaceWithUpdate(key, initialValue, updateFunc, period)
+	um.metrics.observe("replace_with_update", start, err)
+
+	return err
+}
+
+// Stop updating a value.
+func (um *updatingMetrics) CancelUpdate(key interface{}) error {
+	start := time.Now()
+	err := um.underlying.CancelUpdate(key)
+	um.metrics.observe("cancel_update", start, err)
+
+	return err
+}
+
+// Change the period in which a value is updated.
+func (um *updatingMetrics) SetUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	start := time.Now()
+	err := um.underlying.SetUpdatePeriod(key, newPeriod)
+	um.metrics.observe("set_update_period", start, err)
+
+	return err
+}
+
+type metricsExpiringCache struct {
+	*metricsCache
+	*expiringMetrics
+}
+
+var _ ExpiringCache = (*metricsExpiringCache)(nil)
+
+type metricsUpdatingCache struct {
+	*metricsCache
+	*updatingMetrics
+}
+
+var _ UpdatingCache = (*metricsUpdatingCache)(nil)
+
+type metricsUpdatingExpiringCache struct {
+	*metricsCache
+	*expiringMetrics
+	*updatingMetrics
+
+	underlying UpdatingExpiringCache
+}
+
+var _ UpdatingExpiringCache = (*metricsUpdatingExpiringCache)(nil)
+
+// Store a continuously updating value that is removed after totalTTL.
+func (muec *metricsUpdatingExpiringCache) StoreWithExpirationAndUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	start := time.Now()
+	err := muec.underlying.StoreWithExpirationAndUpdate(key, initialValue, updateFunc,
+		updatePeriod, totalTTL)
+	muec.metricsCache.metrics.observe("store_with_expiration_and_update", start, err)
+
+	return err
+}
+
+// Store a continuously updating value that is removed once it was not read for
+// totalTTL.
+func (muec *metricsUpdatingExpiringCache) StoreWithUpdateAndExpiration(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	start := time.Now()
+	err := muec.underlying.StoreWithUpdateAndExpiration(key, initialValue, updateFunc,
+		updatePeriod, totalTTL)
+	muec.metricsCache.metrics.observe("store_with_update_and_expiration", start, err)
+
+	return err
+}
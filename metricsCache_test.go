@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Metrics Cache", func() {
+	var (
+		c   Cache
+		reg *prometheus.Registry
+	)
+
+	BeforeEach(func() {
+		reg = prometheus.NewRegistry()
+		c = NewMetricsCache(NewMapCache(), "test", reg)
+	})
+
+	metricValue := func(name string, operation string) float64 {
+		families, err := reg.Gather()
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "operation" && label.GetValue() == operation {
+						if metric.GetHistogram() != nil {
+							return float64(metric.GetHistogram().GetSampleCount())
+						}
+
+						return metric.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+
+		return 0
+	}
+
+	Context("Get", func() {
+		It("should count hits and misses", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Get("key")).To(Equal("val"))
+			_, err := c.Get("non-existent")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+
+			metrics := c.(*metricsUpdatingExpiringCache).metricsCache.metrics
+			Expect(testutil.ToFloat64(metrics.hits)).To(Equal(1.0))
+			Expect(testutil.ToFloat64(metrics.misses)).To(Equal(1.0))
+			Expect(metricValue("test_cache_errors_total", "get")).To(BeZero(),
+				"a miss should not be counted as an error")
+			Expect(metricValue("test_cache_operation_duration_seconds", "get")).To(Equal(2.0))
+		})
+	})
+
+	Context("Store", func() {
+		It("should count failed operations", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store("key", "val"))).To(BeTrue())
+
+			Expect(metricValue("test_cache_errors_total", "store")).To(Equal(1.0))
+			Expect(metricValue("test_cache_operation_duration_seconds", "store")).To(Equal(2.0))
+		})
+	})
+
+	Context("WithEvictionMetrics", func() {
+		It("should count the evicted items along with the eviction callback", func() {
+			evicted := []interface{}{}
+			lru, err := NewLru(1, WithEvictionMetrics("lru", reg),
+				WithEvictionCallback(func(key, val interface{}) {
+					evicted = append(evicted, key)
+				}))
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 3; i++ {
+				Expect(lru.Store(i, i)).ToNot(HaveOccurred())
+			}
+
+			families, err := reg.Gather()
+			Expect(err).ToNot(HaveOccurred())
+
+			evictions := 0.0
+			for _, family := range families {
+				if family.GetName() == "lru_cache_evictions_total" {
+					evictions = family.GetMetric()[0].GetCounter().GetValue()
+				}
+			}
+			Expect(evictions).To(Equal(2.0))
+			Expect(evicted).To(Equal([]interface{}{0, 1}))
+		})
+	})
+
+	Context("Items", func() {
+		It("should report the number of cached items", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2})).ToNot(HaveOccurred())
+			Expect(testutil.ToFloat64(c.(*metricsUpdatingExpiringCache).metricsCache.metrics.items)).To(Equal(2.0))
+		})
+	})
+
+	Context("NewMetricsCache", func() {
+		It("should implement the extended interfaces of the underlying cache", func() {
+			_, isUpdatingExpiring := c.(UpdatingExpiringCache)
+			Expect(isUpdatingExpiring).To(BeTrue())
+
+			lec, err := NewLruWithExpiration(2)
+			Expect(err).ToNot(HaveOccurred())
+
+			ec := NewMetricsCache(lec, "lru_expiring", reg)
+			_, isExpiring := ec.(ExpiringCache)
+			Expect(isExpiring).To(BeTrue())
+			_, isUpdating := ec.(UpdatingCache)
+			Expect(isUpdating).To(BeFalse())
+
+			lru, err := NewLru(2)
+			Expect(err).ToNot(HaveOccurred())
+
+			lc := NewMetricsCache(lru, "lru", reg)
+			_, isExpiring = lc.(ExpiringCache)
+			Expect(isExpiring).To(BeFalse())
+		})
+
+		It("should record the operations of the extended interfaces", func() {
+			ec := c.(ExpiringCache)
+			Expect(ec.StoreWithExpiration("key", "val", time.Minute)).ToNot(HaveOccurred())
+			Expect(metricValue("test_cache_operation_duration_seconds", "store_with_expiration")).To(Equal(1.0))
+		})
+	})
+})
@@ -0,0 +1,324 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	errorTypeMultiLevelError errorType = "MultiLevelError"
+)
+
+func IsMultiLevelError(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeMultiLevelError
+}
+
+type multiLevelCache struct {
+	// The caches that hold the data, from the fastest to the slowest.
+	levels []Cache
+}
+
+var _ Cache = (*multiLevelCache)(nil)
+
+// NewMultiLevelCache creates a new Cache object that chains levels, from the
+// fastest (L1) to the slowest.
+//
+// Values are written to all levels, and values that are found in a lower
+// level are promoted back into the levels above it.
+func NewMultiLevelCache(levels ...Cache) Cache {
+	return &multiLevelCache{
+		levels: levels,
+	}
+}
+
+// Store a permanent value in all levels.
+func (mlc *multiLevelCache) Store(key, val interface{}) error {
+	errs := map[int]error{}
+
+	for i, level := range mlc.levels {
+		err := level.Store(key, val)
+		if err != nil {
+			errs[i] = err
+		}
+	}
+
+	return mlc.combineErrors(errs)
+}
+
+// Get a value from the highest level that holds it, the value is promoted into
+// the levels above it.
+//
+// Failing to promote a value does not fail Get, the value stays in the lower
+// level.
+func (mlc *multiLevelCache) Get(key interface{}) (interface{}, error) {
+	errs := map[int]error{}
+
+	for i, level := range mlc.levels {
+		val, err := level.Get(key)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		for _, higherLevel := range mlc.levels[:i] {
+			higherLevel.Store(key, val)
+		}
+
+		return val, nil
+	}
+
+	return nil, mlc.combineErrors(errs)
+}
+
+// Check whether any of the levels holds a key.
+func (mlc *multiLevelCache) Contains(key interface{}) (bool, error) {
+	errs := map[int]error{}
+
+	for i, level := range mlc.levels {
+		exists, err := level.Contains(key)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if exists {
+			return true, nil
+		}
+	}
+
+	return false, mlc.combineErrors(errs)
+}
+
+// Get a value from the levels, or store val in all levels if none of them
+// holds the key.
+func (mlc *multiLevelCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	actual, err := mlc.Get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = mlc.Store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a value from all levels, levels that do not hold the key are
+// skipped.
+func (mlc *multiLevelCache) Remove(key interface{}) error {
+	errs := map[int]error{}
+
+	for i, level := range mlc.levels {
+		err := level.Remove(key)
+		if err != nil {
+			errs[i] = err
+		}
+	}
+
+	return mlc.combineErrors(mlc.skipMissing(errs))
+}
+
+// Get a value from the levels and remove it from all of them.
+func (mlc *multiLevelCache) GetAndRemove(key interface{}) (interface{}, error) {
+	val, err := mlc.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mlc.Remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Replace a value in all levels that hold it.
+func (mlc *multiLevelCache) Replace(key, val interface{}) error {
+	errs := map[int]error{}
+
+	for i, level := range mlc.levels {
+		err := level.Replace(key, val)
+		if err != nil {
+			errs[i] = err
+		}
+	}
+
+	return mlc.combineErrors(mlc.skipMissing(errs))
+}
+
+// Store a permanent value in all levels, replacing the current values.
+func (mlc *multiLevelCache) StoreOrReplace(key, val interface{}) error {
+	errs := map[int]error{}
+
+	for i, level := range mlc.levels {
+		err := level.StoreOrReplace(key, val)
+		if err != nil {
+			errs[i] = err
+		}
+	}
+
+	return mlc.combineErrors(errs)
+}
+
+// Store several permanent values in all levels.
+func (mlc *multiLevelCache) StoreMany(items map[interface{}]interface{}) error {
+	return storeMany(items, mlc.Store)
+}
+
+// Get several values from the levels.
+func (mlc *multiLevelCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	return getMany(keys, mlc.Get)
+}
+
+// Clear all levels.
+func (mlc *multiLevelCache) Clear() error {
+	errs := map[int]error{}
+
+	for i, level := range mlc.levels {
+		err := level.Clear()
+		if err != nil {
+			errs[i] = err
+		}
+	}
+
+	return mlc.combineErrors(errs)
+}
+
+// Get the keys of all levels, each key is returned once.
+func (mlc *multiLevelCache) Keys() ([]interface{}, error) {
+	seen := map[interface{}]struct{}{}
+	keys := []interface{}{}
+
+	for _, level := range mlc.levels {
+		levelKeys, err := level.Keys()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range levelKeys {
+			if _, exists := seen[key]; exists {
+				continue
+			}
+
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Calls fn for each key and value of the levels until fn returns false, each
+// key is visited once with the value of the highest level that holds it.
+func (mlc *multiLevelCache) ForEach(fn func(key, val interface{}) bool) error {
+	seen := map[interface{}]struct{}{}
+	stopped := false
+
+	for _, level := range mlc.levels {
+		err := level.ForEach(func(key, val interface{}) bool {
+			if _, exists := seen[key]; exists {
+				return true
+			}
+
+			seen[key] = struct{}{}
+			stopped = !fn(key, val)
+
+			return !stopped
+		})
+		if err != nil {
+			return err
+		}
+
+		if stopped {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Get the keys of all levels for which predicate returns true, without
+// duplicates.
+func (mlc *multiLevelCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	seen := map[interface{}]struct{}{}
+	keys := []interface{}{}
+
+	for _, level := range mlc.levels {
+		levelKeys, err := level.FilterKeys(predicate)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range levelKeys {
+			if _, exists := seen[key]; exists {
+				continue
+			}
+
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Drop the DoesNotExist errors of levels that do not hold a key, unless none
+// of the levels holds it.
+func (mlc *multiLevelCache) skipMissing(errs map[int]error) map[int]error {
+	found := map[int]error{}
+	for i, err := range errs {
+		if !IsDoesNotExist(err) {
+			found[i] = err
+		}
+	}
+
+	if len(errs) == len(mlc.levels) && len(found) == 0 {
+		return errs
+	}
+
+	return found
+}
+
+// Combines the errors of an operation by level, returns nil if there are no
+// errors.
+//
+// When all levels fail with the same type of error, the error of the first
+// level is returned as is, so errors such as DoesNotExist can still be
+// checked.
+func (mlc *multiLevelCache) combineErrors(errs map[int]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	if len(errs) == len(mlc.levels) {
+		firstErr, isCacheErr := errs[0].(cacheError)
+
+		sameType := isCacheErr
+		for _, err := range errs {
+			cacheErr, isCacheErr := err.(cacheError)
+			sameType = sameType && isCacheErr && cacheErr.errType == firstErr.errType
+		}
+
+		if sameType {
+			return firstErr
+		}
+	}
+
+	msgs := []string{}
+	for i, err := range errs {
+		msgs = append(msgs, fmt.Sprintf("level %d: %v", i+1, err))
+	}
+	sort.Strings(msgs)
+
+	return newError(errorTypeMultiLevelError,
+		fmt.Sprintf("%d levels failed: %s", len(errs), strings.Join(msgs, "; ")))
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multi Level Cache", func() {
+	var (
+		c      Cache
+		l1, l2 *mapCache
+	)
+
+	BeforeEach(func() {
+		l1 = NewMapCache()
+		l2 = NewMapCache()
+		c = NewMultiLevelCache(l1, l2)
+	})
+
+	Context("Store", func() {
+		It("should store a value in all levels", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(l1.Get("key")).To(Equal("val"))
+			Expect(l2.Get("key")).To(Equal("val"))
+		})
+
+		It("should return the error of the levels when all of them fail the same way", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store("key", "val"))).To(BeTrue())
+		})
+
+		It("should return a multi level error when only some of the levels fail", func() {
+			Expect(l2.Store("key", "val")).ToNot(HaveOccurred())
+
+			err := c.Store("key", "val")
+			Expect(IsMultiLevelError(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("level 2"))
+			Expect(l1.Get("key")).To(Equal("val"))
+		})
+	})
+
+	Context("Get", func() {
+		It("should promote a value that is found in a lower level", func() {
+			Expect(l2.Store("key", "val")).ToNot(HaveOccurred())
+
+			Expect(c.Get("key")).To(Equal("val"))
+			Expect(l1.Get("key")).To(Equal("val"), "value was not promoted")
+		})
+
+		It("should return an error when no level holds the key", func() {
+			_, err := c.Get("key")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value from all levels", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Remove("key")).ToNot(HaveOccurred())
+			Expect(l1.Contains("key")).To(BeFalse())
+			Expect(l2.Contains("key")).To(BeFalse())
+		})
+
+		It("should skip levels that do not hold the key", func() {
+			Expect(l2.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Remove("key")).ToNot(HaveOccurred())
+			Expect(IsDoesNotExist(c.Remove("key"))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a value in the levels that hold it", func() {
+			Expect(l2.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Replace("key", "new-val")).ToNot(HaveOccurred())
+			Expect(l2.Get("key")).To(Equal("new-val"))
+			Expect(l1.Contains("key")).To(BeFalse())
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return each key once", func() {
+			Expect(c.Store("a", "val")).ToNot(HaveOccurred())
+			Expect(l2.Store("b", "val")).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(ConsistOf("a", "b"))
+		})
+	})
+
+	Context("ForEach", func() {
+		It("should visit each key with the value of the highest level", func() {
+			Expect(l1.Store("a", "l1-val")).ToNot(HaveOccurred())
+			Expect(l2.Store("a", "l2-val")).ToNot(HaveOccurred())
+			Expect(l2.Store("b", "l2-val")).ToNot(HaveOccurred())
+
+			visited := map[interface{}]interface{}{}
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				visited[key] = val
+				return true
+			})).ToNot(HaveOccurred())
+			Expect(visited).To(Equal(map[interface{}]interface{}{"a": "l1-val", "b": "l2-val"}))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+)
+
+type namespacedCache struct {
+	// Prepended to every key, followed by a colon.
+	prefix string
+
+	// The cache that holds the data, possibly shared with other namespaces.
+	underlying Cache
+}
+
+var _ Cache = (*namespacedCache)(nil)
+
+// NewNamespacedCache creates a new Cache object that prepends prefix + ":" to
+// every key before delegating to underlying, so several namespaces can share
+// the same cache.
+//
+// Keys are stored as strings, so Keys and ForEach return the string form of
+// the original keys.
+func NewNamespacedCache(prefix string, underlying Cache) Cache {
+	return &namespacedCache{
+		prefix:     prefix + ":",
+		underlying: underlying,
+	}
+}
+
+func (nc *namespacedCache) key(key interface{}) string {
+	return fmt.Sprintf("%s%v", nc.prefix, key)
+}
+
+// Strip the prefix of an underlying key, returns false if the key does not
+// belong to the namespace.
+func (nc *namespacedCache) stripKey(key interface{}) (string, bool) {
+	strKey, isStr := key.(string)
+	if !isStr || !strings.HasPrefix(strKey, nc.prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(strKey, nc.prefix), true
+}
+
+// Store a permanent value in the namespace.
+func (nc *namespacedCache) Store(key, val interface{}) error {
+	return nc.underlying.Store(nc.key(key), val)
+}
+
+// Get a value from the namespace.
+func (nc *namespacedCache) Get(key interface{}) (interface{}, error) {
+	return nc.underlying.Get(nc.key(key))
+}
+
+// Check whether a key exists in the namespace.
+func (nc *namespacedCache) Contains(key interface{}) (bool, error) {
+	return nc.underlying.Contains(nc.key(key))
+}
+
+// Get a value from the namespace, or store it if the key does not exist.
+func (nc *namespacedCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	return nc.underlying.GetOrStore(nc.key(key), val)
+}
+
+// Remove a value from the namespace.
+func (nc *namespacedCache) Remove(key interface{}) error {
+	return nc.underlying.Remove(nc.key(key))
+}
+
+// Get a value from the namespace and remove it.
+func (nc *namespacedCache) GetAndRemove(key interface{}) (interface{}, error) {
+	return nc.underlying.GetAndRemove(nc.key(key))
+}
+
+// Replace a value in the namespace.
+func (nc *namespacedCache) Replace(key, val interface{}) error {
+	return nc.underlying.Replace(nc.key(key), val)
+}
+
+// Store a permanent value in the namespace, replacing the current value if it
+// exists.
+func (nc *namespacedCache) StoreOrReplace(key, val interface{}) error {
+	return nc.underlying.StoreOrReplace(nc.key(key), val)
+}
+
+// Store several permanent values in the namespace.
+func (nc *namespacedCache) StoreMany(items map[interface{}]interface{}) error {
+	prefixedItems := map[interface{}]interface{}{}
+	for key, val := range items {
+		prefixedItems[nc.key(key)] = val
+	}
+
+	return nc.underlying.StoreMany(prefixedItems)
+}
+
+// Get several values from the namespace, the returned values are keyed by the
+// given keys.
+func (nc *namespacedCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	originalKeys := map[interface{}]interface{}{}
+	prefixedKeys := []interface{}{}
+	for _, key := range keys {
+		prefixedKey := nc.key(key)
+		originalKeys[prefixedKey] = key
+		prefixedKeys = append(prefixedKeys, prefixedKey)
+	}
+
+	prefixedVals, err := nc.underlying.GetMany(prefixedKeys)
+
+	vals := map[interface{}]interface{}{}
+	for prefixedKey, val := range prefixedVals {
+		vals[originalKeys[prefixedKey]] = val
+	}
+
+	return vals, err
+}
+
+// Clear removes only the values of the namespace.
+func (nc *namespacedCache) Clear() error {
+	keys, err := nc.underlying.Keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if _, inNamespace := nc.stripKey(key); !inNamespace {
+			continue
+		}
+
+		err := nc.underlying.Remove(key)
+		if err != nil && !IsDoesNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the keys of the namespace, without the prefix.
+func (nc *namespacedCache) Keys() ([]interface{}, error) {
+	underlyingKeys, err := nc.underlying.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []interface{}{}
+	for _, key := range underlyingKeys {
+		if strKey, inNamespace := nc.stripKey(key); inNamespace {
+			keys = append(keys, strKey)
+		}
+	}
+
+	return keys, nil
+}
+
+// Calls fn for each key and value of the namespace until fn returns false.
+func (nc *namespacedCache) ForEach(fn func(key, val interface{}) bool) error {
+	return nc.underlying.ForEach(func(key, val interface{}) bool {
+		strKey, inNamespace := nc.stripKey(key)
+		if !inNamespace {
+			return true
+		}
+
+		return fn(strKey, val)
+	})
+}
+
+// Get the keys of the namespace for which predicate returns true, predicate
+// is called with the keys without the prefix.
+func (nc *namespacedCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	underlyingKeys, err := nc.underlying.FilterKeys(func(key interface{}) bool {
+		strKey, inNamespace := nc.stripKey(key)
+		return inNamespace && predicate(strKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []interface{}{}
+	for _, key := range underlyingKeys {
+		strKey, _ := nc.stripKey(key)
+		keys = append(keys, strKey)
+	}
+
+	return keys, nil
+}
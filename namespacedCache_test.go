@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namespaced Cache", func() {
+	var (
+		underlying               Cache
+		c, other                 Cache
+		key, val, nonExistentKey string = "test-key", "test-val", "non-existent"
+	)
+
+	BeforeEach(func() {
+		underlying = NewMapCache()
+		c = NewNamespacedCache("tenant-a", underlying)
+		other = NewNamespacedCache("tenant-b", underlying)
+	})
+
+	Context("Store", func() {
+		It("should store a value under the prefixed key", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(underlying.Get("tenant-a:" + key)).To(Equal(val))
+		})
+
+		It("should keep the values of different namespaces apart", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(other.Store(key, "other-val")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(other.Get(key)).To(Equal("other-val"))
+		})
+	})
+
+	Context("GetMany", func() {
+		It("should return the values keyed by the original keys", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			vals, err := c.GetMany([]interface{}{key, nonExistentKey})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(vals).To(Equal(map[interface{}]interface{}{key: val}))
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return only the keys of the namespace without the prefix", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(other.Store("other-key", val)).ToNot(HaveOccurred())
+			Expect(underlying.Store("unprefixed", val)).ToNot(HaveOccurred())
+
+			Expect(c.Keys()).To(Equal([]interface{}{key}))
+		})
+	})
+
+	Context("FilterKeys", func() {
+		It("should call predicate with the keys of the namespace without the prefix", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Store("other", val)).ToNot(HaveOccurred())
+			Expect(other.Store(key, val)).ToNot(HaveOccurred())
+
+			Expect(c.FilterKeys(func(k interface{}) bool {
+				return k == key
+			})).To(Equal([]interface{}{key}))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove only the values of the namespace", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(other.Store(key, val)).ToNot(HaveOccurred())
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Contains(key)).To(BeFalse())
+			Expect(other.Contains(key)).To(BeTrue())
+		})
+	})
+
+	Context("directoryCache", func() {
+		It("should use the prefix as part of the file name", func() {
+			cacheDir := fmt.Sprintf("%s/%s", os.TempDir(), "namespaced-dir-cache")
+			Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+
+			dc, err := NewDirectoryCache(cacheDir)
+			Expect(err).ToNot(HaveOccurred())
+			defer dc.Clear()
+
+			c = NewNamespacedCache("tenant-a", dc)
+			Expect(c.Store(key, testStruct{"Test", 0})).ToNot(HaveOccurred())
+			Expect(fmt.Sprintf("%s/tenant-a:%s", cacheDir, key)).To(BeAnExistingFile())
+			Expect(c.Keys()).To(Equal([]interface{}{key}))
+		})
+	})
+})
@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+type persistentLruCache struct {
+	// The maximal amount of cached items.
+	capacity int
+
+	// Current number of cached items.
+	numberOfItems int
+
+	// A directory that holds the data, so it survives restarts.
+	storage *directoryCache
+
+	// Holds the order of the keys, from the most recently used to the least
+	// recently used.
+	policy *lruPolicy
+
+	mutex sync.Mutex
+}
+
+var _ Cache = (*persistentLruCache)(nil)
+
+// NewPersistentLruCache creates a new Cache object that stores its values in
+// dir and evicts the least recently used value once it holds more than
+// capacity values, capacity must be greater than zero.
+//
+// The values that are already stored in dir are recovered like in
+// NewDirectoryCacheWithRecovery, and ordered by the modification times of
+// their files, so the least recently written value is evicted first. If dir
+// holds more than capacity values, the oldest ones are evicted.
+func NewPersistentLruCache(capacity uint, dir string, valueTypeRegistry map[string]reflect.Type,
+	opts ...DirectoryCacheOption) (*persistentLruCache, error) {
+	if capacity == 0 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
+	storage, err := NewDirectoryCacheWithRecovery(dir, valueTypeRegistry, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	plru := &persistentLruCache{
+		capacity: int(capacity),
+		storage:  storage,
+		policy:   NewLruPolicy(),
+	}
+
+	err = plru.warmUp(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return plru, nil
+}
+
+// Orders the recovered keys by the modification times of their files.
+func (plru *persistentLruCache) warmUp(dir string) error {
+	keys, err := plru.storage.Keys()
+	if err != nil {
+		return err
+	}
+
+	modTimes := map[interface{}]time.Time{}
+	for _, key := range keys {
+		info, err := os.Stat(path.Join(dir, key.(string)))
+		if err != nil {
+			return err
+		}
+
+		modTimes[key] = info.ModTime()
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return modTimes[keys[i]].Before(modTimes[keys[j]])
+	})
+
+	// The most recently written key is added last, so it is at the front.
+	for _, key := range keys {
+		plru.policy.Add(key)
+		plru.numberOfItems++
+	}
+
+	for plru.numberOfItems > plru.capacity {
+		err := plru.evict()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Removes the least recently used value.
+func (plru *persistentLruCache) evict() error {
+	key, ok := plru.policy.Victim()
+	if !ok {
+		return nil
+	}
+
+	_, err := plru.remove(key)
+
+	return err
+}
+
+// Store a value in the directory, evicting the least recently used value if
+// the cache is full.
+func (plru *persistentLruCache) Store(key, val interface{}) error {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.store(key, val)
+}
+
+func (plru *persistentLruCache) store(key, val interface{}) error {
+	err := plru.storage.Store(key, val)
+	if err != nil {
+		return err
+	}
+
+	plru.policy.Add(key)
+	plru.numberOfItems++
+
+	if plru.numberOfItems > plru.capacity {
+		return plru.evict()
+	}
+
+	return nil
+}
+
+// Get a value from the directory.
+func (plru *persistentLruCache) Get(key interface{}) (interface{}, error) {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.get(key)
+}
+
+func (plru *persistentLruCache) get(key interface{}) (interface{}, error) {
+	val, err := plru.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plru.policy.Touch(key)
+
+	return val, nil
+}
+
+// Check whether a key is cached without changing its order.
+func (plru *persistentLruCache) Contains(key interface{}) (bool, error) {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.storage.Contains(key)
+}
+
+// Get a value from the directory, or store val if the key does not exist.
+func (plru *persistentLruCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	actual, err := plru.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = plru.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a value from the directory.
+func (plru *persistentLruCache) Remove(key interface{}) error {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	_, err := plru.remove(key)
+
+	return err
+}
+
+// Get a value from the directory and remove it.
+func (plru *persistentLruCache) GetAndRemove(key interface{}) (interface{}, error) {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.remove(key)
+}
+
+func (plru *persistentLruCache) remove(key interface{}) (interface{}, error) {
+	val, err := plru.storage.GetAndRemove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plru.policy.Remove(key)
+	plru.numberOfItems--
+
+	return val, nil
+}
+
+// Replace a value in the directory, the key becomes the most recently used.
+func (plru *persistentLruCache) Replace(key, val interface{}) error {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.replace(key, val)
+}
+
+func (plru *persistentLruCache) replace(key, val interface{}) error {
+	err := plru.storage.Replace(key, val)
+	if err != nil {
+		return err
+	}
+
+	plru.policy.Touch(key)
+
+	return nil
+}
+
+// Store a value in the directory, replacing the current value if the key
+// exists.
+func (plru *persistentLruCache) StoreOrReplace(key, val interface{}) error {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	err := plru.replace(key, val)
+	if IsDoesNotExist(err) {
+		return plru.store(key, val)
+	}
+
+	return err
+}
+
+// Store several values in the directory.
+func (plru *persistentLruCache) StoreMany(items map[interface{}]interface{}) error {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return storeMany(items, plru.store)
+}
+
+// Get several values from the directory.
+func (plru *persistentLruCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return getMany(keys, plru.get)
+}
+
+// Clear the directory.
+func (plru *persistentLruCache) Clear() error {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	err := plru.storage.Clear()
+	if err != nil {
+		return err
+	}
+
+	plru.policy.Clear()
+	plru.numberOfItems = 0
+
+	return nil
+}
+
+// Get all keys from the directory.
+func (plru *persistentLruCache) Keys() ([]interface{}, error) {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.storage.Keys()
+}
+
+// Calls fn for each key and value in the directory until fn returns false.
+func (plru *persistentLruCache) ForEach(fn func(key, val interface{}) bool) error {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.storage.ForEach(fn)
+}
+
+// Get the keys for which predicate returns true.
+func (plru *persistentLruCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	plru.mutex.Lock()
+	defer plru.mutex.Unlock()
+
+	return plru.storage.FilterKeys(predicate)
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Persistent LRU Cache", func() {
+	var (
+		c        *persistentLruCache
+		cacheDir string
+		registry map[string]reflect.Type
+	)
+
+	BeforeEach(func() {
+		cacheDir = fmt.Sprintf("%s/%s", os.TempDir(), "persistent-lru-cache")
+		Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+
+		registry = map[string]reflect.Type{}
+		for _, key := range []string{"a", "b", "c", "d"} {
+			registry[key] = reflect.TypeOf(testStruct{})
+		}
+
+		var err error
+		c, err = NewPersistentLruCache(3, cacheDir, registry)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(cacheDir)).ToNot(HaveOccurred())
+	})
+
+	Context("Store", func() {
+		It("should evict the least recently used value and its file", func() {
+			Expect(c.Store("a", testStruct{"a", 1})).ToNot(HaveOccurred())
+			Expect(c.Store("b", testStruct{"b", 2})).ToNot(HaveOccurred())
+			Expect(c.Store("c", testStruct{"c", 3})).ToNot(HaveOccurred())
+			Expect(c.Get("a")).To(Equal(testStruct{"a", 1}))
+
+			Expect(c.Store("d", testStruct{"d", 4})).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(ConsistOf("a", "c", "d"))
+
+			_, err := os.Stat(path.Join(cacheDir, "b"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should free room for another value", func() {
+			Expect(c.Store("a", testStruct{"a", 1})).ToNot(HaveOccurred())
+			Expect(c.Store("b", testStruct{"b", 2})).ToNot(HaveOccurred())
+			Expect(c.Store("c", testStruct{"c", 3})).ToNot(HaveOccurred())
+			Expect(c.Remove("b")).ToNot(HaveOccurred())
+
+			Expect(c.Store("d", testStruct{"d", 4})).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(ConsistOf("a", "c", "d"))
+		})
+	})
+
+	Context("NewPersistentLruCache", func() {
+		It("should return an error for a zero capacity", func() {
+			_, err := NewPersistentLruCache(0, cacheDir, registry)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should recover the values ordered by the modification times of their files", func() {
+			// The files are written out of order, only their times matter.
+			start := time.Now().Add(-time.Hour)
+			for i, key := range []string{"c", "a", "b"} {
+				Expect(c.Store(key, testStruct{key, i})).ToNot(HaveOccurred())
+
+				modTime := start.Add(time.Duration(i) * time.Minute)
+				Expect(os.Chtimes(path.Join(cacheDir, key), modTime, modTime)).
+					ToNot(HaveOccurred())
+			}
+
+			rc, err := NewPersistentLruCache(3, cacheDir, registry)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rc.Get("a")).To(Equal(testStruct{"a", 1}))
+
+			Expect(rc.Store("d", testStruct{"d", 3})).ToNot(HaveOccurred())
+			Expect(rc.Keys()).To(ConsistOf("a", "b", "d"))
+		})
+
+		It("should evict the oldest recovered values when there are too many", func() {
+			start := time.Now().Add(-time.Hour)
+			for i, key := range []string{"a", "b", "c"} {
+				Expect(c.Store(key, testStruct{key, i})).ToNot(HaveOccurred())
+
+				modTime := start.Add(time.Duration(i) * time.Minute)
+				Expect(os.Chtimes(path.Join(cacheDir, key), modTime, modTime)).
+					ToNot(HaveOccurred())
+			}
+
+			rc, err := NewPersistentLruCache(2, cacheDir, registry)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rc.Keys()).To(ConsistOf("b", "c"))
+
+			_, err = os.Stat(path.Join(cacheDir, "a"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type randomItem struct {
+	// The cached data.
+	value interface{}
+
+	// The index of the item's key in the keys slice.
+	index int
+}
+
+type randomCache struct {
+	// The maximal amount of cached items.
+	capacity int
+
+	// A cache that holds tha data.
+	storage *mapCache
+
+	// Holds all cached keys, allows picking a random key in O(1).
+	keys []interface{}
+
+	// Called with the key and value of an evicted item.
+	onEvict func(key, val interface{})
+
+	rand *rand.Rand
+
+	mutex sync.Mutex
+}
+
+var _ Cache = (*randomCache)(nil)
+
+// NewRandomCache creates a new randomCache instance using mapCache, when the
+// cache is full a random value is evicted. capacity must be greater than zero.
+func NewRandomCache(capacity int, opts ...EvictionOption) (*randomCache, error) {
+	if capacity < 1 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
+	o := newEvictionOptions(opts)
+
+	src := o.randSource
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+
+	return &randomCache{
+		capacity: capacity,
+		storage:  NewMapCache(),
+		onEvict:  o.onEvict,
+		rand:     rand.New(src),
+	}, nil
+}
+
+// Store caches a new value.
+func (rc *randomCache) Store(key, val interface{}) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return rc.store(key, val)
+}
+
+func (rc *randomCache) store(key, val interface{}) error {
+	exists, err := rc.storage.Contains(key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	if len(rc.keys) > 0 && len(rc.keys) >= rc.capacity {
+		err := rc.evict()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = rc.storage.Store(key, &randomItem{val, len(rc.keys)})
+	if err != nil {
+		return err
+	}
+
+	rc.keys = append(rc.keys, key)
+
+	return nil
+}
+
+func (rc *randomCache) evict() error {
+	key := rc.keys[rc.rand.Intn(len(rc.keys))]
+
+	val, err := rc.getAndRemove(key)
+	if err != nil {
+		return err
+	}
+
+	if rc.onEvict != nil {
+		rc.onEvict(key, val)
+	}
+
+	return nil
+}
+
+// Get a cached value.
+func (rc *randomCache) Get(key interface{}) (interface{}, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return rc.get(key)
+}
+
+func (rc *randomCache) get(key interface{}) (interface{}, error) {
+	item, err := rc.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*randomItem).value, nil
+}
+
+// Check whether a key is cached.
+func (rc *randomCache) Contains(key interface{}) (bool, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return rc.storage.Contains(key)
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (rc *randomCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	actual, err := rc.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = rc.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a cached value.
+func (rc *randomCache) Remove(key interface{}) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	_, err := rc.getAndRemove(key)
+
+	return err
+}
+
+// Get a cached value and remove it.
+func (rc *randomCache) GetAndRemove(key interface{}) (interface{}, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return rc.getAndRemove(key)
+}
+
+func (rc *randomCache) getAndRemove(key interface{}) (interface{}, error) {
+	item, err := rc.storage.GetAndRemove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Move the last key to the place of the removed one.
+	index := item.(*randomItem).index
+	last := len(rc.keys) - 1
+	if index != last {
+		lastItem, err := rc.storage.Get(rc.keys[last])
+		if err != nil {
+			return nil, err
+		}
+
+		lastItem.(*randomItem).index = index
+		rc.keys[index] = rc.keys[last]
+	}
+
+	rc.keys[last] = nil
+	rc.keys = rc.keys[:last]
+
+	return item.(*randomItem).value, nil
+}
+
+// Replace a cached value.
+func (rc *randomCache) Replace(key, val interface{}) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return rc.replace(key, val)
+}
+
+func (rc *randomCache) replace(key, val interface{}) error {
+	item, err := rc.storage.Get(key)
+	if err != nil {
+		return err
+	}
+
+	item.(*randomItem).value = val
+
+	return nil
+}
+
+// Cache a value, replacing the current value of the key if it exists, in
+// which case it keeps its place in the eviction order.
+func (rc *randomCache) StoreOrReplace(key, val interface{}) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	err := rc.replace(key, val)
+	if IsDoesNotExist(err) {
+		return rc.store(key, val)
+	}
+
+	return err
+}
+
+// Store several values.
+func (rc *randomCache) StoreMany(items map[interface{}]interface{}) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return storeMany(items, rc.store)
+}
+
+// Get several cached values.
+func (rc *randomCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return getMany(keys, rc.get)
+}
+
+// Clear all values.
+func (rc *randomCache) Clear() error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	err := rc.storage.Clear()
+	if err != nil {
+		return err
+	}
+
+	rc.keys = nil
+
+	return nil
+}
+
+// Get all keys.
+func (rc *randomCache) Keys() ([]interface{}, error) {
+	return rc.storage.Keys()
+}
+
+// Calls fn for each cached key and value until fn returns false.
+func (rc *randomCache) ForEach(fn func(key, val interface{}) bool) error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return rc.storage.ForEach(func(key, item interface{}) bool {
+		return fn(key, item.(*randomItem).value)
+	})
+}
+
+// Get the keys for which predicate returns true.
+func (rc *randomCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	return rc.storage.FilterKeys(predicate)
+}
+
+// Count returns the number of cached items.
+func (rc *randomCache) Count() int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return len(rc.keys)
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const RandomCacheSize = 3
+
+var _ = Describe("Random Cache", func() {
+	var (
+		c            *randomCache
+		keys, values []string
+	)
+
+	for i := 0; i < RandomCacheSize; i++ {
+		keys = append(keys, fmt.Sprintf("test-key-%v", i))
+		values = append(values, fmt.Sprintf("test-value-%v", i))
+	}
+
+	BeforeEach(func() {
+		var err error
+		c, err = NewRandomCache(RandomCacheSize, WithRandSource(rand.NewSource(1)))
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < RandomCacheSize; i++ {
+			Expect(c.Store(keys[i], values[i])).ToNot(HaveOccurred(), "failed storing a value")
+		}
+	})
+
+	Context("Store", func() {
+		It("should evict a single value when the cache is full", func() {
+			Expect(c.Store("extra-key", "extra-value")).ToNot(HaveOccurred())
+			Expect(c.Count()).To(Equal(RandomCacheSize))
+			Expect(c.Contains("extra-key")).To(BeTrue())
+		})
+
+		It("should evict the same values given the same source", func() {
+			var evicted, otherEvicted []interface{}
+			var err error
+			c, err = NewRandomCache(RandomCacheSize, WithRandSource(rand.NewSource(1)),
+				WithEvictionCallback(func(key, val interface{}) { evicted = append(evicted, key) }))
+			Expect(err).ToNot(HaveOccurred())
+			other, err := NewRandomCache(RandomCacheSize, WithRandSource(rand.NewSource(1)),
+				WithEvictionCallback(func(key, val interface{}) { otherEvicted = append(otherEvicted, key) }))
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 10*RandomCacheSize; i++ {
+				Expect(c.Store(i, i)).ToNot(HaveOccurred())
+				Expect(other.Store(i, i)).ToNot(HaveOccurred())
+			}
+
+			Expect(evicted).To(HaveLen(9 * RandomCacheSize))
+			Expect(evicted).To(Equal(otherEvicted))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(IsAlreadyExists(c.Store(keys[0], values[0]))).To(BeTrue())
+			Expect(c.Count()).To(Equal(RandomCacheSize), "a value was evicted by a failed store")
+		})
+	})
+
+	Context("NewRandomCache", func() {
+		It("should return an error for a non-positive capacity", func() {
+			_, err := NewRandomCache(0)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+			_, err = NewRandomCache(-1)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value and keep the others reachable", func() {
+			Expect(c.Remove(keys[0])).ToNot(HaveOccurred())
+			Expect(c.Contains(keys[0])).To(BeFalse())
+			Expect(c.Get(keys[2])).To(Equal(values[2]))
+			Expect(c.Count()).To(Equal(RandomCacheSize - 1))
+		})
+
+		It("should return an error when attempting to remove a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Remove("non-existent-key"))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a value", func() {
+			Expect(c.Replace(keys[1], "new-value")).ToNot(HaveOccurred())
+			Expect(c.Get(keys[1])).To(Equal("new-value"))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove all values", func() {
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Count()).To(Equal(0))
+			Expect(c.Store(keys[0], values[0])).ToNot(HaveOccurred())
+		})
+	})
+})
+
+func benchmarkRandomAccess(b *testing.B, c Cache) {
+	r := rand.New(rand.NewSource(1))
+
+	hits := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := r.Intn(1000)
+		if _, err := c.Get(key); err == nil {
+			hits++
+		} else if err := c.Store(key, key); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+}
+
+func BenchmarkRandomCacheRandomAccess(b *testing.B) {
+	c, err := NewRandomCache(100, WithRandSource(rand.NewSource(1)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkRandomAccess(b, c)
+}
+
+func BenchmarkLruCacheRandomAccess(b *testing.B) {
+	c, err := NewLru(100)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkRandomAccess(b, c)
+}
@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	errorTypeRateLimitExceeded errorType = "RateLimitExceeded"
+)
+
+func IsRateLimitExceeded(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeRateLimitExceeded
+}
+
+type rateLimitedCache struct {
+	// The cache that holds the data.
+	underlying Cache
+
+	// Hands out a token for every operation.
+	limiter *rate.Limiter
+
+	// Returns the current time, replaced by tests.
+	now func() time.Time
+
+	// Waits for a write token, replaced by tests.
+	sleep func(d time.Duration)
+}
+
+var _ Cache = (*rateLimitedCache)(nil)
+
+// NewRateLimitedCache creates a new Cache object that performs at most maxQPS
+// operations per second on underlying, allowing bursts of up to maxQPS
+// operations.
+//
+// Writes wait until they are allowed, reads that are not allowed fail
+// immediately (check with IsRateLimitExceeded). If maxQPS is not positive,
+// operations are not limited.
+func NewRateLimitedCache(underlying Cache, maxQPS float64) Cache {
+	limit, burst := rate.Limit(maxQPS), int(math.Ceil(maxQPS))
+	if maxQPS <= 0 {
+		limit, burst = rate.Inf, 0
+	}
+
+	return &rateLimitedCache{
+		underlying: underlying,
+		limiter:    rate.NewLimiter(limit, burst),
+		now:        time.Now,
+		sleep:      time.Sleep,
+	}
+}
+
+// Waits until a write is allowed.
+func (rlc *rateLimitedCache) wait() {
+	now := rlc.now()
+
+	delay := rlc.limiter.ReserveN(now, 1).DelayFrom(now)
+	if delay > 0 {
+		rlc.sleep(delay)
+	}
+}
+
+// Returns an error if a read is not allowed.
+func (rlc *rateLimitedCache) allow() error {
+	if !rlc.limiter.AllowN(rlc.now(), 1) {
+		return newError(errorTypeRateLimitExceeded,
+			"the maximal rate of operations was exceeded")
+	}
+
+	return nil
+}
+
+// Store a permanent value once a write is allowed.
+func (rlc *rateLimitedCache) Store(key, val interface{}) error {
+	rlc.wait()
+
+	return rlc.underlying.Store(key, val)
+}
+
+// Get a value, unless the rate limit was exceeded.
+func (rlc *rateLimitedCache) Get(key interface{}) (interface{}, error) {
+	if err := rlc.allow(); err != nil {
+		return nil, err
+	}
+
+	return rlc.underlying.Get(key)
+}
+
+// Check whether a key exists, unless the rate limit was exceeded.
+func (rlc *rateLimitedCache) Contains(key interface{}) (bool, error) {
+	if err := rlc.allow(); err != nil {
+		return false, err
+	}
+
+	return rlc.underlying.Contains(key)
+}
+
+// Get a value, or store it if the key does not exist, once a write is
+// allowed.
+func (rlc *rateLimitedCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	rlc.wait()
+
+	return rlc.underlying.GetOrStore(key, val)
+}
+
+// Remove a value once a write is allowed.
+func (rlc *rateLimitedCache) Remove(key interface{}) error {
+	rlc.wait()
+
+	return rlc.underlying.Remove(key)
+}
+
+// Get a value and remove it once a write is allowed.
+func (rlc *rateLimitedCache) GetAndRemove(key interface{}) (interface{}, error) {
+	rlc.wait()
+
+	return rlc.underlying.GetAndRemove(key)
+}
+
+// Replace a value once a write is allowed.
+func (rlc *rateLimitedCache) Replace(key, val interface{}) error {
+	rlc.wait()
+
+	return rlc.underlying.Replace(key, val)
+}
+
+// Store a value, replacing the current value if the key exists, once a write
+// is allowed.
+func (rlc *rateLimitedCache) StoreOrReplace(key, val interface{}) error {
+	rlc.wait()
+
+	return rlc.underlying.StoreOrReplace(key, val)
+}
+
+// Store several values once a write is allowed, they count as a single
+// operation.
+func (rlc *rateLimitedCache) StoreMany(items map[interface{}]interface{}) error {
+	rlc.wait()
+
+	return rlc.underlying.StoreMany(items)
+}
+
+// Get several values, unless the rate limit was exceeded. They count as a
+// single operation.
+func (rlc *rateLimitedCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	if err := rlc.allow(); err != nil {
+		return nil, err
+	}
+
+	return rlc.underlying.GetMany(keys)
+}
+
+// Clear the cache once a write is allowed.
+func (rlc *rateLimitedCache) Clear() error {
+	rlc.wait()
+
+	return rlc.underlying.Clear()
+}
+
+// Get all keys, unless the rate limit was exceeded.
+func (rlc *rateLimitedCache) Keys() ([]interface{}, error) {
+	if err := rlc.allow(); err != nil {
+		return nil, err
+	}
+
+	return rlc.underlying.Keys()
+}
+
+// Calls fn for each key and value until fn returns false, unless the rate
+// limit was exceeded.
+func (rlc *rateLimitedCache) ForEach(fn func(key, val interface{}) bool) error {
+	if err := rlc.allow(); err != nil {
+		return err
+	}
+
+	return rlc.underlying.ForEach(fn)
+}
+
+// Get the keys for which predicate returns true, unless the rate limit was
+// exceeded.
+func (rlc *rateLimitedCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	if err := rlc.allow(); err != nil {
+		return nil, err
+	}
+
+	return rlc.underlying.FilterKeys(predicate)
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rate Limited Cache", func() {
+	var (
+		c      Cache
+		now    time.Time
+		sleeps []time.Duration
+	)
+
+	BeforeEach(func() {
+		now = time.Unix(0, 0)
+		sleeps = nil
+
+		c = NewRateLimitedCache(NewMapCache(), 2)
+
+		// Sleeping only advances the mock clock.
+		rlc := c.(*rateLimitedCache)
+		rlc.now = func() time.Time {
+			return now
+		}
+		rlc.sleep = func(d time.Duration) {
+			sleeps = append(sleeps, d)
+			now = now.Add(d)
+		}
+	})
+
+	Context("Reads", func() {
+		It("should fail once the rate limit is exceeded", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+
+			Expect(c.Get("key")).To(Equal("val"))
+
+			_, err := c.Get("key")
+			Expect(IsRateLimitExceeded(err)).To(BeTrue())
+
+			_, err = c.Contains("key")
+			Expect(IsRateLimitExceeded(err)).To(BeTrue())
+		})
+
+		It("should be allowed again once tokens are replenished", func() {
+			Expect(c.Keys()).To(BeEmpty())
+			Expect(c.Keys()).To(BeEmpty())
+
+			_, err := c.Keys()
+			Expect(IsRateLimitExceeded(err)).To(BeTrue())
+
+			now = now.Add(500 * time.Millisecond)
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("Writes", func() {
+		It("should wait for a token once the rate limit is exceeded", func() {
+			Expect(c.Store("key-0", "val")).ToNot(HaveOccurred())
+			Expect(c.Store("key-1", "val")).ToNot(HaveOccurred())
+			Expect(sleeps).To(BeEmpty())
+
+			Expect(c.Replace("key-0", "new-val")).ToNot(HaveOccurred())
+			Expect(c.Remove("key-1")).ToNot(HaveOccurred())
+			Expect(sleeps).To(Equal([]time.Duration{500 * time.Millisecond, 500 * time.Millisecond}))
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(sleeps).To(HaveLen(3))
+		})
+
+		It("should leave no tokens for reads", func() {
+			Expect(c.Store("key-0", "val")).ToNot(HaveOccurred())
+			Expect(c.Store("key-1", "val")).ToNot(HaveOccurred())
+
+			_, err := c.Get("key-0")
+			Expect(IsRateLimitExceeded(err)).To(BeTrue())
+		})
+	})
+
+	Context("NewRateLimitedCache", func() {
+		It("should not limit operations when maxQPS is not positive", func() {
+			c = NewRateLimitedCache(NewMapCache(), 0)
+
+			for i := 0; i < 100; i++ {
+				Expect(c.Store(i, "val")).ToNot(HaveOccurred())
+				Expect(c.Get(i)).To(Equal("val"))
+			}
+		})
+	})
+})
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"fmt"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type readThroughCache struct {
+	Cache
+
+	// Loads the values of missing keys.
+	loader func(key interface{}) (interface{}, error)
+
+	// Deduplicates concurrent loads of the same key.
+	loads singleflight.Group
+}
+
+var _ Cache = (*readThroughCache)(nil)
+
+// NewReadThroughCache creates a new Cache object that loads missing values
+// with loader and stores them in underlying, concurrent misses of the same
+// key call loader only once.
+//
+// Values that loader returns as nil are returned without being stored.
+func NewReadThroughCache(underlying Cache, loader func(key interface{}) (interface{}, error)) Cache {
+	return &readThroughCache{
+		Cache:  underlying,
+		loader: loader,
+	}
+}
+
+// Get a value, a missing value is loaded and stored.
+func (rtc *readThroughCache) Get(key interface{}) (interface{}, error) {
+	val, err := rtc.Cache.Get(key)
+	if !IsDoesNotExist(err) {
+		return val, err
+	}
+
+	// The type is part of the key so that keys such as 1 and "1" are loaded
+	// separately.
+	val, err, _ = rtc.loads.Do(fmt.Sprintf("%T:%v", key, key), func() (interface{}, error) {
+		val, err := rtc.loader(key)
+		if err != nil || val == nil {
+			return val, err
+		}
+
+		err = rtc.Cache.Store(key, val)
+		if err != nil && !IsAlreadyExists(err) {
+			return nil, err
+		}
+
+		return val, nil
+	})
+
+	return val, err
+}
+
+// Get several values, missing values are loaded and stored.
+func (rtc *readThroughCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	return getMany(keys, rtc.Get)
+}
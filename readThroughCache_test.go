@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Read Through Cache", func() {
+	var (
+		c          Cache
+		underlying Cache
+		loads      int32
+		loadErr    error
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&loads, 0)
+		loadErr = nil
+		underlying = NewMapCache()
+		c = NewReadThroughCache(underlying, func(key interface{}) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			if loadErr != nil {
+				return nil, loadErr
+			}
+
+			if key == "nil-key" {
+				return nil, nil
+			}
+
+			return "loaded", nil
+		})
+	})
+
+	Context("Get", func() {
+		It("should load and store a missing value", func() {
+			Expect(c.Get("key")).To(Equal("loaded"))
+			Expect(underlying.Get("key")).To(Equal("loaded"))
+
+			Expect(c.Get("key")).To(Equal("loaded"))
+			Expect(atomic.LoadInt32(&loads)).To(Equal(int32(1)))
+		})
+
+		It("should not load an existing value", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Get("key")).To(Equal("val"))
+			Expect(atomic.LoadInt32(&loads)).To(BeZero())
+		})
+
+		It("should not store a nil value", func() {
+			Expect(c.Get("nil-key")).To(BeNil())
+			Expect(underlying.Contains("nil-key")).To(BeFalse())
+		})
+
+		It("should return the error of the loader", func() {
+			loadErr = errors.New("load failed")
+
+			_, err := c.Get("key")
+			Expect(err).To(Equal(loadErr))
+			Expect(underlying.Contains("key")).To(BeFalse())
+		})
+
+		It("should load a value once for concurrent misses", func() {
+			release := make(chan struct{})
+			c = NewReadThroughCache(underlying, func(key interface{}) (interface{}, error) {
+				atomic.AddInt32(&loads, 1)
+				<-release
+				return "loaded", nil
+			})
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					Expect(c.Get("key")).To(Equal("loaded"))
+				}()
+			}
+
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			Expect(atomic.LoadInt32(&loads)).To(Equal(int32(1)))
+		})
+	})
+
+	Context("GetMany", func() {
+		It("should load the missing values", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.GetMany([]interface{}{"key", "other-key"})).To(Equal(map[interface{}]interface{}{
+				"key":       "val",
+				"other-key": "loaded",
+			}))
+		})
+	})
+})
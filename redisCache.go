@@ -2,7 +2,9 @@ package cache
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,6 +15,11 @@ const (
 	errorTypeRedisError errorType = "RedisError"
 )
 
+func IsRedisError(err error) bool {
+	cacheErr, isCacheErr := err.(cacheError)
+	return isCacheErr && cacheErr.errType == errorTypeRedisError
+}
+
 // RedisCache is a client that implements Cache interface.
 type RedisCache struct {
 	// This dictionary is maintained in order to keep track of this
@@ -22,31 +29,125 @@ type RedisCache struct {
 	// Holds the channels that stop the auto removal routines.
 	removeChannels map[interface{}]*cacheChannel
 
-	client *redis.Client
+	// Holds the ttls of keys with a sliding expiration.
+	slidingTTLs map[string]time.Duration
+
+	// Either a single node client or a cluster client.
+	client redis.Cmdable
 
 	mutex sync.Mutex
 }
 
 var _ (ExpiringCache) = (*RedisCache)(nil)
+var _ (ContextCache) = (*RedisCache)(nil)
 
 // --------------------------------------------------------------------------
 
+// RedisCacheOption configures the client of a RedisCache.
+type RedisCacheOption func(*redis.Options)
+
+// WithRedisAddress sets the host:port address of the redis server.
+func WithRedisAddress(addr string) RedisCacheOption {
+	return func(o *redis.Options) {
+		o.Addr = addr
+	}
+}
+
+// WithRedisPassword sets the password of the redis server.
+func WithRedisPassword(pw string) RedisCacheOption {
+	return func(o *redis.Options) {
+		o.Password = pw
+	}
+}
+
+// WithRedisDB sets the redis database number.
+func WithRedisDB(db int) RedisCacheOption {
+	return func(o *redis.Options) {
+		o.DB = db
+	}
+}
+
+// WithRedisTLS connects to the redis server using TLS.
+func WithRedisTLS(cfg *tls.Config) RedisCacheOption {
+	return func(o *redis.Options) {
+		o.TLSConfig = cfg
+	}
+}
+
+// WithRedisPoolSize sets the maximal number of connections to the redis
+// server.
+func WithRedisPoolSize(n int) RedisCacheOption {
+	return func(o *redis.Options) {
+		o.PoolSize = n
+	}
+}
+
+// WithRedisDialTimeout sets the timeout of establishing new connections.
+func WithRedisDialTimeout(d time.Duration) RedisCacheOption {
+	return func(o *redis.Options) {
+		o.DialTimeout = d
+	}
+}
+
 // NewRedisCache creates and returns a reference to a RedisCache instance.
 func NewRedisCache(address, password string, db int) *RedisCache {
+	return NewRedisCacheWithOptions(
+		WithRedisAddress(address),
+		WithRedisPassword(password),
+		WithRedisDB(db),
+	)
+}
+
+// NewRedisCacheWithCluster creates and returns a reference to a RedisCache
+// instance that uses a Redis Cluster.
+//
+// StoreMany, GetMany and Clear send all of their keys in one command, so they
+// fail unless the keys hash to the same slot, which can be forced with hash
+// tags such as "{user}:1" and "{user}:2".
+func NewRedisCacheWithCluster(addrs []string, password string) *RedisCache {
+	return newRedisCache(redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:    addrs,
+		Password: password,
+	}))
+}
+
+// NewRedisCacheSentinel creates and returns a reference to a RedisCache
+// instance that connects to the master that is monitored by the sentinels
+// under masterName, following it through failovers.
+func NewRedisCacheSentinel(masterName string, sentinelAddrs []string,
+	password string, db int) *RedisCache {
+	return newRedisCache(redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    masterName,
+		SentinelAddrs: sentinelAddrs,
+		Password:      password,
+		DB:            db,
+	}))
+}
+
+// NewRedisCacheWithOptions creates and returns a reference to a RedisCache
+// instance whose client is configured by opts, the defaults of go-redis are
+// used for the missing options.
+func NewRedisCacheWithOptions(opts ...RedisCacheOption) *RedisCache {
+	options := &redis.Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return newRedisCache(redis.NewClient(options))
+}
+
+func newRedisCache(client redis.Cmdable) *RedisCache {
 	return &RedisCache{
 		keysSet:        map[string]struct{}{},
 		removeChannels: map[interface{}]*cacheChannel{},
-		client: redis.NewClient(&redis.Options{
-			Addr:     address,
-			Password: password,
-			DB:       db,
-		}),
+		slidingTTLs:    map[string]time.Duration{},
+		client:         client,
 	}
 }
 
-func (r *RedisCache) store(key, val interface{}, ttl time.Duration) error {
+func (r *RedisCache) store(ctx context.Context, key, val interface{}, ttl time.Duration) error {
 	strKey := fmt.Sprintf("%v", key)
-	err := r.client.Set(context.TODO(), strKey, val, ttl).Err()
+	err := r.client.Set(ctx, strKey, val, ttl).Err()
 
 	if err != nil {
 		return newError(errorTypeRedisError, fmt.Sprintf("could not store key %v: %v", strKey, err))
@@ -57,7 +158,7 @@ func (r *RedisCache) store(key, val interface{}, ttl time.Duration) error {
 	return nil
 }
 
-func (r *RedisCache) get(key interface{}) (interface{}, error) {
+func (r *RedisCache) get(ctx context.Context, key interface{}) (interface{}, error) {
 	strKey := fmt.Sprintf("%v", key)
 
 	if _, ok := r.keysSet[strKey]; !ok {
@@ -65,8 +166,12 @@ func (r *RedisCache) get(key interface{}) (interface{}, error) {
 			fmt.Sprintf("cannot get key %v", strKey))
 	}
 
-	val, err := r.client.Get(context.TODO(), strKey).Result()
+	val, err := r.client.Get(ctx, strKey).Result()
 	if err == redis.Nil {
+		// The key expired in redis before its expiration routine forgot it,
+		// for example if the routine was delayed.
+		r.forget(key)
+
 		return nil, newError(errorTypeDoesNotExist,
 			fmt.Sprintf("key %v doesn't exist", strKey))
 	}
@@ -79,7 +184,52 @@ func (r *RedisCache) get(key interface{}) (interface{}, error) {
 	return val, nil
 }
 
-func (r *RedisCache) remove(key interface{}) error {
+func (r *RedisCache) contains(key interface{}) (bool, error) {
+	strKey := fmt.Sprintf("%v", key)
+
+	res, err := r.client.Exists(context.TODO(), strKey).Result()
+	if err != nil {
+		return false, newError(errorTypeRedisError,
+			fmt.Sprintf("failed to check if %v exists in redis", strKey))
+	}
+
+	return res > 0, nil
+}
+
+// Stores the value with SETNX, so only one of the clients that get or store
+// a missing key at the same time stores its value.
+func (r *RedisCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	strKey := fmt.Sprintf("%v", key)
+
+	for {
+		stored, err := r.client.SetNX(context.TODO(), strKey, val, 0).Result()
+		if err != nil {
+			return nil, false, newError(errorTypeRedisError,
+				fmt.Sprintf("could not store key %v: %v", strKey, err))
+		}
+
+		r.keysSet[strKey] = struct{}{}
+
+		if stored {
+			return val, false, nil
+		}
+
+		actual, err := r.client.Get(context.TODO(), strKey).Result()
+		if err == redis.Nil {
+			// The key was removed after SETNX found it, try storing again.
+			continue
+		}
+
+		if err != nil {
+			return nil, false, newError(errorTypeRedisError,
+				fmt.Sprintf("failed to get %v from redis", strKey))
+		}
+
+		return actual, true, nil
+	}
+}
+
+func (r *RedisCache) remove(ctx context.Context, key interface{}) error {
 	strKey := fmt.Sprintf("%v", key)
 
 	if _, ok := r.keysSet[strKey]; !ok {
@@ -87,7 +237,7 @@ func (r *RedisCache) remove(key interface{}) error {
 			fmt.Sprintf("cannot remove key %v", strKey))
 	}
 
-	res := r.client.Del(context.TODO(), strKey).Val()
+	res := r.client.Del(ctx, strKey).Val()
 	if res < 1 {
 
 		fmt.Println("ITAY", res)
@@ -95,24 +245,213 @@ func (r *RedisCache) remove(key interface{}) error {
 			fmt.Sprintf("could not delete key %v", strKey))
 	}
 
+	delete(r.slidingTTLs, strKey)
+
 	return nil
 }
 
-func (r *RedisCache) replace(key, val interface{}) error {
-	return r.store(key, val, 0)
+// Stop tracking a key that no longer exists in redis.
+func (r *RedisCache) forget(key interface{}) {
+	strKey := fmt.Sprintf("%v", key)
+
+	c, exists := r.removeChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(r.removeChannels, key)
+	}
+
+	delete(r.keysSet, strKey)
+	delete(r.slidingTTLs, strKey)
 }
 
-func (r *RedisCache) clear() error {
-	for key := range r.keysSet {
-		err := r.remove(key)
-		if err != nil {
-			return err
+func (r *RedisCache) bulkRemove(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+	errs := map[interface{}]error{}
+	removed := []interface{}{}
+	strKeys := []string{}
+	seen := map[string]struct{}{}
+
+	for _, key := range keys {
+		strKey := fmt.Sprintf("%v", key)
+		if _, ok := r.keysSet[strKey]; !ok {
+			errs[key] = newError(errorTypeDoesNotExist,
+				fmt.Sprintf("cannot remove key %v", strKey))
+			continue
+		}
+
+		if _, isDuplicate := seen[strKey]; isDuplicate {
+			continue
 		}
+
+		seen[strKey] = struct{}{}
+		strKeys = append(strKeys, strKey)
+		removed = append(removed, key)
+	}
+
+	if len(strKeys) == 0 {
+		return removed, combineErrors(errs)
+	}
+
+	// Like in clear, a single DEL removes either all of the keys or none of
+	// them.
+	err := r.client.Del(ctx, strKeys...).Err()
+	if err != nil {
+		return nil, newError(errorTypeRedisError,
+			fmt.Sprintf("could not remove keys: %v", err))
+	}
+
+	for _, key := range removed {
+		r.forget(key)
+	}
+
+	return removed, combineErrors(errs)
+}
+
+// Runs GET and DEL in a single transaction, so only one of the clients that
+// get and remove a key at the same time gets its value.
+func (r *RedisCache) getAndRemove(ctx context.Context, key interface{}) (interface{}, error) {
+	strKey := fmt.Sprintf("%v", key)
+
+	if _, ok := r.keysSet[strKey]; !ok {
+		return nil, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("cannot get key %v", strKey))
+	}
+
+	var getCmd *redis.StringCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(ctx, strKey)
+		pipe.Del(ctx, strKey)
+		return nil
+	})
+	if err == redis.Nil {
+		// Another client removed the key, or it expired in redis.
+		r.forget(key)
+
+		return nil, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", strKey))
+	}
+
+	if err != nil {
+		return nil, newError(errorTypeRedisError,
+			fmt.Sprintf("failed to get and remove %v from redis", strKey))
+	}
+
+	delete(r.slidingTTLs, strKey)
+
+	return getCmd.Val(), nil
+}
+
+func (r *RedisCache) replace(ctx context.Context, key, val interface{}) error {
+	return r.store(ctx, key, val, 0)
+}
+
+func (r *RedisCache) storeMany(ctx context.Context, items map[interface{}]interface{}) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	strKeys := []string{}
+	vals := map[string]interface{}{}
+	for key, val := range items {
+		strKey := fmt.Sprintf("%v", key)
+		strKeys = append(strKeys, strKey)
+		vals[strKey] = val
+	}
+
+	// Sorting the keys keeps the order of the command arguments stable.
+	sort.Strings(strKeys)
+
+	pairs := []interface{}{}
+	for _, strKey := range strKeys {
+		pairs = append(pairs, strKey, vals[strKey])
+	}
+
+	err := r.client.MSet(ctx, pairs...).Err()
+	if err != nil {
+		return newError(errorTypeRedisError, fmt.Sprintf("could not store keys: %v", err))
+	}
+
+	for _, strKey := range strKeys {
+		r.keysSet[strKey] = struct{}{}
 	}
 
 	return nil
 }
 
+func (r *RedisCache) getMany(ctx context.Context,
+	keys []interface{}) (map[interface{}]interface{}, error) {
+	vals := map[interface{}]interface{}{}
+	errs := map[interface{}]error{}
+
+	existingKeys := []interface{}{}
+	strKeys := []string{}
+	for _, key := range keys {
+		strKey := fmt.Sprintf("%v", key)
+		if _, ok := r.keysSet[strKey]; !ok {
+			errs[key] = newError(errorTypeDoesNotExist,
+				fmt.Sprintf("cannot get key %v", strKey))
+			continue
+		}
+
+		existingKeys = append(existingKeys, key)
+		strKeys = append(strKeys, strKey)
+	}
+
+	if len(strKeys) == 0 {
+		return vals, combineErrors(errs)
+	}
+
+	res, err := r.client.MGet(ctx, strKeys...).Result()
+	if err != nil {
+		return nil, newError(errorTypeRedisError,
+			fmt.Sprintf("failed to get keys from redis: %v", err))
+	}
+
+	for i, val := range res {
+		if val == nil {
+			errs[existingKeys[i]] = newError(errorTypeDoesNotExist,
+				fmt.Sprintf("key %v doesn't exist", strKeys[i]))
+			continue
+		}
+
+		vals[existingKeys[i]] = val
+	}
+
+	return vals, combineErrors(errs)
+}
+
+func (r *RedisCache) clear(ctx context.Context) error {
+	if len(r.keysSet) == 0 {
+		return nil
+	}
+
+	strKeys := []string{}
+	for strKey := range r.keysSet {
+		strKeys = append(strKeys, strKey)
+	}
+
+	// Sorting the keys keeps the order of the command arguments stable.
+	sort.Strings(strKeys)
+
+	// A single DEL removes either all of the keys or none of them, so the
+	// keys are only forgotten once it succeeds.
+	err := r.client.Del(ctx, strKeys...).Err()
+	if err != nil {
+		return newError(errorTypeRedisError, fmt.Sprintf("could not clear keys: %v", err))
+	}
+
+	for _, c := range r.removeChannels {
+		if c != nil {
+			c.signal(abort)
+		}
+	}
+
+	r.keysSet = map[string]struct{}{}
+	r.removeChannels = map[interface{}]*cacheChannel{}
+	r.slidingTTLs = map[string]time.Duration{}
+
+	return nil
+}
+
 func (r *RedisCache) keys() ([]interface{}, error) {
 	keys := []interface{}{}
 
@@ -128,7 +467,10 @@ func (r *RedisCache) storeWithExpiration(key, val interface{}, ttl time.Duration
 		return newError(errorTypeNonPositivePeriod, "period must be greater than zero")
 	}
 
-	r.store(key, val, ttl)
+	err := r.store(context.TODO(), key, val, ttl)
+	if err != nil {
+		return err
+	}
 
 	r.createExpirationRoutine(key, ttl)
 
@@ -165,6 +507,66 @@ func (r *RedisCache) expire(key interface{}, ttl time.Duration) error {
 	return nil
 }
 
+func (r *RedisCache) ttl(key interface{}) (time.Duration, bool, error) {
+	strKey := fmt.Sprintf("%v", key)
+
+	if _, ok := r.keysSet[strKey]; !ok {
+		return -1, false, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("cannot get ttl of key %v", strKey))
+	}
+
+	res, err := r.client.TTL(context.TODO(), strKey).Result()
+	if err != nil {
+		return -1, false, newError(errorTypeRedisError,
+			fmt.Sprintf("failed to get ttl of %v from redis", strKey))
+	}
+
+	// Redis returns -2 for missing keys and -1 for keys without a ttl.
+	switch res {
+	case -2:
+		return -1, false, newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v doesn't exist", strKey))
+	case -1:
+		return -1, false, nil
+	}
+
+	return res, true, nil
+}
+
+func (r *RedisCache) storeWithSlidingExpiration(key, val interface{}, ttl time.Duration) error {
+	err := r.storeWithExpiration(key, val, ttl)
+	if err != nil {
+		return err
+	}
+
+	r.slidingTTLs[fmt.Sprintf("%v", key)] = ttl
+
+	return nil
+}
+
+func (r *RedisCache) resetSlidingExpiration(ctx context.Context, key interface{}) error {
+	strKey := fmt.Sprintf("%v", key)
+
+	ttl, isSliding := r.slidingTTLs[strKey]
+	if !isSliding {
+		return nil
+	}
+
+	res := r.client.Expire(ctx, strKey, ttl).Val()
+	if !res {
+		return newError(errorTypeRedisError, fmt.Sprintf("could not expire key %v", strKey))
+	}
+
+	c, exists := r.removeChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+	}
+
+	r.createExpirationRoutine(key, ttl)
+
+	return nil
+}
+
 func (r *RedisCache) createExpirationRoutine(key interface{}, ttl time.Duration) {
 	c := newCacheChannel()
 	r.removeChannels[key] = c
@@ -194,42 +596,149 @@ func (r *RedisCache) createExpirationRoutine(key interface{}, ttl time.Duration)
 
 // Store permanent value in redis.
 func (r *RedisCache) Store(key, val interface{}) error {
+	return r.StoreCtx(context.Background(), key, val)
+}
+
+// StoreCtx stores a permanent value in redis using the given context.
+func (r *RedisCache) StoreCtx(ctx context.Context, key, val interface{}) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	return r.store(key, val, 0)
+	return r.store(ctx, key, val, 0)
 }
 
-// Get a value from redis.
+// Get a value from redis, resets the expiration of sliding keys.
 func (r *RedisCache) Get(key interface{}) (interface{}, error) {
+	return r.GetCtx(context.Background(), key)
+}
+
+// GetCtx gets a value from redis using the given context.
+func (r *RedisCache) GetCtx(ctx context.Context, key interface{}) (interface{}, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	val, err := r.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.resetSlidingExpiration(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Contains checks whether a key exists in redis.
+func (r *RedisCache) Contains(key interface{}) (bool, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	return r.get(key)
+	return r.contains(key)
+}
+
+// GetOrStore gets a value from redis, or stores val if the key does not exist.
+func (r *RedisCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.getOrStore(key, val)
 }
 
 // Remove a value from redis.
 func (r *RedisCache) Remove(key interface{}) error {
+	return r.RemoveCtx(context.Background(), key)
+}
+
+// RemoveCtx removes a value from redis using the given context.
+func (r *RedisCache) RemoveCtx(ctx context.Context, key interface{}) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.remove(ctx, key)
+}
+
+// BulkRemove removes several values from redis with a single DEL, returns the
+// removed keys along with a partial failure for the keys that are not
+// maintained by this instance.
+func (r *RedisCache) BulkRemove(keys []interface{}) ([]interface{}, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.bulkRemove(context.TODO(), keys)
+}
+
+// GetAndRemove gets a value from redis and removes it.
+func (r *RedisCache) GetAndRemove(key interface{}) (interface{}, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	return r.remove(key)
+	return r.getAndRemove(context.TODO(), key)
 }
 
 // Replace an existing value in redis.
 func (r *RedisCache) Replace(key, val interface{}) error {
+	return r.ReplaceCtx(context.Background(), key, val)
+}
+
+// ReplaceCtx replaces an existing value in redis using the given context.
+func (r *RedisCache) ReplaceCtx(ctx context.Context, key, val interface{}) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.replace(ctx, key, val)
+}
+
+// StoreOrReplace stores a permanent value in redis whether or not the key
+// already exists.
+func (r *RedisCache) StoreOrReplace(key, val interface{}) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	strKey := fmt.Sprintf("%v", key)
+
+	// SET overrides the ttl of the key, so it should no longer be tracked as
+	// a temporary key.
+	c, exists := r.removeChannels[key]
+	if exists && c != nil {
+		c.signal(abort)
+		delete(r.removeChannels, key)
+	}
+
+	delete(r.slidingTTLs, strKey)
+
+	return r.store(context.TODO(), key, val, 0)
+}
+
+// StoreMany stores several permanent values in redis using MSET.
+func (r *RedisCache) StoreMany(items map[interface{}]interface{}) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.storeMany(context.TODO(), items)
+}
+
+// GetMany gets several values from redis using MGET.
+func (r *RedisCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	return r.replace(key, val)
+	return r.getMany(context.TODO(), keys)
 }
 
 // Clear all values that maintained by this RedisCache instance.
 func (r *RedisCache) Clear() error {
+	return r.ClearCtx(context.Background())
+}
+
+// ClearCtx clears all values that maintained by this RedisCache instance
+// using the given context.
+func (r *RedisCache) ClearCtx(ctx context.Context) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	return r.clear()
+	return r.clear(ctx)
 }
 
 // Keys return all keys that maintained by this RedisCache instance.
@@ -240,6 +749,49 @@ func (r *RedisCache) Keys() ([]interface{}, error) {
 	return r.keys()
 }
 
+// ForEach calls fn for each key and value that are maintained by this
+// RedisCache instance until fn returns false.
+func (r *RedisCache) ForEach(fn func(key, val interface{}) bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	keys, err := r.keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		val, err := r.get(context.TODO(), key)
+		if IsDoesNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if !fn(key, val) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// FilterKeys gets the keys maintained by this instance for which predicate
+// returns true, the keys are filtered locally without calling redis.
+func (r *RedisCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	keys := []interface{}{}
+	for key := range r.keysSet {
+		if predicate(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
 // StoreWithExpiration stores a key-value pair in redis for limited time.
 func (r *RedisCache) StoreWithExpiration(key, val interface{}, ttl time.Duration) error {
 	r.mutex.Lock()
@@ -248,6 +800,19 @@ func (r *RedisCache) StoreWithExpiration(key, val interface{}, ttl time.Duration
 	return r.storeWithExpiration(key, val, ttl)
 }
 
+// StoreWithDeadline stores a key-value pair in redis until deadline.
+func (r *RedisCache) StoreWithDeadline(key, val interface{}, deadline time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ttl, err := ttlUntil(deadline)
+	if err != nil {
+		return err
+	}
+
+	return r.storeWithExpiration(key, val, ttl)
+}
+
 // ReplaceWithExpiration replaces a key-value pair in redis for limited time.
 func (r *RedisCache) ReplaceWithExpiration(key, val interface{}, ttl time.Duration) error {
 	r.mutex.Lock()
@@ -256,6 +821,23 @@ func (r *RedisCache) ReplaceWithExpiration(key, val interface{}, ttl time.Durati
 	return r.replaceWithExpiration(key, val, ttl)
 }
 
+// TTL returns the remaining ttl of a key in redis.
+func (r *RedisCache) TTL(key interface{}) (time.Duration, bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.ttl(key)
+}
+
+// StoreWithSlidingExpiration stores a key-value pair in redis that expires
+// after it was not accessed for ttl.
+func (r *RedisCache) StoreWithSlidingExpiration(key, val interface{}, ttl time.Duration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.storeWithSlidingExpiration(key, val, ttl)
+}
+
 // Expire a key-value pair.
 func (r *RedisCache) Expire(key interface{}, ttl time.Duration) error {
 	r.mutex.Lock()
@@ -1,9 +1,14 @@
 package cache
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/go-redis/redismock/v8"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -23,6 +28,50 @@ var _ = Describe("Redis Cache", func() {
 		mock = m
 	})
 
+	Context("NewRedisCacheWithOptions", func() {
+		It("should configure the client with the given options", func() {
+			cfg := &tls.Config{}
+			rc := NewRedisCacheWithOptions(
+				WithRedisAddress("127.0.0.1:6380"),
+				WithRedisPassword("password"),
+				WithRedisDB(2),
+				WithRedisTLS(cfg),
+				WithRedisPoolSize(20),
+				WithRedisDialTimeout(time.Second),
+			)
+
+			options := rc.client.(*redis.Client).Options()
+			Expect(options.Addr).To(Equal("127.0.0.1:6380"))
+			Expect(options.Password).To(Equal("password"))
+			Expect(options.DB).To(Equal(2))
+			Expect(options.TLSConfig).To(BeIdenticalTo(cfg))
+			Expect(options.PoolSize).To(Equal(20))
+			Expect(options.DialTimeout).To(Equal(time.Second))
+		})
+	})
+
+	Context("NewRedisCacheWithCluster", func() {
+		It("should use a cluster client", func() {
+			rc := NewRedisCacheWithCluster([]string{"127.0.0.1:7000", "127.0.0.1:7001"}, "password")
+
+			client, isCluster := rc.client.(*redis.ClusterClient)
+			Expect(isCluster).To(BeTrue())
+			Expect(client.Options().Addrs).To(Equal([]string{"127.0.0.1:7000", "127.0.0.1:7001"}))
+			Expect(client.Options().Password).To(Equal("password"))
+		})
+	})
+
+	Context("NewRedisCacheSentinel", func() {
+		It("should use a failover client", func() {
+			rc := NewRedisCacheSentinel("mymaster", []string{"127.0.0.1:26379"}, "password", 1)
+
+			client, isClient := rc.client.(*redis.Client)
+			Expect(isClient).To(BeTrue())
+			Expect(client.Options().Password).To(Equal("password"))
+			Expect(client.Options().DB).To(Equal(1))
+		})
+	})
+
 	Context("Store", func() {
 		It("should store a value", func() {
 			mock.ExpectSet(key, val, 0).SetVal("OK")
@@ -66,6 +115,65 @@ var _ = Describe("Redis Cache", func() {
 		})
 	})
 
+	Context("Contains", func() {
+		It("should return true for an existing key", func() {
+			mock.ExpectExists(key).SetVal(1)
+			Expect(c.Contains(key)).To(BeTrue())
+		})
+
+		It("should return false for a non-existent key", func() {
+			mock.ExpectExists(nonExistentKey).SetVal(0)
+			Expect(c.Contains(nonExistentKey)).To(BeFalse())
+		})
+	})
+
+	Context("GetOrStore", func() {
+		It("should store a value when the key does not exist", func() {
+			mock.ExpectSetNX(key, val, 0).SetVal(true)
+
+			actual, loaded, err := c.GetOrStore(key, val)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeFalse())
+			Expect(actual).To(Equal(val))
+		})
+
+		It("should return the existing value when the key exists", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectSetNX(key, "new-val", 0).SetVal(false)
+			mock.ExpectGet(key).SetVal(val)
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			actual, loaded, err := c.GetOrStore(key, "new-val")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeTrue())
+			Expect(actual).To(Equal(val))
+		})
+
+		It("should return the value that another client stored", func() {
+			mock.ExpectSetNX(key, "new-val", 0).SetVal(false)
+			mock.ExpectGet(key).SetVal(val)
+
+			actual, loaded, err := c.GetOrStore(key, "new-val")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeTrue())
+			Expect(actual).To(Equal(val))
+
+			mock.ExpectGet(key).SetVal(val)
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should store the value again if the key is removed before it is read", func() {
+			mock.ExpectSetNX(key, val, 0).SetVal(false)
+			mock.ExpectGet(key).RedisNil()
+			mock.ExpectSetNX(key, val, 0).SetVal(true)
+
+			actual, loaded, err := c.GetOrStore(key, val)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeFalse())
+			Expect(actual).To(Equal(val))
+		})
+	})
+
 	Context("Remove", func() {
 		BeforeEach(func() {
 			mock.ExpectSet(key, val, 0).SetVal("OK")
@@ -91,6 +199,67 @@ var _ = Describe("Redis Cache", func() {
 		})
 	})
 
+	Context("BulkRemove", func() {
+		It("should remove the values with a single DEL", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectSet("other-key", val, 0).SetVal("OK")
+			mock.ExpectDel(key, "other-key").SetVal(2)
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Store("other-key", val)).ToNot(HaveOccurred())
+
+			removed, err := c.BulkRemove([]interface{}{key, "other-key", nonExistentKey})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(removed).To(Equal([]interface{}{key, "other-key"}))
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+			Expect(c.keysSet).To(BeEmpty())
+		})
+
+		It("should keep track of the keys when DEL fails", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectDel(key).SetErr(fmt.Errorf("connection refused"))
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			removed, err := c.BulkRemove([]interface{}{key})
+			Expect(IsRedisError(err)).To(BeTrue())
+			Expect(removed).To(BeEmpty())
+			Expect(c.keysSet).To(HaveKey(key))
+		})
+	})
+
+	Context("GetAndRemove", func() {
+		It("should return a value and remove it", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectTxPipeline()
+			mock.ExpectGet(key).SetVal(val)
+			mock.ExpectDel(key).SetVal(1)
+			mock.ExpectTxPipelineExec()
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.GetAndRemove(key)).To(Equal(val))
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("should return an error if another client removed the value first", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectTxPipeline()
+			mock.ExpectGet(key).RedisNil()
+			mock.ExpectDel(key).SetVal(0)
+			mock.ExpectTxPipelineExec()
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			_, err := c.GetAndRemove(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+			Expect(c.keysSet).ToNot(HaveKey(key))
+		})
+
+		It("should return an error when attempting to get a non-existent value", func() {
+			_, err := c.GetAndRemove(nonExistentKey)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
 	Context("Replace", func() {
 		BeforeEach(func() {
 			mock.ExpectSet(key, val, 0).SetVal("OK")
@@ -153,9 +322,134 @@ var _ = Describe("Redis Cache", func() {
 			Expect(c.Get(key)).To(Equal(val), "value was not stored in cache")
 		})
 
+		It("should forget a value that expired in redis before its routine removed it", func() {
+			mock.ExpectSet(key, val, time.Minute).SetVal("OK")
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+
+			mock.ExpectGet(key).RedisNil()
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+
+			Expect(c.keysSet).ToNot(HaveKey(key))
+			Expect(c.removeChannels).ToNot(HaveKey(key))
+			Expect(c.FilterKeys(func(interface{}) bool { return true })).To(BeEmpty())
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
 		It("should return an error if ttl is non-positive", func() {
 			Expect(IsNonPositivePeriod(c.StoreWithExpiration(key, val, 0))).To(BeTrue())
 		})
+
+		It("should not expire a value that redis failed to store", func() {
+			mock.ExpectSet(key, val, time.Minute).SetErr(errors.New("connection refused"))
+			Expect(IsRedisError(c.StoreWithExpiration(key, val, time.Minute))).To(BeTrue())
+
+			Expect(c.keysSet).ToNot(HaveKey(key))
+			Expect(c.removeChannels).ToNot(HaveKey(key))
+		})
+	})
+
+	Context("StoreWithDeadline", func() {
+		It("should return an error if the deadline has passed", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithDeadline(key, val, time.Now().Add(-time.Minute)))).To(BeTrue())
+		})
+	})
+
+	Context("TTL", func() {
+		It("should return the remaining ttl of a temporary value", func() {
+			mock.ExpectSet(key, val, time.Minute).SetVal("OK")
+			Expect(c.StoreWithExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+
+			mock.ExpectTTL(key).SetVal(time.Minute)
+			remaining, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(remaining).To(Equal(time.Minute))
+		})
+
+		It("should return no ttl for a permanent value", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			mock.ExpectTTL(key).SetVal(-1)
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeFalse())
+		})
+
+		It("should return an error for a non-existent key", func() {
+			_, _, err := c.TTL(nonExistentKey)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("StoreWithSlidingExpiration", func() {
+		It("should reset the ttl of a value when it is accessed", func() {
+			mock.ExpectSet(key, val, time.Minute).SetVal("OK")
+			Expect(c.StoreWithSlidingExpiration(key, val, time.Minute)).ToNot(HaveOccurred())
+
+			mock.ExpectGet(key).SetVal(val)
+			mock.ExpectExpire(key, time.Minute).SetVal(true)
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		It("should return an error if ttl is non-positive", func() {
+			Expect(IsNonPositivePeriod(c.StoreWithSlidingExpiration(key, val, 0))).To(BeTrue())
+		})
+	})
+
+	Context("Context", func() {
+		It("should store, get and remove a value using a context", func() {
+			ctx := context.Background()
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectGet(key).SetVal(val)
+			mock.ExpectDel(key).SetVal(1)
+
+			Expect(c.StoreCtx(ctx, key, val)).ToNot(HaveOccurred())
+			Expect(c.GetCtx(ctx, key)).To(Equal(val))
+			Expect(c.RemoveCtx(ctx, key)).ToNot(HaveOccurred())
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("StoreMany", func() {
+		It("should store all values using a single command", func() {
+			mock.ExpectMSet("other-key", "other-val", key, val).SetVal("OK")
+
+			Expect(c.StoreMany(map[interface{}]interface{}{key: val, "other-key": "other-val"})).
+				ToNot(HaveOccurred())
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("GetMany", func() {
+		It("should return the found values along with a partial failure", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			mock.ExpectMGet(key).SetVal([]interface{}{val})
+			vals, err := c.GetMany([]interface{}{key, nonExistentKey})
+			Expect(IsPartialFailure(err)).To(BeTrue())
+			Expect(vals).To(Equal(map[interface{}]interface{}{key: val}))
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("ForEach", func() {
+		It("should iterate over all values", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			mock.ExpectGet(key).SetVal(val)
+			vals := map[interface{}]interface{}{}
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				vals[key] = val
+				return true
+			})).ToNot(HaveOccurred())
+			Expect(vals).To(Equal(map[interface{}]interface{}{key: val}))
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
 	})
 
 	Context("Clear", func() {
@@ -164,6 +458,27 @@ var _ = Describe("Redis Cache", func() {
 			_, err := c.Get(key)
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("should delete all keys with a single DEL", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectSet(nonExistentKey, val, 0).SetVal("OK")
+			mock.ExpectDel(nonExistentKey, key).SetVal(2)
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Store(nonExistentKey, val)).ToNot(HaveOccurred())
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+			Expect(c.keysSet).To(BeEmpty())
+		})
+
+		It("should keep track of the keys when DEL fails", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectDel(key).SetErr(fmt.Errorf("connection refused"))
+
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsRedisError(c.Clear())).To(BeTrue())
+			Expect(c.keysSet).To(HaveKey(key))
+		})
 	})
 
 	Context("Keys", func() {
@@ -179,4 +494,20 @@ var _ = Describe("Redis Cache", func() {
 			Expect(keys[0]).To(Equal(key))
 		})
 	})
+
+	Context("FilterKeys", func() {
+		It("should filter the keys without calling redis", func() {
+			mock.ExpectSet(key, val, 0).SetVal("OK")
+			mock.ExpectSet("other-key", val, 0).SetVal("OK")
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Store("other-key", val)).ToNot(HaveOccurred())
+
+			keys, err := c.FilterKeys(func(k interface{}) bool {
+				return strings.HasPrefix(k.(string), "test")
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]interface{}{key}))
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"crypto/rand"
+	"math/big"
+	"time"
+)
+
+type retryCache struct {
+	Cache
+
+	// The number of times a failed operation is retried.
+	maxRetries int
+
+	// How long to wait before the first retry, doubled for every retry.
+	backoff time.Duration
+
+	// Waits between attempts, replaced by tests.
+	sleep func(d time.Duration)
+}
+
+var _ Cache = (*retryCache)(nil)
+
+// NewCacheWithRetry creates a new Cache object that retries Store, Get, Remove
+// and Replace on underlying up to maxRetries times when they fail with an
+// unexpected error or a Redis error.
+//
+// The wait before a retry starts at backoff and doubles for every retry, a
+// random jitter of up to half the wait is added to it.
+func NewCacheWithRetry(underlying Cache, maxRetries int, backoff time.Duration) Cache {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	return &retryCache{
+		Cache:      underlying,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		sleep:      time.Sleep,
+	}
+}
+
+// Returns whether an operation that failed with err may be retried.
+func isTransient(err error) bool {
+	return IsUnexpectedError(err) || IsRedisError(err)
+}
+
+// Returns how long to wait before the given retry, starting at 0.
+func (rc *retryCache) delay(retry int) time.Duration {
+	d := rc.backoff << uint(retry)
+	if d <= 0 {
+		return 0
+	}
+
+	// Without randomness the retries are made without jitter.
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(d/2)+1))
+	if err != nil {
+		return d
+	}
+
+	return d + time.Duration(jitter.Int64())
+}
+
+// Calls op until it does not fail with a transient error or no retries are
+// left.
+func (rc *retryCache) retry(op func() error) error {
+	err := op()
+	for retry := 0; retry < rc.maxRetries && isTransient(err); retry++ {
+		rc.sleep(rc.delay(retry))
+		err = op()
+	}
+
+	return err
+}
+
+// Store a permanent value, retrying transient errors.
+//
+// A retried Store fails with AlreadyExists if the failed attempt had stored
+// the value.
+func (rc *retryCache) Store(key, val interface{}) error {
+	return rc.retry(func() error {
+		return rc.Cache.Store(key, val)
+	})
+}
+
+// Get a value, retrying transient errors.
+func (rc *retryCache) Get(key interface{}) (interface{}, error) {
+	var val interface{}
+	err := rc.retry(func() error {
+		var err error
+		val, err = rc.Cache.Get(key)
+		return err
+	})
+
+	return val, err
+}
+
+// Remove a value, retrying transient errors.
+func (rc *retryCache) Remove(key interface{}) error {
+	return rc.retry(func() error {
+		return rc.Cache.Remove(key)
+	})
+}
+
+// Replace a value, retrying transient errors.
+func (rc *retryCache) Replace(key, val interface{}) error {
+	return rc.retry(func() error {
+		return rc.Cache.Replace(key, val)
+	})
+}
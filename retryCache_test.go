@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Fails the next failures calls of Store, Get, Remove and Replace with err.
+type flakyCache struct {
+	Cache
+	failures int
+	err      error
+	calls    int
+}
+
+func (fc *flakyCache) fail() error {
+	fc.calls++
+	if fc.failures > 0 {
+		fc.failures--
+		return fc.err
+	}
+
+	return nil
+}
+
+func (fc *flakyCache) Store(key, val interface{}) error {
+	if err := fc.fail(); err != nil {
+		return err
+	}
+
+	return fc.Cache.Store(key, val)
+}
+
+func (fc *flakyCache) Get(key interface{}) (interface{}, error) {
+	if err := fc.fail(); err != nil {
+		return nil, err
+	}
+
+	return fc.Cache.Get(key)
+}
+
+var _ = Describe("Retry Cache", func() {
+	var (
+		c        Cache
+		flaky    *flakyCache
+		sleeps   []time.Duration
+		key, val string = "key", "val"
+	)
+
+	BeforeEach(func() {
+		flaky = &flakyCache{
+			Cache: NewMapCache(),
+			err:   newError(errorTypeUnexpectedError, "transient"),
+		}
+		sleeps = nil
+
+		c = NewCacheWithRetry(flaky, 3, 10*time.Millisecond)
+		c.(*retryCache).sleep = func(d time.Duration) {
+			sleeps = append(sleeps, d)
+		}
+	})
+
+	Context("Store", func() {
+		It("should retry transient errors", func() {
+			flaky.failures = 2
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(flaky.calls).To(Equal(3))
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should retry Redis errors", func() {
+			flaky.failures = 1
+			flaky.err = newError(errorTypeRedisError, "connection refused")
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(flaky.calls).To(Equal(2))
+		})
+
+		It("should return the error once no retries are left", func() {
+			flaky.failures = 10
+			Expect(IsUnexpectedError(c.Store(key, val))).To(BeTrue())
+			Expect(flaky.calls).To(Equal(4))
+		})
+
+		It("should not retry AlreadyExists", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(key, val))).To(BeTrue())
+			Expect(flaky.calls).To(Equal(2))
+			Expect(sleeps).To(BeEmpty())
+		})
+	})
+
+	Context("Get", func() {
+		It("should not retry DoesNotExist", func() {
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+			Expect(flaky.calls).To(Equal(1))
+		})
+	})
+
+	Context("Backoff", func() {
+		It("should double the wait for every retry and add jitter", func() {
+			flaky.failures = 3
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+			Expect(sleeps).To(HaveLen(3))
+			for i, d := range sleeps {
+				base := (10 * time.Millisecond) << uint(i)
+				Expect(d).To(BeNumerically(">=", base))
+				Expect(d).To(BeNumerically("<=", base+base/2))
+			}
+		})
+	})
+})
@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+type shardedMapCache struct {
+	// The map caches that hold the data, each key belongs to a single shard.
+	shards []*mapCache
+}
+
+var _ UpdatingExpiringCache = (*shardedMapCache)(nil)
+
+// NewShardedMapCache creates a new Cache object that is backed by several
+// maps, each guarded by its own mutex.
+//
+// If shards is smaller than one, a single shard will be used, opts are applied
+// to every shard.
+func NewShardedMapCache(shards int, opts ...MapCacheOption) UpdatingExpiringCache {
+	if shards < 1 {
+		shards = 1
+	}
+
+	smc := &shardedMapCache{
+		shards: make([]*mapCache, shards),
+	}
+
+	for i := range smc.shards {
+		smc.shards[i] = NewMapCache(opts...)
+	}
+
+	return smc
+}
+
+func (smc *shardedMapCache) shard(key interface{}) *mapCache {
+	h := fnv.New32a()
+
+	// Writing to a hash never returns an error.
+	if strKey, isStr := key.(string); isStr {
+		_, _ = h.Write([]byte(strKey))
+	} else {
+		_, _ = h.Write([]byte(fmt.Sprintf("%v", key)))
+	}
+
+	return smc.shards[h.Sum32()%uint32(len(smc.shards))]
+}
+
+// Store permanent value in the key's shard.
+func (smc *shardedMapCache) Store(key, val interface{}) error {
+	return smc.shard(key).Store(key, val)
+}
+
+// Get a value from the key's shard.
+func (smc *shardedMapCache) Get(key interface{}) (interface{}, error) {
+	return smc.shard(key).Get(key)
+}
+
+// Check whether a key exists in the key's shard.
+func (smc *shardedMapCache) Contains(key interface{}) (bool, error) {
+	return smc.shard(key).Contains(key)
+}
+
+// Get a value from the key's shard, or store val if the key does not exist.
+func (smc *shardedMapCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	return smc.shard(key).GetOrStore(key, val)
+}
+
+// Remove a value from the key's shard.
+func (smc *shardedMapCache) Remove(key interface{}) error {
+	return smc.shard(key).Remove(key)
+}
+
+// Get a value from the key's shard and remove it.
+func (smc *shardedMapCache) GetAndRemove(key interface{}) (interface{}, error) {
+	return smc.shard(key).GetAndRemove(key)
+}
+
+// Replace a value in the key's shard.
+func (smc *shardedMapCache) Replace(key, val interface{}) error {
+	return smc.shard(key).Replace(key, val)
+}
+
+// Store a permanent value in the key's shard, replacing the current value if
+// it exists.
+func (smc *shardedMapCache) StoreOrReplace(key, val interface{}) error {
+	return smc.shard(key).StoreOrReplace(key, val)
+}
+
+// Store several permanent values, each in its key's shard.
+func (smc *shardedMapCache) StoreMany(items map[interface{}]interface{}) error {
+	return storeMany(items, smc.Store)
+}
+
+// Get several values, each from its key's shard.
+func (smc *shardedMapCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	return getMany(keys, smc.Get)
+}
+
+// Clear all shards.
+func (smc *shardedMapCache) Clear() error {
+	for _, shard := range smc.shards {
+		err := shard.Clear()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the keys of all shards.
+func (smc *shardedMapCache) Keys() ([]interface{}, error) {
+	keys := []interface{}{}
+
+	for _, shard := range smc.shards {
+		shardKeys, err := shard.Keys()
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, shardKeys...)
+	}
+
+	return keys, nil
+}
+
+// Calls fn for each key and value of all shards until fn returns false, each
+// shard is locked separately.
+func (smc *shardedMapCache) ForEach(fn func(key, val interface{}) bool) error {
+	proceed := true
+	for _, shard := range smc.shards {
+		err := shard.ForEach(func(key, val interface{}) bool {
+			proceed = fn(key, val)
+			return proceed
+		})
+		if err != nil || !proceed {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the keys of all shards for which predicate returns true.
+func (smc *shardedMapCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	keys := []interface{}{}
+
+	for _, shard := range smc.shards {
+		shardKeys, err := shard.FilterKeys(predicate)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, shardKeys...)
+	}
+
+	return keys, nil
+}
+
+// Store a temporary value in the key's shard, ttl must be greater than zero.
+func (smc *shardedMapCache) StoreWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	return smc.shard(key).StoreWithExpiration(key, val, ttl)
+}
+
+// Store a temporary value in the key's shard that is removed at deadline,
+// deadline must be in the future.
+func (smc *shardedMapCache) StoreWithDeadline(key, val interface{},
+	deadline time.Time) error {
+	return smc.shard(key).StoreWithDeadline(key, val, deadline)
+}
+
+// Replace a value in the key's shard with a temporary value, ttl must be
+// greater than zero.
+func (smc *shardedMapCache) ReplaceWithExpiration(key, val interface{},
+	ttl time.Duration) error {
+	return smc.shard(key).ReplaceWithExpiration(key, val, ttl)
+}
+
+// Update the expiration of a value in the key's shard, ttl must be greater
+// than zero.
+func (smc *shardedMapCache) Expire(key interface{}, ttl time.Duration) error {
+	return smc.shard(key).Expire(key, ttl)
+}
+
+// Get the remaining ttl of a value in the key's shard.
+func (smc *shardedMapCache) TTL(key interface{}) (time.Duration, bool, error) {
+	return smc.shard(key).TTL(key)
+}
+
+// Store a temporary value in the key's shard, every Get resets its ttl, ttl
+// must be greater than zero.
+func (smc *shardedMapCache) StoreWithSlidingExpiration(key, val interface{},
+	ttl time.Duration) error {
+	return smc.shard(key).StoreWithSlidingExpiration(key, val, ttl)
+}
+
+// Store an updating value in the key's shard.
+func (smc *shardedMapCache) StoreWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	return smc.shard(key).StoreWithUpdate(key, initialValue, updateFunc, period)
+}
+
+// Replace a value in the key's shard with a continously updating value.
+func (smc *shardedMapCache) ReplaceWithUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	period time.Duration) error {
+	return smc.shard(key).ReplaceWithUpdate(key, initialValue, updateFunc, period)
+}
+
+// Store an updating value in the key's shard that is removed after totalTTL.
+func (smc *shardedMapCache) StoreWithExpirationAndUpdate(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	return smc.shard(key).StoreWithExpirationAndUpdate(key, initialValue, updateFunc,
+		updatePeriod, totalTTL)
+}
+
+// Store an updating value in the key's shard that is removed once it was not
+// read for totalTTL.
+func (smc *shardedMapCache) StoreWithUpdateAndExpiration(key, initialValue interface{},
+	updateFunc func(currValue interface{}) (interface{}, error),
+	updatePeriod, totalTTL time.Duration) error {
+	return smc.shard(key).StoreWithUpdateAndExpiration(key, initialValue, updateFunc,
+		updatePeriod, totalTTL)
+}
+
+// Stop updating a value in the key's shard.
+func (smc *shardedMapCache) CancelUpdate(key interface{}) error {
+	return smc.shard(key).CancelUpdate(key)
+}
+
+// Change the update period of a value in the key's shard.
+func (smc *shardedMapCache) SetUpdatePeriod(key interface{}, newPeriod time.Duration) error {
+	return smc.shard(key).SetUpdatePeriod(key, newPeriod)
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sharded Map Cache", func() {
+	var (
+		c        UpdatingExpiringCache
+		key, val string = "test-key", "test-val"
+	)
+
+	BeforeEach(func() {
+		c = NewShardedMapCache(16)
+	})
+
+	Context("Store", func() {
+		It("should store a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred(), "failed storing a value")
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(key, val))).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return the keys of all shards", func() {
+			for i := 0; i < 100; i++ {
+				Expect(c.Store(fmt.Sprintf("%s-%d", key, i), val)).ToNot(HaveOccurred())
+			}
+
+			keys, err := c.Keys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(100))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove the values of all shards", func() {
+			for i := 0; i < 100; i++ {
+				Expect(c.Store(fmt.Sprintf("%s-%d", key, i), val)).ToNot(HaveOccurred())
+			}
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			keys, err := c.Keys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(BeEmpty())
+		})
+	})
+
+	Context("StoreWithExpiration", func() {
+		It("should remove a value after timeout", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Second)).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				_, err := c.Get(key)
+				return IsDoesNotExist(err)
+			}, testTimeout).Should(BeTrue(),
+				"value was not removed from cache after timeout")
+		})
+	})
+})
+
+func benchmarkCacheParallel(b *testing.B, c Cache) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		if err := c.Store(keys[i], i); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%4 == 0 {
+				_ = c.Replace(key, i)
+			} else {
+				_, _ = c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMapCache(b *testing.B) {
+	benchmarkCacheParallel(b, NewMapCache())
+}
+
+func BenchmarkShardedMapCache16(b *testing.B) {
+	benchmarkCacheParallel(b, NewShardedMapCache(16))
+}
+
+func BenchmarkShardedMapCache64(b *testing.B) {
+	benchmarkCacheParallel(b, NewShardedMapCache(64))
+}
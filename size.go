@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"reflect"
+)
+
+// Estimates the amount of memory used by val, including the memory that is
+// referenced by its pointers, slices, maps and strings.
+func sizeOf(val interface{}) int64 {
+	if val == nil {
+		return 0
+	}
+
+	v := reflect.ValueOf(val)
+
+	return int64(v.Type().Size()) + referencedSize(v, map[uintptr]struct{}{})
+}
+
+// Estimates the amount of memory that is referenced by v, excluding the size
+// of v itself, seen holds the addresses that were already counted.
+func referencedSize(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Ptr:
+		if v.IsNil() || isSeen(v.Pointer(), seen) {
+			return 0
+		}
+
+		return int64(v.Elem().Type().Size()) + referencedSize(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		return int64(v.Elem().Type().Size()) + referencedSize(v.Elem(), seen)
+	case reflect.Slice:
+		if v.IsNil() || isSeen(v.Pointer(), seen) {
+			return 0
+		}
+
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			size += referencedSize(v.Index(i), seen)
+		}
+
+		return size
+	case reflect.Array:
+		size := int64(0)
+		for i := 0; i < v.Len(); i++ {
+			size += referencedSize(v.Index(i), seen)
+		}
+
+		return size
+	case reflect.Map:
+		if v.IsNil() || isSeen(v.Pointer(), seen) {
+			return 0
+		}
+
+		size := int64(0)
+		iter := v.MapRange()
+		for iter.Next() {
+			size += int64(iter.Key().Type().Size()) + referencedSize(iter.Key(), seen)
+			size += int64(iter.Value().Type().Size()) + referencedSize(iter.Value(), seen)
+		}
+
+		return size
+	case reflect.Struct:
+		size := int64(0)
+		for i := 0; i < v.NumField(); i++ {
+			size += referencedSize(v.Field(i), seen)
+		}
+
+		return size
+	default:
+		return 0
+	}
+}
+
+func isSeen(ptr uintptr, seen map[uintptr]struct{}) bool {
+	if _, exists := seen[ptr]; exists {
+		return true
+	}
+
+	seen[ptr] = struct{}{}
+
+	return false
+}
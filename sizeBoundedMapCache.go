@@ -0,0 +1,310 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+type sizeBoundedMapCache struct {
+	// The maximal total size of the stored values in bytes.
+	maxBytes int64
+
+	// The total size of the stored values in bytes.
+	size int64
+
+	// Holds the size of each stored value.
+	sizes map[interface{}]int64
+
+	// A cache that holds the data.
+	storage *mapCache
+
+	// Decides which values are evicted when there is not enough space.
+	policy EvictionPolicy
+
+	mutex sync.Mutex
+}
+
+var _ Cache = (*sizeBoundedMapCache)(nil)
+
+// NewSizeBoundedMapCache creates a new Cache object that is backed by a map,
+// in which the total size of the values never exceeds maxBytes. When a new
+// value does not fit, values are evicted in the order decided by policy until
+// it does.
+//
+// The size of a value is the length of its JSON encoding, values that cannot
+// be encoded as JSON cannot be stored. A replaced value is treated by policy
+// as a newly stored one. maxBytes must be greater than zero.
+func NewSizeBoundedMapCache(maxBytes int64, policy EvictionPolicy) (Cache, error) {
+	if maxBytes < 1 {
+		return nil, newError(errorTypeNonPositivePeriod, "size limit must be greater than zero")
+	}
+
+	return &sizeBoundedMapCache{
+		maxBytes: maxBytes,
+		sizes:    map[interface{}]int64{},
+		storage:  NewMapCache(),
+		policy:   policy,
+	}, nil
+}
+
+// Returns the length of the JSON encoding of val.
+func jsonSizeOf(key, val interface{}) (int64, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return 0, newWrapperError(errorTypeInvalidValueType,
+			fmt.Sprintf("value of key %v cannot be encoded as json", key), err)
+	}
+
+	return int64(len(data)), nil
+}
+
+// Evicts values until there is room for another size bytes.
+func (sbc *sizeBoundedMapCache) makeRoom(size int64) error {
+	if size > sbc.maxBytes {
+		return newError(errorTypeCapacityExceeded,
+			fmt.Sprintf("a value of %v bytes exceeds the limit of %v bytes",
+				size, sbc.maxBytes))
+	}
+
+	for sbc.size+size > sbc.maxBytes {
+		key, ok := sbc.policy.Victim()
+		if !ok {
+			break
+		}
+
+		_, err := sbc.remove(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Store a value in the map, evicting values if it does not fit.
+func (sbc *sizeBoundedMapCache) Store(key, val interface{}) error {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.store(key, val)
+}
+
+func (sbc *sizeBoundedMapCache) store(key, val interface{}) error {
+	if _, exists := sbc.sizes[key]; exists {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	size, err := jsonSizeOf(key, val)
+	if err != nil {
+		return err
+	}
+
+	err = sbc.makeRoom(size)
+	if err != nil {
+		return err
+	}
+
+	err = sbc.storage.Store(key, val)
+	if err != nil {
+		return err
+	}
+
+	sbc.policy.Add(key)
+	sbc.sizes[key] = size
+	sbc.size += size
+
+	return nil
+}
+
+// Get a value from the map.
+func (sbc *sizeBoundedMapCache) Get(key interface{}) (interface{}, error) {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.get(key)
+}
+
+func (sbc *sizeBoundedMapCache) get(key interface{}) (interface{}, error) {
+	val, err := sbc.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sbc.policy.Touch(key)
+
+	return val, nil
+}
+
+// Check whether a key exists in the map.
+func (sbc *sizeBoundedMapCache) Contains(key interface{}) (bool, error) {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.storage.Contains(key)
+}
+
+// Get a value from the map, or store val if the key does not exist.
+func (sbc *sizeBoundedMapCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	actual, err := sbc.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = sbc.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a value from the map.
+func (sbc *sizeBoundedMapCache) Remove(key interface{}) error {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	_, err := sbc.remove(key)
+
+	return err
+}
+
+// Get a value from the map and remove it.
+func (sbc *sizeBoundedMapCache) GetAndRemove(key interface{}) (interface{}, error) {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.remove(key)
+}
+
+func (sbc *sizeBoundedMapCache) remove(key interface{}) (interface{}, error) {
+	val, err := sbc.storage.GetAndRemove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sbc.policy.Remove(key)
+	sbc.size -= sbc.sizes[key]
+	delete(sbc.sizes, key)
+
+	return val, nil
+}
+
+// Replace a value in the map, evicting other values if it does not fit.
+func (sbc *sizeBoundedMapCache) Replace(key, val interface{}) error {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.replace(key, val)
+}
+
+func (sbc *sizeBoundedMapCache) replace(key, val interface{}) error {
+	if _, exists := sbc.sizes[key]; !exists {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v does not exist", key))
+	}
+
+	size, err := jsonSizeOf(key, val)
+	if err != nil {
+		return err
+	}
+
+	if size > sbc.maxBytes {
+		return newError(errorTypeCapacityExceeded,
+			fmt.Sprintf("a value of %v bytes exceeds the limit of %v bytes",
+				size, sbc.maxBytes))
+	}
+
+	_, err = sbc.remove(key)
+	if err != nil {
+		return err
+	}
+
+	return sbc.store(key, val)
+}
+
+// Store a value in the map, replacing the current value if the key exists.
+func (sbc *sizeBoundedMapCache) StoreOrReplace(key, val interface{}) error {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	err := sbc.replace(key, val)
+	if IsDoesNotExist(err) {
+		return sbc.store(key, val)
+	}
+
+	return err
+}
+
+// Store several values in the map.
+func (sbc *sizeBoundedMapCache) StoreMany(items map[interface{}]interface{}) error {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return storeMany(items, sbc.store)
+}
+
+// Get several values from the map.
+func (sbc *sizeBoundedMapCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return getMany(keys, sbc.get)
+}
+
+// Clear the map.
+func (sbc *sizeBoundedMapCache) Clear() error {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	err := sbc.storage.Clear()
+	if err != nil {
+		return err
+	}
+
+	sbc.policy.Clear()
+	sbc.sizes = map[interface{}]int64{}
+	sbc.size = 0
+
+	return nil
+}
+
+// Get all keys from the map.
+func (sbc *sizeBoundedMapCache) Keys() ([]interface{}, error) {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.storage.Keys()
+}
+
+// Calls fn for each key and value in the map until fn returns false.
+func (sbc *sizeBoundedMapCache) ForEach(fn func(key, val interface{}) bool) error {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.storage.ForEach(fn)
+}
+
+// Get the keys for which predicate returns true.
+func (sbc *sizeBoundedMapCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.storage.FilterKeys(predicate)
+}
+
+// Size returns the total size of the stored values in bytes.
+func (sbc *sizeBoundedMapCache) Size() int64 {
+	sbc.mutex.Lock()
+	defer sbc.mutex.Unlock()
+
+	return sbc.size
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Size Bounded Map Cache", func() {
+	// Every value is encoded as 12 bytes of JSON, so the cache fits three.
+	const maxBytes = 36
+
+	var (
+		c             Cache
+		keys                 = []string{"key-0", "key-1", "key-2", "key-3"}
+		val, otherVal string = "0123456789", "abcdefghij"
+	)
+
+	storeAll := func(count int) {
+		for _, key := range keys[:count] {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+		}
+	}
+
+	Context("NewSizeBoundedMapCache", func() {
+		It("should return an error for a non-positive size limit", func() {
+			_, err := NewSizeBoundedMapCache(0, NewLruPolicy())
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+			_, err = NewSizeBoundedMapCache(-1, NewLruPolicy())
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+	})
+
+	Context("with an lru policy", func() {
+		BeforeEach(func() {
+			var err error
+			c, err = NewSizeBoundedMapCache(maxBytes, NewLruPolicy())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should store values that fit", func() {
+			storeAll(3)
+			Expect(c.Keys()).To(HaveLen(3))
+			Expect(c.(*sizeBoundedMapCache).Size()).To(Equal(int64(maxBytes)))
+		})
+
+		It("should evict the least recently used value when a new value does not fit", func() {
+			storeAll(3)
+			Expect(c.Get(keys[0])).To(Equal(val))
+			Expect(c.Store(keys[3], val)).ToNot(HaveOccurred())
+
+			Expect(c.Contains(keys[1])).To(BeFalse())
+			Expect(c.Contains(keys[0])).To(BeTrue())
+			Expect(c.(*sizeBoundedMapCache).Size()).To(Equal(int64(maxBytes)))
+		})
+
+		It("should evict several values to make room for a large value", func() {
+			storeAll(3)
+			Expect(c.Store(keys[3], val+val)).ToNot(HaveOccurred())
+
+			Expect(c.Contains(keys[0])).To(BeFalse())
+			Expect(c.Contains(keys[1])).To(BeFalse())
+			Expect(c.Contains(keys[2])).To(BeTrue())
+		})
+
+		It("should evict other values when a replaced value grows", func() {
+			storeAll(3)
+			Expect(c.Replace(keys[2], val+val)).ToNot(HaveOccurred())
+
+			Expect(c.Get(keys[2])).To(Equal(val + val))
+			Expect(c.Contains(keys[0])).To(BeFalse())
+			Expect(c.Contains(keys[1])).To(BeTrue())
+		})
+
+		It("should return an error for a value that exceeds the limit", func() {
+			storeAll(1)
+			Expect(IsCapacityExceeded(c.Store(keys[1], val+val+val+val))).To(BeTrue())
+			Expect(IsCapacityExceeded(c.Replace(keys[0], val+val+val+val))).To(BeTrue())
+			Expect(c.Get(keys[0])).To(Equal(val))
+		})
+
+		It("should return an error for a value that cannot be encoded as json", func() {
+			Expect(IsInvalidValueType(c.Store(keys[0], make(chan int)))).To(BeTrue())
+		})
+
+		It("should free the space of removed values", func() {
+			storeAll(3)
+			Expect(c.Remove(keys[0])).ToNot(HaveOccurred())
+			Expect(c.Store(keys[3], otherVal)).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(HaveLen(3))
+		})
+
+		It("should forget all values on clear", func() {
+			storeAll(3)
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.(*sizeBoundedMapCache).Size()).To(BeZero())
+			storeAll(3)
+			Expect(c.Keys()).To(HaveLen(3))
+		})
+	})
+
+	Context("with an lfu policy", func() {
+		BeforeEach(func() {
+			var err error
+			c, err = NewSizeBoundedMapCache(maxBytes, NewLfuPolicy())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should evict the least frequently used value when a new value does not fit", func() {
+			storeAll(3)
+			for _, key := range []string{keys[0], keys[0], keys[1], keys[2], keys[2]} {
+				Expect(c.Get(key)).To(Equal(val))
+			}
+
+			Expect(c.Store(keys[3], val)).ToNot(HaveOccurred())
+
+			Expect(c.Contains(keys[1])).To(BeFalse())
+			Expect(c.Contains(keys[0])).To(BeTrue())
+			Expect(c.Contains(keys[2])).To(BeTrue())
+		})
+
+		It("should evict the least frequently used value after a value is removed", func() {
+			storeAll(3)
+			for _, key := range []string{keys[0], keys[1], keys[1], keys[2], keys[1], keys[1]} {
+				Expect(c.Get(key)).To(Equal(val))
+			}
+
+			Expect(c.Remove(keys[2])).ToNot(HaveOccurred())
+
+			// Every store evicts the newest value, once the cache is full.
+			for i := 3; i < 6; i++ {
+				Expect(c.Store(fmt.Sprintf("key-%d", i), val)).ToNot(HaveOccurred())
+			}
+			Expect(c.Keys()).To(ConsistOf(keys[0], keys[1], "key-5"))
+		})
+	})
+})
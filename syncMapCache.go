@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Holds the value of a key in a syncMapCache, the value is swapped atomically
+// so that Replace cannot resurrect a key that was removed concurrently.
+type syncMapEntry struct {
+	// Holds a *syncMapValue.
+	val atomic.Value
+}
+
+type syncMapValue struct {
+	val interface{}
+
+	// Whether the entry was removed from the map.
+	removed bool
+}
+
+func newSyncMapEntry(val interface{}) *syncMapEntry {
+	e := &syncMapEntry{}
+	e.val.Store(&syncMapValue{val: val})
+
+	return e
+}
+
+func (e *syncMapEntry) load() *syncMapValue {
+	return e.val.Load().(*syncMapValue)
+}
+
+// Mark the entry as removed, returns the last value and false if it was
+// already removed.
+func (e *syncMapEntry) remove() (interface{}, bool) {
+	prev := e.val.Swap(&syncMapValue{removed: true}).(*syncMapValue)
+
+	return prev.val, !prev.removed
+}
+
+type syncMapCache struct {
+	// Holds a *syncMapEntry for each key in the cache.
+	entries sync.Map
+}
+
+var _ Cache = (*syncMapCache)(nil)
+
+// NewSyncMapCache creates a new Cache object that is backed by a sync.Map.
+//
+// It outperforms NewMapCache when most operations are reads of a stable set
+// of keys, and is slower when keys are stored and removed frequently.
+func NewSyncMapCache() Cache {
+	return &syncMapCache{}
+}
+
+// Store a permanent value in the map.
+func (smc *syncMapCache) Store(key, val interface{}) error {
+	_, loaded := smc.entries.LoadOrStore(key, newSyncMapEntry(val))
+	if loaded {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	return nil
+}
+
+// Get a value from the map.
+func (smc *syncMapCache) Get(key interface{}) (interface{}, error) {
+	e, exists := smc.entries.Load(key)
+	if exists {
+		v := e.(*syncMapEntry).load()
+		if !v.removed {
+			return v.val, nil
+		}
+	}
+
+	return nil, newError(errorTypeDoesNotExist,
+		fmt.Sprintf("key %v does not exist", key))
+}
+
+// Check whether a key exists in the map.
+func (smc *syncMapCache) Contains(key interface{}) (bool, error) {
+	_, err := smc.Get(key)
+	if IsDoesNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Get a value from the map, or store val if the key does not exist.
+func (smc *syncMapCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	for {
+		e, loaded := smc.entries.LoadOrStore(key, newSyncMapEntry(val))
+		if !loaded {
+			return val, false, nil
+		}
+
+		v := e.(*syncMapEntry).load()
+		if !v.removed {
+			return v.val, true, nil
+		}
+
+		// The entry was removed right after it was loaded, entries are
+		// deleted from the map before they are marked as removed.
+	}
+}
+
+// Remove a value from the map.
+func (smc *syncMapCache) Remove(key interface{}) error {
+	_, err := smc.GetAndRemove(key)
+
+	return err
+}
+
+// Get a value from the map and remove it.
+func (smc *syncMapCache) GetAndRemove(key interface{}) (interface{}, error) {
+	e, exists := smc.entries.LoadAndDelete(key)
+	if exists {
+		val, removed := e.(*syncMapEntry).remove()
+		if removed {
+			return val, nil
+		}
+	}
+
+	return nil, newError(errorTypeDoesNotExist,
+		fmt.Sprintf("key %v does not exist", key))
+}
+
+// Replace a value in the map.
+func (smc *syncMapCache) Replace(key, val interface{}) error {
+	e, exists := smc.entries.Load(key)
+	if exists {
+		entry := e.(*syncMapEntry)
+
+		for {
+			curr := entry.load()
+			if curr.removed {
+				break
+			}
+
+			if entry.val.CompareAndSwap(curr, &syncMapValue{val: val}) {
+				return nil
+			}
+		}
+	}
+
+	return newError(errorTypeDoesNotExist,
+		fmt.Sprintf("key %v does not exist", key))
+}
+
+// Store a permanent value in the map, replacing the current value if the key
+// exists.
+func (smc *syncMapCache) StoreOrReplace(key, val interface{}) error {
+	for {
+		err := smc.Replace(key, val)
+		if !IsDoesNotExist(err) {
+			return err
+		}
+
+		err = smc.Store(key, val)
+		if !IsAlreadyExists(err) {
+			return err
+		}
+	}
+}
+
+// Store several permanent values in the map.
+func (smc *syncMapCache) StoreMany(items map[interface{}]interface{}) error {
+	return storeMany(items, smc.Store)
+}
+
+// Get several values from the map.
+func (smc *syncMapCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	return getMany(keys, smc.Get)
+}
+
+// Remove all values from the map.
+func (smc *syncMapCache) Clear() error {
+	smc.entries.Range(func(key, _ interface{}) bool {
+		if e, exists := smc.entries.LoadAndDelete(key); exists {
+			e.(*syncMapEntry).remove()
+		}
+
+		return true
+	})
+
+	return nil
+}
+
+// Get all keys in the map.
+func (smc *syncMapCache) Keys() ([]interface{}, error) {
+	keys := []interface{}{}
+
+	smc.entries.Range(func(key, e interface{}) bool {
+		if !e.(*syncMapEntry).load().removed {
+			keys = append(keys, key)
+		}
+
+		return true
+	})
+
+	return keys, nil
+}
+
+// Calls fn for each key and value in the map until fn returns false, keys
+// that are stored or removed while iterating may or may not be visited.
+func (smc *syncMapCache) ForEach(fn func(key, val interface{}) bool) error {
+	smc.entries.Range(func(key, e interface{}) bool {
+		v := e.(*syncMapEntry).load()
+		if v.removed {
+			return true
+		}
+
+		return fn(key, v.val)
+	})
+
+	return nil
+}
+
+// Get the keys for which predicate returns true.
+func (smc *syncMapCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	keys := []interface{}{}
+
+	smc.entries.Range(func(key, e interface{}) bool {
+		if !e.(*syncMapEntry).load().removed && predicate(key) {
+			keys = append(keys, key)
+		}
+
+		return true
+	})
+
+	return keys, nil
+}
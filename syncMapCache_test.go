@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sync Map Cache", func() {
+	var (
+		c                        Cache
+		key, val, nonExistentKey string = "test-key", "test-val", "non-existent"
+	)
+
+	BeforeEach(func() {
+		c = NewSyncMapCache()
+	})
+
+	Context("Store", func() {
+		It("should store a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store(key, val))).To(BeTrue())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+
+		It("should return an error when attempting to remove a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Remove(nonExistentKey))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new-val"))
+		})
+
+		It("should return an error when attempting to replace a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Replace(nonExistentKey, val))).To(BeTrue())
+		})
+
+		It("should not store a value that is removed concurrently", func() {
+			for i := 0; i < 100; i++ {
+				Expect(c.Store(key, val)).ToNot(HaveOccurred())
+
+				var wg sync.WaitGroup
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					c.Replace(key, "new-val")
+				}()
+				go func() {
+					defer wg.Done()
+					Expect(c.Remove(key)).ToNot(HaveOccurred())
+				}()
+				wg.Wait()
+
+				Expect(c.Contains(key)).To(BeFalse())
+			}
+		})
+	})
+
+	Context("GetOrStore", func() {
+		It("should only store the first value", func() {
+			actual, loaded, err := c.GetOrStore(key, val)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeFalse())
+			Expect(actual).To(Equal(val))
+
+			actual, loaded, err = c.GetOrStore(key, "new-val")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeTrue())
+			Expect(actual).To(Equal(val))
+		})
+	})
+
+	Context("StoreOrReplace", func() {
+		It("should store a value or replace the current one", func() {
+			Expect(c.StoreOrReplace(key, val)).ToNot(HaveOccurred())
+			Expect(c.StoreOrReplace(key, "new-val")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new-val"))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove all values", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2})).ToNot(HaveOccurred())
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return all keys", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2})).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(ConsistOf("a", "b"))
+		})
+	})
+
+	Context("ForEach", func() {
+		It("should stop when fn returns false", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2})).ToNot(HaveOccurred())
+
+			calls := 0
+			Expect(c.ForEach(func(key, val interface{}) bool {
+				calls++
+				return false
+			})).ToNot(HaveOccurred())
+			Expect(calls).To(Equal(1))
+		})
+	})
+})
+
+// Runs a parallel workload in which readPercent of the operations are reads
+// of a fixed set of keys and the rest replace them.
+func benchmarkCacheReadRatio(b *testing.B, c Cache, readPercent int) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		if err := c.Store(keys[i], i); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%100 < readPercent {
+				_, _ = c.Get(key)
+			} else {
+				_ = c.Replace(key, i)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMapCacheReads90(b *testing.B) {
+	benchmarkCacheReadRatio(b, NewMapCache(), 90)
+}
+
+func BenchmarkSyncMapCacheReads90(b *testing.B) {
+	benchmarkCacheReadRatio(b, NewSyncMapCache(), 90)
+}
+
+func BenchmarkMapCacheReads50(b *testing.B) {
+	benchmarkCacheReadRatio(b, NewMapCache(), 50)
+}
+
+func BenchmarkSyncMapCacheReads50(b *testing.B) {
+	benchmarkCacheReadRatio(b, NewSyncMapCache(), 50)
+}
+
+func BenchmarkMapCacheReads10(b *testing.B) {
+	benchmarkCacheReadRatio(b, NewMapCache(), 10)
+}
+
+func BenchmarkSyncMapCacheReads10(b *testing.B) {
+	benchmarkCacheReadRatio(b, NewSyncMapCache(), 10)
+}
@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"time"
+)
+
+// NewTTLMapCache creates a new ExpiringCache object that is backed by a map,
+// in which every value is removed after defaultTTL unless it is stored with
+// its own ttl. It is a shorthand for NewMapCache(WithDefaultTTL(defaultTTL)).
+//
+// defaultTTL must be greater than zero, otherwise storing a value without a
+// ttl fails.
+func NewTTLMapCache(defaultTTL time.Duration) ExpiringCache {
+	return NewMapCache(WithDefaultTTL(defaultTTL))
+}
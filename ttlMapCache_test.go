@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TTL Map Cache", func() {
+	var (
+		c        ExpiringCache
+		key, val string = "test-key", "test-val"
+	)
+
+	BeforeEach(func() {
+		c = NewTTLMapCache(500 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		Expect(c.Clear()).ToNot(HaveOccurred())
+	})
+
+	Context("Store", func() {
+		It("should remove a value after the default ttl", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+
+			_, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+
+			Eventually(func() bool {
+				exists, _ := c.Contains(key)
+				return exists
+			}, testTimeout).Should(BeFalse())
+		})
+
+		It("should return an error when the default ttl is not positive", func() {
+			Expect(IsNonPositivePeriod(NewTTLMapCache(0).Store(key, val))).To(BeTrue())
+		})
+	})
+
+	Context("StoreWithExpiration", func() {
+		It("should override the default ttl", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Hour)).ToNot(HaveOccurred())
+
+			remaining, _, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining).To(BeNumerically(">", time.Minute))
+		})
+	})
+
+	Context("Replace", func() {
+		It("should keep the replaced value temporary", func() {
+			Expect(c.StoreWithExpiration(key, val, time.Hour)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+
+			remaining, hasTTL, err := c.TTL(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hasTTL).To(BeTrue())
+			Expect(remaining).To(BeNumerically("<=", 500*time.Millisecond))
+		})
+	})
+
+	Context("StoreMany", func() {
+		It("should store temporary values", func() {
+			Expect(c.StoreMany(map[interface{}]interface{}{"a": 1, "b": 2})).ToNot(HaveOccurred())
+
+			for _, k := range []string{"a", "b"} {
+				_, hasTTL, err := c.TTL(k)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hasTTL).To(BeTrue())
+			}
+		})
+	})
+})
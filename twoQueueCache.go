@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+)
+
+type twoQueueCache struct {
+	// A first in first out queue of keys that were accessed once.
+	recent *lruCache
+
+	// A least recently used queue of keys that were accessed more than once.
+	frequent *lruCache
+
+	mutex sync.Mutex
+}
+
+var _ Cache = (*twoQueueCache)(nil)
+
+// NewTwoQueueCache creates a new 2Q cache using mapCache, recentRatio is the
+// portion of the capacity that is reserved for keys that were accessed once.
+//
+// capacity must be greater than zero, each of the queues holds at least one
+// item.
+func NewTwoQueueCache(capacity int, recentRatio float64) (Cache, error) {
+	if capacity < 1 {
+		return nil, newError(errorTypeNonPositivePeriod, "capacity must be greater than zero")
+	}
+
+	recentCapacity := int(float64(capacity) * recentRatio)
+	if recentCapacity < 1 {
+		recentCapacity = 1
+	}
+
+	frequentCapacity := capacity - recentCapacity
+	if frequentCapacity < 1 {
+		frequentCapacity = 1
+	}
+
+	return &twoQueueCache{
+		recent:   newLru(recentCapacity, NewMapCache(), nil),
+		frequent: newLru(frequentCapacity, NewMapCache(), nil),
+	}, nil
+}
+
+// Store caches a new value in the recent queue.
+func (tq *twoQueueCache) Store(key, val interface{}) error {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.store(key, val)
+}
+
+func (tq *twoQueueCache) store(key, val interface{}) error {
+	exists, err := tq.contains(key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	return tq.recent.Store(key, val)
+}
+
+// Get a cached value, a value from the recent queue is promoted to the
+// frequent queue.
+func (tq *twoQueueCache) Get(key interface{}) (interface{}, error) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.get(key)
+}
+
+func (tq *twoQueueCache) get(key interface{}) (interface{}, error) {
+	val, err := tq.frequent.Get(key)
+	if err == nil || !IsDoesNotExist(err) {
+		return val, err
+	}
+
+	// Peeking keeps the insertion order of the recent queue.
+	val, err = tq.recent.Peek(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tq.recent.Remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tq.frequent.Store(key, val)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Check whether a key is cached in either of the queues.
+func (tq *twoQueueCache) Contains(key interface{}) (bool, error) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.contains(key)
+}
+
+func (tq *twoQueueCache) contains(key interface{}) (bool, error) {
+	exists, err := tq.frequent.Contains(key)
+	if err != nil || exists {
+		return exists, err
+	}
+
+	return tq.recent.Contains(key)
+}
+
+// Get a cached value, or cache val if the key does not exist.
+func (tq *twoQueueCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.getOrStore(key, val)
+}
+
+func (tq *twoQueueCache) getOrStore(key, val interface{}) (interface{}, bool, error) {
+	actual, err := tq.get(key)
+	if err == nil {
+		return actual, true, nil
+	}
+
+	if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = tq.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Remove a cached value.
+func (tq *twoQueueCache) Remove(key interface{}) error {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.remove(key)
+}
+
+func (tq *twoQueueCache) remove(key interface{}) error {
+	err := tq.frequent.Remove(key)
+	if err == nil || !IsDoesNotExist(err) {
+		return err
+	}
+
+	return tq.recent.Remove(key)
+}
+
+// Get a cached value and remove it.
+func (tq *twoQueueCache) GetAndRemove(key interface{}) (interface{}, error) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.getAndRemove(key)
+}
+
+func (tq *twoQueueCache) getAndRemove(key interface{}) (interface{}, error) {
+	val, err := tq.frequent.GetAndRemove(key)
+	if err == nil || !IsDoesNotExist(err) {
+		return val, err
+	}
+
+	return tq.recent.GetAndRemove(key)
+}
+
+// Replace a cached value, the value stays in its current queue.
+func (tq *twoQueueCache) Replace(key, val interface{}) error {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.replace(key, val)
+}
+
+func (tq *twoQueueCache) replace(key, val interface{}) error {
+	err := tq.frequent.Replace(key, val)
+	if err == nil || !IsDoesNotExist(err) {
+		return err
+	}
+
+	return tq.recent.Replace(key, val)
+}
+
+// Cache a value, replacing the current value of the key if it exists, in
+// which case it keeps its place in the queues.
+func (tq *twoQueueCache) StoreOrReplace(key, val interface{}) error {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	err := tq.replace(key, val)
+	if IsDoesNotExist(err) {
+		return tq.store(key, val)
+	}
+
+	return err
+}
+
+// Store several values in the recent queue.
+func (tq *twoQueueCache) StoreMany(items map[interface{}]interface{}) error {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return storeMany(items, tq.store)
+}
+
+// Get several cached values.
+func (tq *twoQueueCache) GetMany(keys []interface{}) (map[interface{}]interface{}, error) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return getMany(keys, tq.get)
+}
+
+// Clear all values from both queues.
+func (tq *twoQueueCache) Clear() error {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.clear()
+}
+
+func (tq *twoQueueCache) clear() error {
+	err := tq.recent.Clear()
+	if err != nil {
+		return err
+	}
+
+	return tq.frequent.Clear()
+}
+
+// Get all keys from both queues.
+func (tq *twoQueueCache) Keys() ([]interface{}, error) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	return tq.keys()
+}
+
+// Calls fn for each key and value in both queues until fn returns false.
+func (tq *twoQueueCache) ForEach(fn func(key, val interface{}) bool) error {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	proceed := true
+	err := tq.recent.ForEach(func(key, val interface{}) bool {
+		proceed = fn(key, val)
+		return proceed
+	})
+	if err != nil || !proceed {
+		return err
+	}
+
+	return tq.frequent.ForEach(fn)
+}
+
+// Get the keys for which predicate returns true.
+func (tq *twoQueueCache) FilterKeys(predicate func(key interface{}) bool) ([]interface{}, error) {
+	tq.mutex.Lock()
+	defer tq.mutex.Unlock()
+
+	keys, err := tq.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterKeys(keys, predicate), nil
+}
+
+func (tq *twoQueueCache) keys() ([]interface{}, error) {
+	recentKeys, err := tq.recent.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	frequentKeys, err := tq.frequent.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(recentKeys, frequentKeys...), nil
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Two Queue Cache", func() {
+	const (
+		capacity    = 10
+		recentRatio = 0.25
+	)
+
+	var (
+		c        Cache
+		key, val string = "test-key", "test-val"
+	)
+
+	BeforeEach(func() {
+		var err error
+		c, err = NewTwoQueueCache(capacity, recentRatio)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("NewTwoQueueCache", func() {
+		It("should return an error for a non-positive capacity", func() {
+			_, err := NewTwoQueueCache(0, recentRatio)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+			_, err = NewTwoQueueCache(-1, recentRatio)
+			Expect(IsNonPositivePeriod(err)).To(BeTrue())
+		})
+	})
+
+	Context("Store", func() {
+		It("should store a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred(), "failed storing a value")
+			Expect(c.Get(key)).To(Equal(val))
+		})
+
+		It("should return an error when attempting to override a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(IsAlreadyExists(c.Store(key, val))).To(BeTrue())
+		})
+	})
+
+	Context("Get", func() {
+		It("should return an error when accessing a key that does not exist", func() {
+			_, err := c.Get("non-existent-key")
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+
+		It("should not let a single access scan evict frequently used values", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+
+			for i := 0; i < 5*capacity; i++ {
+				Expect(c.Store(fmt.Sprintf("scan-key-%d", i), i)).ToNot(HaveOccurred())
+			}
+
+			Expect(c.Get(key)).To(Equal(val), "frequent value was evicted by a scan")
+			Expect(c.Contains("scan-key-0")).To(BeFalse(), "old scan value was not evicted")
+		})
+	})
+
+	Context("Remove", func() {
+		It("should remove a value from the recent queue", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+
+		It("should remove a value from the frequent queue", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Remove(key)).ToNot(HaveOccurred())
+			Expect(c.Contains(key)).To(BeFalse())
+		})
+
+		It("should return an error when attempting to remove a non-existent value", func() {
+			Expect(IsDoesNotExist(c.Remove(key))).To(BeTrue())
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, "new-val")).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal("new-val"))
+		})
+	})
+
+	Context("Clear", func() {
+		It("should remove all values", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Store("other-key", val)).ToNot(HaveOccurred())
+
+			Expect(c.Clear()).ToNot(HaveOccurred())
+			Expect(c.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return the keys of both queues", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(val))
+			Expect(c.Store("other-key", val)).ToNot(HaveOccurred())
+
+			Expect(c.Keys()).To(ConsistOf(key, "other-key"))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypedCache is a type-safe wrapper over a Cache.
+type TypedCache[K comparable, V any] struct {
+	// The wrapped cache that holds the data.
+	storage Cache
+}
+
+// NewTypedCache creates a new TypedCache that delegates to the given cache.
+func NewTypedCache[K comparable, V any](cache Cache) *TypedCache[K, V] {
+	return &TypedCache[K, V]{
+		storage: cache,
+	}
+}
+
+// Store a permanent value.
+func (tc *TypedCache[K, V]) Store(key K, val V) error {
+	return tc.storage.Store(key, val)
+}
+
+// Get a value.
+func (tc *TypedCache[K, V]) Get(key K) (V, error) {
+	val, err := tc.storage.Get(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	return tc.assertValue(val)
+}
+
+// Check whether a key exists.
+func (tc *TypedCache[K, V]) Contains(key K) (bool, error) {
+	return tc.storage.Contains(key)
+}
+
+// Get a value, or store val if the key does not exist.
+func (tc *TypedCache[K, V]) GetOrStore(key K, val V) (V, bool, error) {
+	actual, loaded, err := tc.storage.GetOrStore(key, val)
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+
+	typedActual, err := tc.assertValue(actual)
+	if err != nil {
+		return typedActual, false, err
+	}
+
+	return typedActual, loaded, nil
+}
+
+// Remove a value.
+func (tc *TypedCache[K, V]) Remove(key K) error {
+	return tc.storage.Remove(key)
+}
+
+// Get a value and remove it.
+func (tc *TypedCache[K, V]) GetAndRemove(key K) (V, error) {
+	val, err := tc.storage.GetAndRemove(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	return tc.assertValue(val)
+}
+
+// Replace a value.
+func (tc *TypedCache[K, V]) Replace(key K, val V) error {
+	return tc.storage.Replace(key, val)
+}
+
+// Store a value permanently, replacing the current value if the key already
+// exists.
+func (tc *TypedCache[K, V]) StoreOrReplace(key K, val V) error {
+	return tc.storage.StoreOrReplace(key, val)
+}
+
+// Store several permanent values.
+func (tc *TypedCache[K, V]) StoreMany(items map[K]V) error {
+	untypedItems := map[interface{}]interface{}{}
+	for key, val := range items {
+		untypedItems[key] = val
+	}
+
+	return tc.storage.StoreMany(untypedItems)
+}
+
+// Get several values, the values that were found are returned even if
+// getting some of the others failed.
+func (tc *TypedCache[K, V]) GetMany(keys []K) (map[K]V, error) {
+	untypedKeys := []interface{}{}
+	for _, key := range keys {
+		untypedKeys = append(untypedKeys, key)
+	}
+
+	untypedVals, err := tc.storage.GetMany(untypedKeys)
+
+	vals := map[K]V{}
+	for key, val := range untypedVals {
+		typedVal, assertErr := tc.assertValue(val)
+		if assertErr != nil {
+			return vals, assertErr
+		}
+
+		vals[key.(K)] = typedVal
+	}
+
+	return vals, err
+}
+
+// Clear the cache.
+func (tc *TypedCache[K, V]) Clear() error {
+	return tc.storage.Clear()
+}
+
+// Get all keys from the cache.
+func (tc *TypedCache[K, V]) Keys() ([]K, error) {
+	keys, err := tc.storage.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	typedKeys := []K{}
+	for _, key := range keys {
+		typedKey, ok := key.(K)
+		if !ok {
+			return nil, newError(errorTypeInvalidKeyType,
+				fmt.Sprintf("invalid key type, expected: [%s] found: [%s]",
+					reflect.TypeOf((*K)(nil)).Elem().String(),
+					fmt.Sprintf("%T", key)))
+		}
+
+		typedKeys = append(typedKeys, typedKey)
+	}
+
+	return typedKeys, nil
+}
+
+// Calls fn for each key and value in the cache until fn returns false.
+func (tc *TypedCache[K, V]) ForEach(fn func(key K, val V) bool) error {
+	var err error
+
+	iterErr := tc.storage.ForEach(func(key, val interface{}) bool {
+		typedKey, isKey := key.(K)
+		if !isKey {
+			err = newError(errorTypeInvalidKeyType,
+				fmt.Sprintf("invalid key type, expected: [%s] found: [%s]",
+					reflect.TypeOf((*K)(nil)).Elem().String(),
+					fmt.Sprintf("%T", key)))
+			return false
+		}
+
+		typedVal, assertErr := tc.assertValue(val)
+		if assertErr != nil {
+			err = assertErr
+			return false
+		}
+
+		return fn(typedKey, typedVal)
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+
+	return err
+}
+
+func (tc *TypedCache[K, V]) assertValue(val interface{}) (V, error) {
+	typedVal, ok := val.(V)
+	if !ok {
+		var zero V
+		return zero, newError(errorTypeInvalidValueType,
+			fmt.Sprintf("invalid value type, expected: [%s] found: [%s]",
+				reflect.TypeOf((*V)(nil)).Elem().String(),
+				fmt.Sprintf("%T", val)))
+	}
+
+	return typedVal, nil
+}
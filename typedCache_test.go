@@ -0,0 +1,82 @@
+package cache
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Typed Cache", func() {
+	var (
+		c        *TypedCache[string, testStruct]
+		storage  Cache
+		key      string     = "test-key"
+		val      testStruct = testStruct{"Test", 1}
+		otherVal string     = "not-a-test-struct"
+	)
+
+	BeforeEach(func() {
+		storage = NewMapCache()
+		c = NewTypedCache[string, testStruct](storage)
+	})
+
+	Context("Store", func() {
+		It("should store a typed value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			v, err := c.Get(key)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(v).To(Equal(val))
+		})
+	})
+
+	Context("Get", func() {
+		It("should return an error when the stored value has a different type", func() {
+			Expect(storage.Store(key, otherVal)).ToNot(HaveOccurred())
+			_, err := c.Get(key)
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+		})
+
+		It("should return an error when the stored value is nil", func() {
+			Expect(storage.Store(key, nil)).ToNot(HaveOccurred())
+			_, err := c.Get(key)
+			Expect(IsInvalidValueType(err)).To(BeTrue())
+		})
+
+		It("should return an error when the key does not exist", func() {
+			_, err := c.Get(key)
+			Expect(IsDoesNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("GetOrStore", func() {
+		It("should return the existing typed value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			actual, loaded, err := c.GetOrStore(key, testStruct{"New", 2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(BeTrue())
+			Expect(actual).To(Equal(val))
+		})
+	})
+
+	Context("Replace", func() {
+		It("should replace a typed value", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			Expect(c.Replace(key, testStruct{"New", 2})).ToNot(HaveOccurred())
+			Expect(c.Get(key)).To(Equal(testStruct{"New", 2}))
+		})
+	})
+
+	Context("Keys", func() {
+		It("should return typed keys", func() {
+			Expect(c.Store(key, val)).ToNot(HaveOccurred())
+			keys, err := c.Keys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{key}))
+		})
+
+		It("should return an error when a key has a different type", func() {
+			Expect(storage.Store(1, val)).ToNot(HaveOccurred())
+			_, err := c.Keys()
+			Expect(IsInvalidKeyType(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type writeBehindCache struct {
+	Cache
+
+	// Writes the dirty values to the backend.
+	writer func(key, val interface{}) error
+
+	// Holds the values that were not written to the backend yet.
+	dirty map[interface{}]interface{}
+
+	// Called with the errors of the writer.
+	onFlushError func(key interface{}, err error)
+
+	// Stops the flush routine.
+	stop chan struct{}
+
+	// Makes writing a value and marking it as dirty atomic.
+	mutex sync.Mutex
+
+	// Prevents concurrent flushes from writing the same values.
+	flushMutex sync.Mutex
+
+	closeOnce sync.Once
+}
+
+var _ Cache = (*writeBehindCache)(nil)
+
+// NewWriteBehindCache creates a new Cache object that stores values in
+// underlying immediately and writes them with writer every flushInterval.
+//
+// Values that fail to be written stay dirty and are written again on the next
+// flush, values that are removed before they are flushed are not written. If
+// flushInterval is not positive, values are only written by Flush and Close.
+func NewWriteBehindCache(underlying Cache, writer func(key, val interface{}) error,
+	flushInterval time.Duration) *writeBehindCache {
+	wbc := &writeBehindCache{
+		Cache:  underlying,
+		writer: writer,
+		dirty:  map[interface{}]interface{}{},
+		stop:   make(chan struct{}),
+	}
+
+	if flushInterval > 0 {
+		go wbc.flushRoutine(flushInterval)
+	}
+
+	return wbc
+}
+
+func (wbc *writeBehindCache) flushRoutine(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			wbc.Flush()
+		case <-wbc.stop:
+			return
+		}
+	}
+}
+
+// OnFlushError sets a function that is called whenever writing a value to the
+// backend fails.
+func (wbc *writeBehindCache) OnFlushError(onFlushError func(key interface{}, err error)) {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	wbc.onFlushError = onFlushError
+}
+
+// Flush writes all the dirty values to the backend, the keys that are not
+// mentioned in the returned error were written successfully.
+func (wbc *writeBehindCache) Flush() error {
+	wbc.flushMutex.Lock()
+	defer wbc.flushMutex.Unlock()
+
+	wbc.mutex.Lock()
+	dirty := wbc.dirty
+	wbc.dirty = map[interface{}]interface{}{}
+	onFlushError := wbc.onFlushError
+	wbc.mutex.Unlock()
+
+	// The writer is called without holding the mutex so that a slow backend
+	// does not block the cache.
+	errs := map[interface{}]error{}
+	for key, val := range dirty {
+		err := wbc.writer(key, val)
+		if err != nil {
+			errs[key] = err
+		}
+	}
+
+	wbc.mutex.Lock()
+	for key := range errs {
+		// A value that was written or removed during the flush is newer.
+		if _, isDirty := wbc.dirty[key]; !isDirty {
+			if exists, _ := wbc.Cache.Contains(key); exists {
+				wbc.dirty[key] = dirty[key]
+			}
+		}
+	}
+	wbc.mutex.Unlock()
+
+	if onFlushError != nil {
+		for key, err := range errs {
+			onFlushError(key, err)
+		}
+	}
+
+	return combineErrors(errs)
+}
+
+// Close flushes the dirty values and stops the flush routine.
+func (wbc *writeBehindCache) Close() error {
+	wbc.closeOnce.Do(func() {
+		close(wbc.stop)
+	})
+
+	return wbc.Flush()
+}
+
+// Store a value in the cache, it is written to the backend on the next flush.
+func (wbc *writeBehindCache) Store(key, val interface{}) error {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	return wbc.store(key, val)
+}
+
+func (wbc *writeBehindCache) store(key, val interface{}) error {
+	err := wbc.Cache.Store(key, val)
+	if err != nil {
+		return err
+	}
+
+	wbc.dirty[key] = val
+
+	return nil
+}
+
+// Get a value, or store val if the key does not exist.
+func (wbc *writeBehindCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	actual, loaded, err := wbc.Cache.GetOrStore(key, val)
+	if err == nil && !loaded {
+		wbc.dirty[key] = val
+	}
+
+	return actual, loaded, err
+}
+
+// Remove a value from the cache, a value that was not flushed yet is not
+// written to the backend.
+func (wbc *writeBehindCache) Remove(key interface{}) error {
+	_, err := wbc.GetAndRemove(key)
+
+	return err
+}
+
+// Get a value and remove it from the cache.
+func (wbc *writeBehindCache) GetAndRemove(key interface{}) (interface{}, error) {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	val, err := wbc.Cache.GetAndRemove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(wbc.dirty, key)
+
+	return val, nil
+}
+
+// Replace a value in the cache, it is written to the backend on the next
+// flush.
+func (wbc *writeBehindCache) Replace(key, val interface{}) error {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	err := wbc.Cache.Replace(key, val)
+	if err != nil {
+		return err
+	}
+
+	wbc.dirty[key] = val
+
+	return nil
+}
+
+// Store a value in the cache, replacing the current value if the key exists.
+func (wbc *writeBehindCache) StoreOrReplace(key, val interface{}) error {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	err := wbc.Cache.StoreOrReplace(key, val)
+	if err != nil {
+		return err
+	}
+
+	wbc.dirty[key] = val
+
+	return nil
+}
+
+// Store several values in the cache.
+func (wbc *writeBehindCache) StoreMany(items map[interface{}]interface{}) error {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	return storeMany(items, wbc.store)
+}
+
+// Clear the cache, values that were not flushed yet are not written to the
+// backend.
+func (wbc *writeBehindCache) Clear() error {
+	wbc.mutex.Lock()
+	defer wbc.mutex.Unlock()
+
+	err := wbc.Cache.Clear()
+	if err != nil {
+		return err
+	}
+
+	wbc.dirty = map[interface{}]interface{}{}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Write Behind Cache", func() {
+	var (
+		c        *writeBehindCache
+		backend  map[interface{}]interface{}
+		writeErr error
+		mutex    sync.Mutex
+	)
+
+	written := func(key interface{}) interface{} {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return backend[key]
+	}
+
+	BeforeEach(func() {
+		backend = map[interface{}]interface{}{}
+		writeErr = nil
+		c = NewWriteBehindCache(NewMapCache(), func(key, val interface{}) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if writeErr != nil {
+				return writeErr
+			}
+
+			backend[key] = val
+			return nil
+		}, time.Hour)
+	})
+
+	AfterEach(func() {
+		mutex.Lock()
+		writeErr = nil
+		mutex.Unlock()
+
+		Expect(c.Close()).ToNot(HaveOccurred())
+	})
+
+	Context("Flush", func() {
+		It("should write the dirty values to the backend", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Get("key")).To(Equal("val"))
+			Expect(written("key")).To(BeNil())
+
+			Expect(c.Flush()).ToNot(HaveOccurred())
+			Expect(written("key")).To(Equal("val"))
+		})
+
+		It("should not write a removed value", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Remove("key")).ToNot(HaveOccurred())
+
+			Expect(c.Flush()).ToNot(HaveOccurred())
+			Expect(written("key")).To(BeNil())
+		})
+
+		It("should report and retry a value that failed to be written", func() {
+			errs := make(chan error, 1)
+			c.OnFlushError(func(key interface{}, err error) {
+				errs <- err
+			})
+
+			mutex.Lock()
+			writeErr = errors.New("write failed")
+			mutex.Unlock()
+
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(IsPartialFailure(c.Flush())).To(BeTrue())
+			Expect(errs).To(Receive(Equal(writeErr)))
+
+			mutex.Lock()
+			writeErr = nil
+			mutex.Unlock()
+
+			Expect(c.Flush()).ToNot(HaveOccurred())
+			Expect(written("key")).To(Equal("val"))
+		})
+	})
+
+	Context("NewWriteBehindCache", func() {
+		It("should flush periodically", func() {
+			pc := NewWriteBehindCache(NewMapCache(), func(key, val interface{}) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				backend[key] = val
+				return nil
+			}, 100*time.Millisecond)
+			defer pc.Close()
+
+			Expect(pc.Replace("key", "val")).To(HaveOccurred())
+			Expect(pc.StoreOrReplace("key", "val")).ToNot(HaveOccurred())
+			Eventually(func() interface{} {
+				return written("key")
+			}, testTimeout).Should(Equal("val"))
+		})
+	})
+})
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+)
+
+type writeThroughCache struct {
+	Cache
+
+	// Writes stored and replaced values to the backend.
+	writer func(key, val interface{}) error
+
+	// Deletes removed values from the backend, optional.
+	deleter func(key interface{}) error
+
+	// Makes checking a key and writing it atomic.
+	mutex sync.Mutex
+}
+
+var _ Cache = (*writeThroughCache)(nil)
+
+// WriteThroughOption configures a writeThroughCache.
+type WriteThroughOption func(*writeThroughCache)
+
+// WithDeleter sets a function that deletes removed values from the backend,
+// values are only removed from the cache by default.
+func WithDeleter(deleter func(key interface{}) error) WriteThroughOption {
+	return func(wtc *writeThroughCache) {
+		wtc.deleter = deleter
+	}
+}
+
+// NewWriteThroughCache creates a new Cache object that writes every stored or
+// replaced value with writer before storing it in underlying, a value that
+// writer fails to write is not stored.
+//
+// Clear only clears underlying.
+func NewWriteThroughCache(underlying Cache, writer func(key, val interface{}) error,
+	opts ...WriteThroughOption) Cache {
+	wtc := &writeThroughCache{
+		Cache:  underlying,
+		writer: writer,
+	}
+
+	for _, opt := range opts {
+		opt(wtc)
+	}
+
+	return wtc
+}
+
+// Write a value to the backend and store it in the cache.
+func (wtc *writeThroughCache) Store(key, val interface{}) error {
+	wtc.mutex.Lock()
+	defer wtc.mutex.Unlock()
+
+	return wtc.store(key, val)
+}
+
+func (wtc *writeThroughCache) store(key, val interface{}) error {
+	exists, err := wtc.Cache.Contains(key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return newError(errorTypeAlreadyExists,
+			fmt.Sprintf("key %v is already in use", key))
+	}
+
+	err = wtc.writer(key, val)
+	if err != nil {
+		return err
+	}
+
+	return wtc.Cache.Store(key, val)
+}
+
+// Get a value, or write val to the backend and store it if the key does not
+// exist.
+func (wtc *writeThroughCache) GetOrStore(key, val interface{}) (interface{}, bool, error) {
+	wtc.mutex.Lock()
+	defer wtc.mutex.Unlock()
+
+	actual, err := wtc.Cache.Get(key)
+	if err == nil {
+		return actual, true, nil
+	} else if !IsDoesNotExist(err) {
+		return nil, false, err
+	}
+
+	err = wtc.store(key, val)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, false, nil
+}
+
+// Delete a value from the backend and remove it from the cache.
+func (wtc *writeThroughCache) Remove(key interface{}) error {
+	_, err := wtc.GetAndRemove(key)
+
+	return err
+}
+
+// Get a value, delete it from the backend and remove it from the cache.
+func (wtc *writeThroughCache) GetAndRemove(key interface{}) (interface{}, error) {
+	wtc.mutex.Lock()
+	defer wtc.mutex.Unlock()
+
+	val, err := wtc.Cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if wtc.deleter != nil {
+		err = wtc.deleter(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = wtc.Cache.Remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Write a new value to the backend and replace it in the cache.
+func (wtc *writeThroughCache) Replace(key, val interface{}) error {
+	wtc.mutex.Lock()
+	defer wtc.mutex.Unlock()
+
+	exists, err := wtc.Cache.Contains(key)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return newError(errorTypeDoesNotExist,
+			fmt.Sprintf("key %v does not exist", key))
+	}
+
+	err = wtc.writer(key, val)
+	if err != nil {
+		return err
+	}
+
+	return wtc.Cache.Replace(key, val)
+}
+
+// Write a value to the backend and store it in the cache, replacing the
+// current value if the key exists.
+func (wtc *writeThroughCache) StoreOrReplace(key, val interface{}) error {
+	wtc.mutex.Lock()
+	defer wtc.mutex.Unlock()
+
+	err := wtc.writer(key, val)
+	if err != nil {
+		return err
+	}
+
+	return wtc.Cache.StoreOrReplace(key, val)
+}
+
+// Write several values to the backend and store them in the cache.
+func (wtc *writeThroughCache) StoreMany(items map[interface{}]interface{}) error {
+	wtc.mutex.Lock()
+	defer wtc.mutex.Unlock()
+
+	return storeMany(items, wtc.store)
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Write Through Cache", func() {
+	var (
+		c          Cache
+		underlying Cache
+		backend    map[interface{}]interface{}
+		writeErr   error
+	)
+
+	BeforeEach(func() {
+		backend = map[interface{}]interface{}{}
+		writeErr = nil
+		underlying = NewMapCache()
+		c = NewWriteThroughCache(underlying, func(key, val interface{}) error {
+			if writeErr != nil {
+				return writeErr
+			}
+
+			backend[key] = val
+			return nil
+		}, WithDeleter(func(key interface{}) error {
+			delete(backend, key)
+			return nil
+		}))
+	})
+
+	Context("Store", func() {
+		It("should write a value to the backend and the cache", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(backend).To(HaveKeyWithValue("key", "val"))
+			Expect(underlying.Get("key")).To(Equal("val"))
+		})
+
+		It("should not store a value that the writer failed to write", func() {
+			writeErr = errors.New("write failed")
+
+			Expect(c.Store("key", "val")).To(Equal(writeErr))
+			Expect(underlying.Contains("key")).To(BeFalse())
+		})
+
+		It("should not write an existing key", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(IsAlreadyExists(c.Store("key", "new-val"))).To(BeTrue())
+			Expect(backend).To(HaveKeyWithValue("key", "val"))
+		})
+	})
+
+	Context("Replace", func() {
+		It("should keep the current value when the writer fails", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			writeErr = errors.New("write failed")
+
+			Expect(c.Replace("key", "new-val")).To(Equal(writeErr))
+			Expect(underlying.Get("key")).To(Equal("val"))
+		})
+
+		It("should return an error for a non-existent key", func() {
+			Expect(IsDoesNotExist(c.Replace("key", "val"))).To(BeTrue())
+			Expect(backend).To(BeEmpty())
+		})
+	})
+
+	Context("Remove", func() {
+		It("should delete a value from the backend and the cache", func() {
+			Expect(c.Store("key", "val")).ToNot(HaveOccurred())
+			Expect(c.Remove("key")).ToNot(HaveOccurred())
+			Expect(backend).To(BeEmpty())
+			Expect(underlying.Contains("key")).To(BeFalse())
+		})
+	})
+})